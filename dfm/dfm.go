@@ -0,0 +1,275 @@
+// Package dfm checks boards and footprints against the capabilities of a
+// fabricator, described by a Profile.
+package dfm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/drc"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Rules checked in addition to those of the drc package.
+const (
+	// RuleDrill is used for holes smaller than MinDrill.
+	RuleDrill = "drill"
+	// RuleSlot is used for oval holes narrower than MinSlotWidth or
+	// shorter than MinSlotLength.
+	RuleSlot = "slot"
+	// RuleSilkWidth is used for silkscreen lines and text strokes
+	// narrower than MinSilkWidth.
+	RuleSilkWidth = "silk_width"
+	// RuleTextHeight is used for silkscreen text smaller than
+	// MinTextHeight.
+	RuleTextHeight = "text_height"
+	// RuleBoardSize is used for boards larger than the maximum size.
+	RuleBoardSize = "board_size"
+	// RuleLayerCount is used for boards with a number of copper layers
+	// which is not in LayerCounts.
+	RuleLayerCount = "layer_count"
+)
+
+// tolerance absorbs rounding when comparing dimensions against limits.
+const tolerance = 1e-5
+
+func fmtNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e3)/1e3, 'f', -1, 64)
+}
+
+type checker struct {
+	prof       *Profile
+	violations []drc.Violation
+}
+
+func (c *checker) addf(rule, elem, layer string, at pcb.XY, format string, args ...interface{}) {
+	c.violations = append(c.violations, drc.Violation{
+		Rule:    rule,
+		Element: elem,
+		Layer:   layer,
+		At:      pcb.XY{X: math.Round(at.X*1e3) / 1e3, Y: math.Round(at.Y*1e3) / 1e3},
+		Msg:     fmt.Sprintf(format, args...),
+	})
+}
+
+// copper runs the checks of the drc package with the limits of the profile.
+func (c *checker) copper(p *pcb.PCB, edgeClearance float64) {
+	for _, v := range drc.Check(p, drc.Options{
+		MinTrackWidth:  c.prof.MinTrackWidth,
+		MinAnnularRing: c.prof.MinAnnularRing,
+		MinHoleToHole:  c.prof.MinHoleToHole,
+		EdgeClearance:  edgeClearance,
+		MinClearance:   c.prof.MinClearance,
+	}) {
+		// Without a minimum, clearances would come from the net classes,
+		// which are a matter of design rather than fabrication.
+		if v.Rule == drc.RuleClearance && c.prof.MinClearance <= 0 {
+			continue
+		}
+		c.violations = append(c.violations, v)
+	}
+}
+
+func (c *checker) result() []drc.Violation {
+	out := c.violations
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Rule != out[j].Rule {
+			return out[i].Rule < out[j].Rule
+		}
+		if out[i].Element != out[j].Element {
+			return out[i].Element < out[j].Element
+		}
+		return out[i].Other < out[j].Other
+	})
+	return out
+}
+
+// Check checks a board against the profile. The result is sorted by rule
+// and element.
+func Check(p *pcb.PCB, prof *Profile) []drc.Violation {
+	c := &checker{prof: prof}
+	c.copper(p, prof.EdgeClearance)
+	c.layerCount(p)
+	c.boardSize(p)
+
+	drills := drc.ViaDrills(p)
+	for i, s := range p.Segments {
+		if v, ok := s.(*pcb.Via); ok && drills[v] > 0 {
+			c.drill(fmt.Sprintf("via[%d]", i), v.At, drills[v])
+		}
+	}
+	for i, d := range p.Drawings {
+		switch d := d.(type) {
+		case *pcb.Line:
+			c.silkLine(fmt.Sprintf("gr_line[%d]", i), d.Layer, d.Start, d.Width)
+		case *pcb.Arc:
+			c.silkLine(fmt.Sprintf("gr_arc[%d]", i), d.Layer, d.End, d.Width)
+		case *pcb.Text:
+			if !d.Hidden {
+				c.silkText(fmt.Sprintf("gr_text[%d]", i), d.Layer, pcb.XY{X: d.At.X, Y: d.At.Y}, &d.Effects)
+			}
+		}
+	}
+	for i := range p.Modules {
+		m := &p.Modules[i]
+		c.module(m, moduleElement(m, i)+"/")
+	}
+	return c.result()
+}
+
+// CheckModule checks a footprint against the profile, as it would be
+// placed on a board. Pads of the same number are taken to be connected,
+// and pads of different numbers must be kept apart.
+func CheckModule(m *pcb.Module, prof *Profile) []drc.Violation {
+	p := &pcb.PCB{
+		Nets:    map[int]pcb.Net{0: {}},
+		Modules: []pcb.Module{*m},
+	}
+	p.SetCopperLayers(2)
+
+	c := &checker{prof: prof}
+	c.copper(p, 0)
+	c.module(m, "")
+
+	// Elements are relative to the footprint.
+	prefix := moduleElement(m, 0) + "/"
+	for i := range c.violations {
+		v := &c.violations[i]
+		v.Element = strings.TrimPrefix(v.Element, prefix)
+		v.Other = strings.TrimPrefix(v.Other, prefix)
+		v.Msg = strings.Replace(v.Msg, prefix, "", -1)
+	}
+	return c.result()
+}
+
+func moduleElement(m *pcb.Module, i int) string {
+	if ref := m.Reference(); ref != "" {
+		return fmt.Sprintf("module[%s]", ref)
+	}
+	return fmt.Sprintf("module[%d]", i)
+}
+
+// module checks the holes and silkscreen of a module. Elements are named
+// with the given prefix.
+func (c *checker) module(m *pcb.Module, prefix string) {
+	for j := range m.Pads {
+		pad := &m.Pads[j]
+		elem := fmt.Sprintf("%spad[%d]", prefix, j)
+		if pad.Ident != "" {
+			elem = fmt.Sprintf("%spad[%s]", prefix, pad.Ident)
+		}
+		pos := m.PadPosition(pad)
+		at := pcb.XY{X: pos.X, Y: pos.Y}
+		size := pad.DrillSize
+		if size.X <= 0 {
+			continue
+		}
+		if pad.DrillShape != pcb.ShapeDrillOblong || size.Y <= 0 || size.Y == size.X {
+			c.drill(elem, at, size.X)
+			continue
+		}
+		width, length := math.Min(size.X, size.Y), math.Max(size.X, size.Y)
+		if c.prof.MinSlotWidth > 0 && width < c.prof.MinSlotWidth-tolerance {
+			c.addf(RuleSlot, elem, "", at, "slot width %s is less than %s", fmtNum(width), fmtNum(c.prof.MinSlotWidth))
+		}
+		if c.prof.MinSlotLength > 0 && length < c.prof.MinSlotLength-tolerance {
+			c.addf(RuleSlot, elem, "", at, "slot length %s is less than %s", fmtNum(length), fmtNum(c.prof.MinSlotLength))
+		}
+	}
+
+	for j, g := range m.Graphics {
+		elem := fmt.Sprintf("%sgraphics[%d]", prefix, j)
+		switch g := g.Renderable.(type) {
+		case *pcb.ModLine:
+			c.silkLine(elem, g.Layer, m.ToBoard(g.Start), g.Width)
+		case *pcb.ModArc:
+			c.silkLine(elem, g.Layer, m.ToBoard(g.End), g.Width)
+		case *pcb.ModCircle:
+			c.silkLine(elem, g.Layer, m.ToBoard(g.End), g.Width)
+		case *pcb.ModPolygon:
+			// Polygons without a width are filled.
+			if g.Width > 0 && len(g.Points) > 0 {
+				c.silkLine(elem, g.Layer, m.ToBoard(g.Points[0]), g.Width)
+			}
+		case *pcb.ModText:
+			if !g.Hidden {
+				c.silkText(elem, g.Layer, m.ToBoard(pcb.XY{X: g.At.X, Y: g.At.Y}), &g.Effects)
+			}
+		}
+	}
+}
+
+func (c *checker) drill(elem string, at pcb.XY, drill float64) {
+	if c.prof.MinDrill > 0 && drill < c.prof.MinDrill-tolerance {
+		c.addf(RuleDrill, elem, "", at, "drill %s is less than %s", fmtNum(drill), fmtNum(c.prof.MinDrill))
+	}
+}
+
+func isSilk(layer string) bool {
+	return layer == "F.SilkS" || layer == "B.SilkS"
+}
+
+func (c *checker) silkLine(elem, layer string, at pcb.XY, width float64) {
+	if isSilk(layer) && c.prof.MinSilkWidth > 0 && width < c.prof.MinSilkWidth-tolerance {
+		c.addf(RuleSilkWidth, elem, layer, at, "line width %s is less than %s", fmtNum(width), fmtNum(c.prof.MinSilkWidth))
+	}
+}
+
+func (c *checker) silkText(elem, layer string, at pcb.XY, e *pcb.TextEffects) {
+	if !isSilk(layer) {
+		return
+	}
+	if c.prof.MinSilkWidth > 0 && e.Thickness < c.prof.MinSilkWidth-tolerance {
+		c.addf(RuleSilkWidth, elem, layer, at, "text thickness %s is less than %s", fmtNum(e.Thickness), fmtNum(c.prof.MinSilkWidth))
+	}
+	// The first dimension of the text size is its height.
+	if c.prof.MinTextHeight > 0 && e.FontSize.X < c.prof.MinTextHeight-tolerance {
+		c.addf(RuleTextHeight, elem, layer, at, "text height %s is less than %s", fmtNum(e.FontSize.X), fmtNum(c.prof.MinTextHeight))
+	}
+}
+
+func (c *checker) layerCount(p *pcb.PCB) {
+	if len(c.prof.LayerCounts) == 0 {
+		return
+	}
+	n := len(p.CopperLayers())
+	var allowed []string
+	for _, count := range c.prof.LayerCounts {
+		if count == n {
+			return
+		}
+		allowed = append(allowed, strconv.Itoa(count))
+	}
+	c.addf(RuleLayerCount, "board", "", pcb.XY{}, "board has %d copper layers, the profile allows %s", n, strings.Join(allowed, ", "))
+}
+
+// boardSize checks the size of the board outline, which may be rotated by
+// 90 degrees to fit.
+func (c *checker) boardSize(p *pcb.PCB) {
+	if c.prof.MaxBoardWidth <= 0 && c.prof.MaxBoardHeight <= 0 {
+		return
+	}
+	outline := drc.Outline(p)
+	if len(outline) == 0 {
+		return
+	}
+	min, max := outline[0][0], outline[0][0]
+	for _, line := range outline {
+		for _, pt := range line {
+			min.X, min.Y = math.Min(min.X, pt.X), math.Min(min.Y, pt.Y)
+			max.X, max.Y = math.Max(max.X, pt.X), math.Max(max.Y, pt.Y)
+		}
+	}
+	w, h := max.X-min.X, max.Y-min.Y
+	fits := func(w, h float64) bool {
+		return (c.prof.MaxBoardWidth <= 0 || w <= c.prof.MaxBoardWidth+tolerance) &&
+			(c.prof.MaxBoardHeight <= 0 || h <= c.prof.MaxBoardHeight+tolerance)
+	}
+	if !fits(w, h) && !fits(h, w) {
+		c.addf(RuleBoardSize, "board", "Edge.Cuts", min, "board is %s x %s, larger than %s x %s",
+			fmtNum(w), fmtNum(h), fmtNum(c.prof.MaxBoardWidth), fmtNum(c.prof.MaxBoardHeight))
+	}
+}
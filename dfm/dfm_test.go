@@ -0,0 +1,114 @@
+package dfm
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+func TestCheck(t *testing.T) {
+	p, err := pcb.DecodeFile(filepath.Join("testdata", "board.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range Check(p, Profiles["prototype"]) {
+		got = append(got, v.Rule+" "+v.String())
+	}
+	want := []string{
+		"board_size board: board is 120 x 80, larger than 100 x 100 at (0, 0)",
+		"clearance track[1]: 0.125 from track[2] on F.Cu, need 0.127 at (20, 10.125)",
+		"drill via[0]: drill 0.2 is less than 0.3 at (10, 10)",
+		"silk_width gr_line[4]: line width 0.1 is less than 0.15 at (10, 60)",
+		"silk_width module[J1]/graphics[0]: text thickness 0.12 is less than 0.15 at (50, 37.5)",
+		"slot module[J1]/pad[1]: slot width 0.5 is less than 0.65 at (48.73, 40)",
+		"slot module[J1]/pad[1]: slot length 1.2 is less than 1.3 at (48.73, 40)",
+		"text_height gr_text[5]: text height 0.8 is less than 1 at (20, 70)",
+		"track_width track[1]: width 0.1 is less than 0.127 at (25, 10)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() = %q\nwant %q", got, want)
+	}
+
+	// The same board fits the limits of the advanced profile.
+	if vs := Check(p, Profiles["advanced"]); len(vs) > 0 {
+		t.Errorf("Check() with the advanced profile = %v, want no violations", vs)
+	}
+
+	// Vias without a drill use the drill of their net class, and the
+	// outline includes polygons drawn on Edge.Cuts in footprints.
+	p.Segments[0].(*pcb.Via).Drill = 0
+	p.NetClasses[0].ViaDrill = 0.2
+	p.Modules[0].Graphics = append(p.Modules[0].Graphics, pcb.ModGraphic{Renderable: &pcb.ModPolygon{
+		Points: []pcb.XY{{X: -1, Y: 38}, {X: 1, Y: 38}, {X: 0, Y: 60}},
+		Layer:  "Edge.Cuts",
+	}})
+	got = nil
+	for _, v := range Check(p, &Profile{MinDrill: 0.3, MaxBoardWidth: 130, MaxBoardHeight: 90}) {
+		got = append(got, v.Rule+" "+v.String())
+	}
+	want = []string{
+		"board_size board: board is 120 x 100, larger than 130 x 90 at (0, 0)",
+		"drill via[0]: drill 0.2 is less than 0.3 at (10, 10)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() = %q\nwant %q", got, want)
+	}
+}
+
+func TestCheckModule(t *testing.T) {
+	m, err := pcb.DecodeModuleFile(filepath.Join("testdata", "Pads.kicad_mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range CheckModule(m, Profiles["prototype"]) {
+		got = append(got, v.Rule+" "+v.String())
+	}
+	want := []string{
+		"annular_ring pad[3]: annular ring 0.125 is less than 0.13 at (3, 0)",
+		"clearance pad[1]: 0.1 from pad[2] on F.Cu, need 0.127 at (0, -0.5)",
+		"drill pad[3]: drill 0.25 is less than 0.3 at (3, 0)",
+		"silk_width graphics[2]: line width 0.1 is less than 0.15 at (-2, -1.2)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckModule() = %q\nwant %q", got, want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	p, err := Lookup("standard")
+	if err != nil || p != Profiles["standard"] {
+		t.Errorf("Lookup(standard) = %v, %v, want the bundled profile", p, err)
+	}
+
+	p, err = Lookup(filepath.Join("testdata", "custom.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Profile{Name: "custom", Description: "Four layers only", MinTrackWidth: 0.09, LayerCounts: []int{4}}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Lookup(custom.json) = %+v, want %+v", p, want)
+	}
+	if y, err := Lookup(filepath.Join("testdata", "custom.yaml")); err != nil || !reflect.DeepEqual(y, want) {
+		t.Errorf("Lookup(custom.yaml) = %+v, %v, want %+v", y, err, want)
+	}
+
+	board, err := pcb.DecodeFile(filepath.Join("testdata", "board.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range Check(board, p) {
+		got = append(got, v.Rule+" "+v.String())
+	}
+	if want := []string{"layer_count board: board has 2 copper layers, the profile allows 4 at (0, 0)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() = %q, want %q", got, want)
+	}
+
+	if _, err := Lookup("nope"); err == nil {
+		t.Error("Lookup(nope) succeeded, want an error")
+	}
+}
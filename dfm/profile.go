@@ -0,0 +1,149 @@
+package dfm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Profile describes the capabilities of a fabricator. Zero values are not
+// checked. Dimensions are in millimeters.
+type Profile struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// MinTrackWidth is the narrowest track which can be made.
+	MinTrackWidth float64 `json:"min_track_width,omitempty" yaml:"min_track_width,omitempty"`
+	// MinClearance is the smallest space between copper of different nets.
+	MinClearance float64 `json:"min_clearance,omitempty" yaml:"min_clearance,omitempty"`
+	// MinDrill is the smallest drill, for vias and pads.
+	MinDrill float64 `json:"min_drill,omitempty" yaml:"min_drill,omitempty"`
+	// MinAnnularRing is the narrowest ring of copper around a plated hole.
+	MinAnnularRing float64 `json:"min_annular_ring,omitempty" yaml:"min_annular_ring,omitempty"`
+	// MinHoleToHole is the smallest distance between the edges of holes.
+	MinHoleToHole float64 `json:"min_hole_to_hole,omitempty" yaml:"min_hole_to_hole,omitempty"`
+	// EdgeClearance is the smallest distance from copper to the outline.
+	EdgeClearance float64 `json:"edge_clearance,omitempty" yaml:"edge_clearance,omitempty"`
+	// MinSilkWidth is the narrowest line or text stroke on the silkscreen.
+	MinSilkWidth float64 `json:"min_silk_width,omitempty" yaml:"min_silk_width,omitempty"`
+	// MinTextHeight is the smallest height of silkscreen text.
+	MinTextHeight float64 `json:"min_text_height,omitempty" yaml:"min_text_height,omitempty"`
+	// MaxBoardWidth and MaxBoardHeight limit the size of the board, which
+	// may be rotated to fit.
+	MaxBoardWidth  float64 `json:"max_board_width,omitempty" yaml:"max_board_width,omitempty"`
+	MaxBoardHeight float64 `json:"max_board_height,omitempty" yaml:"max_board_height,omitempty"`
+	// LayerCounts lists the numbers of copper layers which can be made.
+	LayerCounts []int `json:"layer_counts,omitempty" yaml:"layer_counts,omitempty"`
+	// MinSlotWidth and MinSlotLength limit the size of oval holes.
+	MinSlotWidth  float64 `json:"min_slot_width,omitempty" yaml:"min_slot_width,omitempty"`
+	MinSlotLength float64 `json:"min_slot_length,omitempty" yaml:"min_slot_length,omitempty"`
+}
+
+// Profiles are the bundled profiles, by name. They describe typical
+// capabilities rather than those of a particular fabricator, so check the
+// limits of yours.
+var Profiles = map[string]*Profile{
+	"prototype": {
+		Name:           "prototype",
+		Description:    "Low cost prototyping: 5/5mil tracks, 0.3mm drills, boards up to 100x100mm",
+		MinTrackWidth:  0.127,
+		MinClearance:   0.127,
+		MinDrill:       0.3,
+		MinAnnularRing: 0.13,
+		MinHoleToHole:  0.5,
+		EdgeClearance:  0.3,
+		MinSilkWidth:   0.15,
+		MinTextHeight:  1,
+		MaxBoardWidth:  100,
+		MaxBoardHeight: 100,
+		LayerCounts:    []int{1, 2, 4},
+		MinSlotWidth:   0.65,
+		MinSlotLength:  1.3,
+	},
+	"standard": {
+		Name:           "standard",
+		Description:    "Production: 6/6mil tracks, 0.3mm drills, up to 6 layers",
+		MinTrackWidth:  0.15,
+		MinClearance:   0.15,
+		MinDrill:       0.3,
+		MinAnnularRing: 0.15,
+		MinHoleToHole:  0.5,
+		EdgeClearance:  0.3,
+		MinSilkWidth:   0.15,
+		MinTextHeight:  0.8,
+		MaxBoardWidth:  500,
+		MaxBoardHeight: 400,
+		LayerCounts:    []int{1, 2, 4, 6},
+		MinSlotWidth:   0.5,
+		MinSlotLength:  1,
+	},
+	"advanced": {
+		Name:           "advanced",
+		Description:    "Fine pitch production: 4/4mil tracks, 0.15mm drills, up to 16 layers",
+		MinTrackWidth:  0.1,
+		MinClearance:   0.1,
+		MinDrill:       0.15,
+		MinAnnularRing: 0.1,
+		MinHoleToHole:  0.25,
+		EdgeClearance:  0.2,
+		MinSilkWidth:   0.1,
+		MinTextHeight:  0.6,
+		MaxBoardWidth:  600,
+		MaxBoardHeight: 500,
+		LayerCounts:    []int{1, 2, 4, 6, 8, 10, 12, 14, 16},
+		MinSlotWidth:   0.4,
+		MinSlotLength:  0.8,
+	},
+}
+
+// ProfileNames returns the names of the bundled profiles, sorted.
+func ProfileNames() []string {
+	out := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// LoadProfile reads a profile from a JSON file with the fields of Profile,
+// or a YAML file with the same fields if its name ends in .yaml or .yml.
+// The name defaults to the name of the file.
+func LoadProfile(path string) (*Profile, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Profile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(d, &p)
+	default:
+		err = json.Unmarshal(d, &p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return &p, nil
+}
+
+// Lookup returns the bundled profile of the given name, or else loads the
+// profile file at that path.
+func Lookup(name string) (*Profile, error) {
+	if p, ok := Profiles[name]; ok {
+		return p, nil
+	}
+	if _, err := os.Stat(name); err != nil {
+		return nil, fmt.Errorf("unknown profile %q: bundled profiles are %s", name, strings.Join(ProfileNames(), ", "))
+	}
+	return LoadProfile(name)
+}
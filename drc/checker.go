@@ -0,0 +1,612 @@
+package drc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// tolerance absorbs rounding when comparing distances against limits.
+const tolerance = 1e-5
+
+// itemKind is the type of element an item was collected from.
+type itemKind uint8
+
+const (
+	kindTrack itemKind = iota
+	kindVia
+	kindPad
+	kindZone
+)
+
+// item is an element of the board with copper on one or more layers.
+type item struct {
+	kind      itemKind
+	elem      string
+	net       int
+	clearance float64
+	// group is set for pads, which do not conflict with pads of the same
+	// number on the same module.
+	group string
+	// slack is subtracted from the clearance required of the item, to
+	// allow for the approximation of arcs in zone fills.
+	slack float64
+	// at is a point on the copper of the item.
+	at pcb.XY
+	// pad and mod are set for items collected from pads, which zone fills
+	// connect to with thermal reliefs.
+	pad *pcb.Pad
+	mod *pcb.Module
+}
+
+// prim is a straight piece of the copper of an item on one layer: a
+// segment stroked with radius r. Circles have a == b.
+type prim struct {
+	item int32
+	a, b pcb.XY
+	r    float64
+}
+
+// region is a filled area of the copper of an item on one layer. Its
+// outline is also added as prims.
+type region struct {
+	item int32
+	poly []pcb.XY
+}
+
+// trackEnd is an end of a track, which should meet other copper.
+type trackEnd struct {
+	item  int32
+	layer *layerCopper
+	at    pcb.XY
+}
+
+type layerCopper struct {
+	name    string
+	num     int
+	prims   []prim
+	regions []region
+	index   *grid
+	// regionIndex indexes regions by their bounding box.
+	regionIndex *grid
+}
+
+// hole is a drilled hole, stroked like a prim.
+type hole struct {
+	elem, group string
+	a, b        pcb.XY
+	r           float64
+	plated      bool
+}
+
+type checker struct {
+	p       *pcb.PCB
+	opts    Options
+	classes *classTable
+
+	items        []item
+	maxClearance float64
+	layers       []*layerCopper
+	byName       map[string]*layerCopper
+	holes        []hole
+	edges        [][2]pcb.XY
+	ends         []trackEnd
+
+	violations []Violation
+}
+
+func newChecker(p *pcb.PCB, opts Options) *checker {
+	c := &checker{
+		p:       p,
+		opts:    opts,
+		classes: newClassTable(p),
+		byName:  map[string]*layerCopper{},
+	}
+	for _, l := range p.Layers {
+		if strings.HasSuffix(l.Name, ".Cu") {
+			lc := &layerCopper{name: l.Name, num: l.Num}
+			c.layers = append(c.layers, lc)
+			c.byName[l.Name] = lc
+		}
+	}
+	c.collectSegments()
+	c.collectModules()
+	c.collectZones()
+	c.collectEdges()
+	return c
+}
+
+func (c *checker) addf(rule, elem, other, layer string, at pcb.XY, format string, args ...interface{}) int {
+	c.violations = append(c.violations, Violation{
+		Rule:    rule,
+		Element: elem,
+		Other:   other,
+		Layer:   layer,
+		At:      pcb.XY{X: roundUM(at.X), Y: roundUM(at.Y)},
+		Msg:     fmt.Sprintf(format, args...),
+	})
+	return len(c.violations) - 1
+}
+
+func (c *checker) netName(num int) string {
+	return c.p.Nets[num].Name
+}
+
+func (c *checker) addItem(it item) int32 {
+	if c.opts.MinClearance > 0 {
+		it.clearance = c.opts.MinClearance
+	}
+	c.items = append(c.items, it)
+	c.maxClearance = math.Max(c.maxClearance, it.clearance)
+	return int32(len(c.items) - 1)
+}
+
+func (l *layerCopper) addSegment(id int32, a, b pcb.XY, r float64) {
+	l.prims = append(l.prims, prim{item: id, a: a, b: b, r: r})
+}
+
+func (l *layerCopper) addPolygon(id int32, poly []pcb.XY, r float64) {
+	for i := range poly {
+		l.addSegment(id, poly[i], poly[(i+1)%len(poly)], r)
+	}
+	l.regions = append(l.regions, region{item: id, poly: poly})
+}
+
+// buildIndex indexes the prims of the layer once they are all collected.
+func (l *layerCopper) buildIndex() {
+	if l.index != nil {
+		return
+	}
+	l.index = newGrid()
+	for i, p := range l.prims {
+		l.index.insert(int32(i), segmentBox(p.a, p.b, p.r))
+	}
+	l.regionIndex = newGrid()
+	for i, rg := range l.regions {
+		l.regionIndex.insert(int32(i), polyBox(rg.poly))
+	}
+}
+
+// resolveLayers returns the copper layers named, expanding wildcards such
+// as *.Cu.
+func (c *checker) resolveLayers(names []string) []*layerCopper {
+	var out []*layerCopper
+	seen := map[*layerCopper]bool{}
+	for _, n := range names {
+		var ls []*layerCopper
+		switch n {
+		case "*.Cu":
+			ls = c.layers
+		case "F&B.Cu":
+			ls = []*layerCopper{c.byName["F.Cu"], c.byName["B.Cu"]}
+		default:
+			ls = []*layerCopper{c.byName[n]}
+		}
+		for _, l := range ls {
+			if l != nil && !seen[l] {
+				seen[l] = true
+				out = append(out, l)
+			}
+		}
+	}
+	return out
+}
+
+// viaLayers returns the copper layers spanned by a via.
+func (c *checker) viaLayers(v *pcb.Via) []*layerCopper {
+	if len(v.Layers) < 2 {
+		return c.resolveLayers(v.Layers)
+	}
+	a, b := c.byName[v.Layers[0]], c.byName[v.Layers[1]]
+	if a == nil || b == nil {
+		return c.resolveLayers(v.Layers)
+	}
+	lo, hi := a.num, b.num
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	var out []*layerCopper
+	for _, l := range c.layers {
+		if l.num >= lo && l.num <= hi {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func (c *checker) collectSegments() {
+	for i, s := range c.p.Segments {
+		switch s := s.(type) {
+		case *pcb.Track:
+			elem := fmt.Sprintf("track[%d]", i)
+			if s.Width < c.opts.MinTrackWidth-tolerance {
+				c.addf(RuleTrackWidth, elem, "", s.Layer, pcb.XY{X: (s.Start.X + s.End.X) / 2, Y: (s.Start.Y + s.End.Y) / 2},
+					"width %s is less than %s", fmtNum(s.Width), fmtNum(c.opts.MinTrackWidth))
+			}
+			l, ok := c.byName[s.Layer]
+			if !ok {
+				continue
+			}
+			id := c.addItem(item{elem: elem, net: s.NetIndex, clearance: c.classes.netClearance(c.netName(s.NetIndex)), at: s.Start})
+			l.addSegment(id, s.Start, s.End, s.Width/2)
+			c.ends = append(c.ends, trackEnd{id, l, s.Start}, trackEnd{id, l, s.End})
+
+		case *pcb.Via:
+			elem := fmt.Sprintf("via[%d]", i)
+			drill := s.Drill
+			if drill == 0 {
+				drill = c.classes.viaDrillFor(c.netName(s.NetIndex), s.ViaType)
+			}
+			if ring := (s.Size - drill) / 2; ring < c.opts.MinAnnularRing-tolerance {
+				c.addf(RuleAnnularRing, elem, "", "", s.At, "annular ring %s is less than %s", fmtNum(ring), fmtNum(c.opts.MinAnnularRing))
+			}
+			id := c.addItem(item{kind: kindVia, elem: elem, net: s.NetIndex, clearance: c.classes.netClearance(c.netName(s.NetIndex)), at: s.At})
+			for _, l := range c.viaLayers(s) {
+				l.addSegment(id, s.At, s.At, s.Size/2)
+			}
+			if drill > 0 {
+				c.holes = append(c.holes, hole{elem: elem, a: s.At, b: s.At, r: drill / 2, plated: true})
+			}
+		}
+	}
+}
+
+func (c *checker) collectModules() {
+	for i := range c.p.Modules {
+		m := &c.p.Modules[i]
+		mel := fmt.Sprintf("module[%d]", i)
+		if ref := m.Reference(); ref != "" {
+			mel = fmt.Sprintf("module[%s]", ref)
+		}
+		for j := range m.Pads {
+			pad := &m.Pads[j]
+			elem := fmt.Sprintf("%s/pad[%d]", mel, j)
+			group := ""
+			if pad.Ident != "" {
+				elem = fmt.Sprintf("%s/pad[%s]", mel, pad.Ident)
+				group = elem
+			}
+			c.collectPad(m, pad, elem, group)
+		}
+		c.collectModuleEdges(m)
+	}
+}
+
+func (c *checker) collectPad(m *pcb.Module, pad *pcb.Pad, elem, group string) {
+	pos := m.PadPosition(pad)
+	at := pcb.XY{X: pos.X, Y: pos.Y}
+	angle := pad.At.Z
+	center := at.Add(pad.DrillOffset.Rotate(angle))
+
+	if pad.DrillSize.X > 0 {
+		h := hole{elem: elem, group: group, a: at, b: at, r: pad.DrillSize.X / 2, plated: pad.Surface == pcb.SurfaceTH}
+		if pad.DrillShape == pcb.ShapeDrillOblong && pad.DrillSize.Y != pad.DrillSize.X && pad.DrillSize.Y > 0 {
+			h.a, h.b, h.r = capsule(at, pad.DrillSize, angle)
+		}
+		c.holes = append(c.holes, h)
+
+		if pad.Surface == pcb.SurfaceTH {
+			dx, dy := pad.DrillSize.X, pad.DrillSize.Y
+			if pad.DrillShape != pcb.ShapeDrillOblong || dy == 0 {
+				dy = dx
+			}
+			sx, sy := pad.Size.X, pad.Size.Y
+			if pad.Shape == pcb.ShapeCircle {
+				sy = sx
+			}
+			ring := math.Min(sx-dx, sy-dy)/2 - math.Hypot(pad.DrillOffset.X, pad.DrillOffset.Y)
+			if ring < c.opts.MinAnnularRing-tolerance {
+				c.addf(RuleAnnularRing, elem, "", "", at, "annular ring %s is less than %s", fmtNum(ring), fmtNum(c.opts.MinAnnularRing))
+			}
+		}
+	}
+
+	// Unplated holes only have copper if the pad is larger than the hole.
+	if pad.Surface == pcb.SurfaceNPTH && pad.Size.X <= pad.DrillSize.X && pad.Size.Y <= math.Max(pad.DrillSize.X, pad.DrillSize.Y) {
+		return
+	}
+	layers := c.resolveLayers(pad.Layers)
+	if len(layers) == 0 {
+		return
+	}
+	clearance := c.classes.netClearance(c.netName(pad.NetNum))
+	switch {
+	case pad.Clearance > 0:
+		clearance = pad.Clearance
+	case m.Clearance > 0:
+		clearance = m.Clearance
+	}
+	id := c.addItem(item{elem: elem, net: pad.NetNum, clearance: clearance, group: group, at: center, kind: kindPad, pad: pad, mod: m})
+
+	for _, l := range layers {
+		switch pad.Shape {
+		case pcb.ShapeCircle:
+			l.addSegment(id, center, center, pad.Size.X/2)
+		case pcb.ShapeOval:
+			a, b, r := capsule(center, pad.Size, angle)
+			l.addSegment(id, a, b, r)
+		case pcb.ShapeRoundRect:
+			// A rounded rectangle is a smaller rectangle stroked with the
+			// corner radius.
+			rr := pad.RoundRectRRatio * math.Min(pad.Size.X, pad.Size.Y)
+			inner := pcb.XY{X: pad.Size.X - 2*rr, Y: pad.Size.Y - 2*rr}
+			l.addPolygon(id, rectangle(center, inner, angle), rr)
+		default:
+			// Other shapes are approximated by their bounding rectangle.
+			l.addPolygon(id, rectangle(center, pad.Size, angle), 0)
+		}
+	}
+}
+
+// capsule returns the segment and radius of an oval of the given size.
+func capsule(center, size pcb.XY, angle float64) (pcb.XY, pcb.XY, float64) {
+	half := pcb.XY{X: (size.X - size.Y) / 2}
+	r := size.Y / 2
+	if size.Y > size.X {
+		half = pcb.XY{Y: (size.Y - size.X) / 2}
+		r = size.X / 2
+	}
+	half = half.Rotate(angle)
+	return pcb.XY{X: center.X - half.X, Y: center.Y - half.Y}, center.Add(half), r
+}
+
+// rectangle returns the corners of a rectangle of the given size.
+func rectangle(center, size pcb.XY, angle float64) []pcb.XY {
+	hx, hy := size.X/2, size.Y/2
+	out := make([]pcb.XY, 0, 4)
+	for _, p := range []pcb.XY{{X: -hx, Y: -hy}, {X: hx, Y: -hy}, {X: hx, Y: hy}, {X: -hx, Y: hy}} {
+		out = append(out, center.Add(p.Rotate(angle)))
+	}
+	return out
+}
+
+func (c *checker) collectZones() {
+	for i := range c.p.Zones {
+		z := &c.p.Zones[i]
+		if z.IsKeepout || len(z.Polys) == 0 {
+			continue
+		}
+		// Each filled polygon is a separate item, as it need not be
+		// connected to the others. They are drawn with an outline of the
+		// minimum thickness.
+		layers := c.resolveLayers(z.Layers)
+		for _, poly := range z.Polys {
+			if len(poly) < 3 {
+				continue
+			}
+			id := c.addItem(item{
+				kind:      kindZone,
+				elem:      fmt.Sprintf("zone[%d]", i),
+				net:       z.NetNum,
+				clearance: c.classes.netClearance(c.netName(z.NetNum)),
+				slack:     fillArcError(z),
+				at:        poly[0],
+			})
+			for _, l := range layers {
+				l.addPolygon(id, poly, z.MinThickness/2)
+			}
+		}
+	}
+}
+
+// fillArcRadius is the radius of a typical arc in the clearance outline of
+// a zone fill: a pad plus its clearance.
+const fillArcRadius = 1.5
+
+// fillArcError returns how far the chords of the arcs in a zone fill may
+// cut into the clearance around other copper. Fills by older versions of
+// pcbnew use as few as 16 segments per circle.
+func fillArcError(z *pcb.Zone) float64 {
+	n := z.Fill.Segments
+	if n <= 0 {
+		n = 16
+	}
+	return fillArcRadius * (1 - math.Cos(math.Pi/float64(n)))
+}
+
+func (c *checker) addEdge(pts []pcb.XY) {
+	for i := 1; i < len(pts); i++ {
+		c.edges = append(c.edges, [2]pcb.XY{pts[i-1], pts[i]})
+	}
+}
+
+func (c *checker) collectEdges() {
+	for _, d := range c.p.Drawings {
+		switch d := d.(type) {
+		case *pcb.Line:
+			if d.Layer == "Edge.Cuts" {
+				c.addEdge([]pcb.XY{d.Start, d.End})
+			}
+		case *pcb.Arc:
+			if d.Layer == "Edge.Cuts" {
+				c.addEdge(arcPoints(d.Start, d.End, d.Angle))
+			}
+		}
+	}
+}
+
+// collectModuleEdges adds the parts of the board outline drawn in a
+// module, such as cutouts.
+func (c *checker) collectModuleEdges(m *pcb.Module) {
+	toBoard := func(pts []pcb.XY) []pcb.XY {
+		out := make([]pcb.XY, len(pts))
+		for i, p := range pts {
+			out[i] = m.ToBoard(p)
+		}
+		return out
+	}
+	for _, g := range m.Graphics {
+		switch g := g.Renderable.(type) {
+		case *pcb.ModLine:
+			if g.Layer == "Edge.Cuts" {
+				c.addEdge(toBoard([]pcb.XY{g.Start, g.End}))
+			}
+		case *pcb.ModArc:
+			if g.Layer == "Edge.Cuts" {
+				c.addEdge(toBoard(arcPoints(g.Start, g.End, g.Angle)))
+			}
+		case *pcb.ModCircle:
+			if g.Layer == "Edge.Cuts" {
+				c.addEdge(toBoard(arcPoints(g.Center, g.End, 360)))
+			}
+		case *pcb.ModPolygon:
+			if g.Layer == "Edge.Cuts" && len(g.Points) > 0 {
+				pts := make([]pcb.XY, 0, len(g.Points)+1)
+				for _, p := range g.Points {
+					pts = append(pts, p.Add(g.At))
+				}
+				c.addEdge(toBoard(append(pts, pts[0])))
+			}
+		}
+	}
+}
+
+// conflicting reports whether two items must be kept apart.
+func (c *checker) conflicting(a, b int32) bool {
+	if a == b {
+		return false
+	}
+	ia, ib := &c.items[a], &c.items[b]
+	if ia.group != "" && ia.group == ib.group {
+		return false
+	}
+	return ia.net != ib.net || ia.net == 0
+}
+
+// elemPair returns a key for a pair of elements. Items are paired by
+// element, as a zone has an item per filled polygon.
+func elemPair(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+func (c *checker) checkClearances() {
+	type found struct {
+		index int
+		dist  float64
+	}
+	worst := map[[2]string]*found{}
+	report := func(layer string, a, b int32, d, need float64, at pcb.XY) {
+		if a > b {
+			a, b = b, a
+		}
+		key := elemPair(c.items[a].elem, c.items[b].elem)
+		f, ok := worst[key]
+		if ok && f.dist <= d {
+			return
+		}
+		msg := fmt.Sprintf("%s from %s on %s, need %s", fmtNum(math.Max(d, 0)), c.items[b].elem, layer, fmtNum(need))
+		if d <= 0 {
+			msg = fmt.Sprintf("overlaps %s on %s", c.items[b].elem, layer)
+		}
+		if ok {
+			v := &c.violations[f.index]
+			v.Layer, v.At, v.Msg = layer, pcb.XY{X: roundUM(at.X), Y: roundUM(at.Y)}, msg
+			f.dist = d
+			return
+		}
+		worst[key] = &found{
+			index: c.addf(RuleClearance, c.items[a].elem, c.items[b].elem, layer, at, "%s", msg),
+			dist:  d,
+		}
+	}
+
+	for _, l := range c.layers {
+		l.buildIndex()
+		for i := range l.prims {
+			pi := &l.prims[i]
+			l.index.query(segmentBox(pi.a, pi.b, pi.r+c.maxClearance), func(j int32) {
+				if j <= int32(i) {
+					return
+				}
+				pj := &l.prims[j]
+				if !c.conflicting(pi.item, pj.item) {
+					return
+				}
+				ia, ib := &c.items[pi.item], &c.items[pj.item]
+				need := math.Max(ia.clearance, ib.clearance)
+				d, at := pcb.SegmentDistance(pi.a, pi.b, pj.a, pj.b)
+				if d -= pi.r + pj.r; d < need-ia.slack-ib.slack-tolerance {
+					report(l.name, pi.item, pj.item, d, need, at)
+				}
+			})
+		}
+
+		// Copper entirely within a filled area of another net does not
+		// come near its outline, so check whether it is inside.
+		for _, rg := range l.regions {
+			checked := map[int32]bool{}
+			l.index.query(polyBox(rg.poly), func(j int32) {
+				pj := &l.prims[j]
+				if checked[pj.item] || !c.conflicting(rg.item, pj.item) {
+					return
+				}
+				checked[pj.item] = true
+				if _, ok := worst[elemPair(c.items[rg.item].elem, c.items[pj.item].elem)]; !ok && insidePolygon(pj.a, rg.poly) {
+					report(l.name, rg.item, pj.item, -pj.r, 0, pj.a)
+				}
+			})
+		}
+	}
+}
+
+func (c *checker) checkEdges() {
+	if c.opts.EdgeClearance <= 0 || len(c.edges) == 0 {
+		return
+	}
+	g := newGrid()
+	for i, e := range c.edges {
+		g.insert(int32(i), segmentBox(e[0], e[1], 0))
+	}
+	reported := map[int32]int{}
+	worst := map[int32]float64{}
+	for _, l := range c.layers {
+		for _, p := range l.prims {
+			g.query(segmentBox(p.a, p.b, p.r+c.opts.EdgeClearance), func(j int32) {
+				e := c.edges[j]
+				d, at := pcb.SegmentDistance(p.a, p.b, e[0], e[1])
+				if d -= p.r; d >= c.opts.EdgeClearance-tolerance {
+					return
+				}
+				if w, ok := worst[p.item]; ok && w <= d {
+					return
+				}
+				worst[p.item] = d
+				msg := fmt.Sprintf("%s from the board edge on %s, need %s", fmtNum(math.Max(d, 0)), l.name, fmtNum(c.opts.EdgeClearance))
+				if i, ok := reported[p.item]; ok {
+					v := &c.violations[i]
+					v.Layer, v.At, v.Msg = l.name, pcb.XY{X: roundUM(at.X), Y: roundUM(at.Y)}, msg
+					return
+				}
+				reported[p.item] = c.addf(RuleEdgeClearance, c.items[p.item].elem, "", l.name, at, "%s", msg)
+			})
+		}
+	}
+}
+
+func (c *checker) checkHoles() {
+	if c.opts.MinHoleToHole <= 0 {
+		return
+	}
+	g := newGrid()
+	for i, h := range c.holes {
+		g.insert(int32(i), segmentBox(h.a, h.b, h.r))
+	}
+	for i := range c.holes {
+		hi := &c.holes[i]
+		g.query(segmentBox(hi.a, hi.b, hi.r+c.opts.MinHoleToHole), func(j int32) {
+			hj := &c.holes[j]
+			if j <= int32(i) || (hi.group != "" && hi.group == hj.group) {
+				return
+			}
+			d, at := pcb.SegmentDistance(hi.a, hi.b, hj.a, hj.b)
+			if d -= hi.r + hj.r; d < c.opts.MinHoleToHole-tolerance {
+				c.addf(RuleHoleToHole, hi.elem, hj.elem, "", at, "hole is %s from the hole of %s, need %s", fmtNum(math.Max(d, 0)), hj.elem, fmtNum(c.opts.MinHoleToHole))
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package drc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// connectTolerance is how far apart copper may be and still be considered
+// connected, to allow for rounding of coordinates in the board file.
+const connectTolerance = 1e-3
+
+// Connectivity describes how the copper of a board connects its nets.
+type Connectivity struct {
+	// Unrouted lists the connections still needed to connect the pads of
+	// each net, also known as the ratsnest.
+	Unrouted []Connection `json:"unrouted"`
+	// Dangling lists ends of tracks which are not connected to anything.
+	Dangling []DanglingEnd `json:"dangling"`
+	// Islands lists copper which is not connected to any pad of its net.
+	Islands []Island `json:"islands"`
+	// Shorts lists copper of different nets which touches.
+	Shorts []Short `json:"shorts"`
+}
+
+// Complete reports whether the pads of every net are connected, without
+// shorts between nets.
+func (c *Connectivity) Complete() bool {
+	return len(c.Unrouted) == 0 && len(c.Shorts) == 0
+}
+
+// Connection is an unrouted connection between two parts of a net.
+type Connection struct {
+	Net    string  `json:"net"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	FromAt pcb.XY  `json:"from_position"`
+	ToAt   pcb.XY  `json:"to_position"`
+	Length float64 `json:"length"`
+}
+
+func (c Connection) String() string {
+	return fmt.Sprintf("%s: unrouted from %s at %s to %s at %s, length %s", c.Net, c.From, fmtXY(c.FromAt), c.To, fmtXY(c.ToAt), fmtNum(c.Length))
+}
+
+// DanglingEnd is an end of a track which is not connected to anything.
+type DanglingEnd struct {
+	Element string `json:"element"`
+	Net     string `json:"net"`
+	Layer   string `json:"layer"`
+	At      pcb.XY `json:"position"`
+}
+
+func (d DanglingEnd) String() string {
+	return fmt.Sprintf("%s: dangling end on %s at %s", d.Element, d.Layer, fmtXY(d.At))
+}
+
+// Island is connected copper of a net which does not reach any of its pads.
+type Island struct {
+	Net      string   `json:"net"`
+	Elements []string `json:"elements"`
+	At       pcb.XY   `json:"position"`
+}
+
+func (i Island) String() string {
+	return fmt.Sprintf("%s: %s not connected to any pad at %s", i.Net, strings.Join(i.Elements, ", "), fmtXY(i.At))
+}
+
+// Short is copper of two different nets which touches.
+type Short struct {
+	// Nets are the nets of Element and Other.
+	Nets    [2]string `json:"nets"`
+	Element string    `json:"element"`
+	Other   string    `json:"other"`
+	Layer   string    `json:"layer"`
+	At      pcb.XY    `json:"position"`
+}
+
+func (s Short) String() string {
+	return fmt.Sprintf("%s: net %s shorted to net %s of %s on %s at %s", s.Element, s.Nets[0], s.Nets[1], s.Other, s.Layer, fmtXY(s.At))
+}
+
+// CheckConnectivity builds a graph of the copper of each net, from the
+// tracks, vias, pads and zone fills of the board, and reports what is
+// unrouted, dangling, isolated or shorted. Copper without a net is ignored.
+func CheckConnectivity(p *pcb.PCB) *Connectivity {
+	c := newChecker(p, Options{})
+	out := &Connectivity{
+		Unrouted: []Connection{},
+		Dangling: []DanglingEnd{},
+		Islands:  []Island{},
+		Shorts:   []Short{},
+	}
+	sets := newDisjointSets(len(c.items))
+
+	shorted := map[[2]string]bool{}
+	touch := func(l *layerCopper, a, b int32, at pcb.XY) {
+		ia, ib := &c.items[a], &c.items[b]
+		switch {
+		case ia.net == 0 || ib.net == 0:
+		case ia.net == ib.net:
+			sets.union(a, b)
+		default:
+			key := elemPair(ia.elem, ib.elem)
+			if shorted[key] {
+				return
+			}
+			shorted[key] = true
+			if a > b {
+				ia, ib = ib, ia
+			}
+			out.Shorts = append(out.Shorts, Short{
+				Nets:    [2]string{c.netName(ia.net), c.netName(ib.net)},
+				Element: ia.elem,
+				Other:   ib.elem,
+				Layer:   l.name,
+				At:      pcb.XY{X: roundUM(at.X), Y: roundUM(at.Y)},
+			})
+		}
+	}
+
+	for _, l := range c.layers {
+		l.buildIndex()
+		for i := range l.prims {
+			pi := &l.prims[i]
+			l.index.query(segmentBox(pi.a, pi.b, pi.r+connectTolerance), func(j int32) {
+				pj := &l.prims[j]
+				if j <= int32(i) || pi.item == pj.item {
+					return
+				}
+				if d, at := pcb.SegmentDistance(pi.a, pi.b, pj.a, pj.b); d-pi.r-pj.r <= connectTolerance {
+					touch(l, pi.item, pj.item, at)
+				}
+			})
+		}
+		// Copper entirely within a filled area does not touch its outline.
+		for _, rg := range l.regions {
+			l.index.query(polyBox(rg.poly), func(j int32) {
+				pj := &l.prims[j]
+				if pj.item != rg.item && insidePolygon(pj.a, rg.poly) {
+					touch(l, rg.item, pj.item, pj.a)
+				}
+			})
+		}
+	}
+
+	c.findDangling(out)
+	c.findUnrouted(sets, out)
+
+	sort.Slice(out.Unrouted, func(i, j int) bool {
+		a, b := out.Unrouted[i], out.Unrouted[j]
+		if a.Net != b.Net {
+			return a.Net < b.Net
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	sort.Slice(out.Dangling, func(i, j int) bool {
+		a, b := out.Dangling[i], out.Dangling[j]
+		if a.Element != b.Element {
+			return a.Element < b.Element
+		}
+		return a.At.X < b.At.X || (a.At.X == b.At.X && a.At.Y < b.At.Y)
+	})
+	sort.Slice(out.Islands, func(i, j int) bool {
+		a, b := out.Islands[i], out.Islands[j]
+		if a.Net != b.Net {
+			return a.Net < b.Net
+		}
+		return a.Elements[0] < b.Elements[0]
+	})
+	sort.Slice(out.Shorts, func(i, j int) bool {
+		a, b := out.Shorts[i], out.Shorts[j]
+		if a.Element != b.Element {
+			return a.Element < b.Element
+		}
+		return a.Other < b.Other
+	})
+	return out
+}
+
+// findDangling reports track ends which do not meet any other copper.
+func (c *checker) findDangling(out *Connectivity) {
+	for _, e := range c.ends {
+		if c.items[e.item].net == 0 {
+			continue
+		}
+		connected := false
+		e.layer.index.query(segmentBox(e.at, e.at, connectTolerance), func(j int32) {
+			pj := &e.layer.prims[j]
+			if connected || pj.item == e.item {
+				return
+			}
+			if d, _ := pcb.SegmentDistance(e.at, e.at, pj.a, pj.b); d <= pj.r+connectTolerance {
+				connected = true
+			}
+		})
+		if !connected {
+			e.layer.regionIndex.query(segmentBox(e.at, e.at, 0), func(j int32) {
+				rg := &e.layer.regions[j]
+				if !connected && rg.item != e.item && insidePolygon(e.at, rg.poly) {
+					connected = true
+				}
+			})
+		}
+		if !connected {
+			it := &c.items[e.item]
+			out.Dangling = append(out.Dangling, DanglingEnd{
+				Element: it.elem,
+				Net:     c.netName(it.net),
+				Layer:   e.layer.name,
+				At:      pcb.XY{X: roundUM(e.at.X), Y: roundUM(e.at.Y)},
+			})
+		}
+	}
+}
+
+// anchor is a point a ratsnest line may be drawn to.
+type anchor struct {
+	item int32
+	at   pcb.XY
+}
+
+// component is a connected set of items of one net.
+type component struct {
+	net     int
+	items   []int32
+	anchors []anchor
+	pads    bool
+}
+
+// findUnrouted groups connected items into components, reporting those
+// without pads as islands and the shortest connections which would join
+// the rest of each net.
+func (c *checker) findUnrouted(sets *disjointSets, out *Connectivity) {
+	byRoot := map[int32]*component{}
+	nets := map[int][]*component{}
+	var netOrder []int
+	for i := range c.items {
+		it := &c.items[i]
+		if it.net == 0 {
+			continue
+		}
+		root := sets.find(int32(i))
+		comp, ok := byRoot[root]
+		if !ok {
+			comp = &component{net: it.net}
+			byRoot[root] = comp
+			if len(nets[it.net]) == 0 {
+				netOrder = append(netOrder, it.net)
+			}
+			nets[it.net] = append(nets[it.net], comp)
+		}
+		comp.items = append(comp.items, int32(i))
+		switch it.kind {
+		case kindPad:
+			comp.pads = true
+			comp.anchors = append(comp.anchors, anchor{int32(i), it.at})
+		case kindVia:
+			comp.anchors = append(comp.anchors, anchor{int32(i), it.at})
+		}
+	}
+	for _, e := range c.ends {
+		if c.items[e.item].net != 0 {
+			comp := byRoot[sets.find(e.item)]
+			comp.anchors = append(comp.anchors, anchor{e.item, e.at})
+		}
+	}
+
+	for _, net := range netOrder {
+		var routed []*component
+		for _, comp := range nets[net] {
+			if comp.pads {
+				routed = append(routed, comp)
+				continue
+			}
+			island := Island{Net: c.netName(net), At: c.items[comp.items[0]].at}
+			island.At = pcb.XY{X: roundUM(island.At.X), Y: roundUM(island.At.Y)}
+			seen := map[string]bool{}
+			for _, id := range comp.items {
+				if elem := c.items[id].elem; !seen[elem] {
+					seen[elem] = true
+					island.Elements = append(island.Elements, elem)
+				}
+			}
+			sort.Strings(island.Elements)
+			out.Islands = append(out.Islands, island)
+		}
+		c.ratsnest(routed, out)
+	}
+}
+
+// ratsnest reports the connections of a minimum spanning tree of the
+// components of a net, built with Prim's algorithm.
+func (c *checker) ratsnest(comps []*component, out *Connectivity) {
+	if len(comps) < 2 {
+		return
+	}
+	type edge struct {
+		dist     float64
+		from, to anchor
+	}
+	inTree := make([]bool, len(comps))
+	best := make([]edge, len(comps))
+	for i := range best {
+		best[i].dist = math.Inf(1)
+	}
+	last := 0
+	inTree[0] = true
+	for n := 1; n < len(comps); n++ {
+		next := -1
+		for j, comp := range comps {
+			if inTree[j] {
+				continue
+			}
+			for _, a := range comps[last].anchors {
+				for _, b := range comp.anchors {
+					if d := math.Hypot(a.at.X-b.at.X, a.at.Y-b.at.Y); d < best[j].dist {
+						best[j] = edge{d, a, b}
+					}
+				}
+			}
+			if next < 0 || best[j].dist < best[next].dist {
+				next = j
+			}
+		}
+		inTree[next] = true
+		last = next
+		e := best[next]
+		out.Unrouted = append(out.Unrouted, Connection{
+			Net:    c.netName(comps[next].net),
+			From:   c.items[e.from.item].elem,
+			To:     c.items[e.to.item].elem,
+			FromAt: pcb.XY{X: roundUM(e.from.at.X), Y: roundUM(e.from.at.Y)},
+			ToAt:   pcb.XY{X: roundUM(e.to.at.X), Y: roundUM(e.to.at.Y)},
+			Length: roundUM(e.dist),
+		})
+	}
+}
+
+// disjointSets is a union-find structure over item indices.
+type disjointSets struct {
+	parent []int32
+	rank   []uint8
+}
+
+func newDisjointSets(n int) *disjointSets {
+	s := &disjointSets{parent: make([]int32, n), rank: make([]uint8, n)}
+	for i := range s.parent {
+		s.parent[i] = int32(i)
+	}
+	return s
+}
+
+func (s *disjointSets) find(i int32) int32 {
+	for s.parent[i] != i {
+		s.parent[i] = s.parent[s.parent[i]]
+		i = s.parent[i]
+	}
+	return i
+}
+
+func (s *disjointSets) union(a, b int32) {
+	a, b = s.find(a), s.find(b)
+	switch {
+	case a == b:
+	case s.rank[a] < s.rank[b]:
+		s.parent[a] = b
+	case s.rank[a] > s.rank[b]:
+		s.parent[b] = a
+	default:
+		s.parent[b] = a
+		s.rank[a]++
+	}
+}
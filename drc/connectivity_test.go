@@ -0,0 +1,60 @@
+package drc
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+func TestCheckConnectivity(t *testing.T) {
+	p, err := pcb.DecodeFile(filepath.Join("testdata", "connectivity.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := CheckConnectivity(p)
+
+	var got []string
+	for _, v := range []interface{}{c.Unrouted, c.Dangling, c.Islands, c.Shorts} {
+		v := reflect.ValueOf(v)
+		for i := 0; i < v.Len(); i++ {
+			got = append(got, fmt.Sprint(v.Index(i).Interface()))
+		}
+	}
+	want := []string{
+		"A: unrouted from via[3] at (9, 5) to module[R3]/pad[1] at (9, 10), length 5",
+		"B: unrouted from module[R1]/pad[2] at (1, 0) to module[R3]/pad[2] at (11, 10), length 14.142",
+		"track[5]: dangling end on F.Cu at (15, 0)",
+		"track[7]: dangling end on F.Cu at (1, 3)",
+		"A: track[7] not connected to any pad at (1, 0)",
+		"B: via[6], zone[0] not connected to any pad at (22, 6)",
+		"track[7]: net A shorted to net B of module[R1]/pad[2] on F.Cu at (1, 0.75)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckConnectivity() = %q\nwant %q", got, want)
+	}
+	if c.Complete() {
+		t.Error("Complete() = true, want false")
+	}
+}
+
+func TestCheckConnectivityBoards(t *testing.T) {
+	p, err := pcb.DecodeFile(filepath.Join("..", "pcb", "testdata", "cseduino-v4.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := CheckConnectivity(p)
+	if !c.Complete() || len(c.Dangling) > 0 || len(c.Islands) > 0 {
+		t.Errorf("CheckConnectivity() = %+v, want a fully routed board", c)
+	}
+
+	p, err = pcb.DecodeFile(filepath.Join("..", "pcb", "testdata", "sci2c-a7001.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := CheckConnectivity(p); len(c.Unrouted) != 8 || c.Complete() {
+		t.Errorf("CheckConnectivity(sci2c-a7001) has %d unrouted connections, want 8", len(c.Unrouted))
+	}
+}
@@ -0,0 +1,81 @@
+package drc
+
+import (
+	"math"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Copper is the copper of a board, indexed to find room for new copper
+// such as stitching vias.
+type Copper struct {
+	c *checker
+}
+
+// NewCopper indexes the copper of a board, including its zone fills.
+func NewCopper(p *pcb.PCB) *Copper {
+	c := newChecker(p, Options{})
+	for _, l := range c.layers {
+		l.buildIndex()
+	}
+	return &Copper{c: c}
+}
+
+// Clear reports whether a circle of radius r on the copper layer keeps
+// clear of copper of nets other than net, and of pads and holes of any
+// net. The larger of clearance and the clearance of the other copper is
+// kept.
+func (cu *Copper) Clear(layer string, at pcb.XY, r float64, net int, clearance float64) bool {
+	c := cu.c
+	for _, h := range c.holes {
+		if d := at.Distance(pcb.ClosestOnSegment(at, h.a, h.b)); d-h.r-r < clearance {
+			return false
+		}
+	}
+	l, ok := c.byName[layer]
+	if !ok {
+		return true
+	}
+
+	clear := true
+	l.index.query(segmentBox(at, at, r+c.maxClearance+clearance), func(k int32) {
+		pr := &l.prims[k]
+		it := &c.items[pr.item]
+		if !clear || (it.net == net && net != 0 && it.kind != kindPad) {
+			return
+		}
+		need := math.Max(clearance, it.clearance)
+		if d := at.Distance(pcb.ClosestOnSegment(at, pr.a, pr.b)); d-pr.r-r < need-tolerance {
+			clear = false
+		}
+	})
+	l.regionIndex.query(segmentBox(at, at, 0), func(k int32) {
+		rg := &l.regions[k]
+		it := &c.items[rg.item]
+		if clear && (it.net != net || net == 0 || it.kind == kindPad) && insidePolygon(at, rg.poly) {
+			clear = false
+		}
+	})
+	return clear
+}
+
+// OnBoard reports whether a circle of radius r is inside the board
+// outline, at least clearance from its edges. Boards without a closed
+// outline have no inside, so any circle is on them.
+func (cu *Copper) OnBoard(at pcb.XY, r, clearance float64) bool {
+	c := cu.c
+	if !closedOutline(c.edges) {
+		return true
+	}
+	in := false
+	for _, e := range c.edges {
+		a, b := e[0], e[1]
+		if (a.Y > at.Y) != (b.Y > at.Y) && at.X < (b.X-a.X)*(at.Y-a.Y)/(b.Y-a.Y)+a.X {
+			in = !in
+		}
+		if at.Distance(pcb.ClosestOnSegment(at, a, b))-r < clearance-tolerance {
+			return false
+		}
+	}
+	return in
+}
@@ -0,0 +1,212 @@
+// Package drc implements design rule checks of the copper on a board,
+// independently of pcbnew so they can be run in CI. It also fills zones,
+// keeping the clearances it checks.
+package drc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Rules checked by Check.
+const (
+	// RuleClearance is used for copper of different nets which is closer
+	// than the clearance of their net classes.
+	RuleClearance = "clearance"
+	// RuleTrackWidth is used for tracks narrower than MinTrackWidth.
+	RuleTrackWidth = "track_width"
+	// RuleAnnularRing is used for vias and plated holes with less than
+	// MinAnnularRing of copper around the hole.
+	RuleAnnularRing = "annular_ring"
+	// RuleHoleToHole is used for holes closer than MinHoleToHole.
+	RuleHoleToHole = "hole_to_hole"
+	// RuleEdgeClearance is used for copper closer than EdgeClearance to
+	// the board outline.
+	RuleEdgeClearance = "edge_clearance"
+)
+
+// Violation describes a design rule violation found by Check.
+type Violation struct {
+	Rule string `json:"rule"`
+	// Element identifies the offending element, in the same form as
+	// pcb.Issue, such as track[3] or module[R1]/pad[2].
+	Element string `json:"element"`
+	// Other identifies the second element involved, if any.
+	Other string `json:"other,omitempty"`
+	// Layer is the copper layer of the violation, if it is specific to
+	// one.
+	Layer string `json:"layer,omitempty"`
+	// At is the location of the violation, in board coordinates.
+	At  pcb.XY `json:"position"`
+	Msg string `json:"msg"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s at %s", v.Element, v.Msg, fmtXY(v.At))
+}
+
+func fmtNum(v float64) string {
+	return strconv.FormatFloat(roundUM(v), 'f', -1, 64)
+}
+
+func fmtXY(p pcb.XY) string {
+	return "(" + fmtNum(p.X) + ", " + fmtNum(p.Y) + ")"
+}
+
+// roundUM rounds to the nearest micrometer, for reporting.
+func roundUM(v float64) float64 {
+	return math.Round(v*1e3) / 1e3
+}
+
+// Options sets the limits checked by Check. Clearances between nets come
+// from the net classes of the board.
+type Options struct {
+	// MinTrackWidth is the narrowest allowed track.
+	MinTrackWidth float64 `json:"min_track_width"`
+	// MinAnnularRing is the narrowest allowed ring of copper around a
+	// plated hole.
+	MinAnnularRing float64 `json:"min_annular_ring"`
+	// MinHoleToHole is the smallest allowed distance between the edges of
+	// two holes.
+	MinHoleToHole float64 `json:"min_hole_to_hole"`
+	// EdgeClearance is the smallest allowed distance between copper and
+	// the board outline.
+	EdgeClearance float64 `json:"edge_clearance"`
+	// MinClearance, if positive, is required between copper of different
+	// nets instead of the clearances of the net classes, such as for the
+	// limits of a fabricator.
+	MinClearance float64 `json:"min_clearance,omitempty"`
+}
+
+// Defaults used when a board does not specify a limit.
+const (
+	defaultHoleToHole  = 0.25
+	defaultAnnularRing = 0.05
+)
+
+// DefaultOptions returns the limits set by the design rules of the board.
+// Limits which pcbnew 5 does not store in the board file are read from
+// the setup section if present, as written by later versions.
+func DefaultOptions(p *pcb.PCB) Options {
+	s := &p.EditorSetup
+	o := Options{
+		MinTrackWidth:  s.TraceMin,
+		MinAnnularRing: defaultAnnularRing,
+		MinHoleToHole:  setupValue(p, "hole_to_hole_min", defaultHoleToHole),
+		EdgeClearance:  setupValue(p, "copper_edge_clearance", newClassTable(p).defaultClearance),
+	}
+	if s.ViaMinSize > s.ViaMinDrill && s.ViaMinDrill > 0 {
+		o.MinAnnularRing = (s.ViaMinSize - s.ViaMinDrill) / 2
+	}
+	o.MinAnnularRing = setupValue(p, "via_min_annulus", o.MinAnnularRing)
+	return o
+}
+
+// setupValue returns a numeric setting from the setup section which the
+// pcb package does not parse.
+func setupValue(p *pcb.PCB, name string, def float64) float64 {
+	n, ok := p.EditorSetup.Unrecognised[name]
+	if !ok || n.NumChildren() < 2 {
+		return def
+	}
+	v, err := n.Child(1).Float64()
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// Check checks the copper of the board against the design rules. The
+// result is sorted by rule and element; an empty result means no
+// violations were found.
+func Check(p *pcb.PCB, opts Options) []Violation {
+	c := newChecker(p, opts)
+	c.checkClearances()
+	c.checkEdges()
+	c.checkHoles()
+
+	out := c.violations
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Rule != out[j].Rule {
+			return out[i].Rule < out[j].Rule
+		}
+		if out[i].Element != out[j].Element {
+			return out[i].Element < out[j].Element
+		}
+		return out[i].Other < out[j].Other
+	})
+	return out
+}
+
+// classTable maps net names to their clearance.
+type classTable struct {
+	defaultClearance float64
+	clearance        map[string]float64
+	viaDrill         map[string]float64
+	uviaDrill        map[string]float64
+	defaultViaDrill  float64
+	defaultUViaDrill float64
+}
+
+func newClassTable(p *pcb.PCB) *classTable {
+	t := &classTable{
+		defaultClearance: p.EditorSetup.TraceClearance,
+		defaultViaDrill:  p.EditorSetup.ViaDrill,
+		defaultUViaDrill: p.EditorSetup.UViaDrill,
+		clearance:        map[string]float64{},
+		viaDrill:         map[string]float64{},
+		uviaDrill:        map[string]float64{},
+	}
+	for _, nc := range p.NetClasses {
+		if nc.Name == "Default" {
+			t.defaultClearance = nc.Clearance
+			t.defaultViaDrill = nc.ViaDrill
+			t.defaultUViaDrill = nc.UViaDrill
+		}
+		for _, n := range nc.Nets {
+			t.clearance[n] = nc.Clearance
+			t.viaDrill[n] = nc.ViaDrill
+			t.uviaDrill[n] = nc.UViaDrill
+		}
+	}
+	return t
+}
+
+func (t *classTable) netClearance(net string) float64 {
+	if c, ok := t.clearance[net]; ok {
+		return c
+	}
+	return t.defaultClearance
+}
+
+// viaDrillFor returns the drill of a via which does not specify one.
+func (t *classTable) viaDrillFor(net string, typ pcb.ViaType) float64 {
+	m, def := t.viaDrill, t.defaultViaDrill
+	if typ == pcb.ViaMicro {
+		m, def = t.uviaDrill, t.defaultUViaDrill
+	}
+	if d, ok := m[net]; ok {
+		return d
+	}
+	return def
+}
+
+// ViaDrills returns the drill of each via of the board. Vias which do not
+// specify a drill use the via drill of their net class, as in pcbnew.
+func ViaDrills(p *pcb.PCB) map[*pcb.Via]float64 {
+	t := newClassTable(p)
+	out := map[*pcb.Via]float64{}
+	for _, s := range p.Segments {
+		if v, ok := s.(*pcb.Via); ok {
+			out[v] = v.Drill
+			if v.Drill == 0 {
+				out[v] = t.viaDrillFor(p.Nets[v.NetIndex].Name, v.ViaType)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,195 @@
+package drc
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+func TestCheck(t *testing.T) {
+	p, err := pcb.DecodeFile(filepath.Join("testdata", "violations.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions(p)
+	if want := (Options{MinTrackWidth: 0.2, MinAnnularRing: 0.125, MinHoleToHole: 0.25, EdgeClearance: 0.2}); opts != want {
+		t.Errorf("DefaultOptions() = %+v, want %+v", opts, want)
+	}
+
+	want := []string{
+		"annular_ring via[3]: annular ring 0.05 is less than 0.125 at (20, 0)",
+		"clearance track[0]: 0.1 from track[1] on F.Cu, need 0.2 at (0, 0.175)",
+		"clearance track[6]: overlaps zone[0] on F.Cu at (3, 3)",
+		"clearance track[7]: 0.05 from module[R1]/pad[1] on F.Cu, need 0.2 at (15.825, 3.5)",
+		"clearance via[3]: overlaps via[4] on F.Cu at (20, 0.3)",
+		"edge_clearance track[5]: 0.075 from the board edge on B.Cu, need 0.2 at (0, 9.9)",
+		"hole_to_hole via[3]: hole is 0.2 from the hole of via[4], need 0.25 at (20, 0.3)",
+		"track_width track[2]: width 0.1 is less than 0.2 at (5, 5)",
+	}
+	var got []string
+	for _, v := range Check(p, opts) {
+		got = append(got, v.Rule+" "+v.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() = %q\nwant %q", got, want)
+	}
+
+	// Zero limits disable the checks which do not involve net classes.
+	got = nil
+	for _, v := range Check(p, Options{}) {
+		got = append(got, v.Rule+" "+v.String())
+	}
+	if want := want[1:5]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() with no limits = %q\nwant %q", got, want)
+	}
+
+	// A minimum clearance replaces those of the net classes.
+	got = nil
+	for _, v := range Check(p, Options{MinClearance: 0.05}) {
+		got = append(got, v.Rule+" "+v.String())
+	}
+	if want := []string{want[2], want[4]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() with a minimum clearance = %q\nwant %q", got, want)
+	}
+}
+
+func TestCheckBoards(t *testing.T) {
+	for _, name := range []string{"t1.kicad_pcb", "sci2c-a7001.kicad_pcb", "zone_equality.kicad_pcb"} {
+		p, err := pcb.DecodeFile(filepath.Join("..", "pcb", "testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vs := Check(p, DefaultOptions(p)); len(vs) > 0 {
+			t.Errorf("Check(%s) = %v, want no violations", name, vs)
+		}
+	}
+}
+
+func TestGrid(t *testing.T) {
+	g := newGrid()
+	boxes := []box{
+		{min: pcb.XY{X: 0, Y: 0}, max: pcb.XY{X: 5, Y: 0.1}},
+		{min: pcb.XY{X: 4.5, Y: -3}, max: pcb.XY{X: 4.6, Y: 3}},
+		{min: pcb.XY{X: -10, Y: -10}, max: pcb.XY{X: -9, Y: -9}},
+	}
+	for i, b := range boxes {
+		g.insert(int32(i), b)
+	}
+	var got []int32
+	g.query(box{min: pcb.XY{X: 4, Y: -0.5}, max: pcb.XY{X: 4.8, Y: 0.5}}, func(id int32) {
+		got = append(got, id)
+	})
+	sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+	if want := []int32{0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("query() = %v, want each of %v once", got, want)
+	}
+}
+
+// benchmarkBoard returns a board with n parallel tracks of alternating
+// nets, 0.25mm wide at 0.5mm pitch, split into 10mm segments.
+func benchmarkBoard(n int) *pcb.PCB {
+	p := &pcb.PCB{
+		Nets:         map[int]pcb.Net{0: {}, 1: {Name: "A"}, 2: {Name: "B"}},
+		LayersByName: map[string]*pcb.Layer{},
+		EditorSetup:  pcb.EditorSetup{TraceClearance: 0.2, TraceMin: 0.2},
+	}
+	for _, l := range []*pcb.Layer{{Num: 0, Name: "F.Cu", Typ: "signal"}, {Num: 31, Name: "B.Cu", Typ: "signal"}} {
+		p.Layers = append(p.Layers, l)
+		p.LayersByName[l.Name] = l
+	}
+	for i := 0; i < n; i++ {
+		row, col := i/20, i%20
+		y := float64(row) * 0.5
+		p.Segments = append(p.Segments, &pcb.Track{
+			Start:    pcb.XY{X: float64(col) * 10, Y: y},
+			End:      pcb.XY{X: float64(col+1) * 10, Y: y},
+			Width:    0.25,
+			Layer:    "F.Cu",
+			NetIndex: 1 + row%2,
+		})
+	}
+	return p
+}
+
+func TestCheckLargeBoard(t *testing.T) {
+	p := benchmarkBoard(20000)
+	if vs := Check(p, DefaultOptions(p)); len(vs) > 0 {
+		t.Errorf("Check() = %d violations, want none; first %v", len(vs), vs[0])
+	}
+}
+
+func BenchmarkCheck(b *testing.B) {
+	p := benchmarkBoard(50000)
+	opts := DefaultOptions(p)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Check(p, opts)
+	}
+}
+
+func TestChain(t *testing.T) {
+	a, b, c, d, e := pcb.XY{X: 0, Y: 0}, pcb.XY{X: 1, Y: 0}, pcb.XY{X: 1, Y: 1}, pcb.XY{X: 0, Y: 1}, pcb.XY{X: 2, Y: 1}
+	tcs := []struct {
+		name string
+		segs [][2]pcb.XY
+		want [][]pcb.XY
+	}{
+		{"reversed", [][2]pcb.XY{{a, b}, {c, b}}, [][]pcb.XY{{a, b, c}}},
+		{"loop", [][2]pcb.XY{{a, b}, {b, c}, {c, d}, {d, a}}, [][]pcb.XY{{a, b, c, d, a}}},
+		{"branch", [][2]pcb.XY{{a, b}, {b, c}, {b, e}}, [][]pcb.XY{{a, b}, {b, c}, {b, e}}},
+		{"empty segment", [][2]pcb.XY{{a, a}, {a, b}}, [][]pcb.XY{{a, b}}},
+	}
+	for _, tc := range tcs {
+		if got := Chain(tc.segs); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Chain() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestOutline(t *testing.T) {
+	p, err := pcb.DecodeFile(filepath.Join("testdata", "violations.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]pcb.XY{{{X: -5, Y: -5}, {X: 30, Y: -5}, {X: 30, Y: 10}, {X: -5, Y: 10}, {X: -5, Y: -5}}}
+	if got := Outline(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("Outline() = %v, want %v", got, want)
+	}
+}
+
+func TestViaDrills(t *testing.T) {
+	p, err := pcb.DecodeFile(filepath.Join("testdata", "violations.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &pcb.Via{Size: 0.8, Layers: []string{"F.Cu", "B.Cu"}}
+	p.Segments = append(p.Segments, v)
+	if got, want := ViaDrills(p)[v], p.NetClasses[0].ViaDrill; got != want || got == 0 {
+		t.Errorf("ViaDrills() = %v for a via without a drill, want the net class drill %v", got, want)
+	}
+}
+
+func TestOnBoard(t *testing.T) {
+	p, err := pcb.DecodeFile(filepath.Join("testdata", "fill.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cu := NewCopper(p)
+	tcs := []struct {
+		at   pcb.XY
+		want bool
+	}{
+		{pcb.XY{X: 15, Y: 10}, true},
+		{pcb.XY{X: 15, Y: 0.6}, true},
+		{pcb.XY{X: 15, Y: 0.4}, false},
+		{pcb.XY{X: 15, Y: -1}, false},
+	}
+	for _, tc := range tcs {
+		if got := cu.OnBoard(tc.at, 0.3, 0.2); got != tc.want {
+			t.Errorf("OnBoard(%v) = %v, want %v", tc.at, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,713 @@
+package drc
+
+import (
+	"math"
+	"sort"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Zones are filled by sampling, on a grid, how far each point is from the
+// outline of the zone and from the copper it must keep clear of, and
+// tracing the outline where that distance is half the minimum thickness
+// of the zone: pcbnew draws filled polygons with an outline of the
+// minimum thickness.
+
+const (
+	// fillStep is the spacing of the grid zones are filled on, in
+	// millimeters.
+	fillStep = 0.05
+	// maxFillSamples limits the size of the grid. Larger zones are filled
+	// on a coarser grid.
+	maxFillSamples = 16000000
+	// fillMargin is kept beyond the clearances of a fill, to allow for the
+	// approximation of its outline.
+	fillMargin = 0.01
+	// fillSimplify is how far the simplified outline of a fill may stray
+	// from the traced outline.
+	fillSimplify = 0.005
+)
+
+// FillZones computes the filled polygons of the zones of a board, as
+// pcbnew does when zones are filled. Fills keep clear of copper of other
+// nets, the board outline, unplated holes, keepouts and zones of other
+// nets with a higher priority. Pads of the same net are connected as set
+// by the zone, their module or themselves: solidly, with thermal reliefs
+// or not at all. Areas narrower than the minimum thickness of a zone, and
+// islands not connected to copper of its net, are removed.
+func FillZones(p *pcb.PCB) {
+	c := newChecker(p, Options{})
+	var order []int
+	for i := range p.Zones {
+		if !p.Zones[i].IsKeepout {
+			order = append(order, i)
+		}
+	}
+	// Zones with a higher priority are filled first, so the zones below
+	// them can keep clear of their fills.
+	sort.SliceStable(order, func(a, b int) bool {
+		return p.Zones[order[a]].Priority > p.Zones[order[b]].Priority
+	})
+	for _, i := range order {
+		c.fillZone(&p.Zones[i])
+	}
+}
+
+// FillZone returns the filled polygons of a zone of the board, as
+// FillZones computes them, without changing the board. Zones of other nets
+// with a higher priority are kept clear of as they are currently filled.
+func FillZone(p *pcb.PCB, z *pcb.Zone) [][]pcb.XY {
+	c := newChecker(p, Options{})
+	fz := *z
+	c.fillZone(&fz)
+	return fz.Polys
+}
+
+// zoneClearance returns the clearance of a zone to copper of other nets,
+// before the clearance of that copper is considered.
+func (c *checker) zoneClearance(z *pcb.Zone) float64 {
+	clearance := z.ConnectPads.Clearance
+	if _, ok := c.p.Nets[z.NetNum]; ok && z.NetNum > 0 {
+		clearance = math.Max(clearance, c.classes.netClearance(c.netName(z.NetNum)))
+	}
+	return clearance
+}
+
+// zoneLayer returns the copper layer a zone is filled on. pcbnew fills
+// zones on a single layer.
+func (c *checker) zoneLayer(z *pcb.Zone) *layerCopper {
+	layers := c.resolveLayers(z.Layers)
+	if len(layers) == 0 {
+		return nil
+	}
+	return layers[0]
+}
+
+// padConnection returns how a zone connects to a pad of its net.
+func padConnection(z *pcb.Zone, it *item) pcb.ZoneConnectMode {
+	var mode pcb.ZoneConnectMode
+	switch {
+	case it.pad.ZoneConnect != pcb.ZoneConnectInherited:
+		mode = it.pad.ZoneConnect
+	case it.mod.ZoneConnect != pcb.ZoneConnectInherited:
+		mode = it.mod.ZoneConnect
+	default:
+		switch z.ConnectPads.Mode {
+		case "yes":
+			mode = pcb.ZoneConnectSolid
+		case "no":
+			mode = pcb.ZoneConnectNone
+		case "thru_hole_only":
+			mode = pcb.ZoneConnectThroughHole
+		default:
+			mode = pcb.ZoneConnectThermal
+		}
+	}
+	if mode == pcb.ZoneConnectThroughHole {
+		if it.pad.Surface == pcb.SurfaceTH {
+			return pcb.ZoneConnectThermal
+		}
+		return pcb.ZoneConnectSolid
+	}
+	return mode
+}
+
+func (c *checker) fillZone(z *pcb.Zone) {
+	z.Polys = nil
+	z.Fill.IsFilled = true
+	l := c.zoneLayer(z)
+	var outline [][2]pcb.XY
+	for _, poly := range z.BasePolys {
+		outline = append(outline, polyEdges(poly)...)
+	}
+	if l == nil || len(outline) == 0 {
+		return
+	}
+
+	level := z.MinThickness/2 + fillMargin
+	g := newFillGrid(edgesBox(outline), level)
+	clearance := c.zoneClearance(z)
+
+	// Only the inside of the zone outline and the board outline is filled.
+	inside := make([]bool, len(g.v))
+	g.spans(outline, func(j, i0, i1 int) {
+		for i := i0; i <= i1; i++ {
+			inside[j*g.nx+i] = true
+		}
+	})
+	if closedOutline(c.edges) {
+		board := make([]bool, len(g.v))
+		g.spans(c.edges, func(j, i0, i1 int) {
+			for i := i0; i <= i1; i++ {
+				board[j*g.nx+i] = true
+			}
+		})
+		for i := range inside {
+			inside[i] = inside[i] && board[i]
+		}
+	}
+	for i, in := range inside {
+		if in {
+			g.v[i] = float32(g.cap)
+		}
+	}
+	for _, e := range outline {
+		g.limitSegment(e[0], e[1], 0)
+	}
+	for _, e := range c.edges {
+		g.limitSegment(e[0], e[1], clearance)
+	}
+
+	// Copper of other nets is kept clear of, as are pads of the zone's net
+	// which it does not connect to. Pads it connects to with thermal
+	// reliefs are collected to be relieved once all prims are known.
+	connected := func(it *item) bool {
+		return z.NetNum != 0 && it.net == z.NetNum
+	}
+	thermals := map[int32][]int{}
+	for k := range l.prims {
+		pr := &l.prims[k]
+		it := &c.items[pr.item]
+		need := math.Max(clearance, it.clearance)
+		switch {
+		case it.kind == kindZone:
+			continue
+		case connected(it) && it.kind == kindPad:
+			switch padConnection(z, it) {
+			case pcb.ZoneConnectThermal:
+				thermals[pr.item] = append(thermals[pr.item], k)
+				continue
+			case pcb.ZoneConnectNone:
+				need = clearance
+			default:
+				continue
+			}
+		case connected(it):
+			continue
+		}
+		g.limitSegment(pr.a, pr.b, pr.r+need)
+	}
+	for _, rg := range l.regions {
+		it := &c.items[rg.item]
+		if it.kind == kindZone || (connected(it) && (it.kind != kindPad || padConnection(z, it) != pcb.ZoneConnectNone)) {
+			continue
+		}
+		g.exclude(polyEdges(rg.poly))
+	}
+	for _, h := range c.holes {
+		if !h.plated {
+			g.limitSegment(h.a, h.b, h.r+clearance)
+		}
+	}
+
+	ids := make([]int32, 0, len(thermals))
+	for id := range thermals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+	for _, id := range ids {
+		c.relieve(g, z, l, id, thermals[id])
+	}
+
+	for i := range c.p.Zones {
+		o := &c.p.Zones[i]
+		if o == z || c.zoneLayer(o) != l {
+			continue
+		}
+		switch {
+		case o.IsKeepout:
+			if !o.Keepout.CopperPourAllowed {
+				var edges [][2]pcb.XY
+				for _, poly := range o.BasePolys {
+					edges = append(edges, polyEdges(poly)...)
+				}
+				g.exclude(edges)
+				for _, e := range edges {
+					g.limitSegment(e[0], e[1], 0)
+				}
+			}
+		case o.Priority > z.Priority && (o.NetNum != z.NetNum || z.NetNum == 0):
+			need := math.Max(clearance, c.zoneClearance(o))
+			for _, poly := range o.Polys {
+				edges := polyEdges(poly)
+				g.exclude(edges)
+				for _, e := range edges {
+					g.limitSegment(e[0], e[1], o.MinThickness/2+need)
+				}
+			}
+		}
+	}
+
+	z.Polys = c.fillPolygons(z, l, g.trace())
+}
+
+// relieve connects the fill to a pad with thermal spokes: the pad is kept
+// clear of by the thermal gap, except along its axes.
+func (c *checker) relieve(g *fillGrid, z *pcb.Zone, l *layerCopper, id int32, prims []int) {
+	it := &c.items[id]
+	gap, width := z.Fill.ThermalGap, z.Fill.ThermalBridgeWidth
+	if it.pad.ThermalGap > 0 {
+		gap = it.pad.ThermalGap
+	}
+	if it.pad.ThermalWidth > 0 {
+		width = it.pad.ThermalWidth
+	}
+	// Spokes narrower than the minimum thickness would not be filled.
+	hw := math.Max(width/2, g.level+fillMargin)
+	angle := it.pad.At.Z
+	if it.pad.Shape == pcb.ShapeCircle {
+		// pcbnew turns the spokes of round pads by 45 degrees.
+		angle += 45
+	}
+	reach := math.Max(it.pad.Size.X, it.pad.Size.Y)/2 + gap + g.cap
+
+	var regions [][]pcb.XY
+	for _, rg := range l.regions {
+		if rg.item == id {
+			regions = append(regions, rg.poly)
+		}
+	}
+	b := segmentBox(it.at, it.at, 0)
+	for _, k := range prims {
+		pb := segmentBox(l.prims[k].a, l.prims[k].b, l.prims[k].r)
+		b.min.X, b.min.Y = math.Min(b.min.X, pb.min.X), math.Min(b.min.Y, pb.min.Y)
+		b.max.X, b.max.Y = math.Max(b.max.X, pb.max.X), math.Max(b.max.Y, pb.max.Y)
+	}
+
+	g.limit(b.inflate(gap+g.cap), func(p pcb.XY) float64 {
+		d := math.Inf(1)
+		for _, k := range prims {
+			pr := &l.prims[k]
+			d = math.Min(d, p.Distance(pcb.ClosestOnSegment(p, pr.a, pr.b))-pr.r)
+		}
+		for _, poly := range regions {
+			if insidePolygon(p, poly) {
+				d = math.Min(d, 0)
+			}
+		}
+		q := pcb.XY{X: p.X - it.at.X, Y: p.Y - it.at.Y}.Rotate(-angle)
+		spoke := math.Max(
+			math.Min(hw-math.Abs(q.Y), reach-math.Abs(q.X)),
+			math.Min(hw-math.Abs(q.X), reach-math.Abs(q.Y)))
+		return math.Max(d-gap, spoke)
+	})
+}
+
+// fillPolygons turns the traced outlines of a fill into the filled
+// polygons of a zone: islands are removed, and holes are joined to the
+// outline around them, as pcbnew cannot represent them otherwise.
+func (c *checker) fillPolygons(z *pcb.Zone, l *layerCopper, loops [][]pcb.XY) [][]pcb.XY {
+	type area struct {
+		outline []pcb.XY
+		size    float64
+		holes   [][]pcb.XY
+	}
+	var outers []*area
+	var holes [][]pcb.XY
+	for _, loop := range loops {
+		loop = simplifyLoop(loop, fillSimplify)
+		a := signedArea(loop)
+		switch {
+		case len(loop) < 3 || math.Abs(a) < fillStep*fillStep:
+		case a > 0:
+			outers = append(outers, &area{outline: loop, size: a})
+		default:
+			holes = append(holes, loop)
+		}
+	}
+	for _, h := range holes {
+		var parent *area
+		for _, o := range outers {
+			if (parent == nil || o.size < parent.size) && insidePolygon(h[0], o.outline) {
+				parent = o
+			}
+		}
+		if parent != nil {
+			parent.holes = append(parent.holes, h)
+		}
+	}
+
+	var out [][]pcb.XY
+	for _, o := range outers {
+		if z.NetNum != 0 && !c.touchesNet(z, l, o.outline, o.holes) {
+			continue
+		}
+		out = append(out, fracture(o.outline, o.holes))
+	}
+	return out
+}
+
+// touchesNet reports whether an area of a fill meets copper of the net of
+// the zone, other than zones.
+func (c *checker) touchesNet(z *pcb.Zone, l *layerCopper, outline []pcb.XY, holes [][]pcb.XY) bool {
+	l.buildIndex()
+	var edges [][2]pcb.XY
+	for _, poly := range append([][]pcb.XY{outline}, holes...) {
+		edges = append(edges, polyEdges(poly)...)
+	}
+	reach := z.MinThickness/2 + fillMargin
+	found := false
+	l.index.query(polyBox(outline).inflate(reach), func(k int32) {
+		pr := &l.prims[k]
+		if it := &c.items[pr.item]; found || it.kind == kindZone || it.net != z.NetNum {
+			return
+		}
+		if insidePolygon(pr.a, outline) {
+			in := true
+			for _, h := range holes {
+				in = in && !insidePolygon(pr.a, h)
+			}
+			if in {
+				found = true
+				return
+			}
+		}
+		for _, e := range edges {
+			if d, _ := pcb.SegmentDistance(pr.a, pr.b, e[0], e[1]); d <= pr.r+reach {
+				found = true
+				return
+			}
+		}
+	})
+	return found
+}
+
+// fillGrid holds samples of how far points are inside the area which may
+// be filled. Values are clamped to cap, as only those near the level of
+// the outline matter.
+type fillGrid struct {
+	origin pcb.XY
+	step   float64
+	nx, ny int
+	v      []float32
+	level  float64
+	cap    float64
+}
+
+func newFillGrid(b box, level float64) *fillGrid {
+	w, h := b.max.X-b.min.X, b.max.Y-b.min.Y
+	step := math.Max(fillStep, math.Sqrt(w*h/maxFillSamples))
+	// The samples around the edge of the grid are never filled, so every
+	// outline traced is closed.
+	b = b.inflate(2 * step)
+	g := &fillGrid{
+		origin: b.min,
+		step:   step,
+		nx:     int(math.Ceil((b.max.X-b.min.X)/step)) + 1,
+		ny:     int(math.Ceil((b.max.Y-b.min.Y)/step)) + 1,
+		level:  level,
+		cap:    level + 2*step,
+	}
+	g.v = make([]float32, g.nx*g.ny)
+	for i := range g.v {
+		g.v[i] = float32(-g.cap)
+	}
+	return g
+}
+
+func (g *fillGrid) at(i, j int) pcb.XY {
+	return pcb.XY{X: g.origin.X + float64(i)*g.step, Y: g.origin.Y + float64(j)*g.step}
+}
+
+func (g *fillGrid) index(x, y float64) (int, int) {
+	return int(math.Floor((x - g.origin.X) / g.step)), int(math.Floor((y - g.origin.Y) / g.step))
+}
+
+// limit lowers each sample within the box to the value of fn at it.
+func (g *fillGrid) limit(b box, fn func(p pcb.XY) float64) {
+	i0, j0 := g.index(b.min.X, b.min.Y)
+	i1, j1 := g.index(b.max.X, b.max.Y)
+	i0, j0 = maxInt(i0, 0), maxInt(j0, 0)
+	i1, j1 = minInt(i1+1, g.nx-1), minInt(j1+1, g.ny-1)
+	for j := j0; j <= j1; j++ {
+		for i := i0; i <= i1; i++ {
+			k := j*g.nx + i
+			if v := fn(g.at(i, j)); v < float64(g.v[k]) {
+				g.v[k] = float32(v)
+			}
+		}
+	}
+}
+
+// limitSegment keeps the fill at least r from the segment a-b.
+func (g *fillGrid) limitSegment(a, b pcb.XY, r float64) {
+	g.limit(segmentBox(a, b, r+g.cap), func(p pcb.XY) float64 {
+		return p.Distance(pcb.ClosestOnSegment(p, a, b)) - r
+	})
+}
+
+// exclude keeps the fill out of the area within the edges. Keeping clear
+// of its outline is left to the caller.
+func (g *fillGrid) exclude(edges [][2]pcb.XY) {
+	g.spans(edges, func(j, i0, i1 int) {
+		for i := i0; i <= i1; i++ {
+			g.v[j*g.nx+i] = float32(-g.cap)
+		}
+	})
+}
+
+// spans calls fn with the runs of samples on each row which are inside
+// the edges, by the even-odd rule.
+func (g *fillGrid) spans(edges [][2]pcb.XY, fn func(j, i0, i1 int)) {
+	if len(edges) == 0 {
+		return
+	}
+	b := edgesBox(edges)
+	_, j0 := g.index(b.min.X, b.min.Y)
+	_, j1 := g.index(b.max.X, b.max.Y)
+	var xs []float64
+	for j := maxInt(j0, 0); j <= minInt(j1+1, g.ny-1); j++ {
+		y := g.origin.Y + float64(j)*g.step
+		xs = xs[:0]
+		for _, e := range edges {
+			a, b := e[0], e[1]
+			if (a.Y > y) != (b.Y > y) {
+				xs = append(xs, a.X+(y-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+			}
+		}
+		sort.Float64s(xs)
+		for k := 0; k+1 < len(xs); k += 2 {
+			i0 := maxInt(int(math.Ceil((xs[k]-g.origin.X)/g.step)), 0)
+			i1 := minInt(int(math.Floor((xs[k+1]-g.origin.X)/g.step)), g.nx-1)
+			if i0 <= i1 {
+				fn(j, i0, i1)
+			}
+		}
+	}
+}
+
+// trace returns the outlines where the samples cross the level, using
+// marching squares. Outlines go anticlockwise around filled areas and
+// clockwise around holes, in a frame where Y points up.
+func (g *fillGrid) trace() [][]pcb.XY {
+	nx := g.nx
+	val := func(i, j int) float64 {
+		if i == 0 || j == 0 || i == nx-1 || j == g.ny-1 {
+			return -g.cap
+		}
+		return float64(g.v[j*nx+i]) - g.level
+	}
+	// Crossings are identified by the edge between samples they are on.
+	hEdge := func(i, j int) int { return (j*nx + i) * 2 }
+	vEdge := func(i, j int) int { return (j*nx+i)*2 + 1 }
+	point := func(e int) pcb.XY {
+		i, j := (e/2)%nx, (e/2)/nx
+		i2, j2 := i+1, j
+		if e%2 == 1 {
+			i2, j2 = i, j+1
+		}
+		va, vb := val(i, j), val(i2, j2)
+		t := va / (va - vb)
+		a, b := g.at(i, j), g.at(i2, j2)
+		return pcb.XY{X: a.X + t*(b.X-a.X), Y: a.Y + t*(b.Y-a.Y)}
+	}
+
+	next := map[int]int{}
+	var starts []int
+	for j := 0; j+1 < g.ny; j++ {
+		for i := 0; i+1 < nx; i++ {
+			v := [4]float64{val(i, j), val(i+1, j), val(i+1, j+1), val(i, j+1)}
+			if v[0] <= 0 && v[1] <= 0 && v[2] <= 0 && v[3] <= 0 {
+				continue
+			}
+			edges := [4]int{hEdge(i, j), vEdge(i+1, j), hEdge(i, j+1), vEdge(i, j)}
+			// Crossings are listed anticlockwise around the cell. Leaving
+			// the filled area at one, the outline continues to the next,
+			// or to the previous if the cell is a saddle whose middle is
+			// not filled.
+			var cross [4]int
+			var leaving [4]bool
+			n := 0
+			for k := 0; k < 4; k++ {
+				if a, b := v[k] > 0, v[(k+1)%4] > 0; a != b {
+					cross[n], leaving[n] = edges[k], a
+					n++
+				}
+			}
+			saddleOpen := n == 4 && v[0]+v[1]+v[2]+v[3] <= 0
+			for m := 0; m < n; m++ {
+				if !leaving[m] {
+					continue
+				}
+				to := (m + 1) % n
+				if saddleOpen {
+					to = (m + n - 1) % n
+				}
+				next[cross[m]] = cross[to]
+				starts = append(starts, cross[m])
+			}
+		}
+	}
+
+	var loops [][]pcb.XY
+	done := map[int]bool{}
+	for _, s := range starts {
+		if done[s] {
+			continue
+		}
+		var loop []pcb.XY
+		for e := s; !done[e]; e = next[e] {
+			done[e] = true
+			loop = append(loop, point(e))
+		}
+		loops = append(loops, loop)
+	}
+	return loops
+}
+
+// fracture joins holes to the outline around them with bridges of zero
+// width, as pcbnew does, so the area can be described by one polygon.
+// Each hole is bridged leftwards from its leftmost point, in order from
+// the left so bridges only meet the outline and holes already joined.
+func fracture(outline []pcb.XY, holes [][]pcb.XY) []pcb.XY {
+	leftmost := func(h []pcb.XY) int {
+		best := 0
+		for i, p := range h {
+			if p.X < h[best].X || (p.X == h[best].X && p.Y < h[best].Y) {
+				best = i
+			}
+		}
+		return best
+	}
+	sort.SliceStable(holes, func(a, b int) bool {
+		return holes[a][leftmost(holes[a])].X < holes[b][leftmost(holes[b])].X
+	})
+
+	poly := append([]pcb.XY(nil), outline...)
+	for _, h := range holes {
+		li := leftmost(h)
+		start := h[li]
+		at, best := -1, math.Inf(-1)
+		for i := range poly {
+			a, b := poly[i], poly[(i+1)%len(poly)]
+			if (a.Y > start.Y) == (b.Y > start.Y) {
+				continue
+			}
+			if x := a.X + (start.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y); x <= start.X && x > best {
+				at, best = i, x
+			}
+		}
+		if at < 0 {
+			continue
+		}
+		bridge := pcb.XY{X: best, Y: start.Y}
+		joined := make([]pcb.XY, 0, len(poly)+len(h)+3)
+		joined = append(joined, poly[:at+1]...)
+		joined = append(joined, bridge)
+		joined = append(joined, h[li:]...)
+		joined = append(joined, h[:li+1]...)
+		joined = append(joined, bridge)
+		joined = append(joined, poly[at+1:]...)
+		poly = joined
+	}
+	return poly
+}
+
+// simplifyLoop removes points from a closed outline which are within tol
+// of the outline without them, using the Douglas-Peucker algorithm.
+func simplifyLoop(pts []pcb.XY, tol float64) []pcb.XY {
+	if len(pts) < 4 {
+		return pts
+	}
+	far := 0
+	for i, p := range pts {
+		if p.Distance(pts[0]) > pts[far].Distance(pts[0]) {
+			far = i
+		}
+	}
+	if far == 0 {
+		return pts[:1]
+	}
+	a := simplifyLine(pts[:far+1], tol)
+	b := simplifyLine(append(append([]pcb.XY(nil), pts[far:]...), pts[0]), tol)
+	return append(a[:len(a)-1], b[:len(b)-1]...)
+}
+
+func simplifyLine(pts []pcb.XY, tol float64) []pcb.XY {
+	keep := make([]bool, len(pts))
+	keep[0], keep[len(pts)-1] = true, true
+	stack := [][2]int{{0, len(pts) - 1}}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		worst, at := tol, -1
+		for i := s[0] + 1; i < s[1]; i++ {
+			if d := pts[i].Distance(pcb.ClosestOnSegment(pts[i], pts[s[0]], pts[s[1]])); d > worst {
+				worst, at = d, i
+			}
+		}
+		if at >= 0 {
+			keep[at] = true
+			stack = append(stack, [2]int{s[0], at}, [2]int{at, s[1]})
+		}
+	}
+	out := make([]pcb.XY, 0, len(pts))
+	for i, p := range pts {
+		if keep[i] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func signedArea(poly []pcb.XY) float64 {
+	var a float64
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		a += p.X*q.Y - q.X*p.Y
+	}
+	return a / 2
+}
+
+func polyEdges(poly []pcb.XY) [][2]pcb.XY {
+	out := make([][2]pcb.XY, 0, len(poly))
+	for i := range poly {
+		out = append(out, [2]pcb.XY{poly[i], poly[(i+1)%len(poly)]})
+	}
+	return out
+}
+
+func edgesBox(edges [][2]pcb.XY) box {
+	b := box{min: edges[0][0], max: edges[0][0]}
+	for _, e := range edges {
+		for _, p := range e {
+			b.min.X, b.min.Y = math.Min(b.min.X, p.X), math.Min(b.min.Y, p.Y)
+			b.max.X, b.max.Y = math.Max(b.max.X, p.X), math.Max(b.max.Y, p.Y)
+		}
+	}
+	return b
+}
+
+// closedOutline reports whether the edges form closed loops, so the
+// inside of the board can be found from them.
+func closedOutline(edges [][2]pcb.XY) bool {
+	if len(edges) == 0 {
+		return false
+	}
+	ends := map[[2]int64]int{}
+	for _, e := range edges {
+		for _, p := range e {
+			ends[[2]int64{int64(math.Round(p.X * 1e3)), int64(math.Round(p.Y * 1e3))}]++
+		}
+	}
+	for _, n := range ends {
+		if n%2 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+package drc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+func filled(z *pcb.Zone, p pcb.XY) bool {
+	for _, poly := range z.Polys {
+		if insidePolygon(p, poly) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFillZones(t *testing.T) {
+	p, err := pcb.DecodeFile(filepath.Join("testdata", "fill.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	FillZones(p)
+	gnd, vcc := &p.Zones[0], &p.Zones[1]
+	if len(gnd.Polys) != 1 || len(vcc.Polys) != 1 {
+		t.Fatalf("got %d GND and %d VCC polygons, want 1 of each", len(gnd.Polys), len(vcc.Polys))
+	}
+
+	tcs := []struct {
+		name string
+		zone *pcb.Zone
+		at   pcb.XY
+		want bool
+	}{
+		{"open area", gnd, pcb.XY{X: 12, Y: 5}, true},
+		{"board edge clearance", gnd, pcb.XY{X: 12, Y: 0.4}, false},
+		{"track clearance", gnd, pcb.XY{X: 10, Y: 12.9}, false},
+		{"beyond track clearance", gnd, pcb.XY{X: 10, Y: 13.2}, true},
+		{"thermal gap", gnd, pcb.XY{X: 6.1, Y: 10}, false},
+		{"thermal spoke", gnd, pcb.XY{X: 5.75, Y: 10.75}, true},
+		{"solid SMD pad", gnd, pcb.XY{X: 13.4, Y: 10}, true},
+		{"other net pad", gnd, pcb.XY{X: 16.6, Y: 10}, false},
+		{"unplated hole", gnd, pcb.XY{X: 26.6, Y: 4}, false},
+		{"keepout", gnd, pcb.XY{X: 22, Y: 17}, false},
+		{"island", gnd, pcb.XY{X: 29.2, Y: 10}, false},
+		{"higher priority zone", gnd, pcb.XY{X: 5, Y: 3}, false},
+		{"priority zone", vcc, pcb.XY{X: 5, Y: 3}, true},
+		{"priority zone outline", vcc, pcb.XY{X: 5, Y: 1.1}, false},
+	}
+	for _, tc := range tcs {
+		if got := filled(tc.zone, tc.at); got != tc.want {
+			t.Errorf("%s: filled at %v = %v, want %v", tc.name, tc.at, got, tc.want)
+		}
+	}
+
+	if vs := Check(p, Options{}); len(vs) > 0 {
+		t.Errorf("Check() = %v, want no violations", vs)
+	}
+	if c := CheckConnectivity(p); len(c.Unrouted) > 0 || len(c.Shorts) > 0 {
+		t.Errorf("CheckConnectivity() = %+v, want the GND pads connected by the fill", c)
+	}
+}
+
+func TestFillZonesDeletedNet(t *testing.T) {
+	p, err := pcb.DecodeFile(filepath.Join("testdata", "fill.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Net numbers are sparse once a net is deleted, so VCC is numbered
+	// beyond the number of nets.
+	if err := p.DeleteNet(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	p.NetClasses[0].Nets = []string{"GND"}
+	p.NetClasses = append(p.NetClasses, pcb.NetClass{Name: "Power", Clearance: 1, TraceWidth: 0.5, ViaDiameter: 0.6, ViaDrill: 0.3, Nets: []string{"VCC"}})
+	p.Segments = append(p.Segments, &pcb.Via{At: pcb.XY{X: 6, Y: 3}, Size: 0.6, Drill: 0.3, Layers: []string{"F.Cu", "B.Cu"}, NetIndex: 1})
+	FillZones(p)
+
+	vcc := &p.Zones[1]
+	if filled(vcc, pcb.XY{X: 6.9, Y: 3}) {
+		t.Error("VCC is filled within the clearance of its net class of the GND via")
+	}
+	if !filled(vcc, pcb.XY{X: 7.5, Y: 3}) {
+		t.Error("VCC is not filled beyond the clearance of its net class")
+	}
+}
+
+func TestFillZonesBoards(t *testing.T) {
+	// The fills of this board are out of date, and short tracks routed
+	// after they were filled.
+	p, err := pcb.DecodeFile(filepath.Join("..", "pcb", "testdata", "hp34401a_oled.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := CheckConnectivity(p); len(c.Shorts) == 0 {
+		t.Fatal("CheckConnectivity() found no shorts before filling")
+	}
+	FillZones(p)
+	if c := CheckConnectivity(p); !c.Complete() {
+		t.Errorf("CheckConnectivity() = %+v after filling, want a complete board", c)
+	}
+	for _, v := range Check(p, Options{}) {
+		if v.Element == "zone[0]" || v.Element == "zone[1]" || v.Other == "zone[0]" || v.Other == "zone[1]" {
+			t.Errorf("Check() = %v after filling", v)
+		}
+	}
+}
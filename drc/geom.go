@@ -0,0 +1,66 @@
+package drc
+
+import (
+	"math"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// arcSteps is the number of segments used per 360 degrees of an arc.
+const arcSteps = 64
+
+// box is an axis-aligned bounding box.
+type box struct {
+	min, max pcb.XY
+}
+
+func segmentBox(a, b pcb.XY, r float64) box {
+	return box{
+		min: pcb.XY{X: math.Min(a.X, b.X) - r, Y: math.Min(a.Y, b.Y) - r},
+		max: pcb.XY{X: math.Max(a.X, b.X) + r, Y: math.Max(a.Y, b.Y) + r},
+	}
+}
+
+func polyBox(poly []pcb.XY) box {
+	b := box{min: poly[0], max: poly[0]}
+	for _, p := range poly[1:] {
+		b.min.X, b.min.Y = math.Min(b.min.X, p.X), math.Min(b.min.Y, p.Y)
+		b.max.X, b.max.Y = math.Max(b.max.X, p.X), math.Max(b.max.Y, p.Y)
+	}
+	return b
+}
+
+func (b box) inflate(d float64) box {
+	return box{
+		min: pcb.XY{X: b.min.X - d, Y: b.min.Y - d},
+		max: pcb.XY{X: b.max.X + d, Y: b.max.Y + d},
+	}
+}
+
+// arcPoints approximates an arc centered on center, sweeping angle
+// degrees clockwise from start, as a polyline.
+func arcPoints(center, start pcb.XY, angle float64) []pcb.XY {
+	n := int(math.Ceil(math.Abs(angle) / 360 * arcSteps))
+	if n < 1 {
+		n = 1
+	}
+	rel := pcb.XY{X: start.X - center.X, Y: start.Y - center.Y}
+	out := []pcb.XY{start}
+	for i := 1; i <= n; i++ {
+		out = append(out, center.Add(rel.Rotate(-angle*float64(i)/float64(n))))
+	}
+	return out
+}
+
+// insidePolygon reports whether p is inside the polygon, using the
+// even-odd rule.
+func insidePolygon(p pcb.XY, poly []pcb.XY) bool {
+	in := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,56 @@
+package drc
+
+import "math"
+
+// cellSize is the size of a grid cell of the spatial index, in
+// millimeters. It is a few times the typical clearance, so most queries
+// touch only a handful of cells.
+const cellSize = 1.0
+
+// grid is a spatial index which buckets entries by the grid cells their
+// bounding box overlaps.
+type grid struct {
+	cells map[[2]int32][]int32
+	// seen and stamp deduplicate entries spanning several cells within a
+	// query.
+	seen  []int32
+	stamp int32
+}
+
+func newGrid() *grid {
+	return &grid{cells: map[[2]int32][]int32{}}
+}
+
+func cellRange(b box) (x0, y0, x1, y1 int32) {
+	return int32(math.Floor(b.min.X / cellSize)), int32(math.Floor(b.min.Y / cellSize)),
+		int32(math.Floor(b.max.X / cellSize)), int32(math.Floor(b.max.Y / cellSize))
+}
+
+// insert adds the entry with the given ID, which must be the number of
+// entries inserted so far.
+func (g *grid) insert(id int32, b box) {
+	x0, y0, x1, y1 := cellRange(b)
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			k := [2]int32{x, y}
+			g.cells[k] = append(g.cells[k], id)
+		}
+	}
+	g.seen = append(g.seen, 0)
+}
+
+// query calls fn once for each entry whose cells overlap the box.
+func (g *grid) query(b box, fn func(id int32)) {
+	g.stamp++
+	x0, y0, x1, y1 := cellRange(b)
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			for _, id := range g.cells[[2]int32{x, y}] {
+				if g.seen[id] != g.stamp {
+					g.seen[id] = g.stamp
+					fn(id)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package drc
+
+import (
+	"math"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Outline returns the board outline as polylines, with arcs approximated
+// by segments. Closed loops end at the point they start from.
+func Outline(p *pcb.PCB) [][]pcb.XY {
+	c := &checker{p: p}
+	c.collectEdges()
+	for i := range p.Modules {
+		c.collectModuleEdges(&p.Modules[i])
+	}
+	return Chain(c.edges)
+}
+
+// Chain joins segments which share ends into polylines. Polylines end
+// where three or more segments meet, and closed loops end at the point
+// they start from.
+func Chain(segs [][2]pcb.XY) [][]pcb.XY {
+	type key [2]int64
+	keyOf := func(p pcb.XY) key {
+		return key{int64(math.Round(p.X * 1e3)), int64(math.Round(p.Y * 1e3))}
+	}
+	ends := map[key][]int{}
+	used := make([]bool, len(segs))
+	for i, s := range segs {
+		if keyOf(s[0]) == keyOf(s[1]) {
+			used[i] = true
+			continue
+		}
+		ends[keyOf(s[0])] = append(ends[keyOf(s[0])], i)
+		ends[keyOf(s[1])] = append(ends[keyOf(s[1])], i)
+	}
+
+	walk := func(i int, from pcb.XY) []pcb.XY {
+		line := []pcb.XY{from}
+		for {
+			used[i] = true
+			s := segs[i]
+			next := s[1]
+			if keyOf(s[1]) == keyOf(from) {
+				next = s[0]
+			}
+			line = append(line, next)
+			at := ends[keyOf(next)]
+			if len(at) != 2 {
+				return line
+			}
+			j := at[0]
+			if j == i {
+				j = at[1]
+			}
+			if used[j] {
+				return line
+			}
+			i, from = j, next
+		}
+	}
+
+	var out [][]pcb.XY
+	// Open polylines start at their ends, or where they branch.
+	for i, s := range segs {
+		for _, p := range s {
+			if !used[i] && len(ends[keyOf(p)]) != 2 {
+				out = append(out, walk(i, p))
+			}
+		}
+	}
+	// What remains are closed loops.
+	for i, s := range segs {
+		if !used[i] {
+			out = append(out, walk(i, s[0]))
+		}
+	}
+	return out
+}
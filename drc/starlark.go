@@ -0,0 +1,103 @@
+package drc
+
+import (
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// MakeConnectivity checks the connectivity of a board from starlark. The
+// result is a struct with the fields of Connectivity, plus complete.
+var MakeConnectivity = starlark.NewBuiltin("connectivity", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var p *pcb.PCB
+	if err := starlark.UnpackArgs("connectivity", args, kwargs, "pcb", &p); err != nil {
+		return starlark.None, err
+	}
+	return connectivityValue(CheckConnectivity(p)), nil
+})
+
+// MakeFillZones computes the filled polygons of the zones of a board from
+// starlark, returning the board.
+var MakeFillZones = starlark.NewBuiltin("fill_zones", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var p *pcb.PCB
+	if err := starlark.UnpackArgs("fill_zones", args, kwargs, "pcb", &p); err != nil {
+		return starlark.None, err
+	}
+	FillZones(p)
+	return p, nil
+})
+
+// MakeBoardOutline returns the board outline from starlark, as a list of
+// polylines of XY points.
+var MakeBoardOutline = starlark.NewBuiltin("board_outline", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var p *pcb.PCB
+	if err := starlark.UnpackArgs("board_outline", args, kwargs, "pcb", &p); err != nil {
+		return starlark.None, err
+	}
+	var out []starlark.Value
+	for _, line := range Outline(p) {
+		pts := make([]starlark.Value, len(line))
+		for i, pt := range line {
+			pts[i] = xyValue(pt)
+		}
+		out = append(out, starlark.NewList(pts))
+	}
+	return starlark.NewList(out), nil
+})
+
+func connectivityValue(c *Connectivity) starlark.Value {
+	var unrouted, dangling, islands, shorts []starlark.Value
+	for _, u := range c.Unrouted {
+		unrouted = append(unrouted, makeStruct(starlark.StringDict{
+			"net":           starlark.String(u.Net),
+			"from":          starlark.String(u.From),
+			"to":            starlark.String(u.To),
+			"from_position": xyValue(u.FromAt),
+			"to_position":   xyValue(u.ToAt),
+			"length":        starlark.Float(u.Length),
+		}))
+	}
+	for _, d := range c.Dangling {
+		dangling = append(dangling, makeStruct(starlark.StringDict{
+			"element":  starlark.String(d.Element),
+			"net":      starlark.String(d.Net),
+			"layer":    starlark.String(d.Layer),
+			"position": xyValue(d.At),
+		}))
+	}
+	for _, i := range c.Islands {
+		var elems []starlark.Value
+		for _, e := range i.Elements {
+			elems = append(elems, starlark.String(e))
+		}
+		islands = append(islands, makeStruct(starlark.StringDict{
+			"net":      starlark.String(i.Net),
+			"elements": starlark.NewList(elems),
+			"position": xyValue(i.At),
+		}))
+	}
+	for _, s := range c.Shorts {
+		shorts = append(shorts, makeStruct(starlark.StringDict{
+			"nets":     starlark.Tuple{starlark.String(s.Nets[0]), starlark.String(s.Nets[1])},
+			"element":  starlark.String(s.Element),
+			"other":    starlark.String(s.Other),
+			"layer":    starlark.String(s.Layer),
+			"position": xyValue(s.At),
+		}))
+	}
+	return makeStruct(starlark.StringDict{
+		"complete": starlark.Bool(c.Complete()),
+		"unrouted": starlark.NewList(unrouted),
+		"dangling": starlark.NewList(dangling),
+		"islands":  starlark.NewList(islands),
+		"shorts":   starlark.NewList(shorts),
+	})
+}
+
+func makeStruct(d starlark.StringDict) starlark.Value {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, d)
+}
+
+func xyValue(p pcb.XY) starlark.Value {
+	return &pcb.XY{X: p.X, Y: p.Y}
+}
@@ -3,7 +3,6 @@ module github.com/twitchyliquid64/kcgen
 go 1.12
 
 require (
-	github.com/JamesMilnerUK/pip-go v0.0.0-20180711171552-99c4cbbc7deb
 	github.com/alecthomas/chroma v0.6.8
 	github.com/erikstmartin/go-testdb v0.0.0-20160219214506-8d10e4a1bae5 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
@@ -18,4 +17,5 @@ require (
 	github.com/ziutek/mymysql v1.5.4 // indirect
 	go.starlark.net v0.0.0-20191016215632-c9eda478e54e
 	golang.org/x/image v0.5.0
+	gopkg.in/yaml.v2 v2.4.0
 )
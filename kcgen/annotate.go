@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/netlist"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// stringList is a flag which may be given multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// annotateMain implements 'kcgen annotate', which updates a PCB to match
+// a netlist exported from the schematic.
+func annotateMain(args []string) error {
+	fs := flag.NewFlagSet("annotate", flag.ExitOnError)
+	var libDirs stringList
+	fs.Var(&libDirs, "L", "Directory containing <library>.pretty footprint libraries. May be repeated; searched before the fp-lib-table files and $KISYSMOD.")
+	outPath := fs.String("o", "", "Where to write the updated PCB. Defaults to overwriting the input.")
+	deleteExtra := fs.Bool("delete-extra", false, "Remove unlocked modules which are not in the netlist.")
+	replace := fs.Bool("replace", false, "Replace modules whose footprint differs from the netlist.")
+	dryRun := fs.Bool("n", false, "Report changes without writing the PCB.")
+	asJSON := fs.Bool("json", false, "Report changes as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s annotate [flags] <file.kicad_pcb> <file.net>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("expected a PCB and a netlist")
+	}
+
+	board, err := pcb.DecodeFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	nl, err := netlist.DecodeFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	res, err := pcb.Annotate(board, nl, pcb.AnnotateOptions{
+		Footprints:        pcb.FootprintLoaders(pcb.DirFootprintLoader(libDirs...), pcb.DefaultFootprintLoader(filepath.Dir(fs.Arg(0)))),
+		ReplaceFootprints: *replace,
+		DeleteExtra:       *deleteExtra,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			return err
+		}
+	} else {
+		printAnnotateResult(res)
+	}
+	if *dryRun {
+		return nil
+	}
+
+	// Serialize fully before writing, so a failure leaves the file intact.
+	var buf bytes.Buffer
+	if err := board.Write(&buf); err != nil {
+		return err
+	}
+	if *outPath == "" {
+		*outPath = fs.Arg(0)
+	}
+	return ioutil.WriteFile(*outPath, buf.Bytes(), 0644)
+}
+
+func printAnnotateResult(res *pcb.AnnotateResult) {
+	for _, ref := range res.Added {
+		fmt.Printf("add %s\n", ref)
+	}
+	for _, ref := range res.Replaced {
+		fmt.Printf("replace %s\n", ref)
+	}
+	for _, ref := range res.Removed {
+		fmt.Printf("remove %s\n", ref)
+	}
+	for _, ref := range res.Extra {
+		fmt.Printf("extra %s: not in the netlist\n", ref)
+	}
+	for _, name := range res.NetsAdded {
+		fmt.Printf("add net %s\n", name)
+	}
+	for _, name := range res.NetsRemoved {
+		fmt.Printf("remove net %s\n", name)
+	}
+	for _, w := range res.Warnings {
+		fmt.Printf("warning: %s\n", w)
+	}
+}
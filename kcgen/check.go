@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/twitchyliquid64/kcgen/kcsl"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// checkMain implements 'kcgen check', which checks boards against the
+// rules defined by a script.
+func checkMain(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	rules := fs.String("rules", "rules.kcsl", "Path of the script which defines the rules.")
+	asJSON := fs.Bool("json", false, "Report violations as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s check [flags] <file.kicad_pcb>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no files specified")
+	}
+
+	data, err := ioutil.ReadFile(*rules)
+	if err != nil {
+		return err
+	}
+	script, err := kcsl.NewScript(data, *rules, false, &kcsl.WDLoader{}, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer script.Close()
+	if len(script.Rules()) == 0 {
+		return fmt.Errorf("%s: no rules defined", *rules)
+	}
+
+	type fileViolation struct {
+		File string `json:"file"`
+		kcsl.RuleViolation
+	}
+	violations := []fileViolation{}
+	errs := 0
+	for _, path := range fs.Args() {
+		board, err := pcb.DecodeFile(path)
+		if err != nil {
+			return err
+		}
+		vs, err := script.CheckRules(board)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		for _, v := range vs {
+			if v.Severity == kcsl.SeverityError {
+				errs++
+			}
+			violations = append(violations, fileViolation{File: path, RuleViolation: v})
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(violations); err != nil {
+			return err
+		}
+	} else {
+		for _, v := range violations {
+			fmt.Printf("%s: %v\n", v.File, v.RuleViolation)
+		}
+	}
+
+	if errs > 0 {
+		return fmt.Errorf("%d errors found", errs)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/twitchyliquid64/kcgen/drc"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// connectivityMain implements 'kcgen connectivity', which reports the
+// unrouted connections, dangling tracks, isolated copper and shorts of
+// boards.
+func connectivityMain(args []string) error {
+	fs := flag.NewFlagSet("connectivity", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Report the connectivity as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s connectivity [flags] <file.kicad_pcb>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no files specified")
+	}
+
+	type fileConnectivity struct {
+		File string `json:"file"`
+		*drc.Connectivity
+	}
+	var (
+		results    []fileConnectivity
+		incomplete int
+	)
+	for _, path := range fs.Args() {
+		board, err := pcb.DecodeFile(path)
+		if err != nil {
+			return err
+		}
+		c := drc.CheckConnectivity(board)
+		if !c.Complete() {
+			incomplete++
+		}
+		results = append(results, fileConnectivity{File: path, Connectivity: c})
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			for _, c := range r.Unrouted {
+				fmt.Printf("%s: %v\n", r.File, c)
+			}
+			for _, s := range r.Shorts {
+				fmt.Printf("%s: %v\n", r.File, s)
+			}
+			for _, i := range r.Islands {
+				fmt.Printf("%s: %v\n", r.File, i)
+			}
+			for _, d := range r.Dangling {
+				fmt.Printf("%s: %v\n", r.File, d)
+			}
+		}
+	}
+
+	if incomplete > 0 {
+		return fmt.Errorf("%d boards not fully connected", incomplete)
+	}
+	return nil
+}
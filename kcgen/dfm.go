@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/dfm"
+	"github.com/twitchyliquid64/kcgen/drc"
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"github.com/twitchyliquid64/kcgen/pcb/library"
+)
+
+// dfmMain implements 'kcgen dfm', which checks boards and footprints
+// against the capabilities of a fabricator.
+func dfmMain(args []string) error {
+	fs := flag.NewFlagSet("dfm", flag.ExitOnError)
+	profile := fs.String("profile", "standard", "Name of a bundled fab profile, or the path of a JSON or YAML profile.")
+	list := fs.Bool("profiles", false, "List the bundled fab profiles.")
+	asJSON := fs.Bool("json", false, "Report violations as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s dfm [flags] <file.kicad_pcb|file.kicad_mod|library.pretty>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *list {
+		for _, name := range dfm.ProfileNames() {
+			fmt.Printf("%s\t%s\n", name, dfm.Profiles[name].Description)
+		}
+		return nil
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no files specified")
+	}
+	prof, err := dfm.Lookup(*profile)
+	if err != nil {
+		return err
+	}
+
+	type fileViolation struct {
+		File string `json:"file"`
+		drc.Violation
+	}
+	violations := []fileViolation{}
+	add := func(path string, vs []drc.Violation) {
+		for _, v := range vs {
+			violations = append(violations, fileViolation{File: path, Violation: v})
+		}
+	}
+	var unreadable int
+	for _, path := range fs.Args() {
+		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			l, err := library.Open(path)
+			if err != nil {
+				return err
+			}
+			for _, e := range l.Footprints() {
+				// One broken footprint should not hide the results for the
+				// rest of the library.
+				m, err := l.Load(e.Name)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "warning: skipping footprint %s: %v\n", e.ID(), err)
+					unreadable++
+					continue
+				}
+				add(filepath.Join(path, e.Name+library.Ext), dfm.CheckModule(m, prof))
+			}
+			continue
+		}
+		if strings.HasSuffix(path, library.Ext) {
+			m, err := pcb.DecodeModuleFile(path)
+			if err != nil {
+				return err
+			}
+			add(path, dfm.CheckModule(m, prof))
+			continue
+		}
+		board, err := pcb.DecodeFile(path)
+		if err != nil {
+			return err
+		}
+		add(path, dfm.Check(board, prof))
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(violations); err != nil {
+			return err
+		}
+	} else {
+		for _, v := range violations {
+			fmt.Printf("%s: %s %v\n", v.File, v.Rule, v.Violation)
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%d violations of the %s profile found", len(violations), prof.Name)
+	}
+	if unreadable > 0 {
+		return fmt.Errorf("%d footprints could not be read", unreadable)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// diffMain implements 'kcgen diff', which reports the semantic differences
+// between two footprints or two boards.
+func diffMain(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Report differences as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s diff [-json] <a.kicad_mod> <b.kicad_mod>\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "       %s diff [-json] <a.kicad_pcb> <b.kicad_pcb>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("expected two files")
+	}
+
+	var changes []pcb.Change
+	switch a, b := fs.Arg(0), fs.Arg(1); {
+	case filepath.Ext(a) == ".kicad_mod" && filepath.Ext(b) == ".kicad_mod":
+		ma, err := pcb.DecodeModuleFile(a)
+		if err != nil {
+			return err
+		}
+		mb, err := pcb.DecodeModuleFile(b)
+		if err != nil {
+			return err
+		}
+		changes = pcb.DiffModules(ma, mb)
+	case filepath.Ext(a) == ".kicad_pcb" && filepath.Ext(b) == ".kicad_pcb":
+		pa, err := pcb.DecodeFile(a)
+		if err != nil {
+			return err
+		}
+		pb, err := pcb.DecodeFile(b)
+		if err != nil {
+			return err
+		}
+		changes = pcb.DiffPCBs(pa, pb)
+	default:
+		return errors.New("expected two .kicad_mod files or two .kicad_pcb files")
+	}
+
+	if *asJSON {
+		if changes == nil {
+			changes = []pcb.Change{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(changes); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	}
+
+	// Like diff(1), exit with a non-zero status if the files differ.
+	if len(changes) > 0 {
+		return fmt.Errorf("%d differences found", len(changes))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/twitchyliquid64/kcgen/drc"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// drcMain implements 'kcgen drc', which checks the copper of boards
+// against their design rules.
+func drcMain(args []string) error {
+	fs := flag.NewFlagSet("drc", flag.ExitOnError)
+	minTrack := fs.Float64("min-track-width", -1, "Narrowest allowed track in millimeters. Negative uses the board's design rules.")
+	minRing := fs.Float64("min-annular-ring", -1, "Narrowest allowed annular ring in millimeters. Negative uses the board's design rules.")
+	minHoles := fs.Float64("min-hole-to-hole", -1, "Smallest allowed distance between holes in millimeters. Negative uses the board's design rules.")
+	edge := fs.Float64("edge-clearance", -1, "Smallest allowed distance from copper to the board edge in millimeters. Negative uses the board's design rules.")
+	asJSON := fs.Bool("json", false, "Report violations as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s drc [flags] <file.kicad_pcb>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no files specified")
+	}
+
+	type fileViolation struct {
+		File string `json:"file"`
+		drc.Violation
+	}
+	violations := []fileViolation{}
+	for _, path := range fs.Args() {
+		board, err := pcb.DecodeFile(path)
+		if err != nil {
+			return err
+		}
+		opts := drc.DefaultOptions(board)
+		for _, o := range []struct {
+			flag float64
+			opt  *float64
+		}{
+			{*minTrack, &opts.MinTrackWidth},
+			{*minRing, &opts.MinAnnularRing},
+			{*minHoles, &opts.MinHoleToHole},
+			{*edge, &opts.EdgeClearance},
+		} {
+			if o.flag >= 0 {
+				*o.opt = o.flag
+			}
+		}
+		for _, v := range drc.Check(board, opts) {
+			violations = append(violations, fileViolation{File: path, Violation: v})
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(violations); err != nil {
+			return err
+		}
+	} else {
+		for _, v := range violations {
+			fmt.Printf("%s: %s %v\n", v.File, v.Rule, v.Violation)
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%d violations found", len(violations))
+	}
+	return nil
+}
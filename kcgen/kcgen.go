@@ -2,20 +2,39 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 
 	"github.com/twitchyliquid64/kcgen/kcsl"
+	"github.com/twitchyliquid64/kcgen/pcb"
 	"go.starlark.net/resolve"
 )
 
 var (
 	verbose = flag.Bool("verbose", false, "Enables verbose logging.")
 	out     = flag.String("o", "-", "Where to write output.")
+	inPlace = flag.String("in-place", "", "PCB to run the script against, which is overwritten with the result.")
 )
 
+// commands are invoked as 'kcgen <command> [args...]' instead of running
+// a script.
+var commands = map[string]func(args []string) error{
+	"validate":     validateMain,
+	"annotate":     annotateMain,
+	"lib":          libMain,
+	"diff":         diffMain,
+	"lint":         lintMain,
+	"drc":          drcMain,
+	"connectivity": connectivityMain,
+	"length":       lengthMain,
+	"dfm":          dfmMain,
+	"check":        checkMain,
+	"stitch":       stitchMain,
+}
+
 func loadScript(p string) ([]byte, error) {
 	d, err := os.Stat(p)
 	if err != nil {
@@ -30,19 +49,43 @@ func loadScript(p string) ([]byte, error) {
 func main() {
 	flag.Parse()
 	resolve.AllowFloat = true
+
+	if cmd, ok := commands[flag.Arg(0)]; ok {
+		if err := cmd(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	sData, err := loadScript(flag.Arg(0))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load script: %v\n", err)
 		os.Exit(1)
 	}
 
-	script, err := kcsl.NewScript(sData, flag.Arg(0), *verbose, &kcsl.WDLoader{}, flag.Args()[1:], nil)
+	var script *kcsl.Script
+	if *inPlace != "" {
+		var board *pcb.PCB
+		if board, err = pcb.DecodeFile(*inPlace); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load PCB: %v\n", err)
+			os.Exit(1)
+		}
+		script, err = kcsl.NewEditScript(sData, flag.Arg(0), *verbose, &kcsl.WDLoader{}, flag.Args()[1:], board, nil)
+	} else {
+		script, err = kcsl.NewScript(sData, flag.Arg(0), *verbose, &kcsl.WDLoader{}, flag.Args()[1:], nil)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Initialization failed: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := run(script); err != nil {
+	if *inPlace != "" {
+		err = runInPlace(script, *inPlace)
+	} else {
+		err = run(script)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
@@ -60,7 +103,11 @@ func run(s *kcsl.Script) error {
 		defer outF.Close()
 	}
 
-	if m := s.Mod(); m != nil {
+	if p := s.Pcb(); p != nil {
+		if err := p.Write(outF); err != nil {
+			return err
+		}
+	} else if m := s.Mod(); m != nil {
 		m.WriteModule(outF)
 	}
 
@@ -69,3 +116,20 @@ func run(s *kcsl.Script) error {
 	}
 	return nil
 }
+
+// runInPlace writes the edited PCB back to the file it was loaded from.
+// The board is fully serialized before the file is touched, so a failure
+// leaves the original intact.
+func runInPlace(s *kcsl.Script, path string) error {
+	defer s.Close()
+	p := s.Pcb()
+	if p == nil {
+		return fmt.Errorf("script did not produce a PCB")
+	}
+
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/twitchyliquid64/kcgen/netlen"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// lengthMain implements 'kcgen length', which reports the routed length of
+// each net and checks differential pairs and length groups.
+func lengthMain(args []string) error {
+	fs := flag.NewFlagSet("length", flag.ExitOnError)
+	config := fs.String("config", "", "JSON file setting the diff pair skew and length groups.")
+	asJSON := fs.Bool("json", false, "Report lengths as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s length [flags] <file.kicad_pcb>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no files specified")
+	}
+
+	cfg := netlen.DefaultConfig()
+	if *config != "" {
+		var err error
+		if cfg, err = netlen.LoadConfig(*config); err != nil {
+			return err
+		}
+	}
+
+	type fileReport struct {
+		File string `json:"file"`
+		*netlen.Report
+	}
+	var (
+		reports  []fileReport
+		problems int
+	)
+	for _, path := range fs.Args() {
+		board, err := pcb.DecodeFile(path)
+		if err != nil {
+			return err
+		}
+		r, err := netlen.Measure(board, cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		problems += len(r.Problems)
+		reports = append(reports, fileReport{File: path, Report: r})
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range reports {
+			for _, c := range r.Classes {
+				fmt.Printf("%s: net class %s\n", r.File, c.Name)
+				for _, n := range c.Nets {
+					fmt.Printf("  %v\n", n)
+				}
+			}
+			if len(r.DiffPairs) > 0 {
+				fmt.Printf("%s: diff pairs\n", r.File)
+				for _, d := range r.DiffPairs {
+					fmt.Printf("  %v\n", d)
+				}
+			}
+			if len(r.Groups) > 0 {
+				fmt.Printf("%s: length groups\n", r.File)
+				for _, g := range r.Groups {
+					fmt.Printf("  %v\n", g)
+				}
+			}
+			for _, p := range r.Problems {
+				fmt.Printf("%s: %s %v\n", r.File, p.Kind, p)
+			}
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%d length problems found", problems)
+	}
+	return nil
+}
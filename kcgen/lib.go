@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"github.com/twitchyliquid64/kcgen/pcb/library"
+)
+
+// libMain implements 'kcgen lib', which works with footprint libraries.
+func libMain(args []string) error {
+	if len(args) > 0 && args[0] == "search" {
+		return libSearchMain(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "Usage: %s lib search [flags] [keywords...]\n", os.Args[0])
+	return errors.New("expected a lib subcommand")
+}
+
+func libSearchMain(args []string) error {
+	fs := flag.NewFlagSet("lib search", flag.ExitOnError)
+	var libDirs, tags stringList
+	fs.Var(&libDirs, "L", "A .pretty library, or a directory containing them, to search. May be repeated. Defaults to the libraries in the fp-lib-table files.")
+	fs.Var(&tags, "tag", "Only list footprints with this tag. May be repeated.")
+	pads := fs.Int("pads", 0, "Only list footprints with this many numbered pads.")
+	pitch := fs.Float64("pitch", 0, "Only list footprints with this pad pitch, in millimeters.")
+	asJSON := fs.Bool("json", false, "List footprints as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s lib search [flags] [keywords...]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	libs, err := openLibraries(libDirs)
+	if err != nil {
+		return err
+	}
+	for _, l := range libs {
+		for _, e := range l.Footprints() {
+			if e.Error != "" {
+				fmt.Fprintf(os.Stderr, "warning: skipping footprint %s: %s\n", e.ID(), e.Error)
+			}
+		}
+	}
+	res := library.NewIndex(libs...).Search(library.Query{
+		Keywords: fs.Args(),
+		Tags:     tags,
+		Pads:     *pads,
+		Pitch:    *pitch,
+	})
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if res == nil {
+			res = []library.Entry{}
+		}
+		return enc.Encode(res)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, e := range res {
+		fmt.Fprintf(w, "%s\t%d pads\t%s\n", e.ID(), e.Pads, e.Description)
+	}
+	return w.Flush()
+}
+
+// openLibraries opens the libraries given with -L, or those in the
+// fp-lib-table files if there are none.
+func openLibraries(dirs []string) ([]*library.Library, error) {
+	var out []*library.Library
+	for _, dir := range dirs {
+		if strings.HasSuffix(dir, ".pretty") {
+			l, err := library.Open(dir)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, l)
+			continue
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if !f.IsDir() || !strings.HasSuffix(f.Name(), ".pretty") {
+				continue
+			}
+			l, err := library.Open(filepath.Join(dir, f.Name()))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, l)
+		}
+	}
+	if len(dirs) > 0 {
+		return out, nil
+	}
+
+	t, err := pcb.LoadFPLibTables(".")
+	if err != nil {
+		return nil, err
+	}
+	for _, fl := range t.Libraries() {
+		if fl.Type != "" && !strings.EqualFold(fl.Type, "KiCad") {
+			continue
+		}
+		l, err := library.Open(t.ResolveURI(&fl))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping library %s: %v\n", fl.Name, err)
+			continue
+		}
+		l.Name = fl.Name
+		out = append(out, l)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no footprint libraries found: configure a fp-lib-table or use -L")
+	}
+	return out, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/twitchyliquid64/kcgen/lint"
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"github.com/twitchyliquid64/kcgen/pcb/library"
+)
+
+// lintMain implements 'kcgen lint', which checks footprints against the
+// KiCad library conventions.
+func lintMain(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	var ignore stringList
+	fs.Var(&ignore, "ignore", "Rule ID to skip, such as F5.1. May be repeated.")
+	fix := fs.Bool("fix", false, "Apply automatic fixes, overwriting the footprints.")
+	rules := fs.Bool("rules", false, "List the rules which are checked.")
+	asJSON := fs.Bool("json", false, "Report results as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s lint [flags] <file.kicad_mod|library.pretty>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *rules {
+		for _, id := range lint.RuleIDs() {
+			fmt.Printf("%s\t%s\n", id, lint.Rules[id])
+		}
+		return nil
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no footprints specified")
+	}
+	skip := map[string]bool{}
+	for _, id := range ignore {
+		if _, ok := lint.Rules[id]; !ok {
+			return fmt.Errorf("unknown rule %q", id)
+		}
+		skip[id] = true
+	}
+
+	type fileResult struct {
+		File string `json:"file"`
+		lint.Result
+	}
+	results := []fileResult{}
+	check := func(path string, m *pcb.Module, save func(*pcb.Module) error) error {
+		var rs []lint.Result
+		for _, r := range lint.Check(m) {
+			if !skip[r.Rule] {
+				rs = append(rs, r)
+			}
+		}
+		if *fix && lint.ApplyFixes(rs) > 0 {
+			if err := save(m); err != nil {
+				return err
+			}
+			// Report what is left to fix by hand.
+			rs = rs[:0]
+			for _, r := range lint.Check(m) {
+				if !skip[r.Rule] {
+					rs = append(rs, r)
+				}
+			}
+		}
+		for _, r := range rs {
+			results = append(results, fileResult{File: path, Result: r})
+		}
+		return nil
+	}
+
+	for _, path := range fs.Args() {
+		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			l, err := library.Open(path)
+			if err != nil {
+				return err
+			}
+			for _, e := range l.Footprints() {
+				m, err := l.Load(e.Name)
+				if err != nil {
+					return err
+				}
+				if err := check(filepath.Join(path, e.Name+library.Ext), m, l.Replace); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		m, err := pcb.DecodeModuleFile(path)
+		if err != nil {
+			return err
+		}
+		save := func(m *pcb.Module) error {
+			var buf bytes.Buffer
+			if err := m.WriteModule(&buf); err != nil {
+				return err
+			}
+			buf.WriteString("\n")
+			return ioutil.WriteFile(path, buf.Bytes(), 0644)
+		}
+		if err := check(path, m, save); err != nil {
+			return err
+		}
+	}
+
+	var errs int
+	for _, r := range results {
+		if r.Severity == lint.Error {
+			errs++
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			fmt.Printf("%s: %v\n", r.File, r.Result)
+		}
+	}
+
+	// Warnings alone do not fail the check.
+	if errs > 0 {
+		return fmt.Errorf("%d errors found", errs)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"github.com/twitchyliquid64/kcgen/stitch"
+)
+
+// stitchMain implements 'kcgen stitch', which adds stitching vias joining
+// the zones of a net on different layers of a PCB.
+func stitchMain(args []string) error {
+	def := stitch.DefaultOptions()
+	fs := flag.NewFlagSet("stitch", flag.ExitOnError)
+	net := fs.String("net", def.Net, "The net whose zones are stitched.")
+	strategy := fs.String("strategy", def.Strategy, "How vias are placed: grid, staggered or edge.")
+	spacing := fs.Float64("spacing", def.Spacing, "The distance between vias, in mm.")
+	viaSize := fs.Float64("via-size", 0, "The diameter of vias, in mm. Defaults to that of the net class.")
+	viaDrill := fs.Float64("via-drill", 0, "The drill of vias, in mm. Defaults to that of the net class.")
+	clearance := fs.Float64("clearance", 0, "The minimum clearance to other nets, in mm, if larger than that of the net classes.")
+	outPath := fs.String("o", "", "Where to write the updated PCB. Defaults to overwriting the input.")
+	dryRun := fs.Bool("n", false, "Report vias without writing the PCB.")
+	asJSON := fs.Bool("json", false, "Report the vias as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s stitch [flags] <file.kicad_pcb>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected a PCB")
+	}
+
+	board, err := pcb.DecodeFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	vias, err := stitch.Stitch(board, stitch.Options{
+		Net:       *net,
+		Strategy:  *strategy,
+		Spacing:   *spacing,
+		ViaSize:   *viaSize,
+		ViaDrill:  *viaDrill,
+		Clearance: *clearance,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", fs.Arg(0), err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(vias); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("%s: added %d vias to %s\n", fs.Arg(0), len(vias), *net)
+	}
+	if *dryRun {
+		return nil
+	}
+
+	// Serialize fully before writing, so a failure leaves the file intact.
+	var buf bytes.Buffer
+	if err := board.Write(&buf); err != nil {
+		return err
+	}
+	if *outPath == "" {
+		*outPath = fs.Arg(0)
+	}
+	return ioutil.WriteFile(*outPath, buf.Bytes(), 0644)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// validateMain implements 'kcgen validate', which reports structural
+// problems in PCB files.
+func validateMain(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Report issues as JSON.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s validate [-json] <file.kicad_pcb>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no files specified")
+	}
+
+	type fileIssue struct {
+		File string `json:"file"`
+		pcb.Issue
+	}
+	issues := []fileIssue{}
+	for _, path := range fs.Args() {
+		board, err := pcb.DecodeFile(path)
+		if err != nil {
+			return err
+		}
+		for _, issue := range pcb.Validate(board) {
+			issues = append(issues, fileIssue{File: path, Issue: issue})
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Printf("%s: %v\n", issue.File, issue.Issue)
+		}
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("%d issues found", len(issues))
+	}
+	return nil
+}
@@ -2,12 +2,28 @@ package kcsl
 
 import (
 	"bufio"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/twitchyliquid64/kcgen/pcb"
 	"go.starlark.net/starlark"
 )
 
+// footprintsKey is the thread-local key of the pcb.FootprintLoader used
+// to resolve footprint library identifiers.
+const footprintsKey = "footprints"
+
+// isFootprintID returns true if path looks like a library identifier such
+// as Resistor_SMD:R_0805_2012Metric, rather than a path to a file.
+func isFootprintID(path string) bool {
+	if !strings.Contains(path, ":") || strings.ContainsAny(path, `/\`) || strings.HasSuffix(path, ".kicad_mod") {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err != nil
+}
+
 var fileLoadMod = starlark.NewBuiltin("load_mod", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var p starlark.String
 	if err := starlark.UnpackArgs("load_mod", args, kwargs,
@@ -15,6 +31,15 @@ var fileLoadMod = starlark.NewBuiltin("load_mod", func(thread *starlark.Thread,
 		return starlark.None, err
 	}
 
+	if isFootprintID(string(p)) {
+		loader, ok := thread.Local(footprintsKey).(pcb.FootprintLoader)
+		if !ok || loader == nil {
+			return starlark.None, fmt.Errorf("load_mod: cannot load %s: no footprint libraries configured", p)
+		}
+		parts := strings.SplitN(string(p), ":", 2)
+		return loader(parts[0], parts[1])
+	}
+
 	f, err := os.Open(string(p))
 	if err != nil {
 		return starlark.None, err
@@ -22,3 +47,12 @@ var fileLoadMod = starlark.NewBuiltin("load_mod", func(thread *starlark.Thread,
 	defer f.Close()
 	return pcb.ParseModule(bufio.NewReader(f))
 })
+
+var fileLoadPCB = starlark.NewBuiltin("load_pcb", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var p starlark.String
+	if err := starlark.UnpackArgs("load_pcb", args, kwargs,
+		"path", &p); err != nil {
+		return starlark.None, err
+	}
+	return pcb.DecodeFile(string(p))
+})
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 
 	"github.com/twitchyliquid64/kcgen/kcsl/lib"
 	"github.com/twitchyliquid64/kcgen/pcb"
@@ -45,6 +46,8 @@ func (s *Script) loadScript(script []byte, fname string, loader ScriptLoader) (*
 			Print: s.printFromSkylark,
 			Load:  load,
 		}
+		thread.SetLocal(footprintsKey, s.footprints)
+		thread.SetLocal(rulesKey, &s.rules)
 		mod, err2 := starlark.ExecFile(thread, module, d, builtins)
 		if err2 != nil {
 			return nil, err2
@@ -57,8 +60,19 @@ func (s *Script) loadScript(script []byte, fname string, loader ScriptLoader) (*
 		Print: s.printFromSkylark,
 		Load:  load,
 	}
+	thread.SetLocal(footprintsKey, s.footprints)
+	thread.SetLocal(rulesKey, &s.rules)
+
+	predeclared := builtins
+	if s.board != nil {
+		predeclared = make(starlark.StringDict, len(builtins)+1)
+		for k, v := range builtins {
+			predeclared[k] = v
+		}
+		predeclared["pcb"] = s.board
+	}
 
-	globals, err := starlark.ExecFile(thread, fname, script, builtins)
+	globals, err := starlark.ExecFile(thread, fname, script, predeclared)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -77,6 +91,13 @@ type Script struct {
 	thread   *starlark.Thread
 	globals  starlark.StringDict
 	setupVal starlark.Value
+
+	// board is the PCB being edited, if any.
+	board *pcb.PCB
+	// footprints resolves the library identifiers passed to file.load_mod.
+	footprints pcb.FootprintLoader
+	// rules are the checks registered with rule().
+	rules []scriptRule
 }
 
 // Close shuts down all resources associated with the script.
@@ -86,10 +107,17 @@ func (s *Script) Close() error {
 
 // NewScript initializes a new raspberry-box script environment.
 func NewScript(data []byte, fname string, verbose bool, loader ScriptLoader, args []string, printer func(string)) (*Script, error) {
-	return makeScript(data, fname, loader, args, verbose, nil, printer)
+	return makeScript(data, fname, loader, args, verbose, nil, nil, printer)
 }
 
-func makeScript(data []byte, fname string, loader ScriptLoader, args []string, verbose bool,
+// NewEditScript initializes a script environment which edits an existing
+// PCB. The board is available to the script as the global pcb, and is
+// modified in place.
+func NewEditScript(data []byte, fname string, verbose bool, loader ScriptLoader, args []string, board *pcb.PCB, printer func(string)) (*Script, error) {
+	return makeScript(data, fname, loader, args, verbose, board, nil, printer)
+}
+
+func makeScript(data []byte, fname string, loader ScriptLoader, args []string, verbose bool, board *pcb.PCB,
 	testHook func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error),
 	printer func(string)) (*Script, error) {
 	out := &Script{
@@ -97,6 +125,9 @@ func makeScript(data []byte, fname string, loader ScriptLoader, args []string, v
 		args:    args,
 		verbose: verbose,
 		printer: printer,
+		board:   board,
+		// Scripts are treated as part of the project in their directory.
+		footprints: pcb.DefaultFootprintLoader(filepath.Dir(fname)),
 	}
 
 	var err error
@@ -162,12 +193,13 @@ func (s *Script) Mod() *pcb.Module {
 	return nil
 }
 
-// Pcb returns a generated PCB, if applicable.
+// Pcb returns a generated PCB, if applicable. When editing a PCB, the
+// edited board is returned unless the script replaced it.
 func (s *Script) Pcb() *pcb.PCB {
 	if p, ok := s.globals["pcb"]; ok {
 		if pcb, ok := p.(*pcb.PCB); ok {
 			return pcb
 		}
 	}
-	return nil
+	return s.board
 }
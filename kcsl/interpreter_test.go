@@ -0,0 +1,679 @@
+package kcsl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+)
+
+func init() {
+	resolve.AllowFloat = true
+}
+
+func TestLoadPCB(t *testing.T) {
+	s, err := NewScript([]byte(`
+pcb = file.load_pcb("../pcb/testdata/zone_equality.kicad_pcb")
+num_zones = len(pcb.zones)
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	if s.Pcb() == nil {
+		t.Fatal("Pcb() = nil, want loaded board")
+	}
+	if got, want := s.globals["num_zones"].String(), "1"; got != want {
+		t.Errorf("len(pcb.zones) = %v, want %v", got, want)
+	}
+}
+
+func TestEditNets(t *testing.T) {
+	board, err := pcb.DecodeFile("../pcb/testdata/zone_equality.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewEditScript([]byte(`
+pcb.nets[1].name = "VSS"
+`), "test.kcsl", false, nil, nil, board, func(string) {})
+	if err != nil {
+		t.Fatalf("NewEditScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	if got, want := board.Nets[1].Name, "VSS"; got != want {
+		t.Errorf("board.Nets[1].Name = %q, want %q", got, want)
+	}
+}
+
+func TestEditScript(t *testing.T) {
+	board, err := pcb.DecodeFile("../pcb/testdata/sci2c-a7001.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	numDrawings := len(board.Drawings)
+
+	s, err := NewEditScript([]byte(`
+def snap(v):
+    return float(int(v))
+
+def edit():
+    for m in pcb.modules:
+        m.placement.at.x = snap(m.placement.at.x)
+        m.placement.at.y = snap(m.placement.at.y)
+    for nc in pcb.net_classes:
+        nc.clearance = 0.3
+
+edit()
+pcb.drawings = pcb.drawings + [Line(start=XY(0, 0), end=XY(10, 10), layer=layers.edge, width=0.1)]
+`), "test.kcsl", false, nil, nil, board, func(string) {})
+	if err != nil {
+		t.Fatalf("NewEditScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	if got := s.Pcb(); got != board {
+		t.Fatalf("Pcb() = %p, want edited board %p", got, board)
+	}
+	for i, m := range board.Modules {
+		if at := m.Placement.At; at.X != float64(int(at.X)) || at.Y != float64(int(at.Y)) {
+			t.Errorf("module %d at (%v, %v), want snapped to integer grid", i, at.X, at.Y)
+		}
+	}
+	for _, nc := range board.NetClasses {
+		if nc.Clearance != 0.3 {
+			t.Errorf("net class %q clearance = %v, want 0.3", nc.Name, nc.Clearance)
+		}
+	}
+	if got, want := len(board.Drawings), numDrawings+1; got != want {
+		t.Errorf("len(board.Drawings) = %d, want %d", got, want)
+	}
+}
+
+func TestZones(t *testing.T) {
+	s, err := NewScript([]byte(`
+load("pcb.lib", p="pcb")
+load("shapes.lib", "shapes")
+
+pcb = PCB(
+    nets = [Net("GND")],
+    zones = [
+        p.pour(net=1, net_name="GND", layers=[layers.back.copper], outline=shapes.box(20, 10), clearance=0.3),
+        p.keepout(outline=shapes.box(4, 4), vias=True),
+    ],
+)
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	board := s.Pcb()
+	if board == nil {
+		t.Fatal("Pcb() = nil, want generated board")
+	}
+	if got, want := board.Nets[1].Name, "GND"; got != want {
+		t.Errorf("net 1 name = %q, want %q", got, want)
+	}
+	if got, want := len(board.Zones), 2; got != want {
+		t.Fatalf("len(board.Zones) = %d, want %d", got, want)
+	}
+
+	pour, keepout := board.Zones[0], board.Zones[1]
+	if pour.IsKeepout || pour.NetNum != 1 || pour.NetName != "GND" {
+		t.Errorf("pour = {keepout: %v, net: %d %q}, want {false, 1 \"GND\"}", pour.IsKeepout, pour.NetNum, pour.NetName)
+	}
+	if got, want := pour.ConnectPads.Clearance, 0.3; got != want {
+		t.Errorf("pour clearance = %v, want %v", got, want)
+	}
+	if got, want := len(pour.BasePolys), 1; got != want || len(pour.BasePolys[0]) != 4 {
+		t.Errorf("pour outline = %v, want a single 4 point polygon", pour.BasePolys)
+	}
+	if !keepout.IsKeepout {
+		t.Error("keepout.IsKeepout = false, want true")
+	}
+	if want := (pcb.ZoneKeepout{ViasAllowed: true}); keepout.Keepout != want {
+		t.Errorf("keepout rules = %+v, want %+v", keepout.Keepout, want)
+	}
+}
+
+func TestDimensionBox(t *testing.T) {
+	s, err := NewScript([]byte(`
+load("shapes.lib", "shapes")
+load("draw.lib", "draw")
+
+pcb = PCB(drawings = draw.pcb.dimension_box(shapes.box(40, 20, center=XY(100, 100))))
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	var got []string
+	for _, d := range s.Pcb().Drawings {
+		dim, ok := d.(*pcb.Dimension)
+		if !ok {
+			t.Fatalf("drawing has type %T, want *pcb.Dimension", d)
+		}
+		if dim.Layer != "Dwgs.User" {
+			t.Errorf("dimension %q on layer %q, want Dwgs.User", dim.Text.Text, dim.Layer)
+		}
+		got = append(got, dim.Text.Text)
+	}
+	if want := []string{"40 mm", "20 mm"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dimensions = %q, want %q", got, want)
+	}
+}
+
+func TestDesignRules(t *testing.T) {
+	s, err := NewScript([]byte(`
+pcb = PCB(
+    nets = [Net("GND"), Net("VBUS")],
+    net_classes = [
+        NetClass(name="Default", description="House rules", clearance=0.15),
+        NetClass(name="Power", description="Power", trace_width=0.5, nets=["VBUS"]),
+    ],
+    setup = EditorSetup(trace_clearance=0.15, via_drill=0.3, plot_params={"outputdirectory": "gerbers/"}),
+    title_info = TitleInfo(title="Widget", revision="B", comments=["Made by kcgen"]),
+)
+pcb.setup.solder_mask_min_width = 0.1
+pcb.title_info.company = "ACME"
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	board, defaults := s.Pcb(), pcb.EmptyPCB()
+	if got, want := len(board.NetClasses), 2; got != want {
+		t.Fatalf("len(board.NetClasses) = %d, want %d", got, want)
+	}
+	if nc := board.NetClasses[0]; nc.Name != "Default" || nc.Clearance != 0.15 || nc.TraceWidth != defaults.NetClasses[0].TraceWidth {
+		t.Errorf("default net class = %+v, want clearance overridden and other rules defaulted", nc)
+	}
+	if nc := board.NetClasses[1]; nc.TraceWidth != 0.5 || nc.Clearance != defaults.NetClasses[0].Clearance || !reflect.DeepEqual(nc.Nets, []string{"VBUS"}) {
+		t.Errorf("power net class = %+v, want trace width 0.5 with default clearance", nc)
+	}
+
+	setup := board.EditorSetup
+	if setup.TraceClearance != 0.15 || setup.ViaDrill != 0.3 || setup.SolderMaskMinWidth != 0.1 {
+		t.Errorf("setup = {clearance %v, via drill %v, mask min %v}, want {0.15, 0.3, 0.1}", setup.TraceClearance, setup.ViaDrill, setup.SolderMaskMinWidth)
+	}
+	if got, want := setup.ViaSize, defaults.EditorSetup.ViaSize; got != want {
+		t.Errorf("setup.ViaSize = %v, want default %v", got, want)
+	}
+	if got, want := len(setup.PlotParams), len(defaults.EditorSetup.PlotParams); got != want {
+		t.Errorf("len(setup.PlotParams) = %d, want %d", got, want)
+	}
+
+	want := &pcb.TitleInfo{Title: "Widget", Revision: "B", Company: "ACME", Comments: [4]string{"Made by kcgen"}}
+	if !reflect.DeepEqual(board.TitleInfo, want) {
+		t.Errorf("board.TitleInfo = %+v, want %+v", board.TitleInfo, want)
+	}
+}
+
+func TestCopperLayers(t *testing.T) {
+	s, err := NewScript([]byte(`
+pcb = PCB(
+    copper_layers = 4,
+    drawings = [Line(start=XY(0, 0), end=XY(10, 0), layer=layers.user.comments, width=0.1)],
+    segments = [Track(start=XY(0, 0), end=XY(10, 0), layer=layers.inner(2), width=0.2)],
+)
+stackup = [(l.name, l.type) for l in pcb.stackup]
+num_copper = pcb.copper_layers
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	if got, want := s.globals["num_copper"].String(), "4"; got != want {
+		t.Errorf("pcb.copper_layers = %v, want %v", got, want)
+	}
+	if l := s.Pcb().LayersByName["In2.Cu"]; l == nil || l.Num != 2 {
+		t.Errorf("In2.Cu = %+v, want layer 2", l)
+	}
+	if got, want := s.Pcb().Segments[0].(*pcb.Track).Layer, "In2.Cu"; got != want {
+		t.Errorf("track layer = %q, want %q", got, want)
+	}
+	if got, want := s.Pcb().Drawings[0].(*pcb.Line).Layer, "Cmts.User"; got != want {
+		t.Errorf("line layer = %q, want %q", got, want)
+	}
+	if got, want := s.globals["stackup"].(*starlark.List).Len(), 9; got != want {
+		t.Errorf("len(pcb.stackup) = %d, want %d", got, want)
+	}
+
+	if _, err := NewScript([]byte(`x = layers.inner(31)`), "test.kcsl", false, nil, nil, func(string) {}); err == nil {
+		t.Error("layers.inner(31) did not fail")
+	}
+	if _, err := NewScript([]byte(`pcb = PCB(copper_layers = 3)`), "test.kcsl", false, nil, nil, func(string) {}); err == nil {
+		t.Error("PCB(copper_layers = 3) did not fail")
+	}
+}
+
+func TestBoardGeneral(t *testing.T) {
+	s, err := NewScript([]byte(`
+pcb = PCB(
+    thickness = 0.8,
+    page = Page("User", width=200, height=100),
+    nets = [Net("GND")],
+    drawings = [Line(start=XY(0, 0), end=XY(10, 0), layer=layers.edge, width=0.1)],
+)
+thickness = pcb.thickness
+pcb.page.width = 250
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	board := s.Pcb()
+	if got, want := board.Page, (pcb.Page{Size: "User", Width: 250, Height: 100}); got != want {
+		t.Errorf("board.Page = %+v, want %+v", got, want)
+	}
+	if got, want := s.globals["thickness"].String(), "0.8"; got != want {
+		t.Errorf("pcb.thickness = %v, want %v", got, want)
+	}
+
+	var b bytes.Buffer
+	if err := board.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"(thickness 0.8)", "(drawings 1)", "(nets 2)", "(page User 250 100)"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+
+	if _, err := NewScript([]byte(`pcb = PCB(page = "A9")`), "test.kcsl", false, nil, nil, func(string) {}); err == nil {
+		t.Error("PCB(page = \"A9\") did not fail")
+	}
+}
+
+func TestNetNames(t *testing.T) {
+	s, err := NewScript([]byte(`
+pcb = PCB(
+    nets = [Net("GND")],
+    segments = [
+        Track(start=XY(0, 0), end=XY(10, 0), layer=layers.front.copper, width=0.2, net_index="VCC"),
+        Via(at=XY(10, 0), size=0.8, drill=0.4, layers=[layers.front.copper, layers.back.copper], net_index="GND"),
+    ],
+    zones = [Zone(net_num="GND", layers=[layers.back.copper], outline=[XY(0, 0), XY(10, 0), XY(10, 10)])],
+)
+vcc = pcb.net("VCC")
+missing = pcb.net("MISO")
+sda = pcb.add_net("SDA")
+pcb.rename_net("VCC", "3V3")
+pcb.merge_nets("SDA", "GND")
+pcb.compact_nets()
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	if got, want := s.globals["vcc"].String(), "2"; got != want {
+		t.Errorf("pcb.net(\"VCC\") = %v, want %v", got, want)
+	}
+	if got := s.globals["missing"]; got != starlark.None {
+		t.Errorf("pcb.net(\"MISO\") = %v, want None", got)
+	}
+	if got, want := s.globals["sda"].String(), "3"; got != want {
+		t.Errorf("pcb.add_net(\"SDA\") = %v, want %v", got, want)
+	}
+
+	board := s.Pcb()
+	if got, want := board.Nets[2].Name, "3V3"; got != want {
+		t.Errorf("net 2 = %q, want %q", got, want)
+	}
+	if _, ok := board.Nets[3]; ok {
+		t.Errorf("net 3 was not merged")
+	}
+	if got := board.Segments[1].(*pcb.Via).NetIndex; got != 1 {
+		t.Errorf("via net = %d, want 1", got)
+	}
+	if z := board.Zones[0]; z.NetNum != 1 || z.NetName != "GND" {
+		t.Errorf("zone net = %d (%q), want 1 (GND)", z.NetNum, z.NetName)
+	}
+	if issues := pcb.Validate(board); len(issues) > 0 {
+		t.Errorf("Validate() = %v", issues)
+	}
+
+	if _, err := NewScript([]byte(`
+pcb = PCB()
+pcb.delete_net("MISO")
+`), "test.kcsl", false, nil, nil, func(string) {}); err == nil {
+		t.Error("pcb.delete_net(\"MISO\") did not fail")
+	}
+
+	// Renaming a net of the board through its nets updates the elements
+	// on the net.
+	s, err = NewScript([]byte(`
+pcb = PCB(
+    nets = [Net("GND")],
+    zones = [Zone(net_num="GND", layers=[layers.back.copper], outline=[XY(0, 0), XY(10, 0), XY(10, 10)])],
+)
+pcb.nets[1].name = "VSS"
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+	if z := s.Pcb().Zones[0]; z.NetName != "VSS" {
+		t.Errorf("zone net name = %q, want VSS", z.NetName)
+	}
+}
+
+func TestModFlip(t *testing.T) {
+	s, err := NewScript([]byte(`
+m = Mod(
+    name = "test",
+    layer = layers.front.copper,
+    placement = ModPlacement(at = XYZ(10, 20, 90)),
+    graphics = [ModGraphic("fp_line", ModLine(start=XY(-1, 1), end=XY(1, 1), layer=layers.front.silkscreen, width=0.12))],
+    pads = [Pad("1", at=XYZ(1, 0, 90), size=XY(1, 1), layers=layers.front.smd, shape=shape.rect, surface=pad.smd)],
+)
+before = m.pad_position("1")
+m.flip()
+after = m.pad_position(m.pads[0])
+corner = m.to_board(XY(1, 0))
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	m := s.globals["m"].(*pcb.Module)
+	if m.Layer != "B.Cu" || m.Placement.At.Z != -90 {
+		t.Errorf("flipped module on %s at %v, want B.Cu at -90", m.Layer, m.Placement.At.Z)
+	}
+	if got, want := m.Graphics[0].Renderable.(*pcb.ModLine).Layer, "B.SilkS"; got != want {
+		t.Errorf("line layer = %q, want %q", got, want)
+	}
+	if got, want := *s.globals["before"].(*pcb.XYZ), (pcb.XYZ{X: 10, Y: 19, Z: 90, ZPresent: true}); got != want {
+		t.Errorf("pad position before flip = %+v, want %+v", got, want)
+	}
+	if got, want := *s.globals["after"].(*pcb.XYZ), (pcb.XYZ{X: 10, Y: 21, Z: 270, ZPresent: true}); got != want {
+		t.Errorf("pad position after flip = %+v, want %+v", got, want)
+	}
+	if got, want := *s.globals["corner"].(*pcb.XY), (pcb.XY{X: 10, Y: 21}); got != want {
+		t.Errorf("mod.to_board() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadModFromLibrary(t *testing.T) {
+	libs, err := filepath.Abs("../pcb/testdata/fplib/shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range map[string]string{"KICAD_CONFIG_HOME": "../pcb/testdata/fplib/config", "KCGEN_TEST_LIBS": libs} {
+		old, set := os.LookupEnv(k)
+		os.Setenv(k, v)
+		if set {
+			defer os.Setenv(k, old)
+		} else {
+			defer os.Unsetenv(k)
+		}
+	}
+
+	s, err := NewScript([]byte(`
+button = file.load_mod("Button_Switch_SMD:SW_SPST_PTS645")
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+	if got, want := s.globals["button"].(*pcb.Module).Name, "SW_SPST_PTS645"; got != want {
+		t.Errorf("loaded module %q, want %q", got, want)
+	}
+
+	if _, err := NewScript([]byte(`file.load_mod("Nope:SW_SPST_PTS645")`), "test.kcsl", false, nil, nil, func(string) {}); err == nil || !strings.Contains(err.Error(), "footprint Nope:SW_SPST_PTS645 not found") {
+		t.Errorf("loading a missing library returned %v, want not found", err)
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	s, err := NewScript([]byte(`
+lib = library.open("../pcb/library/testdata/Test.pretty")
+names = [e.name for e in lib.footprints]
+soic = lib.search(keywords="soic", pads=8, pitch=1.27)
+resistors = lib.search(tags=["resistor"])
+mod = lib.load_mod(resistors[0].name)
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	if got, want := s.globals["names"].String(), `["MountingHole_3.2mm_M3", "R_0805", "SOIC-8_3.9x4.9mm_P1.27mm"]`; got != want {
+		t.Errorf("names = %s, want %s", got, want)
+	}
+	if got := s.globals["soic"].(*starlark.List); got.Len() != 1 {
+		t.Errorf("soic = %v, want one result", got)
+	}
+	if m := s.Mod(); m == nil || len(m.Pads) != 2 {
+		t.Errorf("mod = %v, want R_0805", m)
+	}
+}
+
+func TestConnectivity(t *testing.T) {
+	s, err := NewScript([]byte(`
+board = file.load_pcb("../drc/testdata/connectivity.kicad_pcb")
+c = connectivity(board)
+complete = c.complete
+unrouted = [u.net for u in c.unrouted]
+shorted = c.shorts[0].nets
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	if got := s.globals["complete"]; got != starlark.False {
+		t.Errorf("complete = %v, want False", got)
+	}
+	if got, want := s.globals["unrouted"].String(), `["A", "B"]`; got != want {
+		t.Errorf("unrouted = %s, want %s", got, want)
+	}
+	if got, want := s.globals["shorted"].String(), `("A", "B")`; got != want {
+		t.Errorf("shorted = %s, want %s", got, want)
+	}
+}
+
+func TestRules(t *testing.T) {
+	s, err := NewScript([]byte(`
+def usb_on_front(board):
+    return [violation("USB track on " + t.layer, element=t.element, at=t.at)
+            for t in query.tracks(board, net="USB*") if t.layer != "F.Cu"]
+
+def no_vias_under_ic(board):
+    return [violation("via under " + m.ref, element=v.element)
+            for m in query.modules(board, ref="IC*")
+            for v in query.vias(board, under=m.ref)]
+
+def npth_holes(board):
+    return ["%s is plated" % p.element for p in query.pads(board, ref="IC1", number="1")
+            if p.pad.surface != pad.np_through_hole]
+
+rule("usb_on_front", usb_on_front)
+rule("no_vias_under_ic", no_vias_under_ic)
+rule("npth_holes", npth_holes, severity="warning")
+`), "rules.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	if got, want := s.Rules(), []string{"usb_on_front", "no_vias_under_ic", "npth_holes"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Rules() = %v, want %v", got, want)
+	}
+
+	tcs := []struct {
+		board string
+		want  []string
+	}{
+		{
+			board: "../pcb/testdata/cseduino-v4.kicad_pcb",
+			want: []string{
+				"no_vias_under_ic error via[75]: via under IC1",
+				"npth_holes warning module[IC1]/pad[1] is plated",
+			},
+		},
+		{
+			board: "../netlen/testdata/lengths.kicad_pcb",
+			want: []string{
+				"usb_on_front error track[2]: USB track on B.Cu at (10, 0)",
+			},
+		},
+	}
+	for _, tc := range tcs {
+		board, err := pcb.DecodeFile(tc.board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vs, err := s.CheckRules(board)
+		if err != nil {
+			t.Fatalf("CheckRules(%s) failed: %v", tc.board, err)
+		}
+		var got []string
+		for _, v := range vs {
+			got = append(got, v.String())
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("CheckRules(%s) = %q, want %q", tc.board, got, tc.want)
+		}
+	}
+}
+
+func TestRuleErrors(t *testing.T) {
+	tcs := []struct {
+		name, script, err string
+	}{
+		{"bad severity", "def r(b):\n  pass\nrule(\"r\", r, severity=\"fatal\")", `severity must be "error" or "warning"`},
+		{"bad pattern", "def r(b):\n  return query.tracks(b, net=\"[\")\nrule(\"r\", r)", "bad pattern"},
+		{"bad result", "def r(b):\n  return 5\nrule(\"r\", r)", "want a list of violations"},
+	}
+	board, err := pcb.DecodeFile("../netlen/testdata/lengths.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewScript([]byte(tc.script), "rules.kcsl", false, nil, nil, func(string) {})
+			if err == nil {
+				defer s.Close()
+				_, err = s.CheckRules(board)
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("err = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestFillZones(t *testing.T) {
+	s, err := NewScript([]byte(`
+board = fill_zones(file.load_pcb("../drc/testdata/fill.kicad_pcb"))
+filled = [len(z.polys) for z in board.zones]
+complete = connectivity(board).complete
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	if got, want := s.globals["filled"].String(), "[1, 1, 0]"; got != want {
+		t.Errorf("filled = %s, want %s", got, want)
+	}
+	if got := s.globals["complete"]; got != starlark.True {
+		t.Errorf("complete = %v, want True", got)
+	}
+}
+
+func TestStitch(t *testing.T) {
+	s, err := NewScript([]byte(`
+board = file.load_pcb("../stitch/testdata/stitch.kicad_pcb")
+vias = stitch(board, net="GND", strategy="staggered", spacing=2)
+added = len(vias) > 0 and len(board.segments) == 4 + len(vias)
+nets = {v.net_index: True for v in vias}.keys()
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	if got := s.globals["added"]; got != starlark.True {
+		t.Errorf("added = %v, want True", got)
+	}
+	if got, want := s.globals["nets"].String(), "[1]"; got != want {
+		t.Errorf("nets = %s, want %s", got, want)
+	}
+
+	_, err = NewScript([]byte(`
+stitch(file.load_pcb("../stitch/testdata/stitch.kicad_pcb"), via_size="0.6")
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "via_size must be a number") {
+		t.Errorf("stitch(via_size=\"0.6\") error = %v, want via_size must be a number", err)
+	}
+}
+
+func TestFence(t *testing.T) {
+	s, err := NewScript([]byte(`
+board = file.load_pcb("../stitch/testdata/fence.kicad_pcb")
+rf = fence(board, "RF", pitch=1.5)
+tracks = [t for t in board.segments if type(t) == "Track" and t.net_index == 2]
+again = fence(board, tracks, pitch=1.5)
+outline = board_outline(board)
+edge = fence(board, outline, offset=0.8, pitch=3)
+counts = [len(rf) > 0, len(again), len(outline), len(edge) > 0]
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	// Fencing the same tracks again places no vias beside the first fence.
+	if got, want := s.globals["counts"].String(), "[True, 0, 1, True]"; got != want {
+		t.Errorf("counts = %s, want %s", got, want)
+	}
+
+	_, err = NewScript([]byte(`
+fence(file.load_pcb("../stitch/testdata/fence.kicad_pcb"), "RF", pitch="1")
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "pitch must be a number") {
+		t.Errorf("fence(pitch=\"1\") error = %v, want pitch must be a number", err)
+	}
+}
+
+func TestTeardrops(t *testing.T) {
+	s, err := NewScript([]byte(`
+board = file.load_pcb("../teardrop/testdata/teardrop.kicad_pcb")
+first = teardrops(board)
+again = teardrops(board, skip_smd=True, curve_segments=0, fill=False)
+counts = [first, again, len(board.zones)]
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err != nil {
+		t.Fatalf("NewScript() failed: %v", err)
+	}
+	defer s.Close()
+
+	// Teardrops added again replace those already on the board.
+	if got, want := s.globals["counts"].String(), "[6, 5, 6]"; got != want {
+		t.Errorf("counts = %s, want %s", got, want)
+	}
+
+	_, err = NewScript([]byte(`
+teardrops(file.load_pcb("../teardrop/testdata/teardrop.kicad_pcb"), max_width=None)
+`), "test.kcsl", false, nil, nil, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "max_width must be a number") {
+		t.Errorf("teardrops(max_width=None) error = %v, want max_width must be a number", err)
+	}
+}
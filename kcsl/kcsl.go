@@ -3,10 +3,15 @@ package kcsl
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/twitchyliquid64/kcgen"
+	"github.com/twitchyliquid64/kcgen/drc"
 	"github.com/twitchyliquid64/kcgen/kcsl/adv"
 	"github.com/twitchyliquid64/kcgen/pcb"
+	"github.com/twitchyliquid64/kcgen/pcb/library"
+	"github.com/twitchyliquid64/kcgen/stitch"
+	"github.com/twitchyliquid64/kcgen/teardrop"
 	"go.starlark.net/starlark"
 	"go.starlark.net/starlarkstruct"
 )
@@ -32,15 +37,32 @@ var (
 		"PadOptions":   pcb.MakePadOptions,
 		"Pad":          pcb.MakePad,
 		// PCB
-		"PCB":        pcb.MakePCB,
-		"Line":       pcb.MakeLine,
-		"Arc":        pcb.MakeArc,
-		"Text":       pcb.MakeText,
-		"Track":      pcb.MakeTrack,
-		"ViaThrough": pcb.ViaThrough,
-		"ViaBlind":   pcb.ViaBlind,
-		"ViaMicro":   pcb.ViaMicro,
-		"Via":        pcb.MakeVia,
+		"PCB":         pcb.MakePCB,
+		"NetClass":    pcb.MakeNetClass,
+		"EditorSetup": pcb.MakeEditorSetup,
+		"TitleInfo":   pcb.MakeTitleInfo,
+		"Page":        pcb.MakePage,
+		"Line":        pcb.MakeLine,
+		"Arc":         pcb.MakeArc,
+		"Text":        pcb.MakeText,
+		"Dimension":   pcb.MakeDimension,
+		"Track":       pcb.MakeTrack,
+		"ViaThrough":  pcb.ViaThrough,
+		"ViaBlind":    pcb.ViaBlind,
+		"ViaMicro":    pcb.ViaMicro,
+		"Via":         pcb.MakeVia,
+		// zones
+		"Zone":            pcb.MakeZone,
+		"Keepout":         pcb.MakeKeepout,
+		"ZoneHatch":       pcb.MakeZoneHatch,
+		"ZoneConnectPads": pcb.MakeZoneConnectPads,
+		"ZoneFill":        pcb.MakeZoneFill,
+		"ZoneKeepout":     pcb.MakeZoneKeepout,
+		"fill_zones":      drc.MakeFillZones,
+		"stitch":          stitch.MakeStitch,
+		"fence":           stitch.MakeFence,
+		"board_outline":   drc.MakeBoardOutline,
+		"teardrops":       teardrop.MakeTeardrops,
 		// builtins in own namespace
 		"math":         starlarkstruct.FromStringDict(starlarkstruct.Default, mathBuiltins),
 		"layers":       starlarkstruct.FromStringDict(starlarkstruct.Default, layers),
@@ -63,11 +85,21 @@ var (
 			}
 			return p, adv.Carve(p, adv.MakeRegion(*from, *to))
 		}),
+		// design checks
+		"connectivity": drc.MakeConnectivity,
+		"rule":         ruleBuiltin,
+		"violation":    violationBuiltin,
+		"query":        starlarkstruct.FromStringDict(starlarkstruct.Default, queryBuiltins),
 		// textpoly
 		"TextPoly": makeTextPoly,
 		// file manipulation
 		"file": starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
 			"load_mod": fileLoadMod,
+			"load_pcb": fileLoadPCB,
+		}),
+		// footprint libraries
+		"library": starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"open": library.MakeLibrary,
 		}),
 	}
 
@@ -78,6 +110,8 @@ var (
 			"silkscreen": starlark.String(kcgen.LayerFrontSilkscreen.Strictname()),
 			"paste":      starlark.String(kcgen.LayerFrontPaste.Strictname()),
 			"courtyard":  starlark.String(kcgen.LayerFrontCourtyard.Strictname()),
+			"mask":       starlark.String(kcgen.LayerFrontMask.Strictname()),
+			"adhesive":   starlark.String(kcgen.LayerFrontAdhesive.Strictname()),
 			"smd": starlark.NewList([]starlark.Value{
 				starlark.String(kcgen.LayerFrontCopper.Strictname()),
 				starlark.String(kcgen.LayerFrontPaste.Strictname()),
@@ -90,6 +124,8 @@ var (
 			"silkscreen": starlark.String(kcgen.LayerBackSilkscreen.Strictname()),
 			"paste":      starlark.String(kcgen.LayerBackPaste.Strictname()),
 			"courtyard":  starlark.String(kcgen.LayerBackCourtyard.Strictname()),
+			"mask":       starlark.String(kcgen.LayerBackMask.Strictname()),
+			"adhesive":   starlark.String(kcgen.LayerBackAdhesive.Strictname()),
 			"smd": starlark.NewList([]starlark.Value{
 				starlark.String(kcgen.LayerBackCopper.Strictname()),
 				starlark.String(kcgen.LayerBackPaste.Strictname()),
@@ -101,15 +137,40 @@ var (
 			starlark.String(kcgen.LayerAllMask.Strictname()),
 		}),
 		"edge": starlark.String(kcgen.LayerEdgeCuts.Strictname()),
+		"user": starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"drawings": starlark.String(kcgen.LayerDrawings.Strictname()),
+			"comments": starlark.String(kcgen.LayerComments.Strictname()),
+			"eco1":     starlark.String(kcgen.LayerEco1.Strictname()),
+			"eco2":     starlark.String(kcgen.LayerEco2.Strictname()),
+			"margin":   starlark.String(kcgen.LayerMargin.Strictname()),
+			"adhes": starlark.NewList([]starlark.Value{
+				starlark.String(kcgen.LayerFrontAdhesive.Strictname()),
+				starlark.String(kcgen.LayerBackAdhesive.Strictname()),
+			}),
+		}),
+		"inner": starlark.NewBuiltin("inner", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var n int
+			if err := starlark.UnpackPositionalArgs("inner", args, kwargs, 1, &n); err != nil {
+				return starlark.None, err
+			}
+			if n < 1 || n > kcgen.MaxInnerCopper {
+				return starlark.None, fmt.Errorf("inner: layer %d out of range, must be between 1 and %d", n, kcgen.MaxInnerCopper)
+			}
+			return starlark.String(kcgen.LayerInnerCopper(n).Strictname()), nil
+		}),
 	}
 
 	zci = pcb.ZoneConnectInherited
 	zcn = pcb.ZoneConnectNone
 	zct = pcb.ZoneConnectThermal
+	zcs = pcb.ZoneConnectSolid
+	zch = pcb.ZoneConnectThroughHole
 	zc  = starlark.StringDict{
-		"inherited": &zci,
-		"none":      &zcn,
-		"thermal":   &zct,
+		"inherited":      &zci,
+		"none":           &zcn,
+		"thermal":        &zct,
+		"solid":          &zcs,
+		"thru_hole_only": &zch,
 	}
 
 	rt  = pcb.RefText
@@ -60,12 +60,43 @@ def draw_outline(line, points, layer, width, inclusive):
     ))
   return out
 
+# draw_pcb_dimension_box returns dimensions annotating the overall width
+# and height of the given points, placed above and to the right of them.
+def draw_pcb_dimension_box(points=[],
+  layer="Dwgs.User",
+  offset=5.0,
+  units="mm",
+  precision=2):
+  if len(points) == 0:
+    return []
+  min_x, max_x = min([p.x for p in points]), max([p.x for p in points])
+  min_y, max_y = min([p.y for p in points]), max([p.y for p in points])
+  return [
+    Dimension(
+      start = XY(min_x, min_y),
+      end = XY(max_x, min_y),
+      offset = -offset,
+      layer = layer,
+      units = units,
+      precision = precision,
+    ),
+    Dimension(
+      start = XY(max_x, max_y),
+      end = XY(max_x, min_y),
+      offset = offset,
+      layer = layer,
+      units = units,
+      precision = precision,
+    ),
+  ]
+
 draw = struct(
   mod = struct(
     outline = draw_mod_outline,
   ),
   pcb = struct(
     outline = draw_pcb_outline,
+    dimension_box = draw_pcb_dimension_box,
   )
 )
 `)
@@ -51,11 +51,36 @@ def mk_text(pos=XYZ(), layer=layers.front.silkscreen, size=XY(1,1), thickness=de
         effects = TextEffects(font_size = size, thickness = thickness),
     )
 
+# mk_pour returns a copper zone filling the given outline, which is
+# connected to the specified net.
+def mk_pour(net=0, layers=[layers.front.copper], outline=[], clearance=0.508, net_name="", priority=0, min_thickness=0.254, thermal_gap=0.508, thermal_bridge_width=0.508):
+    return Zone(
+        net_num = net,
+        net_name = net_name,
+        layers = layers,
+        outline = outline,
+        priority = priority,
+        min_thickness = min_thickness,
+        connect_pads = ZoneConnectPads(clearance = clearance),
+        fill = ZoneFill(thermal_gap = thermal_gap, thermal_bridge_width = thermal_bridge_width),
+    )
+
+# mk_keepout returns a keepout zone covering the given outline. Nothing is
+# allowed within the keepout unless specified.
+def mk_keepout(layers=[layers.front.copper, layers.back.copper], outline=[], tracks=False, vias=False, pour=False):
+    return Keepout(
+        layers = layers,
+        outline = outline,
+        keepout = ZoneKeepout(tracks_allowed = tracks, vias_allowed = vias, copperpour_allowed = pour),
+    )
+
 pcb = struct(
-    line  = mk_line,
-    arc   = mk_arc,
-    text  = mk_text,
-    via   = mk_via,
-    track = mk_track,
+    line    = mk_line,
+    arc     = mk_arc,
+    text    = mk_text,
+    via     = mk_via,
+    track   = mk_track,
+    pour    = mk_pour,
+    keepout = mk_keepout,
 )
 `)
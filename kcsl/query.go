@@ -0,0 +1,274 @@
+package kcsl
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// queryBuiltins find the elements of a board, for use by rules. Each
+// result is a struct with the element name used in reports, such as
+// track[3] or module[R1]/pad[2], and the element itself. Filters are
+// patterns matched with path.Match, such as "USB*".
+var queryBuiltins = starlark.StringDict{
+	"tracks":  queryTracks,
+	"vias":    queryVias,
+	"modules": queryModules,
+	"pads":    queryPads,
+}
+
+func matches(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
+
+func checkPatterns(fn string, patterns ...starlark.String) error {
+	for _, p := range patterns {
+		if _, err := path.Match(string(p), ""); err != nil {
+			return fmt.Errorf("%s: bad pattern %q: %v", fn, p, err)
+		}
+	}
+	return nil
+}
+
+func makeResult(d starlark.StringDict) starlark.Value {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, d)
+}
+
+var queryTracks = starlark.NewBuiltin("tracks", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		board      *pcb.PCB
+		net, layer starlark.String
+	)
+	if err := starlark.UnpackArgs("tracks", args, kwargs, "pcb", &board, "net?", &net, "layer?", &layer); err != nil {
+		return starlark.None, err
+	}
+	if err := checkPatterns("tracks", net, layer); err != nil {
+		return starlark.None, err
+	}
+	var out []starlark.Value
+	for i, s := range board.Segments {
+		t, ok := s.(*pcb.Track)
+		if !ok {
+			continue
+		}
+		name := board.Nets[t.NetIndex].Name
+		if !matches(string(net), name) || !matches(string(layer), t.Layer) {
+			continue
+		}
+		out = append(out, makeResult(starlark.StringDict{
+			"element": starlark.String(fmt.Sprintf("track[%d]", i)),
+			"track":   t,
+			"net":     starlark.String(name),
+			"layer":   starlark.String(t.Layer),
+			"at":      &pcb.XY{X: t.Start.X, Y: t.Start.Y},
+		}))
+	}
+	return starlark.NewList(out), nil
+})
+
+var queryVias = starlark.NewBuiltin("vias", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		board      *pcb.PCB
+		net, under starlark.String
+	)
+	if err := starlark.UnpackArgs("vias", args, kwargs, "pcb", &board, "net?", &net, "under?", &under); err != nil {
+		return starlark.None, err
+	}
+	if err := checkPatterns("vias", net, under); err != nil {
+		return starlark.None, err
+	}
+	// Vias under modules are found within the outline of their courtyard.
+	var areas [][]pcb.XY
+	if under != "" {
+		for i := range board.Modules {
+			m := &board.Modules[i]
+			if matches(string(under), m.Reference()) {
+				areas = append(areas, moduleArea(m))
+			}
+		}
+	}
+
+	var out []starlark.Value
+	for i, s := range board.Segments {
+		v, ok := s.(*pcb.Via)
+		if !ok {
+			continue
+		}
+		name := board.Nets[v.NetIndex].Name
+		if !matches(string(net), name) {
+			continue
+		}
+		if under != "" {
+			inside := false
+			for _, a := range areas {
+				inside = inside || insideQuad(v.At, a)
+			}
+			if !inside {
+				continue
+			}
+		}
+		out = append(out, makeResult(starlark.StringDict{
+			"element": starlark.String(fmt.Sprintf("via[%d]", i)),
+			"via":     v,
+			"net":     starlark.String(name),
+			"at":      &pcb.XY{X: v.At.X, Y: v.At.Y},
+		}))
+	}
+	return starlark.NewList(out), nil
+})
+
+func moduleElement(m *pcb.Module, i int) string {
+	if ref := m.Reference(); ref != "" {
+		return fmt.Sprintf("module[%s]", ref)
+	}
+	return fmt.Sprintf("module[%d]", i)
+}
+
+var queryModules = starlark.NewBuiltin("modules", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		board     *pcb.PCB
+		ref, name starlark.String
+	)
+	if err := starlark.UnpackArgs("modules", args, kwargs, "pcb", &board, "ref?", &ref, "name?", &name); err != nil {
+		return starlark.None, err
+	}
+	if err := checkPatterns("modules", ref, name); err != nil {
+		return starlark.None, err
+	}
+	var out []starlark.Value
+	for i := range board.Modules {
+		m := &board.Modules[i]
+		if !matches(string(ref), m.Reference()) || !matches(string(name), m.Name) {
+			continue
+		}
+		out = append(out, makeResult(starlark.StringDict{
+			"element": starlark.String(moduleElement(m, i)),
+			"module":  m,
+			"ref":     starlark.String(m.Reference()),
+			"at":      &pcb.XY{X: m.Placement.At.X, Y: m.Placement.At.Y},
+		}))
+	}
+	return starlark.NewList(out), nil
+})
+
+var queryPads = starlark.NewBuiltin("pads", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		board            *pcb.PCB
+		ref, net, number starlark.String
+	)
+	if err := starlark.UnpackArgs("pads", args, kwargs, "pcb", &board, "ref?", &ref, "net?", &net, "number?", &number); err != nil {
+		return starlark.None, err
+	}
+	if err := checkPatterns("pads", ref, net, number); err != nil {
+		return starlark.None, err
+	}
+	var out []starlark.Value
+	for i := range board.Modules {
+		m := &board.Modules[i]
+		if !matches(string(ref), m.Reference()) {
+			continue
+		}
+		mel := moduleElement(m, i)
+		for j := range m.Pads {
+			p := &m.Pads[j]
+			if !matches(string(net), p.NetName) || !matches(string(number), p.Ident) {
+				continue
+			}
+			elem := fmt.Sprintf("%s/pad[%d]", mel, j)
+			if p.Ident != "" {
+				elem = fmt.Sprintf("%s/pad[%s]", mel, p.Ident)
+			}
+			pos := m.PadPosition(p)
+			out = append(out, makeResult(starlark.StringDict{
+				"element": starlark.String(elem),
+				"pad":     p,
+				"module":  m,
+				"ref":     starlark.String(m.Reference()),
+				"net":     starlark.String(p.NetName),
+				"at":      &pcb.XY{X: pos.X, Y: pos.Y},
+			}))
+		}
+	}
+	return starlark.NewList(out), nil
+})
+
+// moduleArea returns the corners of the bounding box of the courtyard of
+// a module, or of its pads and graphics if it has no courtyard, in board
+// coordinates.
+func moduleArea(m *pcb.Module) []pcb.XY {
+	min := pcb.XY{X: math.Inf(1), Y: math.Inf(1)}
+	max := pcb.XY{X: math.Inf(-1), Y: math.Inf(-1)}
+	add := func(pts ...pcb.XY) {
+		for _, p := range pts {
+			min.X, min.Y = math.Min(min.X, p.X), math.Min(min.Y, p.Y)
+			max.X, max.Y = math.Max(max.X, p.X), math.Max(max.Y, p.Y)
+		}
+	}
+	graphics := func(courtyard bool) {
+		for _, g := range m.Graphics {
+			switch g := g.Renderable.(type) {
+			case *pcb.ModLine:
+				if strings.HasSuffix(g.Layer, ".CrtYd") == courtyard {
+					add(g.Start, g.End)
+				}
+			case *pcb.ModCircle:
+				if strings.HasSuffix(g.Layer, ".CrtYd") == courtyard {
+					r := math.Hypot(g.End.X-g.Center.X, g.End.Y-g.Center.Y)
+					add(pcb.XY{X: g.Center.X - r, Y: g.Center.Y - r}, pcb.XY{X: g.Center.X + r, Y: g.Center.Y + r})
+				}
+			case *pcb.ModArc:
+				if strings.HasSuffix(g.Layer, ".CrtYd") == courtyard {
+					r := math.Hypot(g.End.X-g.Start.X, g.End.Y-g.Start.Y)
+					add(pcb.XY{X: g.Start.X - r, Y: g.Start.Y - r}, pcb.XY{X: g.Start.X + r, Y: g.Start.Y + r})
+				}
+			case *pcb.ModPolygon:
+				if strings.HasSuffix(g.Layer, ".CrtYd") == courtyard {
+					for _, p := range g.Points {
+						add(pcb.XY{X: p.X + g.At.X, Y: p.Y + g.At.Y})
+					}
+				}
+			}
+		}
+	}
+
+	graphics(true)
+	if math.IsInf(min.X, 1) {
+		graphics(false)
+		for _, p := range m.Pads {
+			add(pcb.XY{X: p.At.X - p.Size.X/2, Y: p.At.Y - p.Size.Y/2}, pcb.XY{X: p.At.X + p.Size.X/2, Y: p.At.Y + p.Size.Y/2})
+		}
+	}
+	if math.IsInf(min.X, 1) {
+		return nil
+	}
+	return []pcb.XY{
+		m.ToBoard(min),
+		m.ToBoard(pcb.XY{X: max.X, Y: min.Y}),
+		m.ToBoard(max),
+		m.ToBoard(pcb.XY{X: min.X, Y: max.Y}),
+	}
+}
+
+// insideQuad reports whether p is inside the convex quadrilateral q.
+func insideQuad(p pcb.XY, q []pcb.XY) bool {
+	if len(q) != 4 {
+		return false
+	}
+	var pos, neg bool
+	for i := range q {
+		a, b := q[i], q[(i+1)%4]
+		c := (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+		pos = pos || c > 0
+		neg = neg || c < 0
+	}
+	return !(pos && neg)
+}
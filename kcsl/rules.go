@@ -0,0 +1,187 @@
+package kcsl
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// rulesKey is the thread-local key of the rules registered with rule().
+const rulesKey = "rules"
+
+// Severities of rule violations.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// scriptRule is a check registered by a script with rule().
+type scriptRule struct {
+	name, severity string
+	check          starlark.Callable
+}
+
+// RuleViolation is a violation reported by a rule of a script.
+type RuleViolation struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	// Element identifies the offending element, such as track[3] or
+	// module[R1]/pad[2], if given by the rule.
+	Element string `json:"element,omitempty"`
+	// At is the location of the violation, if given by the rule.
+	At  *pcb.XY `json:"position,omitempty"`
+	Msg string  `json:"msg"`
+}
+
+func (v RuleViolation) String() string {
+	s := v.Rule + " " + v.Severity + " "
+	if v.Element != "" {
+		s += v.Element + ": "
+	}
+	s += v.Msg
+	if v.At != nil {
+		s += " at (" + fmtNum(v.At.X) + ", " + fmtNum(v.At.Y) + ")"
+	}
+	return s
+}
+
+func fmtNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e3)/1e3, 'f', -1, 64)
+}
+
+func checkSeverity(fn, severity string) error {
+	if severity != SeverityError && severity != SeverityWarning {
+		return fmt.Errorf("%s: severity must be %q or %q, got %q", fn, SeverityError, SeverityWarning, severity)
+	}
+	return nil
+}
+
+// ruleBuiltin registers a rule, which is called with a board by
+// Script.CheckRules and returns a list of violations.
+var ruleBuiltin = starlark.NewBuiltin("rule", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		name     starlark.String
+		check    starlark.Callable
+		severity = starlark.String(SeverityError)
+	)
+	if err := starlark.UnpackArgs("rule", args, kwargs, "name", &name, "check", &check, "severity?", &severity); err != nil {
+		return starlark.None, err
+	}
+	if err := checkSeverity("rule", string(severity)); err != nil {
+		return starlark.None, err
+	}
+	rules, ok := thread.Local(rulesKey).(*[]scriptRule)
+	if !ok {
+		return starlark.None, fmt.Errorf("rule: rules cannot be registered here")
+	}
+	*rules = append(*rules, scriptRule{name: string(name), severity: string(severity), check: check})
+	return starlark.None, nil
+})
+
+// violationBuiltin describes a violation, to be returned by a rule.
+var violationBuiltin = starlark.NewBuiltin("violation", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		msg, element starlark.String
+		at           starlark.Value = starlark.None
+		severity     starlark.Value = starlark.None
+	)
+	if err := starlark.UnpackArgs("violation", args, kwargs, "msg", &msg, "element?", &element, "at?", &at, "severity?", &severity); err != nil {
+		return starlark.None, err
+	}
+	if _, ok := at.(*pcb.XY); !ok && at != starlark.None {
+		return starlark.None, fmt.Errorf("violation: at must be an XY, got %s", at.Type())
+	}
+	if s, ok := severity.(starlark.String); ok {
+		if err := checkSeverity("violation", string(s)); err != nil {
+			return starlark.None, err
+		}
+	} else if severity != starlark.None {
+		return starlark.None, fmt.Errorf("violation: severity must be a string, got %s", severity.Type())
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"msg":      msg,
+		"element":  element,
+		"at":       at,
+		"severity": severity,
+	}), nil
+})
+
+// Rules returns the names of the rules registered by the script.
+func (s *Script) Rules() []string {
+	out := make([]string, len(s.rules))
+	for i, r := range s.rules {
+		out[i] = r.name
+	}
+	return out
+}
+
+// CheckRules calls each rule registered by the script with the board,
+// returning the violations they report in order.
+func (s *Script) CheckRules(board *pcb.PCB) ([]RuleViolation, error) {
+	var out []RuleViolation
+	for _, r := range s.rules {
+		ret, err := starlark.Call(s.thread, r.check, starlark.Tuple{board}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", r.name, err)
+		}
+		vs, err := ruleViolations(r, ret)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", r.name, err)
+		}
+		out = append(out, vs...)
+	}
+	return out, nil
+}
+
+// ruleViolations converts the result of a rule, which is None or a list
+// of violations and messages.
+func ruleViolations(r scriptRule, ret starlark.Value) ([]RuleViolation, error) {
+	if ret == starlark.None {
+		return nil, nil
+	}
+	iter, ok := ret.(starlark.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("returned %s, want a list of violations", ret.Type())
+	}
+	var (
+		out []RuleViolation
+		x   starlark.Value
+	)
+	it := iter.Iterate()
+	defer it.Done()
+	for it.Next(&x) {
+		v := RuleViolation{Rule: r.name, Severity: r.severity}
+		switch x := x.(type) {
+		case starlark.String:
+			v.Msg = string(x)
+		case *starlarkstruct.Struct:
+			for _, name := range []string{"msg", "element", "severity"} {
+				if s, err := x.Attr(name); err == nil {
+					if s, ok := s.(starlark.String); ok && s != "" {
+						switch name {
+						case "msg":
+							v.Msg = string(s)
+						case "element":
+							v.Element = string(s)
+						case "severity":
+							v.Severity = string(s)
+						}
+					}
+				}
+			}
+			if at, err := x.Attr("at"); err == nil {
+				if at, ok := at.(*pcb.XY); ok {
+					v.At = &pcb.XY{X: at.X, Y: at.Y}
+				}
+			}
+		default:
+			return nil, fmt.Errorf("returned a %s, want violation() or a string", x.Type())
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
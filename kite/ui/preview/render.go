@@ -3,6 +3,7 @@ package preview
 import (
 	"fmt"
 	"math"
+	"strings"
 
 	"github.com/gotk3/gotk3/cairo"
 	"github.com/gotk3/gotk3/gtk"
@@ -30,6 +31,8 @@ type modRenderOptions struct {
 	X, Y          float64
 	Zoom          float64
 	PadClearance  float64
+	// Rotation is the orientation of the module being rendered, in degrees.
+	Rotation float64
 }
 
 func (o modRenderOptions) ProjectXY(pt pcb.XY) (x, y float64) {
@@ -59,12 +62,31 @@ func (o modRenderOptions) GetLayerColor(layer string) (r, g, b float64) {
 		return 0, 132.0 / 255, 132.0 / 255
 	case kcgen.LayerEdgeCuts.Strictname():
 		return 132.0 / 255, 132.0 / 255, 0
+	case kcgen.LayerBackCourtyard.Strictname():
+		return 100.0 / 255, 72.0 / 255, 100.0 / 255
+	case kcgen.LayerBackCopper.Strictname():
+		return 0, 132.0 / 255, 0
+	case kcgen.LayerBackSilkscreen.Strictname():
+		return 132.0 / 255, 0, 132.0 / 255
 	}
 
 	return 1, 1, 1
 }
 
 func (o modRenderOptions) GetColorFromLayers(l []string) (r, g, b float64) {
+	// Pads which are only on the back copper layer are drawn in its color.
+	var onBack bool
+	for _, layer := range l {
+		switch {
+		case layer == kcgen.LayerBackCopper.Strictname():
+			onBack = true
+		case strings.HasSuffix(layer, ".Cu"):
+			return 132.0 / 255, 0, 0
+		}
+	}
+	if onBack {
+		return 0, 132.0 / 255, 0
+	}
 	return 132.0 / 255, 0, 0
 }
 
@@ -116,7 +138,10 @@ func renderPCB(board *pcb.PCB, opts modRenderOptions, da *gtk.DrawingArea, cr *c
 
 	for _, mod := range board.Modules {
 		cr.SetMatrix(m)
+		// Module geometry is stored relative to the module position and
+		// before rotation. Back-side modules are stored already mirrored.
 		cr.Translate(mod.Placement.At.X, mod.Placement.At.Y)
+		cr.Rotate(-mod.Placement.At.Z * math.Pi / 180)
 		if err := renderModule(&mod, opts, da, cr); err != nil {
 			return fmt.Errorf("rendering module in PCB: %v", err)
 		}
@@ -164,6 +189,7 @@ func renderTrack(t *pcb.Track, opts modRenderOptions, da *gtk.DrawingArea, cr *c
 }
 
 func renderModule(mod *pcb.Module, opts modRenderOptions, da *gtk.DrawingArea, cr *cairo.Context) error {
+	opts.Rotation = mod.Placement.At.Z
 	for _, graphic := range mod.Graphics {
 		switch graphic.Ident {
 		case "fp_line":
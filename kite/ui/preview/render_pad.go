@@ -19,6 +19,15 @@ func renderPad(pad pcb.Pad, opts modRenderOptions, da *gtk.DrawingArea, cr *cair
 	newOpts := opts
 	newOpts.PadClearance = padClearance
 
+	// Pad orientations are stored as they appear on the board, so the
+	// rotation of the module must be removed.
+	cr.Save()
+	defer cr.Restore()
+	x, y, _ := opts.ProjectXYZ(pad.At)
+	cr.Translate(x, y)
+	cr.Rotate(-(pad.At.Z - opts.Rotation) * math.Pi / 180)
+	pad.At = pcb.XYZ{}
+
 	switch pad.Shape {
 	case pcb.ShapeRect, pcb.ShapeRoundRect:
 		renderRectPad(pad, newOpts, da, cr)
@@ -1,6 +1,8 @@
 // Package kcgen manipulates and generates kicad footprints and pcb files.
 package kcgen
 
+import "fmt"
+
 // Layer represents the layer on which a graphical element resides.
 type Layer int
 
@@ -21,8 +23,41 @@ const (
 	LayerBackMask
 	LayerFrontCourtyard
 	LayerBackCourtyard
+	LayerFrontAdhesive
+	LayerBackAdhesive
+	LayerDrawings
+	LayerComments
+	LayerEco1
+	LayerEco2
+	LayerMargin
 )
 
+// layerInnerCopper is the base value of inner copper layers, which are
+// numbered from 1 (the layer closest to the front) to MaxInnerCopper.
+const layerInnerCopper Layer = 1000
+
+// MaxInnerCopper is the largest number of inner copper layers a board
+// can have.
+const MaxInnerCopper = 30
+
+// LayerInnerCopper returns the n'th inner copper layer, counting from
+// the front. It panics if n is not between 1 and MaxInnerCopper.
+func LayerInnerCopper(n int) Layer {
+	if n < 1 || n > MaxInnerCopper {
+		panic(fmt.Sprintf("invalid inner copper layer %d", n))
+	}
+	return layerInnerCopper + Layer(n)
+}
+
+// innerCopper returns the number of the inner copper layer, or zero if
+// the layer is not an inner copper layer.
+func (l Layer) innerCopper() int {
+	if l > layerInnerCopper && l <= layerInnerCopper+MaxInnerCopper {
+		return int(l - layerInnerCopper)
+	}
+	return 0
+}
+
 // Strictname returns the string representing the layer used in the Kicad 4 (and probably later) formats.
 func (l Layer) Strictname() string {
 	switch l {
@@ -56,6 +91,23 @@ func (l Layer) Strictname() string {
 		return "F.CrtYd"
 	case LayerBackCourtyard:
 		return "B.CrtYd"
+	case LayerFrontAdhesive:
+		return "F.Adhes"
+	case LayerBackAdhesive:
+		return "B.Adhes"
+	case LayerDrawings:
+		return "Dwgs.User"
+	case LayerComments:
+		return "Cmts.User"
+	case LayerEco1:
+		return "Eco1.User"
+	case LayerEco2:
+		return "Eco2.User"
+	case LayerMargin:
+		return "Margin"
+	}
+	if n := l.innerCopper(); n > 0 {
+		return fmt.Sprintf("In%d.Cu", n)
 	}
 	panic("invalid layer")
 }
@@ -93,6 +145,23 @@ func (l Layer) String() string {
 		return "Front Courtyard"
 	case LayerBackCourtyard:
 		return "Back Courtyard"
+	case LayerFrontAdhesive:
+		return "Front Adhesive"
+	case LayerBackAdhesive:
+		return "Back Adhesive"
+	case LayerDrawings:
+		return "User Drawings"
+	case LayerComments:
+		return "User Comments"
+	case LayerEco1:
+		return "User Eco1"
+	case LayerEco2:
+		return "User Eco2"
+	case LayerMargin:
+		return "Margin"
+	}
+	if n := l.innerCopper(); n > 0 {
+		return fmt.Sprintf("Inner Copper %d", n)
 	}
 	return "?"
 }
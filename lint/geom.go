@@ -0,0 +1,180 @@
+package lint
+
+import (
+	"math"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// outline describes the stroke of a graphic in a footprint.
+type outline struct {
+	layer string
+	// width points at the width field of the graphic, so it can be fixed.
+	width *float64
+	// segs approximates the stroke as straight segments.
+	segs [][2]pcb.XY
+	// ends are the endpoints of an open shape, which must meet the ends
+	// of other shapes to form a closed outline.
+	ends []pcb.XY
+}
+
+// arcSteps is the number of segments used per 360 degrees of an arc.
+const arcSteps = 36
+
+// graphicOutline returns the outline of a line, arc, circle or polygon.
+// Text has no outline.
+func graphicOutline(d interface{}) (outline, bool) {
+	switch g := d.(type) {
+	case *pcb.ModLine:
+		return outline{
+			layer: g.Layer,
+			width: &g.Width,
+			segs:  [][2]pcb.XY{{g.Start, g.End}},
+			ends:  []pcb.XY{g.Start, g.End},
+		}, true
+	case *pcb.ModPolygon:
+		o := outline{layer: g.Layer, width: &g.Width}
+		for i := range g.Points {
+			a, b := g.Points[i], g.Points[(i+1)%len(g.Points)]
+			o.segs = append(o.segs, [2]pcb.XY{
+				{X: a.X + g.At.X, Y: a.Y + g.At.Y},
+				{X: b.X + g.At.X, Y: b.Y + g.At.Y},
+			})
+		}
+		return o, true
+	case *pcb.ModCircle:
+		return outline{
+			layer: g.Layer,
+			width: &g.Width,
+			segs:  arcSegments(g.Center, g.End, 360),
+		}, true
+	case *pcb.ModArc:
+		// Arcs are centered on Start, sweeping Angle degrees from End.
+		segs := arcSegments(g.Start, g.End, g.Angle)
+		return outline{
+			layer: g.Layer,
+			width: &g.Width,
+			segs:  segs,
+			ends:  []pcb.XY{g.End, segs[len(segs)-1][1]},
+		}, true
+	}
+	return outline{}, false
+}
+
+// arcSegments approximates an arc centered on center, sweeping angle
+// degrees clockwise from start.
+func arcSegments(center, start pcb.XY, angle float64) [][2]pcb.XY {
+	n := int(math.Ceil(math.Abs(angle) / 360 * arcSteps))
+	if n < 1 {
+		n = 1
+	}
+	rel := pcb.XY{X: start.X - center.X, Y: start.Y - center.Y}
+	out := make([][2]pcb.XY, n)
+	prev := start
+	for i := 1; i <= n; i++ {
+		p := rel.Rotate(-angle * float64(i) / float64(n))
+		next := pcb.XY{X: center.X + p.X, Y: center.Y + p.Y}
+		out[i-1] = [2]pcb.XY{prev, next}
+		prev = next
+	}
+	return out
+}
+
+func roundNM(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// padFrame converts a footprint-local point to the frame of the pad, in
+// which the pad is centered on the origin and unrotated.
+func padFrame(m *pcb.Module, p *pcb.Pad, pt pcb.XY) pcb.XY {
+	return pcb.XY{X: pt.X - p.At.X, Y: pt.Y - p.At.Y}.Rotate(-(p.At.Z - m.Placement.At.Z))
+}
+
+// padCorners returns the corners of the bounding rectangle of a pad, in
+// footprint-local coordinates.
+func padCorners(m *pcb.Module, p *pcb.Pad) []pcb.XY {
+	hx, hy := p.Size.X/2, p.Size.Y/2
+	out := make([]pcb.XY, 0, 4)
+	for _, c := range []pcb.XY{{X: -hx, Y: -hy}, {X: hx, Y: -hy}, {X: hx, Y: hy}, {X: -hx, Y: hy}} {
+		c = c.Rotate(p.At.Z - m.Placement.At.Z)
+		out = append(out, pcb.XY{X: p.At.X + c.X, Y: p.At.Y + c.Y})
+	}
+	return out
+}
+
+// padDistance returns the distance between the segment a-b and the pad,
+// or zero if they intersect. Pads other than circles are treated as their
+// bounding rectangle.
+func padDistance(m *pcb.Module, p *pcb.Pad, a, b pcb.XY) float64 {
+	a, b = padFrame(m, p, a), padFrame(m, p, b)
+	if p.Shape == pcb.ShapeCircle {
+		return math.Max(0, pcb.XY{}.Distance(pcb.ClosestOnSegment(pcb.XY{}, a, b))-p.Size.X/2)
+	}
+	hx, hy := p.Size.X/2, p.Size.Y/2
+	if segmentHitsBox(a, b, hx, hy) {
+		return 0
+	}
+	d := math.Min(pointBoxDistance(a, hx, hy), pointBoxDistance(b, hx, hy))
+	for _, c := range []pcb.XY{{X: -hx, Y: -hy}, {X: hx, Y: -hy}, {X: hx, Y: hy}, {X: -hx, Y: hy}} {
+		d = math.Min(d, c.Distance(pcb.ClosestOnSegment(c, a, b)))
+	}
+	return d
+}
+
+// segmentHitsBox reports whether the segment a-b intersects the box
+// centered on the origin with half-widths hx and hy.
+func segmentHitsBox(a, b pcb.XY, hx, hy float64) bool {
+	t0, t1 := 0.0, 1.0
+	dx, dy := b.X-a.X, b.Y-a.Y
+	for _, c := range [][2]float64{{-dx, a.X + hx}, {dx, hx - a.X}, {-dy, a.Y + hy}, {dy, hy - a.Y}} {
+		p, q := c[0], c[1]
+		if p == 0 {
+			if q < 0 {
+				return false
+			}
+			continue
+		}
+		r := q / p
+		if p < 0 {
+			if r > t1 {
+				return false
+			}
+			t0 = math.Max(t0, r)
+		} else {
+			if r < t0 {
+				return false
+			}
+			t1 = math.Min(t1, r)
+		}
+	}
+	return true
+}
+
+func pointBoxDistance(p pcb.XY, hx, hy float64) float64 {
+	return math.Hypot(math.Max(math.Abs(p.X)-hx, 0), math.Max(math.Abs(p.Y)-hy, 0))
+}
+
+// box is an axis-aligned bounding box.
+type box struct {
+	min, max pcb.XY
+	ok       bool
+}
+
+func (b *box) add(p pcb.XY) {
+	if !b.ok {
+		b.min, b.max, b.ok = p, p, true
+		return
+	}
+	b.min.X, b.min.Y = math.Min(b.min.X, p.X), math.Min(b.min.Y, p.Y)
+	b.max.X, b.max.Y = math.Max(b.max.X, p.X), math.Max(b.max.Y, p.Y)
+}
+
+// contains reports whether p is within the box, allowing for rounding.
+func (b *box) contains(p pcb.XY) bool {
+	const eps = 1e-6
+	return b.ok && p.X >= b.min.X-eps && p.X <= b.max.X+eps && p.Y >= b.min.Y-eps && p.Y <= b.max.Y+eps
+}
+
+func (b *box) center() pcb.XY {
+	return pcb.XY{X: (b.min.X + b.max.X) / 2, Y: (b.min.Y + b.max.Y) / 2}
+}
@@ -0,0 +1,143 @@
+// Package lint checks footprints against rules modelled on the KiCad
+// Library Convention (KLC), so generated footprints can be verified to be
+// of library quality.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Severity describes how serious a problem is.
+type Severity string
+
+// Valid Severity values.
+const (
+	// Error is used for violations of the library conventions.
+	Error Severity = "error"
+	// Warning is used for likely mistakes which are sometimes intentional.
+	Warning Severity = "warning"
+)
+
+// Rules maps the ID of each rule checked by Check to a short description.
+// The IDs follow the numbering of the KLC footprint rules.
+var Rules = map[string]string{
+	"G1.1": "Footprint names only use letters, digits and _-.+,",
+	"F5.1": "Silkscreen lines are at least 0.12mm wide",
+	"F5.2": "Silkscreen does not overlap pads",
+	"F5.3": "Courtyard is present, closed, drawn with 0.05mm lines and encloses the pads",
+	"F5.4": "Fabrication layer has an outline drawn with 0.1mm lines and a ${REFERENCE} text",
+	"F5.5": "Reference is on the silkscreen and value on the fabrication layer",
+	"F6.1": "Pin 1 is at the origin or the top-left of the footprint",
+	"F6.2": "SMD pads are on the copper, paste and mask layers",
+	"F7.2": "Through-hole pads are on all copper and mask layers",
+	"F9.1": "Footprint has a description and tags",
+	"F9.2": "Footprint attributes match its pad types",
+}
+
+// RuleIDs returns the IDs of all rules, sorted.
+func RuleIDs() []string {
+	out := make([]string, 0, len(Rules))
+	for id := range Rules {
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Result describes a rule violation found by Check.
+type Result struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Element identifies the offending element, such as pad[2] or
+	// graphics[3]. Pads are identified by their number, graphics by their
+	// index. Problems with the footprint as a whole use module.
+	Element string `json:"element"`
+	Msg     string `json:"msg"`
+	// Fix is a suggested correction, or nil if the problem must be fixed
+	// by hand.
+	Fix *Fix `json:"fix,omitempty"`
+}
+
+func (r Result) String() string {
+	s := fmt.Sprintf("%s %s %s: %s", r.Rule, r.Severity, r.Element, r.Msg)
+	if r.Fix != nil {
+		s += " (fix: " + r.Fix.Description + ")"
+	}
+	return s
+}
+
+// Fix is an automatic correction for a Result.
+type Fix struct {
+	Description string `json:"description"`
+	apply       func()
+}
+
+// Apply makes the correction to the footprint which was checked.
+func (f *Fix) Apply() {
+	f.apply()
+}
+
+// ApplyFixes applies the fixes of the given results, which must have come
+// from checking the same footprint, returning the number applied.
+func ApplyFixes(results []Result) int {
+	var n int
+	for _, r := range results {
+		if r.Fix != nil {
+			r.Fix.Apply()
+			n++
+		}
+	}
+	return n
+}
+
+// Check checks the footprint against the library conventions. An empty
+// result means no problems were found. Fixes in the result modify m.
+func Check(m *pcb.Module) []Result {
+	l := linter{m: m, side: "F."}
+	if m.OnBack() {
+		l.side = "B."
+	}
+	l.checkMetadata()
+	l.checkSilkscreen()
+	l.checkCourtyard()
+	l.checkFab()
+	l.checkTexts()
+	l.checkPin1()
+	l.checkPads()
+	l.checkAttrs()
+	return l.results
+}
+
+type linter struct {
+	m *pcb.Module
+	// side is the layer prefix of the side the footprint is on.
+	side    string
+	results []Result
+}
+
+func (l *linter) add(rule string, sev Severity, element string, fix *Fix, format string, args ...interface{}) {
+	l.results = append(l.results, Result{
+		Rule:     rule,
+		Severity: sev,
+		Element:  element,
+		Msg:      fmt.Sprintf(format, args...),
+		Fix:      fix,
+	})
+}
+
+func (l *linter) errorf(rule, element string, fix *Fix, format string, args ...interface{}) {
+	l.add(rule, Error, element, fix, format, args...)
+}
+
+func (l *linter) warnf(rule, element string, fix *Fix, format string, args ...interface{}) {
+	l.add(rule, Warning, element, fix, format, args...)
+}
+
+// layer returns the named layer on the side of the footprint, such as
+// F.SilkS for "SilkS".
+func (l *linter) layer(name string) string {
+	return l.side + name
+}
@@ -0,0 +1,164 @@
+package lint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+const goodModule = `(module R_0805_2012Metric (layer F.Cu) (tedit 5DB1A000)
+  (descr "Resistor SMD 0805 (2012 Metric)")
+  (tags resistor)
+  (attr smd)
+  (fp_text reference REF** (at 0 -1.65) (layer F.SilkS)
+    (effects (font (size 1 1) (thickness 0.15)))
+  )
+  (fp_text value R_0805_2012Metric (at 0 1.65) (layer F.Fab)
+    (effects (font (size 1 1) (thickness 0.15)))
+  )
+  (fp_text user %R (at 0 0) (layer F.Fab)
+    (effects (font (size 0.5 0.5) (thickness 0.08)))
+  )
+  (fp_line (start -1 0.6) (end -1 -0.6) (layer F.Fab) (width 0.1))
+  (fp_line (start -1 -0.6) (end 1 -0.6) (layer F.Fab) (width 0.1))
+  (fp_line (start 1 -0.6) (end 1 0.6) (layer F.Fab) (width 0.1))
+  (fp_line (start 1 0.6) (end -1 0.6) (layer F.Fab) (width 0.1))
+  (fp_line (start -0.26 -0.71) (end 0.26 -0.71) (layer F.SilkS) (width 0.12))
+  (fp_line (start -0.26 0.71) (end 0.26 0.71) (layer F.SilkS) (width 0.12))
+  (fp_line (start -1.68 0.95) (end -1.68 -0.95) (layer F.CrtYd) (width 0.05))
+  (fp_line (start -1.68 -0.95) (end 1.68 -0.95) (layer F.CrtYd) (width 0.05))
+  (fp_line (start 1.68 -0.95) (end 1.68 0.95) (layer F.CrtYd) (width 0.05))
+  (fp_line (start 1.68 0.95) (end -1.68 0.95) (layer F.CrtYd) (width 0.05))
+  (pad 1 smd roundrect (at -0.9375 0) (size 0.975 1.4) (layers F.Cu F.Paste F.Mask) (roundrect_rratio 0.25))
+  (pad 2 smd roundrect (at 0.9375 0) (size 0.975 1.4) (layers F.Cu F.Paste F.Mask) (roundrect_rratio 0.25))
+)`
+
+func parse(t *testing.T, s string) *pcb.Module {
+	t.Helper()
+	m, err := pcb.ParseModule(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func results(rs []Result) []string {
+	var out []string
+	for _, r := range rs {
+		out = append(out, r.String())
+	}
+	return out
+}
+
+func TestCheckClean(t *testing.T) {
+	if rs := Check(parse(t, goodModule)); len(rs) > 0 {
+		t.Errorf("Check() = %q, want no results", results(rs))
+	}
+
+	// A through-hole footprint with pin 1 at the origin and an arc in the
+	// courtyard.
+	tht := parse(t, `(module PinHeader_1x02_P2.54mm_Vertical (layer F.Cu) (tedit 5DB1A000)
+  (descr "Through hole straight pin header, 1x02, 2.54mm pitch")
+  (tags "Through hole pin header THT 1x02 2.54mm")
+  (fp_text reference REF** (at 0 -2.33) (layer F.SilkS)
+    (effects (font (size 1 1) (thickness 0.15)))
+  )
+  (fp_text value PinHeader_1x02_P2.54mm_Vertical (at 0 4.87) (layer F.Fab)
+    (effects (font (size 1 1) (thickness 0.15)))
+  )
+  (fp_text user ${REFERENCE} (at 0 1.27 90) (layer F.Fab)
+    (effects (font (size 1 1) (thickness 0.15)))
+  )
+  (fp_circle (center 0 1.27) (end 1.27 1.27) (layer F.Fab) (width 0.1))
+  (fp_line (start -1.33 -1.33) (end -1.33 0) (layer F.SilkS) (width 0.12))
+  (fp_line (start -1.8 -1.8) (end 1.8 -1.8) (layer F.CrtYd) (width 0.05))
+  (fp_line (start 1.8 -1.8) (end 1.8 4.35) (layer F.CrtYd) (width 0.05))
+  (fp_arc (start 0 4.35) (end 1.8 4.35) (angle -180) (layer F.CrtYd) (width 0.05))
+  (fp_line (start -1.8 4.35) (end -1.8 -1.8) (layer F.CrtYd) (width 0.05))
+  (pad 1 thru_hole rect (at 0 0) (size 1.7 1.7) (drill 1) (layers *.Cu *.Mask))
+  (pad 2 thru_hole oval (at 0 2.54) (size 1.7 1.7) (drill 1) (layers *.Cu *.Mask))
+)`)
+	if rs := Check(tht); len(rs) > 0 {
+		t.Errorf("Check() = %q, want no results", results(rs))
+	}
+}
+
+func TestCheck(t *testing.T) {
+	m := parse(t, strings.NewReplacer(
+		"R_0805_2012Metric (layer", "R_0805/2012Metric (layer",
+		`(tags resistor)`, ``,
+		`(attr smd)`, ``,
+		`(fp_text value R_0805_2012Metric (at 0 1.65) (layer F.Fab)`, `(fp_text value R_0805_2012Metric (at 0 1.65) (layer F.SilkS)`,
+		`(fp_text user %R`, `(fp_text user REF`,
+		`(start -0.26 -0.71) (end 0.26 -0.71) (layer F.SilkS) (width 0.12)`, `(start -1 -0.5) (end 1 -0.5) (layer F.SilkS) (width 0.1)`,
+		`(start 1 -0.6) (end 1 0.6) (layer F.Fab) (width 0.1)`, `(start 1 -0.6) (end 1 0.6) (layer F.Fab) (width 0.15)`,
+		`(start -1.68 0.95) (end -1.68 -0.95) (layer F.CrtYd) (width 0.05)`, `(start -1.68 0.95) (end -1.68 -0.9) (layer F.CrtYd) (width 0.1)`,
+		`(pad 2 smd roundrect (at 0.9375 0) (size 0.975 1.4) (layers F.Cu F.Paste F.Mask)`, `(pad 2 smd roundrect (at 0.9375 0) (size 0.975 1.4) (layers F.Cu F.Mask)`,
+		`(pad 1 smd roundrect (at -0.9375 0) (size 0.975 1.4) (layers F.Cu F.Paste F.Mask)`, `(pad 1 smd roundrect (at 1.5 0) (size 0.975 1.4) (layers F.Cu B.Cu F.Mask)`,
+	).Replace(goodModule))
+
+	want := []string{
+		`G1.1 error module: name "R_0805/2012Metric" contains '/'`,
+		"F9.1 error module: no tags",
+		"F5.1 error graphics[7]: line width 0.1 is less than 0.12 (fix: set width to 0.12)",
+		"F5.2 error graphics[7]: overlaps pad[1]",
+		"F5.3 error graphics[9]: courtyard line width is 0.1, want 0.05 (fix: set width to 0.05)",
+		"F5.3 error courtyard: outline is not closed at (-1.68, -0.95)",
+		"F5.3 error courtyard: outline is not closed at (-1.68, -0.9)",
+		"F5.3 error pad[1]: extends outside the courtyard",
+		"F5.4 warning graphics[5]: fabrication line width is 0.15, want 0.1 (fix: set width to 0.1)",
+		"F5.4 error module: no ${REFERENCE} text on F.Fab (fix: add a ${REFERENCE} text on F.Fab)",
+		"F5.5 error graphics[1]: value text is on F.SilkS, want F.Fab (fix: move to F.Fab)",
+		"F6.1 warning pad[1]: pin 1 at (1.5, 0) is neither at the origin nor the top-left",
+		"F6.2 error pad[1]: SMD pad layers: missing F.Paste, unexpected B.Cu (fix: set layers to F.Cu F.Paste F.Mask)",
+		"F6.2 warning pad[2]: SMD pad layers: missing F.Paste (fix: set layers to F.Cu F.Paste F.Mask)",
+		"F9.2 error module: footprint has 2 SMD and 0 through-hole pads, but no smd attribute (fix: set the smd attribute)",
+	}
+	rs := Check(m)
+	if got := results(rs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() = %q\nwant %q", got, want)
+	}
+	for _, r := range rs {
+		if _, ok := Rules[r.Rule]; !ok {
+			t.Errorf("result %q uses undocumented rule %q", r, r.Rule)
+		}
+	}
+
+	if n := ApplyFixes(rs); n != 8 {
+		t.Errorf("ApplyFixes() = %d, want 8", n)
+	}
+	want = []string{
+		`G1.1 error module: name "R_0805/2012Metric" contains '/'`,
+		"F9.1 error module: no tags",
+		"F5.2 error graphics[7]: overlaps pad[1]",
+		"F5.3 error courtyard: outline is not closed at (-1.68, -0.95)",
+		"F5.3 error courtyard: outline is not closed at (-1.68, -0.9)",
+		"F5.3 error pad[1]: extends outside the courtyard",
+		"F6.1 warning pad[1]: pin 1 at (1.5, 0) is neither at the origin nor the top-left",
+	}
+	if got := results(Check(m)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() after fixes = %q\nwant %q", got, want)
+	}
+}
+
+func TestAddCourtyard(t *testing.T) {
+	m := parse(t, goodModule)
+	var kept []pcb.ModGraphic
+	for _, g := range m.Graphics {
+		if l, ok := g.Renderable.(*pcb.ModLine); !ok || l.Layer != "F.CrtYd" {
+			kept = append(kept, g)
+		}
+	}
+	m.Graphics = kept
+
+	rs := Check(m)
+	if got, want := results(rs), []string{"F5.3 error module: no courtyard on F.CrtYd (fix: add a courtyard from (-1.68, -0.95) to (1.68, 0.95))"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Check() = %q, want %q", got, want)
+	}
+	ApplyFixes(rs)
+	if rs := Check(m); len(rs) > 0 {
+		t.Errorf("Check() after adding a courtyard = %q, want no results", results(rs))
+	}
+}
@@ -0,0 +1,435 @@
+package lint
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Dimensions required by the rules, in millimeters.
+const (
+	minSilkWidth      = 0.12
+	courtyardWidth    = 0.05
+	courtyardMargin   = 0.25
+	courtyardGrid     = 0.01
+	fabWidth          = 0.1
+	widthTolerance    = 1e-6
+	positionTolerance = 1e-3
+)
+
+// fabReferences are the texts which pcbnew replaces with the reference
+// of the footprint.
+var fabReferences = []string{"${REFERENCE}", "%R"}
+
+func fmtNum(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func fmtXY(p pcb.XY) string {
+	return "(" + fmtNum(p.X) + ", " + fmtNum(p.Y) + ")"
+}
+
+func padElement(i int, p *pcb.Pad) string {
+	if p.Ident == "" {
+		return fmt.Sprintf("pad[#%d]", i)
+	}
+	return "pad[" + p.Ident + "]"
+}
+
+func setWidth(w *float64, v float64) *Fix {
+	return &Fix{
+		Description: "set width to " + fmtNum(v),
+		apply:       func() { *w = v },
+	}
+}
+
+func (l *linter) checkMetadata() {
+	name := l.m.Name
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" {
+		l.errorf("G1.1", "module", nil, "footprint has no name")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.+,", r)) {
+			l.errorf("G1.1", "module", nil, "name %q contains %q", name, r)
+			break
+		}
+	}
+	if strings.TrimSpace(l.m.Description) == "" {
+		l.errorf("F9.1", "module", nil, "description is empty")
+	}
+	if len(l.m.Tags) == 0 {
+		l.errorf("F9.1", "module", nil, "no tags")
+	}
+}
+
+func (l *linter) checkSilkscreen() {
+	silk := l.layer("SilkS")
+	for i, g := range l.m.Graphics {
+		o, ok := graphicOutline(g.Renderable)
+		if !ok || o.layer != silk {
+			continue
+		}
+		elem := fmt.Sprintf("graphics[%d]", i)
+		if *o.width < minSilkWidth-widthTolerance {
+			l.errorf("F5.1", elem, setWidth(o.width, minSilkWidth), "line width %s is less than %s", fmtNum(*o.width), fmtNum(minSilkWidth))
+		}
+	pads:
+		for j := range l.m.Pads {
+			p := &l.m.Pads[j]
+			for _, s := range o.segs {
+				if padDistance(l.m, p, s[0], s[1]) < *o.width/2 {
+					l.errorf("F5.2", elem, nil, "overlaps %s", padElement(j, p))
+					break pads
+				}
+			}
+		}
+	}
+}
+
+func (l *linter) checkCourtyard() {
+	crtyd := l.layer("CrtYd")
+	var (
+		found bool
+		b     box
+		ends  = map[[2]int64][]pcb.XY{}
+	)
+	for i, g := range l.m.Graphics {
+		o, ok := graphicOutline(g.Renderable)
+		if !ok || o.layer != crtyd {
+			continue
+		}
+		found = true
+		if math.Abs(*o.width-courtyardWidth) > widthTolerance {
+			l.errorf("F5.3", fmt.Sprintf("graphics[%d]", i), setWidth(o.width, courtyardWidth), "courtyard line width is %s, want %s", fmtNum(*o.width), fmtNum(courtyardWidth))
+		}
+		for _, s := range o.segs {
+			b.add(s[0])
+			b.add(s[1])
+		}
+		for _, e := range o.ends {
+			k := [2]int64{int64(math.Round(e.X / positionTolerance)), int64(math.Round(e.Y / positionTolerance))}
+			ends[k] = append(ends[k], e)
+		}
+	}
+	if !found {
+		l.errorf("F5.3", "module", l.addCourtyard(), "no courtyard on %s", crtyd)
+		return
+	}
+
+	// Each endpoint of an open shape must meet another to form a closed
+	// outline.
+	var open []pcb.XY
+	for _, pts := range ends {
+		if len(pts)%2 != 0 {
+			open = append(open, pts[0])
+		}
+	}
+	sort.Slice(open, func(i, j int) bool {
+		if open[i].X != open[j].X {
+			return open[i].X < open[j].X
+		}
+		return open[i].Y < open[j].Y
+	})
+	for _, p := range open {
+		l.errorf("F5.3", "courtyard", nil, "outline is not closed at %s", fmtXY(p))
+	}
+
+	for i := range l.m.Pads {
+		p := &l.m.Pads[i]
+		for _, c := range padCorners(l.m, p) {
+			if !b.contains(c) {
+				l.errorf("F5.3", padElement(i, p), nil, "extends outside the courtyard")
+				break
+			}
+		}
+	}
+}
+
+// addCourtyard returns a fix which draws a rectangular courtyard around
+// the pads and fabrication outline, or nil if there is nothing to enclose.
+func (l *linter) addCourtyard() *Fix {
+	var b box
+	for i := range l.m.Pads {
+		for _, c := range padCorners(l.m, &l.m.Pads[i]) {
+			b.add(c)
+		}
+	}
+	for _, g := range l.m.Graphics {
+		if o, ok := graphicOutline(g.Renderable); ok && o.layer == l.layer("Fab") {
+			for _, s := range o.segs {
+				b.add(s[0])
+				b.add(s[1])
+			}
+		}
+	}
+	if !b.ok {
+		return nil
+	}
+	outward := func(v, dir float64) float64 {
+		v += dir * courtyardMargin
+		if dir < 0 {
+			return roundNM(math.Floor(roundNM(v/courtyardGrid)) * courtyardGrid)
+		}
+		return roundNM(math.Ceil(roundNM(v/courtyardGrid)) * courtyardGrid)
+	}
+	x0, y0 := outward(b.min.X, -1), outward(b.min.Y, -1)
+	x1, y1 := outward(b.max.X, 1), outward(b.max.Y, 1)
+	m, layer := l.m, l.layer("CrtYd")
+	return &Fix{
+		Description: fmt.Sprintf("add a courtyard from %s to %s", fmtXY(pcb.XY{X: x0, Y: y0}), fmtXY(pcb.XY{X: x1, Y: y1})),
+		apply: func() {
+			corners := []pcb.XY{{X: x0, Y: y0}, {X: x1, Y: y0}, {X: x1, Y: y1}, {X: x0, Y: y1}}
+			for i := range corners {
+				m.Graphics = append(m.Graphics, pcb.ModGraphic{
+					Ident: "fp_line",
+					Renderable: &pcb.ModLine{
+						Start: corners[i],
+						End:   corners[(i+1)%len(corners)],
+						Layer: layer,
+						Width: courtyardWidth,
+					},
+				})
+			}
+		},
+	}
+}
+
+func (l *linter) checkFab() {
+	fab := l.layer("Fab")
+	var hasOutline, hasRef bool
+	for i, g := range l.m.Graphics {
+		if t, ok := g.Renderable.(*pcb.ModText); ok {
+			for _, ref := range fabReferences {
+				hasRef = hasRef || (t.Kind == pcb.UserText && t.Layer == fab && t.Text == ref)
+			}
+			continue
+		}
+		o, ok := graphicOutline(g.Renderable)
+		if !ok || o.layer != fab {
+			continue
+		}
+		hasOutline = true
+		if math.Abs(*o.width-fabWidth) > widthTolerance {
+			l.warnf("F5.4", fmt.Sprintf("graphics[%d]", i), setWidth(o.width, fabWidth), "fabrication line width is %s, want %s", fmtNum(*o.width), fmtNum(fabWidth))
+		}
+	}
+	if !hasOutline {
+		l.errorf("F5.4", "module", nil, "no outline on %s", fab)
+	}
+	if !hasRef {
+		m := l.m
+		l.errorf("F5.4", "module", &Fix{
+			Description: "add a " + fabReferences[0] + " text on " + fab,
+			apply: func() {
+				m.Graphics = append(m.Graphics, pcb.ModGraphic{
+					Ident: "fp_text",
+					Renderable: &pcb.ModText{
+						Kind:  pcb.UserText,
+						Text:  fabReferences[0],
+						At:    pcb.XYZ{Z: m.Placement.At.Z},
+						Layer: fab,
+						Effects: pcb.TextEffects{
+							FontSize:  pcb.XY{X: 1, Y: 1},
+							Thickness: 0.15,
+							Mirrored:  m.OnBack(),
+						},
+					},
+				})
+			},
+		}, "no %s text on %s", fabReferences[0], fab)
+	}
+}
+
+func (l *linter) checkTexts() {
+	want := map[pcb.ModTextKind]string{
+		pcb.RefText:   l.layer("SilkS"),
+		pcb.ValueText: l.layer("Fab"),
+	}
+	found := map[pcb.ModTextKind]bool{}
+	for i, g := range l.m.Graphics {
+		t, ok := g.Renderable.(*pcb.ModText)
+		if !ok {
+			continue
+		}
+		layer, ok := want[t.Kind]
+		if !ok {
+			continue
+		}
+		found[t.Kind] = true
+		if t.Layer != layer {
+			l.errorf("F5.5", fmt.Sprintf("graphics[%d]", i), &Fix{
+				Description: "move to " + layer,
+				apply:       func() { t.Layer = layer },
+			}, "%s text is on %s, want %s", t.Kind, t.Layer, layer)
+		}
+	}
+	for _, k := range []pcb.ModTextKind{pcb.RefText, pcb.ValueText} {
+		if !found[k] {
+			l.errorf("F5.5", "module", nil, "no %s text", k)
+		}
+	}
+}
+
+func (l *linter) checkPin1() {
+	var (
+		pin1   *pcb.Pad
+		b      box
+		idents = map[string]bool{}
+	)
+	for i := range l.m.Pads {
+		p := &l.m.Pads[i]
+		if p.Ident == "" {
+			continue
+		}
+		idents[p.Ident] = true
+		b.add(pcb.XY{X: p.At.X, Y: p.At.Y})
+		if pin1 == nil && (p.Ident == "1" || p.Ident == "A1") {
+			pin1 = p
+		}
+	}
+	if pin1 == nil || len(idents) < 2 {
+		return
+	}
+	at := pcb.XY{X: pin1.At.X, Y: pin1.At.Y}
+	if at.Distance(pcb.XY{}) <= positionTolerance {
+		return
+	}
+	if c := b.center(); at.X > c.X+positionTolerance || at.Y > c.Y+positionTolerance {
+		l.warnf("F6.1", "pad["+pin1.Ident+"]", nil, "pin 1 at %s is neither at the origin nor the top-left", fmtXY(at))
+	}
+}
+
+func (l *linter) checkPads() {
+	for i := range l.m.Pads {
+		p := &l.m.Pads[i]
+		switch p.Surface {
+		case pcb.SurfaceSMD:
+			l.checkSMDLayers(i, p)
+		case pcb.SurfaceTH:
+			l.checkTHLayers(i, p)
+		}
+	}
+}
+
+func (l *linter) checkSMDLayers(i int, p *pcb.Pad) {
+	want := []string{l.layer("Cu"), l.layer("Paste"), l.layer("Mask")}
+	has := map[string]bool{}
+	for _, layer := range p.Layers {
+		has[layer] = true
+	}
+	var missing, extra []string
+	for _, layer := range want {
+		if !has[layer] {
+			missing = append(missing, layer)
+		}
+		delete(has, layer)
+	}
+	for layer := range has {
+		extra = append(extra, layer)
+	}
+	sort.Strings(extra)
+	if len(missing) == 0 && len(extra) == 0 {
+		return
+	}
+	fix := &Fix{
+		Description: "set layers to " + strings.Join(want, " "),
+		apply:       func() { p.Layers = want },
+	}
+	var msgs []string
+	if len(missing) > 0 {
+		msgs = append(msgs, "missing "+strings.Join(missing, " "))
+	}
+	if len(extra) > 0 {
+		msgs = append(msgs, "unexpected "+strings.Join(extra, " "))
+	}
+	// Pads without paste are sometimes intentional, for example exposed
+	// pads with separate paste apertures.
+	if len(extra) == 0 && len(missing) == 1 && missing[0] == l.layer("Paste") {
+		l.warnf("F6.2", padElement(i, p), fix, "SMD pad layers: %s", strings.Join(msgs, ", "))
+		return
+	}
+	l.errorf("F6.2", padElement(i, p), fix, "SMD pad layers: %s", strings.Join(msgs, ", "))
+}
+
+func (l *linter) checkTHLayers(i int, p *pcb.Pad) {
+	var cu, mask bool
+	var other []string
+	for _, layer := range p.Layers {
+		switch {
+		case layer == "*.Cu":
+			cu = true
+		case layer == "*.Mask":
+			mask = true
+		case !strings.HasSuffix(layer, ".Cu") && !strings.HasSuffix(layer, ".Mask"):
+			other = append(other, layer)
+		}
+	}
+	if cu && mask {
+		return
+	}
+	want := append([]string{"*.Cu", "*.Mask"}, other...)
+	l.errorf("F7.2", padElement(i, p), &Fix{
+		Description: "set layers to " + strings.Join(want, " "),
+		apply:       func() { p.Layers = want },
+	}, "through-hole pad layers are %s, want *.Cu *.Mask", strings.Join(p.Layers, " "))
+}
+
+func (l *linter) checkAttrs() {
+	var smd, th int
+	for _, p := range l.m.Pads {
+		switch p.Surface {
+		case pcb.SurfaceSMD:
+			smd++
+		case pcb.SurfaceTH:
+			th++
+		}
+	}
+	var want string
+	switch {
+	case th > 0:
+	case smd > 0:
+		want = "smd"
+	case len(l.m.Pads) == 0:
+		want = "virtual"
+	}
+
+	var kept []string
+	var got string
+	for _, a := range l.m.Attrs {
+		if a == "smd" || a == "virtual" {
+			got = a
+			continue
+		}
+		kept = append(kept, a)
+	}
+	// Footprints with only unplated holes may be virtual, like mounting
+	// holes.
+	if got == want || (got == "virtual" && smd == 0 && th == 0) {
+		return
+	}
+	if want != "" {
+		kept = append(kept, want)
+	}
+	m := l.m
+	desc := "remove the " + got + " attribute"
+	if want != "" {
+		desc = "set the " + want + " attribute"
+	}
+	msg := fmt.Sprintf("footprint has %d SMD and %d through-hole pads", smd, th)
+	if got == "" {
+		msg += ", but no " + want + " attribute"
+	} else {
+		msg += ", but the " + got + " attribute"
+	}
+	l.errorf("F9.2", "module", &Fix{
+		Description: desc,
+		apply:       func() { m.Attrs = kept },
+	}, "%s", msg)
+}
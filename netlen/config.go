@@ -0,0 +1,80 @@
+package netlen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+)
+
+// DefaultDiffPairSkew is the largest length difference allowed between
+// the nets of a differential pair when no configuration is given.
+const DefaultDiffPairSkew = 0.1
+
+// Config sets the tolerances checked by Measure. It is usually read from a
+// JSON file such as:
+//
+//	{
+//	  "diff_pair_skew": 0.1,
+//	  "groups": [
+//	    {"name": "DDR data", "nets": ["/DDR/DQ*", "/DDR/DQS*"], "tolerance": 0.5}
+//	  ]
+//	}
+type Config struct {
+	// DiffPairSkew is the largest allowed difference in length between
+	// the nets of a differential pair. Zero disables the check.
+	DiffPairSkew float64 `json:"diff_pair_skew"`
+	// Groups are sets of nets which must be routed to the same length.
+	Groups []Group `json:"groups"`
+}
+
+// Group is a set of nets which must be routed to the same length.
+type Group struct {
+	Name string `json:"name"`
+	// Nets lists the nets of the group, as names or patterns matched with
+	// path.Match, such as "/DDR/DQ*".
+	Nets []string `json:"nets"`
+	// Tolerance is the largest allowed difference between the longest and
+	// shortest nets of the group.
+	Tolerance float64 `json:"tolerance"`
+}
+
+// DefaultConfig returns the configuration used when none is given, which
+// checks the skew of differential pairs.
+func DefaultConfig() *Config {
+	return &Config{DiffPairSkew: DefaultDiffPairSkew}
+}
+
+// LoadConfig reads a configuration from a JSON file. Tolerances which are
+// not given take the values of DefaultConfig.
+func LoadConfig(fpath string) (*Config, error) {
+	d, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	c := DefaultConfig()
+	if err := json.Unmarshal(d, c); err != nil {
+		return nil, fmt.Errorf("%s: %v", fpath, err)
+	}
+	for i, g := range c.Groups {
+		if g.Name == "" {
+			return nil, fmt.Errorf("%s: group %d has no name", fpath, i)
+		}
+		for _, pattern := range g.Nets {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%s: group %s: bad pattern %q: %v", fpath, g.Name, pattern, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// matches reports whether the group includes the net.
+func (g *Group) matches(net string) bool {
+	for _, pattern := range g.Nets {
+		if ok, _ := path.Match(pattern, net); ok {
+			return true
+		}
+	}
+	return false
+}
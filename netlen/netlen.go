@@ -0,0 +1,391 @@
+// Package netlen reports the routed length of each net of a board, and
+// checks differential pairs and groups of nets for length matching.
+package netlen
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Kinds of Problem.
+const (
+	// ProblemSkew is used for differential pairs whose nets differ in
+	// length by more than the configured skew.
+	ProblemSkew = "skew"
+	// ProblemWidth is used for differential pairs with tracks which are
+	// not the diff pair width of their net class.
+	ProblemWidth = "width"
+	// ProblemGap is used for differential pairs routed closer together
+	// than the diff pair gap of their net class.
+	ProblemGap = "gap"
+	// ProblemGroup is used for nets which are not within the tolerance of
+	// the longest net of their length group.
+	ProblemGroup = "group"
+)
+
+// tolerance absorbs rounding when comparing widths and gaps.
+const tolerance = 1e-3
+
+// Report describes the routed lengths of the nets of a board.
+type Report struct {
+	// Classes lists the routed nets of each net class.
+	Classes   []Class        `json:"classes"`
+	DiffPairs []DiffPair     `json:"diff_pairs"`
+	Groups    []GroupLengths `json:"groups"`
+	Problems  []Problem      `json:"problems"`
+}
+
+// Class lists the routed nets of a net class.
+type Class struct {
+	Name string      `json:"name"`
+	Nets []NetLength `json:"nets"`
+}
+
+// NetLength is the routed length of a net.
+type NetLength struct {
+	Net   string `json:"net"`
+	Class string `json:"class"`
+	// TrackLength is the total length of the tracks of the net.
+	TrackLength float64 `json:"track_length"`
+	Vias        int     `json:"vias"`
+	// ViaLength is the total length of via barrels the signal travels
+	// through, between the outermost layers with tracks at each via.
+	ViaLength float64 `json:"via_length"`
+	// Length is the sum of TrackLength and ViaLength.
+	Length float64 `json:"length"`
+}
+
+func (n NetLength) String() string {
+	return fmt.Sprintf("%s: %s (tracks %s + vias %s)", n.Net, fmtNum(n.Length), fmtNum(n.TrackLength), fmtNum(n.ViaLength))
+}
+
+// DiffPair describes a differential pair, detected by nets named with
+// _P and _N or + and - suffixes.
+type DiffPair struct {
+	Name     string `json:"name"`
+	Positive string `json:"positive"`
+	Negative string `json:"negative"`
+	Class    string `json:"class"`
+	// PositiveLength and NegativeLength are the routed lengths of the nets.
+	PositiveLength float64 `json:"positive_length"`
+	NegativeLength float64 `json:"negative_length"`
+	Skew           float64 `json:"skew"`
+	// Width and Gap are the diff pair dimensions of the net class.
+	Width float64 `json:"width"`
+	Gap   float64 `json:"gap"`
+	// MinGap is the smallest distance between tracks of the two nets on
+	// the same layer, or -1 if they are never on the same layer.
+	MinGap float64 `json:"min_gap"`
+}
+
+func (d DiffPair) String() string {
+	return fmt.Sprintf("%s: %s %s, %s %s, skew %s", d.Name, d.Positive, fmtNum(d.PositiveLength), d.Negative, fmtNum(d.NegativeLength), fmtNum(d.Skew))
+}
+
+// GroupLengths describes the lengths of the nets of a length group.
+type GroupLengths struct {
+	Name      string   `json:"name"`
+	Nets      []string `json:"nets"`
+	Min       float64  `json:"min"`
+	Max       float64  `json:"max"`
+	Tolerance float64  `json:"tolerance"`
+}
+
+func (g GroupLengths) String() string {
+	return fmt.Sprintf("%s: %d nets from %s to %s, tolerance %s", g.Name, len(g.Nets), fmtNum(g.Min), fmtNum(g.Max), fmtNum(g.Tolerance))
+}
+
+// Problem describes a length or differential pair mismatch.
+type Problem struct {
+	Kind string `json:"kind"`
+	// Net is the net, or the name of the differential pair, at fault.
+	Net string `json:"net"`
+	Msg string `json:"msg"`
+}
+
+func (p Problem) String() string {
+	return p.Net + ": " + p.Msg
+}
+
+func fmtNum(v float64) string {
+	return strconv.FormatFloat(roundUM(v), 'f', -1, 64)
+}
+
+// roundUM rounds to the nearest micrometer, for reporting.
+func roundUM(v float64) float64 {
+	return math.Round(v*1e3) / 1e3
+}
+
+// net collects the copper of a net.
+type net struct {
+	NetLength
+	tracks []*pcb.Track
+	vias   []*pcb.Via
+}
+
+// Measure computes the routed length of each net of the board, and checks
+// differential pairs and the length groups of the configuration. The
+// length of via barrels comes from the stackup of the board.
+func Measure(p *pcb.PCB, cfg *Config) (*Report, error) {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	depth, err := copperDepths(p)
+	if err != nil {
+		return nil, err
+	}
+
+	classOf := map[string]*pcb.NetClass{}
+	var defaultClass *pcb.NetClass
+	for i := range p.NetClasses {
+		nc := &p.NetClasses[i]
+		if nc.Name == "Default" {
+			defaultClass = nc
+		}
+		for _, n := range nc.Nets {
+			classOf[n] = nc
+		}
+	}
+	if defaultClass == nil {
+		defaultClass = &pcb.NetClass{Name: "Default"}
+	}
+	class := func(name string) *pcb.NetClass {
+		if nc, ok := classOf[name]; ok {
+			return nc
+		}
+		return defaultClass
+	}
+
+	nets := map[string]*net{}
+	get := func(num int) *net {
+		name := p.Nets[num].Name
+		n, ok := nets[name]
+		if !ok {
+			n = &net{NetLength: NetLength{Net: name, Class: class(name).Name}}
+			nets[name] = n
+		}
+		return n
+	}
+	for _, s := range p.Segments {
+		switch s := s.(type) {
+		case *pcb.Track:
+			if s.NetIndex != 0 {
+				n := get(s.NetIndex)
+				n.tracks = append(n.tracks, s)
+				n.TrackLength += math.Hypot(s.End.X-s.Start.X, s.End.Y-s.Start.Y)
+			}
+		case *pcb.Via:
+			if s.NetIndex != 0 {
+				n := get(s.NetIndex)
+				n.vias = append(n.vias, s)
+				n.Vias++
+			}
+		}
+	}
+	for _, n := range nets {
+		for _, v := range n.vias {
+			n.ViaLength += viaLength(v, n.tracks, depth)
+		}
+		n.TrackLength = roundUM(n.TrackLength)
+		n.ViaLength = roundUM(n.ViaLength)
+		n.Length = roundUM(n.TrackLength + n.ViaLength)
+	}
+
+	r := &Report{
+		Classes:   []Class{},
+		DiffPairs: []DiffPair{},
+		Groups:    []GroupLengths{},
+		Problems:  []Problem{},
+	}
+	names := make([]string, 0, len(nets))
+	for name := range nets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	classIndex := map[string]int{}
+	for _, name := range names {
+		n := nets[name]
+		i, ok := classIndex[n.Class]
+		if !ok {
+			i = len(r.Classes)
+			classIndex[n.Class] = i
+			r.Classes = append(r.Classes, Class{Name: n.Class})
+		}
+		r.Classes[i].Nets = append(r.Classes[i].Nets, n.NetLength)
+	}
+	sort.Slice(r.Classes, func(i, j int) bool { return r.Classes[i].Name < r.Classes[j].Name })
+
+	length := func(name string) float64 {
+		if n, ok := nets[name]; ok {
+			return n.Length
+		}
+		return 0
+	}
+	for _, pair := range diffPairs(p) {
+		nc := class(pair.Positive)
+		pos, neg := nets[pair.Positive], nets[pair.Negative]
+		pair.Class = nc.Name
+		pair.PositiveLength, pair.NegativeLength = length(pair.Positive), length(pair.Negative)
+		pair.Skew = roundUM(math.Abs(pair.PositiveLength - pair.NegativeLength))
+		pair.Width, pair.Gap = nc.DiffPairWidth, nc.DiffPairGap
+		pair.MinGap = -1
+		if pos != nil && neg != nil {
+			pair.MinGap = minGap(pos.tracks, neg.tracks)
+		}
+		r.DiffPairs = append(r.DiffPairs, pair)
+
+		if cfg.DiffPairSkew > 0 && pair.Skew > cfg.DiffPairSkew+tolerance {
+			r.addf(ProblemSkew, pair.Name, "skew %s between %s and %s is more than %s", fmtNum(pair.Skew), pair.Positive, pair.Negative, fmtNum(cfg.DiffPairSkew))
+		}
+		if pair.Width > 0 {
+			for _, n := range []*net{pos, neg} {
+				if n == nil {
+					continue
+				}
+				wrong := 0
+				for _, t := range n.tracks {
+					if math.Abs(t.Width-pair.Width) > tolerance {
+						wrong++
+					}
+				}
+				if wrong > 0 {
+					r.addf(ProblemWidth, n.Net, "%d of %d tracks are not the diff pair width %s", wrong, len(n.tracks), fmtNum(pair.Width))
+				}
+			}
+		}
+		if pair.Gap > 0 && pair.MinGap >= 0 && pair.MinGap < pair.Gap-tolerance {
+			r.addf(ProblemGap, pair.Name, "gap %s is less than the diff pair gap %s", fmtNum(pair.MinGap), fmtNum(pair.Gap))
+		}
+	}
+
+	for _, g := range cfg.Groups {
+		gl := GroupLengths{Name: g.Name, Nets: []string{}, Tolerance: g.Tolerance}
+		longest := ""
+		for _, num := range sortedNetNums(p) {
+			name := p.Nets[num].Name
+			if num == 0 || !g.matches(name) {
+				continue
+			}
+			l := length(name)
+			if len(gl.Nets) == 0 || l < gl.Min {
+				gl.Min = l
+			}
+			if len(gl.Nets) == 0 || l > gl.Max {
+				gl.Max, longest = l, name
+			}
+			gl.Nets = append(gl.Nets, name)
+		}
+		sort.Strings(gl.Nets)
+		r.Groups = append(r.Groups, gl)
+		for _, name := range gl.Nets {
+			if d := gl.Max - length(name); d > g.Tolerance+tolerance {
+				r.addf(ProblemGroup, name, "%s shorter than %s in group %s, tolerance %s", fmtNum(d), longest, g.Name, fmtNum(g.Tolerance))
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *Report) addf(kind, net, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, Problem{Kind: kind, Net: net, Msg: fmt.Sprintf(format, args...)})
+}
+
+func sortedNetNums(p *pcb.PCB) []int {
+	nums := make([]int, 0, len(p.Nets))
+	for num := range p.Nets {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// diffPairSuffixes are the suffixes of the positive and negative nets of
+// a differential pair.
+var diffPairSuffixes = [][2]string{{"_P", "_N"}, {"+", "-"}}
+
+// diffPairs returns the differential pairs of the board, by name.
+func diffPairs(p *pcb.PCB) []DiffPair {
+	names := map[string]bool{}
+	for _, n := range p.Nets {
+		names[n.Name] = true
+	}
+	var out []DiffPair
+	for _, num := range sortedNetNums(p) {
+		name := p.Nets[num].Name
+		for _, s := range diffPairSuffixes {
+			if !strings.HasSuffix(name, s[0]) {
+				continue
+			}
+			base := strings.TrimSuffix(name, s[0])
+			if base != "" && names[base+s[1]] {
+				out = append(out, DiffPair{Name: base, Positive: name, Negative: base + s[1]})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// copperDepths returns the depth of the middle of each copper layer from
+// the front of the board.
+func copperDepths(p *pcb.PCB) (map[string]float64, error) {
+	s, err := p.Stackup()
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]float64{}
+	var z float64
+	for _, l := range s.Layers {
+		if l.Typ == "copper" {
+			out[l.Name] = z + l.Thickness/2
+		}
+		z += l.Thickness
+	}
+	return out, nil
+}
+
+// viaLength returns the length of the barrel of a via which a signal
+// travels through: between the outermost layers of the tracks which meet
+// it. A via with tracks on only one layer does not add to the length.
+func viaLength(v *pcb.Via, tracks []*pcb.Track, depth map[string]float64) float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, t := range tracks {
+		d, ok := depth[t.Layer]
+		if !ok {
+			continue
+		}
+		for _, end := range []pcb.XY{t.Start, t.End} {
+			if math.Hypot(end.X-v.At.X, end.Y-v.At.Y) <= v.Size/2 {
+				lo, hi = math.Min(lo, d), math.Max(hi, d)
+			}
+		}
+	}
+	if hi <= lo {
+		return 0
+	}
+	return hi - lo
+}
+
+// minGap returns the smallest distance between the edges of tracks of two
+// nets on the same layer, or -1 if they do not share a layer.
+func minGap(a, b []*pcb.Track) float64 {
+	gap := math.Inf(1)
+	for _, ta := range a {
+		for _, tb := range b {
+			if ta.Layer == tb.Layer {
+				d, _ := pcb.SegmentDistance(ta.Start, ta.End, tb.Start, tb.End)
+				d -= ta.Width/2 + tb.Width/2
+				gap = math.Min(gap, d)
+			}
+		}
+	}
+	if math.IsInf(gap, 1) {
+		return -1
+	}
+	return roundUM(math.Max(gap, 0))
+}
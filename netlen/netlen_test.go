@@ -0,0 +1,101 @@
+package netlen
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+func TestMeasure(t *testing.T) {
+	p, err := pcb.DecodeFile(filepath.Join("testdata", "lengths.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(filepath.Join("testdata", "lengths.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Measure(p, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range r.Classes {
+		for _, n := range c.Nets {
+			got = append(got, c.Name+" "+n.String())
+		}
+	}
+	for _, v := range []interface{}{r.DiffPairs, r.Groups, r.Problems} {
+		v := reflect.ValueOf(v)
+		for i := 0; i < v.Len(); i++ {
+			got = append(got, fmt.Sprint(v.Index(i).Interface()))
+		}
+	}
+	// The via barrel between the copper layers of a 1.6mm board is
+	// 1.51mm of dielectric and half of each 35um copper layer.
+	want := []string{
+		"Default /DDR/DQ0: 30 (tracks 30 + vias 0)",
+		"Default /DDR/DQ1: 29 (tracks 29 + vias 0)",
+		"Default /DDR/DQ2: 31.345 (tracks 29.8 + vias 1.545)",
+		"USB USB_D+: 21.545 (tracks 20 + vias 1.545)",
+		"USB USB_D-: 22 (tracks 22 + vias 0)",
+		"USB_D: USB_D+ 21.545, USB_D- 22, skew 0.455",
+		"DDR: 3 nets from 29 to 31.345, tolerance 0.5",
+		"USB_D: skew 0.455 between USB_D+ and USB_D- is more than 0.1",
+		"USB_D-: 1 of 2 tracks are not the diff pair width 0.2",
+		"/DDR/DQ0: 1.345 shorter than /DDR/DQ2 in group DDR, tolerance 0.5",
+		"/DDR/DQ1: 2.345 shorter than /DDR/DQ2 in group DDR, tolerance 0.5",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Measure() = %q\nwant %q", got, want)
+	}
+	if pair := r.DiffPairs[0]; pair.MinGap != 0.15 || pair.Width != 0.2 || pair.Gap != 0.15 {
+		t.Errorf("diff pair = %+v, want a gap of 0.15 and the width and gap of the USB class", pair)
+	}
+
+	// Without groups, only the diff pair is checked.
+	r, err = Measure(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Groups) != 0 || len(r.Problems) != 2 {
+		t.Errorf("Measure() with the default config = %v, want the skew and width problems", r.Problems)
+	}
+}
+
+func TestDiffPairs(t *testing.T) {
+	p := &pcb.PCB{Nets: map[int]pcb.Net{
+		0: {},
+		1: {Name: "/CLK_P"},
+		2: {Name: "/CLK_N"},
+		3: {Name: "USB+"},
+		4: {Name: "USB-"},
+		5: {Name: "+5V"},
+		6: {Name: "LONE_P"},
+	}}
+	var got []string
+	for _, d := range diffPairs(p) {
+		got = append(got, d.Name+" "+d.Positive+" "+d.Negative)
+	}
+	if want := []string{"/CLK /CLK_P /CLK_N", "USB USB+ USB-"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("diffPairs() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join("testdata", "lengths.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Config{DiffPairSkew: 0.1, Groups: []Group{{Name: "DDR", Nets: []string{"/DDR/DQ*"}, Tolerance: 0.5}}}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("LoadConfig() = %+v, want %+v", cfg, want)
+	}
+	if !cfg.Groups[0].matches("/DDR/DQ7") || cfg.Groups[0].matches("/DDR/DM0") {
+		t.Error("group patterns matched the wrong nets")
+	}
+}
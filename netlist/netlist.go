@@ -2,77 +2,234 @@
 package netlist
 
 import (
-	"errors"
-	"fmt"
 	"io/ioutil"
-	"strings"
 
-	"github.com/nsf/sexp"
+	"github.com/twitchyliquid64/kcgen/sreader"
 )
 
+// Design describes the schematic the netlist was exported from.
+type Design struct {
+	Source string
+	Date   string
+	Tool   string
+	Sheets []Sheet
+}
+
+// Sheet describes a sheet of the schematic.
+type Sheet struct {
+	Number     int
+	Name       string
+	Tstamps    string
+	TitleBlock TitleBlock
+}
+
+// TitleBlock describes the title block of a schematic sheet.
+type TitleBlock struct {
+	Title, Company, Rev, Date, Source string
+	Comments                          []Comment
+}
+
+// Comment is a numbered comment in a title block.
+type Comment struct {
+	Number int
+	Value  string
+}
+
 // Component describes a component in the netlist.
 type Component struct {
 	Ref, Value string
 	Footprint  string
+	Datasheet  string
+	Fields     []Field
+	LibSource  LibSource
+	SheetPath  SheetPath
 	TStamp     string
 }
 
+// Field returns the value of the named user field of the component, or
+// the empty string if it has no such field.
+func (c *Component) Field(name string) string {
+	for _, f := range c.Fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Path returns the path pcbnew uses to associate a module with the
+// component: the timestamps of the sheets containing it, followed by
+// its own timestamp.
+func (c *Component) Path() string {
+	tstamps := c.SheetPath.Tstamps
+	if tstamps == "" {
+		tstamps = "/"
+	}
+	return tstamps + c.TStamp
+}
+
+// Field is a named value attached to a component or library part.
+type Field struct {
+	Name, Value string
+}
+
+// LibSource identifies the library part a component was instantiated from.
+type LibSource struct {
+	Lib, Part, Description string
+}
+
+// SheetPath identifies the sheet containing a component, by the names and
+// timestamps of the sheets on the path to it.
+type SheetPath struct {
+	Names, Tstamps string
+}
+
+// LibPart describes a library part used by the schematic.
+type LibPart struct {
+	Lib, Part   string
+	Description string
+	Docs        string
+	Aliases     []string
+	// Footprints are the footprint filters of the part, such as R_*.
+	Footprints []string
+	Fields     []Field
+	Pins       []Pin
+}
+
+// Pin describes a pin of a library part.
+type Pin struct {
+	Num, Name string
+	// Type is the electrical type, such as input, passive or power_in.
+	Type string
+}
+
+// Library describes a symbol library used by the schematic.
+type Library struct {
+	Logical, URI string
+}
+
 // Net describes a net in the netlist.
 type Net struct {
-	Name string
-	Code int
+	Name  string
+	Code  int
+	Nodes []Node
+}
+
+// Node describes a component pin connected to a net.
+type Node struct {
+	Ref, Pin string
+	// PinFunction is the name of the pin, if it has one.
+	PinFunction string
 }
 
 // Netlist represents the parsed contents of a netlist file.
 type Netlist struct {
 	Version    string
+	Design     Design
 	Components []Component
+	LibParts   []LibPart
+	Libraries  []Library
 	Nets       []Net
 }
 
-// DecodeFile reads a .sch file at fpath, returning a parsed representation.
+// Component returns the component with the given reference, or nil.
+func (nl *Netlist) Component(ref string) *Component {
+	for i := range nl.Components {
+		if nl.Components[i].Ref == ref {
+			return &nl.Components[i]
+		}
+	}
+	return nil
+}
+
+// LibPart returns the library part a component was instantiated from, or
+// nil if it is not described by the netlist.
+func (nl *Netlist) LibPart(c *Component) *LibPart {
+	for i := range nl.LibParts {
+		if p := &nl.LibParts[i]; p.Lib == c.LibSource.Lib && p.Part == c.LibSource.Part {
+			return p
+		}
+	}
+	return nil
+}
+
+// PinNet returns the net a component pin is connected to, or nil if the
+// pin is not connected.
+func (nl *Netlist) PinNet(ref, pin string) *Net {
+	for i := range nl.Nets {
+		for _, n := range nl.Nets[i].Nodes {
+			if n.Ref == ref && n.Pin == pin {
+				return &nl.Nets[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ParseError describes malformed input, and where it was encountered.
+type ParseError = sreader.ParseError
+
+// DecodeFile reads a .net file at fpath, returning a parsed representation.
 func DecodeFile(fpath string) (*Netlist, error) {
 	f, err := ioutil.ReadFile(fpath)
 	if err != nil {
 		return nil, err
 	}
+	return decode(f, fpath)
+}
+
+// Decode parses the provided netlist content. Malformed input is reported
+// as a *ParseError.
+func Decode(f []byte) (*Netlist, error) {
+	return decode(f, "")
+}
 
-	ast, err := sexp.Parse(strings.NewReader(string(f)), nil)
+func decode(f []byte, fname string) (*Netlist, error) {
+	ast, err := sreader.Parse(f, fname)
 	if err != nil {
 		return nil, err
 	}
 
-	if !ast.IsList() {
-		return nil, errors.New("invalid format: expected s-expression list at top level")
-	}
 	if ast.NumChildren() != 1 {
-		return nil, errors.New("invalid format: top level list of size 1")
+		return nil, ast.Errorf("invalid format: expected a single top-level expression, got %d", ast.NumChildren())
 	}
-	mainAST, _ := ast.Nth(0)
+	mainAST := ast.Child(0)
 	if !mainAST.IsList() {
-		return nil, errors.New("invalid format: expected s-expression list at 1st level")
+		return nil, mainAST.Errorf("invalid format: expected s-expression list at 1st level")
 	}
 
 	if mainAST.NumChildren() < 5 {
-		return nil, errors.New("invalid format: expected at least 5 nodes in main expression")
+		return nil, mainAST.Errorf("invalid format: expected at least 5 nodes in main expression")
 	}
-	if mainAST.Children.Value != "export" {
-		return nil, errors.New("invalid format: missing leading element export")
+	if mainAST.Name() != "export" {
+		return nil, mainAST.Errorf("invalid format: missing leading element export")
+	}
+
+	nl, err := parseNetlist(mainAST)
+	if err != nil {
+		return nil, err
 	}
+	return nl, nil
+}
 
-	nl := &Netlist{}
+func parseNetlist(mainAST sreader.Node) (nl *Netlist, err error) {
+	defer sreader.Recover(&err)
+	nl = &Netlist{}
 
 	for i := 1; i < mainAST.NumChildren(); i++ {
-		n := sexp.Help(mainAST).Child(i)
+		n := mainAST.Child(i)
 		if n.IsList() && n.Child(1).IsValid() {
 			switch n.Child(0).MustString() {
 			case "version":
-				nl.Version, err = n.Child(1).String()
-				if err != nil {
-					return nil, errors.New("invalid format: version value[1] must be a string")
+				if nl.Version, err = n.Child(1).String(); err != nil {
+					return nil, err
+				}
+			case "design":
+				if nl.Design, err = parseDesign(n); err != nil {
+					return nil, err
 				}
 			case "components":
-				for x := 1; x < n.MustNode().NumChildren(); x++ {
+				for x := 1; x < n.NumChildren(); x++ {
 					c := n.Child(x)
 					comp, err := parseComponent(c)
 					if err != nil {
@@ -80,8 +237,33 @@ func DecodeFile(fpath string) (*Netlist, error) {
 					}
 					nl.Components = append(nl.Components, comp)
 				}
+			case "libparts":
+				for x := 1; x < n.NumChildren(); x++ {
+					p, err := parseLibPart(n.Child(x))
+					if err != nil {
+						return nil, err
+					}
+					nl.LibParts = append(nl.LibParts, p)
+				}
+			case "libraries":
+				for x := 1; x < n.NumChildren(); x++ {
+					c := n.Child(x)
+					if c.Name() != "library" {
+						return nil, c.Errorf("invalid format: library must be 'library'")
+					}
+					var l Library
+					for y := 1; y < c.NumChildren(); y++ {
+						switch c2 := c.Child(y); c2.Name() {
+						case "logical":
+							l.Logical = optString(c2)
+						case "uri":
+							l.URI = optString(c2)
+						}
+					}
+					nl.Libraries = append(nl.Libraries, l)
+				}
 			case "nets":
-				for x := 1; x < n.MustNode().NumChildren(); x++ {
+				for x := 1; x < n.NumChildren(); x++ {
 					c := n.Child(x)
 					net, err := parseNet(c)
 					if err != nil {
@@ -96,67 +278,272 @@ func DecodeFile(fpath string) (*Netlist, error) {
 	return nl, nil
 }
 
-func parseComponent(c sexp.Helper) (Component, error) {
+// optString returns the value of a (name value) element, or the empty
+// string if the value is omitted.
+func optString(n sreader.Node) string {
+	if !n.Child(1).IsValid() {
+		return ""
+	}
+	return n.Child(1).MustString()
+}
+
+func parseDesign(n sreader.Node) (Design, error) {
+	out := Design{}
+	for x := 1; x < n.NumChildren(); x++ {
+		c := n.Child(x)
+		switch c.Name() {
+		case "source":
+			out.Source = optString(c)
+		case "date":
+			out.Date = optString(c)
+		case "tool":
+			out.Tool = optString(c)
+		case "sheet":
+			s, err := parseSheet(c)
+			if err != nil {
+				return Design{}, err
+			}
+			out.Sheets = append(out.Sheets, s)
+		}
+	}
+	return out, nil
+}
+
+func parseSheet(n sreader.Node) (Sheet, error) {
+	out := Sheet{}
+	var err error
+	for x := 1; x < n.NumChildren(); x++ {
+		c := n.Child(x)
+		switch c.Name() {
+		case "number":
+			if out.Number, err = c.Child(1).Int(); err != nil {
+				return Sheet{}, err
+			}
+		case "name":
+			out.Name = optString(c)
+		case "tstamps":
+			out.Tstamps = optString(c)
+		case "title_block":
+			for y := 1; y < c.NumChildren(); y++ {
+				c2 := c.Child(y)
+				switch c2.Name() {
+				case "title":
+					out.TitleBlock.Title = optString(c2)
+				case "company":
+					out.TitleBlock.Company = optString(c2)
+				case "rev":
+					out.TitleBlock.Rev = optString(c2)
+				case "date":
+					out.TitleBlock.Date = optString(c2)
+				case "source":
+					out.TitleBlock.Source = optString(c2)
+				case "comment":
+					var cm Comment
+					for z := 1; z < c2.NumChildren(); z++ {
+						switch c3 := c2.Child(z); c3.Name() {
+						case "number":
+							if cm.Number, err = c3.Child(1).Int(); err != nil {
+								return Sheet{}, err
+							}
+						case "value":
+							cm.Value = optString(c3)
+						}
+					}
+					out.TitleBlock.Comments = append(out.TitleBlock.Comments, cm)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseComponent(c sreader.Node) (Component, error) {
 	ident, err := c.Child(0).String()
 	if err != nil {
 		return Component{}, err
 	}
 	if ident != "comp" {
-		return Component{}, errors.New("invalid format: component must be 'comp'")
+		return Component{}, c.Errorf("invalid format: component must be 'comp'")
 	}
 
 	out := Component{}
-	for x := 1; x < c.MustNode().NumChildren(); x++ {
+	for x := 1; x < c.NumChildren(); x++ {
 		c2 := c.Child(x)
 		switch c2.Child(0).MustString() {
-		case "code":
+		case "ref":
 			out.Ref, err = c2.Child(1).String()
 			if err != nil {
-				return Component{}, fmt.Errorf("ref value: %v", err)
+				return Component{}, err
 			}
 		case "value":
 			out.Value, err = c2.Child(1).String()
 			if err != nil {
-				return Component{}, fmt.Errorf("value value: %v", err)
+				return Component{}, err
 			}
 		case "footprint":
 			out.Footprint, err = c2.Child(1).String()
 			if err != nil {
-				return Component{}, fmt.Errorf("footprint value: %v", err)
+				return Component{}, err
+			}
+		case "datasheet":
+			out.Datasheet = optString(c2)
+		case "fields":
+			out.Fields = parseFields(c2)
+		case "libsource":
+			for y := 1; y < c2.NumChildren(); y++ {
+				switch c3 := c2.Child(y); c3.Name() {
+				case "lib":
+					out.LibSource.Lib = optString(c3)
+				case "part":
+					out.LibSource.Part = optString(c3)
+				case "description":
+					out.LibSource.Description = optString(c3)
+				}
+			}
+		case "sheetpath":
+			for y := 1; y < c2.NumChildren(); y++ {
+				switch c3 := c2.Child(y); c3.Name() {
+				case "names":
+					out.SheetPath.Names = optString(c3)
+				case "tstamps":
+					out.SheetPath.Tstamps = optString(c3)
+				}
 			}
 		case "tstamp":
 			out.TStamp, err = c2.Child(1).String()
 			if err != nil {
-				return Component{}, fmt.Errorf("tstamp value: %v", err)
+				return Component{}, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// parseFields decodes a list of (field (name N) value) elements.
+func parseFields(n sreader.Node) []Field {
+	var out []Field
+	for x := 1; x < n.NumChildren(); x++ {
+		c := n.Child(x)
+		if c.Name() != "field" {
+			continue
+		}
+		var f Field
+		for y := 1; y < c.NumChildren(); y++ {
+			c2 := c.Child(y)
+			if c2.IsScalar() {
+				f.Value = c2.MustString()
+			} else if c2.Name() == "name" {
+				f.Name = optString(c2)
+			}
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func parseLibPart(n sreader.Node) (LibPart, error) {
+	if n.Name() != "libpart" {
+		return LibPart{}, n.Errorf("invalid format: library part must be 'libpart'")
+	}
+
+	out := LibPart{}
+	for x := 1; x < n.NumChildren(); x++ {
+		c := n.Child(x)
+		switch c.Name() {
+		case "lib":
+			out.Lib = optString(c)
+		case "part":
+			out.Part = optString(c)
+		case "description":
+			out.Description = optString(c)
+		case "docs":
+			out.Docs = optString(c)
+		case "aliases":
+			for y := 1; y < c.NumChildren(); y++ {
+				out.Aliases = append(out.Aliases, optString(c.Child(y)))
+			}
+		case "footprints":
+			for y := 1; y < c.NumChildren(); y++ {
+				out.Footprints = append(out.Footprints, optString(c.Child(y)))
+			}
+		case "fields":
+			out.Fields = parseFields(c)
+		case "pins":
+			for y := 1; y < c.NumChildren(); y++ {
+				c2 := c.Child(y)
+				if c2.Name() != "pin" {
+					return LibPart{}, c2.Errorf("invalid format: pin must be 'pin'")
+				}
+				var p Pin
+				for z := 1; z < c2.NumChildren(); z++ {
+					switch c3 := c2.Child(z); c3.Name() {
+					case "num":
+						p.Num = optString(c3)
+					case "name":
+						p.Name = optString(c3)
+					case "type":
+						p.Type = optString(c3)
+					}
+				}
+				out.Pins = append(out.Pins, p)
 			}
 		}
 	}
 	return out, nil
 }
 
-func parseNet(c sexp.Helper) (Net, error) {
+func parseNet(c sreader.Node) (Net, error) {
 	ident, err := c.Child(0).String()
 	if err != nil {
 		return Net{}, err
 	}
 	if ident != "net" {
-		return Net{}, errors.New("invalid format: net must be 'net'")
+		return Net{}, c.Errorf("invalid format: net must be 'net'")
 	}
 
 	out := Net{}
-	for x := 1; x < c.MustNode().NumChildren(); x++ {
+	for x := 1; x < c.NumChildren(); x++ {
 		c2 := c.Child(x)
 		switch c2.Child(0).MustString() {
 		case "code":
 			out.Code, err = c2.Child(1).Int()
 			if err != nil {
-				return Net{}, fmt.Errorf("code value: %v", err)
+				return Net{}, err
 			}
 		case "name":
 			out.Name, err = c2.Child(1).String()
 			if err != nil {
-				return Net{}, fmt.Errorf("name value: %v", err)
+				return Net{}, err
+			}
+		case "node":
+			node, err := parseNode(c2)
+			if err != nil {
+				return Net{}, err
+			}
+			out.Nodes = append(out.Nodes, node)
+		}
+	}
+	return out, nil
+}
+
+func parseNode(c sreader.Node) (Node, error) {
+	out := Node{}
+	var err error
+	for x := 1; x < c.NumChildren(); x++ {
+		c2 := c.Child(x)
+		switch c2.Child(0).MustString() {
+		case "ref":
+			out.Ref, err = c2.Child(1).String()
+			if err != nil {
+				return Node{}, err
+			}
+		case "pin":
+			out.Pin, err = c2.Child(1).String()
+			if err != nil {
+				return Node{}, err
 			}
+		case "pinfunction":
+			out.PinFunction = optString(c2)
 		}
 	}
 	return out, nil
@@ -0,0 +1,90 @@
+package netlist
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeFile(t *testing.T) {
+	nl, err := DecodeFile("testdata/sensor.net")
+	if err != nil {
+		t.Fatalf("DecodeFile() failed: %v", err)
+	}
+
+	if got, want := nl.Design.Tool, "Eeschema 5.1.4"; got != want {
+		t.Errorf("Design.Tool = %q, want %q", got, want)
+	}
+	if got, want := nl.Design.Sheets[0].TitleBlock.Comments[1], (Comment{Number: 2, Value: "Checked by: nobody"}); got != want {
+		t.Errorf("comment 2 = %+v, want %+v", got, want)
+	}
+	if got, want := len(nl.Components), 3; got != want {
+		t.Fatalf("got %d components, want %d", got, want)
+	}
+
+	r1 := nl.Component("R1")
+	if r1 == nil {
+		t.Fatal("Component(R1) = nil")
+	}
+	want := Component{
+		Ref:       "R1",
+		Value:     "10k",
+		Footprint: "Resistor_SMD:R_0805_2012Metric",
+		Datasheet: "~",
+		Fields:    []Field{{Name: "MPN", Value: "RC0805FR-0710KL"}, {Name: "Supplier", Value: "Digikey"}},
+		LibSource: LibSource{Lib: "Device", Part: "R", Description: "Resistor"},
+		SheetPath: SheetPath{Names: "/", Tstamps: "/"},
+		TStamp:    "5DB3C0A1",
+	}
+	if !reflect.DeepEqual(*r1, want) {
+		t.Errorf("Component(R1) = %+v, want %+v", *r1, want)
+	}
+	if got, want := r1.Field("MPN"), "RC0805FR-0710KL"; got != want {
+		t.Errorf("Field(MPN) = %q, want %q", got, want)
+	}
+	if got, want := nl.Component("C1").Path(), "/5DB3C1E2/5DB3C2F0"; got != want {
+		t.Errorf("C1 Path() = %q, want %q", got, want)
+	}
+
+	part := nl.LibPart(nl.Component("U1"))
+	if part == nil {
+		t.Fatal("LibPart(U1) = nil")
+	}
+	if got, want := part.Pins[2], (Pin{Num: "3", Name: "SDI", Type: "BiDi"}); got != want {
+		t.Errorf("BME280 pin 3 = %+v, want %+v", got, want)
+	}
+	if got, want := nl.LibPart(r1).Aliases, []string{"R_US"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("R aliases = %v, want %v", got, want)
+	}
+	if got, want := nl.Libraries[1], (Library{Logical: "Sensor", URI: "/usr/share/kicad/library/Sensor.lib"}); got != want {
+		t.Errorf("library 1 = %+v, want %+v", got, want)
+	}
+
+	if got := nl.PinNet("U1", "4"); got == nil || got.Name != "Net-(U1-Pad4)" {
+		t.Errorf("PinNet(U1, 4) = %+v, want Net-(U1-Pad4)", got)
+	}
+	if got, want := nl.Nets[0].Nodes[0], (Node{Ref: "U1", Pin: "7", PinFunction: "GND"}); got != want {
+		t.Errorf("GND node 0 = %+v, want %+v", got, want)
+	}
+	if got := nl.PinNet("R1", "3"); got != nil {
+		t.Errorf("PinNet(R1, 3) = %+v, want nil", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	nl, err := DecodeFile("testdata/sensor.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := nl.Write(&b); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	got, err := Decode(b.Bytes())
+	if err != nil {
+		t.Fatalf("Decode() of written netlist failed: %v\n%s", err, b.String())
+	}
+	if !reflect.DeepEqual(got, nl) {
+		t.Errorf("written netlist decoded to %+v, want %+v", got, nl)
+	}
+}
@@ -0,0 +1,340 @@
+package netlist
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/twitchyliquid64/kcgen/swriter"
+)
+
+// Write serializes the netlist in the format produced by eeschema.
+func (nl *Netlist) Write(w io.Writer) error {
+	sw, err := swriter.NewSExpWriter(w)
+	if err != nil {
+		return err
+	}
+	sw.StartList(false)
+	sw.StringScalar("export")
+	version := nl.Version
+	if version == "" {
+		version = "D"
+	}
+	if err := writePair(sw, false, "version", version); err != nil {
+		return err
+	}
+
+	if err := nl.Design.write(sw); err != nil {
+		return err
+	}
+
+	sw.StartList(true)
+	sw.StringScalar("components")
+	for i := range nl.Components {
+		if err := nl.Components[i].write(sw); err != nil {
+			return err
+		}
+	}
+	if err := sw.CloseList(false); err != nil {
+		return err
+	}
+
+	sw.StartList(true)
+	sw.StringScalar("libparts")
+	for i := range nl.LibParts {
+		if err := nl.LibParts[i].write(sw); err != nil {
+			return err
+		}
+	}
+	if err := sw.CloseList(false); err != nil {
+		return err
+	}
+
+	sw.StartList(true)
+	sw.StringScalar("libraries")
+	for _, l := range nl.Libraries {
+		sw.StartList(true)
+		sw.StringScalar("library")
+		if err := writePair(sw, false, "logical", l.Logical); err != nil {
+			return err
+		}
+		if err := writePair(sw, true, "uri", l.URI); err != nil {
+			return err
+		}
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
+	if err := sw.CloseList(false); err != nil {
+		return err
+	}
+
+	sw.StartList(true)
+	sw.StringScalar("nets")
+	for i := range nl.Nets {
+		if err := nl.Nets[i].write(sw); err != nil {
+			return err
+		}
+	}
+	if err := sw.CloseList(false); err != nil {
+		return err
+	}
+
+	if err := sw.CloseList(false); err != nil {
+		return err
+	}
+	w.Write([]byte("\n"))
+	return nil
+}
+
+// writePair writes a (name value) element.
+func writePair(sw *swriter.SExpWriter, newBlock bool, name, value string) error {
+	sw.StartList(newBlock)
+	sw.StringScalar(name)
+	sw.StringScalar(value)
+	return sw.CloseList(false)
+}
+
+func (d *Design) write(sw *swriter.SExpWriter) error {
+	sw.StartList(true)
+	sw.StringScalar("design")
+	if err := writePair(sw, true, "source", d.Source); err != nil {
+		return err
+	}
+	if err := writePair(sw, true, "date", d.Date); err != nil {
+		return err
+	}
+	if err := writePair(sw, true, "tool", d.Tool); err != nil {
+		return err
+	}
+	for _, s := range d.Sheets {
+		sw.StartList(true)
+		sw.StringScalar("sheet")
+		if err := writePair(sw, false, "number", strconv.Itoa(s.Number)); err != nil {
+			return err
+		}
+		if err := writePair(sw, false, "name", s.Name); err != nil {
+			return err
+		}
+		if err := writePair(sw, false, "tstamps", s.Tstamps); err != nil {
+			return err
+		}
+		if err := s.TitleBlock.write(sw); err != nil {
+			return err
+		}
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
+	return sw.CloseList(false)
+}
+
+func (t *TitleBlock) write(sw *swriter.SExpWriter) error {
+	sw.StartList(true)
+	sw.StringScalar("title_block")
+	for _, f := range [][2]string{
+		{"title", t.Title},
+		{"company", t.Company},
+		{"rev", t.Rev},
+		{"date", t.Date},
+		{"source", t.Source},
+	} {
+		if err := writePair(sw, true, f[0], f[1]); err != nil {
+			return err
+		}
+	}
+	for _, c := range t.Comments {
+		sw.StartList(true)
+		sw.StringScalar("comment")
+		if err := writePair(sw, false, "number", strconv.Itoa(c.Number)); err != nil {
+			return err
+		}
+		if err := writePair(sw, false, "value", c.Value); err != nil {
+			return err
+		}
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
+	return sw.CloseList(false)
+}
+
+func (c *Component) write(sw *swriter.SExpWriter) error {
+	sw.StartList(true)
+	sw.StringScalar("comp")
+	if err := writePair(sw, false, "ref", c.Ref); err != nil {
+		return err
+	}
+	if err := writePair(sw, true, "value", c.Value); err != nil {
+		return err
+	}
+	if c.Footprint != "" {
+		if err := writePair(sw, true, "footprint", c.Footprint); err != nil {
+			return err
+		}
+	}
+	if c.Datasheet != "" {
+		if err := writePair(sw, true, "datasheet", c.Datasheet); err != nil {
+			return err
+		}
+	}
+	if len(c.Fields) > 0 {
+		if err := writeFields(sw, c.Fields); err != nil {
+			return err
+		}
+	}
+
+	sw.StartList(true)
+	sw.StringScalar("libsource")
+	if err := writePair(sw, false, "lib", c.LibSource.Lib); err != nil {
+		return err
+	}
+	if err := writePair(sw, false, "part", c.LibSource.Part); err != nil {
+		return err
+	}
+	if err := writePair(sw, false, "description", c.LibSource.Description); err != nil {
+		return err
+	}
+	if err := sw.CloseList(false); err != nil {
+		return err
+	}
+
+	sw.StartList(true)
+	sw.StringScalar("sheetpath")
+	if err := writePair(sw, false, "names", c.SheetPath.Names); err != nil {
+		return err
+	}
+	if err := writePair(sw, false, "tstamps", c.SheetPath.Tstamps); err != nil {
+		return err
+	}
+	if err := sw.CloseList(false); err != nil {
+		return err
+	}
+
+	if err := writePair(sw, true, "tstamp", c.TStamp); err != nil {
+		return err
+	}
+	return sw.CloseList(false)
+}
+
+func writeFields(sw *swriter.SExpWriter, fields []Field) error {
+	sw.StartList(true)
+	sw.StringScalar("fields")
+	for _, f := range fields {
+		sw.StartList(true)
+		sw.StringScalar("field")
+		if err := writePair(sw, false, "name", f.Name); err != nil {
+			return err
+		}
+		if f.Value != "" {
+			sw.StringScalar(f.Value)
+		}
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
+	return sw.CloseList(false)
+}
+
+func (p *LibPart) write(sw *swriter.SExpWriter) error {
+	sw.StartList(true)
+	sw.StringScalar("libpart")
+	if err := writePair(sw, false, "lib", p.Lib); err != nil {
+		return err
+	}
+	if err := writePair(sw, false, "part", p.Part); err != nil {
+		return err
+	}
+	if len(p.Aliases) > 0 {
+		sw.StartList(true)
+		sw.StringScalar("aliases")
+		for _, a := range p.Aliases {
+			if err := writePair(sw, true, "alias", a); err != nil {
+				return err
+			}
+		}
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
+	if p.Description != "" {
+		if err := writePair(sw, true, "description", p.Description); err != nil {
+			return err
+		}
+	}
+	if p.Docs != "" {
+		if err := writePair(sw, true, "docs", p.Docs); err != nil {
+			return err
+		}
+	}
+	if len(p.Footprints) > 0 {
+		sw.StartList(true)
+		sw.StringScalar("footprints")
+		for _, fp := range p.Footprints {
+			if err := writePair(sw, true, "fp", fp); err != nil {
+				return err
+			}
+		}
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
+	if len(p.Fields) > 0 {
+		if err := writeFields(sw, p.Fields); err != nil {
+			return err
+		}
+	}
+	if len(p.Pins) > 0 {
+		sw.StartList(true)
+		sw.StringScalar("pins")
+		for _, pin := range p.Pins {
+			sw.StartList(true)
+			sw.StringScalar("pin")
+			if err := writePair(sw, false, "num", pin.Num); err != nil {
+				return err
+			}
+			if err := writePair(sw, false, "name", pin.Name); err != nil {
+				return err
+			}
+			if err := writePair(sw, false, "type", pin.Type); err != nil {
+				return err
+			}
+			if err := sw.CloseList(false); err != nil {
+				return err
+			}
+		}
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
+	return sw.CloseList(false)
+}
+
+func (n *Net) write(sw *swriter.SExpWriter) error {
+	sw.StartList(true)
+	sw.StringScalar("net")
+	if err := writePair(sw, false, "code", strconv.Itoa(n.Code)); err != nil {
+		return err
+	}
+	if err := writePair(sw, false, "name", n.Name); err != nil {
+		return err
+	}
+	for _, node := range n.Nodes {
+		sw.StartList(true)
+		sw.StringScalar("node")
+		if err := writePair(sw, false, "ref", node.Ref); err != nil {
+			return err
+		}
+		if err := writePair(sw, false, "pin", node.Pin); err != nil {
+			return err
+		}
+		if node.PinFunction != "" {
+			if err := writePair(sw, false, "pinfunction", node.PinFunction); err != nil {
+				return err
+			}
+		}
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
+	return sw.CloseList(false)
+}
@@ -0,0 +1,286 @@
+package pcb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/netlist"
+)
+
+// FootprintLoader loads a footprint given the library nickname and name
+// of a footprint identifier, such as Resistor_SMD:R_0805_2012Metric.
+type FootprintLoader func(lib, name string) (*Module, error)
+
+// DirFootprintLoader returns a FootprintLoader which loads footprints from
+// <dir>/<lib>.pretty/<name>.kicad_mod, trying each directory in turn.
+func DirFootprintLoader(dirs ...string) FootprintLoader {
+	return func(lib, name string) (*Module, error) {
+		for _, dir := range dirs {
+			path := filepath.Join(dir, lib+".pretty", name+".kicad_mod")
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			return DecodeModuleFile(path)
+		}
+		return nil, &footprintNotFoundError{lib: lib, name: name}
+	}
+}
+
+// AnnotateOptions configures Annotate.
+type AnnotateOptions struct {
+	// Footprints loads the footprints of components which are not yet on
+	// the board.
+	Footprints FootprintLoader
+	// ReplaceFootprints replaces modules whose footprint differs from the
+	// netlist, keeping their placement. Otherwise the difference is
+	// reported as a warning.
+	ReplaceFootprints bool
+	// DeleteExtra removes modules which are not in the netlist, unless they
+	// are locked. Otherwise they are reported in Extra.
+	DeleteExtra bool
+	// Origin is where new modules are placed.
+	Origin XY
+}
+
+// AnnotateResult describes the changes made by Annotate. Modules are
+// identified by their reference.
+type AnnotateResult struct {
+	Added    []string `json:"added,omitempty"`
+	Replaced []string `json:"replaced,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	// Extra lists modules which are not in the netlist but were kept.
+	Extra []string `json:"extra,omitempty"`
+
+	NetsAdded   []string `json:"nets_added,omitempty"`
+	NetsRemoved []string `json:"nets_removed,omitempty"`
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Annotate updates the board to match a netlist, as pcbnew's "Update PCB
+// from netlist" does. Modules are matched to components by reference:
+// missing modules are loaded and added, reference and value texts and
+// schematic paths are updated, and pads are assigned to the nets they are connected to in
+// the netlist. Nets which are no longer used are removed, and the
+// remaining nets are renumbered.
+//
+// The board is not modified if an error is returned.
+func Annotate(p *PCB, nl *netlist.Netlist, opts AnnotateOptions) (*AnnotateResult, error) {
+	a := annotator{p: p, opts: opts, res: &AnnotateResult{}}
+	comps := map[string]*netlist.Component{}
+	for i := range nl.Components {
+		c := &nl.Components[i]
+		if c.Ref == "" {
+			return nil, fmt.Errorf("netlist contains a component with no reference")
+		}
+		if _, ok := comps[c.Ref]; ok {
+			return nil, fmt.Errorf("netlist contains %s more than once", c.Ref)
+		}
+		comps[c.Ref] = c
+	}
+
+	// Load footprints before touching the board, so a missing footprint
+	// leaves it unchanged.
+	mods := map[string]int{}
+	for i := range p.Modules {
+		if ref := p.Modules[i].Reference(); ref != "" {
+			if _, ok := mods[ref]; !ok {
+				mods[ref] = i
+			}
+		}
+	}
+	loaded := map[string]*Module{}
+	for _, c := range nl.Components {
+		i, onBoard := mods[c.Ref]
+		if onBoard && (c.Footprint == "" || c.Footprint == p.Modules[i].Name || !opts.ReplaceFootprints) {
+			continue
+		}
+		m, err := a.load(c)
+		if err != nil {
+			return nil, err
+		}
+		loaded[c.Ref] = m
+	}
+
+	for _, c := range nl.Components {
+		i, onBoard := mods[c.Ref]
+		switch {
+		case !onBoard:
+			m := loaded[c.Ref]
+			m.Placement = ModPlacement{At: XYZ{X: opts.Origin.X, Y: opts.Origin.Y}}
+			p.Modules = append(p.Modules, *m)
+			mods[c.Ref] = len(p.Modules) - 1
+			a.res.Added = append(a.res.Added, c.Ref)
+		case loaded[c.Ref] != nil:
+			old, m := &p.Modules[i], loaded[c.Ref]
+			if old.OnBack() {
+				m.Flip()
+			}
+			// Orientations in the library are relative to the module, but
+			// are stored on the board with its rotation added.
+			m.Rotate(old.Placement.At.Z - m.Placement.At.Z)
+			m.Placement, m.Placed, m.Locked, m.Path = old.Placement, old.Placed, old.Locked, old.Path
+			*old = *m
+			a.res.Replaced = append(a.res.Replaced, c.Ref)
+		case c.Footprint != "" && c.Footprint != p.Modules[i].Name:
+			a.warnf("%s: footprint %s differs from %s in the netlist", c.Ref, p.Modules[i].Name, c.Footprint)
+		}
+		m := &p.Modules[mods[c.Ref]]
+		if c.TStamp != "" {
+			m.Path = c.Path()
+		}
+		setModText(m, RefText, c.Ref)
+		setModText(m, ValueText, c.Value)
+	}
+
+	a.removeExtra(comps)
+	a.assignNets(nl)
+	return a.res, nil
+}
+
+type annotator struct {
+	p    *PCB
+	opts AnnotateOptions
+	res  *AnnotateResult
+}
+
+func (a *annotator) warnf(format string, args ...interface{}) {
+	a.res.Warnings = append(a.res.Warnings, fmt.Sprintf(format, args...))
+}
+
+// load loads the footprint of a component.
+func (a *annotator) load(c netlist.Component) (*Module, error) {
+	if c.Footprint == "" {
+		return nil, fmt.Errorf("%s has no footprint assigned", c.Ref)
+	}
+	parts := strings.SplitN(c.Footprint, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%s: footprint %q has no library nickname", c.Ref, c.Footprint)
+	}
+	if a.opts.Footprints == nil {
+		return nil, fmt.Errorf("%s: cannot load footprint %s: no footprint libraries configured", c.Ref, c.Footprint)
+	}
+	m, err := a.opts.Footprints(parts[0], parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", c.Ref, err)
+	}
+	m.Name = c.Footprint
+	if m.Layer == "" {
+		m.Layer = "F.Cu"
+	}
+	return m, nil
+}
+
+// removeExtra removes or reports modules which are not in the netlist.
+// Modules without a schematic reference, such as logos, are left alone.
+func (a *annotator) removeExtra(comps map[string]*netlist.Component) {
+	kept := a.p.Modules[:0]
+	for _, m := range a.p.Modules {
+		ref := m.Reference()
+		if _, ok := comps[ref]; ok || ref == "" || strings.HasSuffix(ref, "**") {
+			kept = append(kept, m)
+			continue
+		}
+		if a.opts.DeleteExtra && !m.Locked {
+			a.res.Removed = append(a.res.Removed, ref)
+			continue
+		}
+		a.res.Extra = append(a.res.Extra, ref)
+		kept = append(kept, m)
+	}
+	a.p.Modules = kept
+}
+
+// assignNets connects pads to the nets given in the netlist, and removes
+// nets which are no longer used.
+func (a *annotator) assignNets(nl *netlist.Netlist) {
+	pins := map[string]map[string]string{}
+	netNames := map[string]bool{}
+	for _, n := range nl.Nets {
+		netNames[n.Name] = true
+		for _, node := range n.Nodes {
+			if pins[node.Ref] == nil {
+				pins[node.Ref] = map[string]string{}
+			}
+			pins[node.Ref][node.Pin] = n.Name
+		}
+	}
+
+	for i := range a.p.Modules {
+		m := &a.p.Modules[i]
+		modPins, ok := pins[m.Reference()]
+		if !ok {
+			continue
+		}
+		found := map[string]bool{}
+		for j := range m.Pads {
+			pad := &m.Pads[j]
+			name := modPins[pad.Ident]
+			if pad.Ident == "" || name == "" {
+				pad.NetNum, pad.NetName = 0, ""
+				continue
+			}
+			found[pad.Ident] = true
+			if _, ok := a.p.NetByName(name); !ok {
+				a.res.NetsAdded = append(a.res.NetsAdded, name)
+			}
+			pad.NetNum, pad.NetName = a.p.AddNet(name), name
+		}
+		for _, n := range nl.Nets {
+			for _, node := range n.Nodes {
+				if node.Ref == m.Reference() && !found[node.Pin] {
+					a.warnf("%s: pad %s on net %s is not in footprint %s", node.Ref, node.Pin, n.Name, m.Name)
+				}
+			}
+		}
+	}
+
+	used := map[int]bool{}
+	for _, s := range a.p.Segments {
+		switch s := s.(type) {
+		case *Track:
+			used[s.NetIndex] = true
+		case *Via:
+			used[s.NetIndex] = true
+		}
+	}
+	for _, z := range a.p.Zones {
+		used[z.NetNum] = true
+	}
+	for _, m := range a.p.Modules {
+		for _, pad := range m.Pads {
+			used[pad.NetNum] = true
+		}
+	}
+	nums := make([]int, 0, len(a.p.Nets))
+	for num := range a.p.Nets {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		n := a.p.Nets[num]
+		if num == 0 || netNames[n.Name] {
+			continue
+		}
+		if used[num] {
+			a.warnf("net %s is not in the netlist, but is still used", n.Name)
+			continue
+		}
+		a.p.DeleteNet(num, 0)
+		a.res.NetsRemoved = append(a.res.NetsRemoved, n.Name)
+	}
+	a.p.CompactNets()
+}
+
+// setModText sets the text of the module's reference or value.
+func setModText(m *Module, kind ModTextKind, text string) {
+	for _, g := range m.Graphics {
+		if t, ok := g.Renderable.(*ModText); ok && t.Kind == kind {
+			t.Text = text
+			return
+		}
+	}
+}
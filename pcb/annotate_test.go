@@ -0,0 +1,205 @@
+package pcb
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/netlist"
+)
+
+const annotateTestNetlist = `(export (version D)
+  (design (source test.sch))
+  (components
+    (comp (ref R1) (value 10k) (footprint Resistor_SMD:R_0805) (tstamp 5DB1A001))
+    (comp (ref R2) (value 4k7) (footprint Resistor_SMD:R_0805) (tstamp 5DB1A002))
+    (comp (ref C1) (value 100n) (footprint Capacitor_SMD:C_0603) (tstamp 5DB1A003)))
+  (libparts)
+  (libraries)
+  (nets
+    (net (code 1) (name GND)
+      (node (ref R2) (pin 2))
+      (node (ref C1) (pin 2)))
+    (net (code 2) (name /SDA)
+      (node (ref R1) (pin 2))
+      (node (ref R2) (pin 1))
+      (node (ref C1) (pin 3)))
+    (net (code 3) (name VCC)
+      (node (ref R1) (pin 1))
+      (node (ref C1) (pin 1)))))
+`
+
+func annotateTestFootprint(name string) *Module {
+	return &Module{
+		Name:  name,
+		Layer: "F.Cu",
+		Graphics: []ModGraphic{
+			{Ident: "fp_text", Renderable: &ModText{Kind: RefText, Text: "REF**", Layer: "F.SilkS"}},
+			{Ident: "fp_text", Renderable: &ModText{Kind: ValueText, Text: name, Layer: "F.Fab"}},
+		},
+		Pads: []Pad{
+			{Ident: "1", At: XYZ{X: -1}, Size: XY{X: 1, Y: 1}, Layers: []string{"F.Cu"}},
+			{Ident: "2", At: XYZ{X: 1}, Size: XY{X: 1, Y: 1}, Layers: []string{"F.Cu"}},
+		},
+	}
+}
+
+func annotateTestLoader(lib, name string) (*Module, error) {
+	if lib != "Resistor_SMD" && lib != "Capacitor_SMD" {
+		return nil, fmt.Errorf("footprint %s:%s not found", lib, name)
+	}
+	return annotateTestFootprint(name), nil
+}
+
+func TestAnnotate(t *testing.T) {
+	nl, err := netlist.Decode([]byte(annotateTestNetlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := EmptyPCB()
+	p.Nets[1] = Net{Name: "OLD"}
+	p.Nets[2] = Net{Name: "VCC"}
+	r1 := annotateTestFootprint("Resistor_SMD:R_0805")
+	r1.Placement.At = XYZ{X: 20, Y: 30}
+	setModText(r1, RefText, "R1")
+	r1.Pads[0].NetNum, r1.Pads[0].NetName = 1, "OLD"
+	r9 := annotateTestFootprint("Resistor_SMD:R_0805")
+	setModText(r9, RefText, "R9")
+	logo := annotateTestFootprint("Logo")
+	p.Modules = []Module{*r1, *r9, *logo}
+
+	res, err := Annotate(p, nl, AnnotateOptions{Footprints: annotateTestLoader, DeleteExtra: true, Origin: XY{X: 100, Y: 100}})
+	if err != nil {
+		t.Fatalf("Annotate() failed: %v", err)
+	}
+	want := &AnnotateResult{
+		Added:       []string{"R2", "C1"},
+		Removed:     []string{"R9"},
+		NetsAdded:   []string{"/SDA", "GND"},
+		NetsRemoved: []string{"OLD"},
+		Warnings:    []string{"C1: pad 3 on net /SDA is not in footprint Capacitor_SMD:C_0603"},
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("Annotate() = %+v, want %+v", res, want)
+	}
+
+	var refs []string
+	for _, m := range p.Modules {
+		refs = append(refs, m.Reference())
+	}
+	if want := []string{"R1", "REF**", "R2", "C1"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("modules = %v, want %v", refs, want)
+	}
+	if got := p.Modules[0].Placement.At; got.X != 20 || got.Y != 30 {
+		t.Errorf("R1 moved to %+v", got)
+	}
+	if got := p.Modules[3].Placement.At; got.X != 100 || got.Y != 100 {
+		t.Errorf("C1 placed at %+v, want (100, 100)", got)
+	}
+	if got, want := p.Modules[0].Path, "/5DB1A001"; got != want {
+		t.Errorf("R1 path = %q, want %q", got, want)
+	}
+	for _, m := range p.Modules {
+		if m.Reference() == "C1" {
+			for _, g := range m.Graphics {
+				if txt := g.Renderable.(*ModText); txt.Kind == ValueText && txt.Text != "100n" {
+					t.Errorf("C1 value = %q, want 100n", txt.Text)
+				}
+			}
+		}
+	}
+
+	pads := map[string]string{}
+	for _, m := range p.Modules {
+		for _, pad := range m.Pads {
+			if pad.NetNum != 0 || pad.NetName != "" {
+				if got := p.Nets[pad.NetNum].Name; got != pad.NetName {
+					t.Errorf("%s pad %s is on net %d (%q), named %q", m.Reference(), pad.Ident, pad.NetNum, got, pad.NetName)
+				}
+			}
+			pads[m.Reference()+"."+pad.Ident] = pad.NetName
+		}
+	}
+	wantPads := map[string]string{
+		"R1.1": "VCC", "R1.2": "/SDA",
+		"R2.1": "/SDA", "R2.2": "GND",
+		"C1.1": "VCC", "C1.2": "GND",
+		"REF**.1": "", "REF**.2": "",
+	}
+	if !reflect.DeepEqual(pads, wantPads) {
+		t.Errorf("pad nets = %v, want %v", pads, wantPads)
+	}
+	if got := len(p.Nets); got != 4 {
+		t.Errorf("got %d nets, want 4", got)
+	}
+	if issues := Validate(p); len(issues) > 0 {
+		t.Errorf("Validate() = %v", issues)
+	}
+}
+
+func TestAnnotateReplace(t *testing.T) {
+	nl, err := netlist.Decode([]byte(strings.Replace(annotateTestNetlist, "(ref R2) (value 4k7) (footprint Resistor_SMD:R_0805)", "(ref R2) (value 4k7) (footprint Resistor_SMD:R_0603)", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		name  string
+		back  bool
+		angle float64
+	}{
+		{"front", false, 0},
+		{"rotated", false, 90},
+		{"back", true, 90},
+	}
+	var r2 *Module
+	for _, tc := range tcs {
+		r2 = annotateTestFootprint("Resistor_SMD:R_0805")
+		setModText(r2, RefText, "R2")
+		r2.Placement.At = XYZ{X: 5, Y: 5}
+		r2.Rotate(tc.angle)
+		if tc.back {
+			r2.Flip()
+		}
+
+		p := EmptyPCB()
+		p.Modules = []Module{*r2}
+		res, err := Annotate(p, nl, AnnotateOptions{Footprints: annotateTestLoader, ReplaceFootprints: true})
+		if err != nil {
+			t.Fatalf("%s: Annotate() failed: %v", tc.name, err)
+		}
+		if want := []string{"R2"}; !reflect.DeepEqual(res.Replaced, want) {
+			t.Errorf("%s: Replaced = %v, want %v", tc.name, res.Replaced, want)
+		}
+		got := p.Modules[0]
+		if got.Name != "Resistor_SMD:R_0603" || got.OnBack() != tc.back || got.Placement.At != r2.Placement.At {
+			t.Errorf("%s: R2 = %s on %s at %+v, want Resistor_SMD:R_0603 on %s at %+v", tc.name, got.Name, got.Layer, got.Placement.At, r2.Layer, r2.Placement.At)
+		}
+		// Pads and text keep their orientations on the board.
+		for i := range got.Pads {
+			if g, w := got.Pads[i].At.Z, r2.Pads[i].At.Z; g != w {
+				t.Errorf("%s: pad %s orientation = %v, want %v", tc.name, got.Pads[i].Ident, g, w)
+			}
+			if g, w := got.PadPosition(&got.Pads[i]), r2.PadPosition(&r2.Pads[i]); math.Abs(g.X-w.X) > 1e-9 || math.Abs(g.Y-w.Y) > 1e-9 {
+				t.Errorf("%s: pad %s at %+v, want %+v", tc.name, got.Pads[i].Ident, g, w)
+			}
+		}
+		for i := range got.Graphics {
+			if g, w := got.Graphics[i].Renderable.(*ModText).At.Z, r2.Graphics[i].Renderable.(*ModText).At.Z; g != w {
+				t.Errorf("%s: text %d orientation = %v, want %v", tc.name, i, g, w)
+			}
+		}
+	}
+
+	p := EmptyPCB()
+	p.Modules = []Module{*r2}
+	if _, err := Annotate(p, nl, AnnotateOptions{Footprints: DirFootprintLoader("testdata")}); err == nil {
+		t.Error("Annotate() with missing footprints did not fail")
+	}
+	if len(p.Modules) != 1 || len(p.Nets) != 1 {
+		t.Error("Annotate() modified the board after failing")
+	}
+}
@@ -1,11 +1,9 @@
 package pcb
 
 import (
-	"errors"
-	"fmt"
 	"math"
 
-	"github.com/nsf/sexp"
+	"github.com/twitchyliquid64/kcgen/sreader"
 )
 
 // XY represents a point in space.
@@ -48,7 +46,10 @@ type Via struct {
 	StatusFlags string `json:"status_flags"`
 	ViaType     ViaType
 
-	order int
+	// netName is set when the via was created referring to a net by
+	// name, until it is added to a board and the net is resolved.
+	netName string
+	order   int
 }
 
 type ZoneHatch struct {
@@ -114,12 +115,15 @@ type Track struct {
 
 	StatusFlags string `json:"status_flags"`
 
-	order int
+	// netName is set when the track was created referring to a net by
+	// name, until it is added to a board and the net is resolved.
+	netName string
+	order   int
 }
 
-func parseVia(n sexp.Helper, ordering int) (Via, error) {
+func parseVia(n sreader.Node, ordering int) (Via, error) {
 	v := Via{order: ordering}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		if c.IsList() {
 			switch c.Child(0).MustString() {
@@ -135,7 +139,7 @@ func parseVia(n sexp.Helper, ordering int) (Via, error) {
 			case "status":
 				v.StatusFlags = c.Child(1).MustString()
 			case "layers":
-				for j := 1; j < c.MustNode().NumChildren(); j++ {
+				for j := 1; j < c.NumChildren(); j++ {
 					v.Layers = append(v.Layers, c.Child(j).MustString())
 				}
 			}
@@ -147,16 +151,16 @@ func parseVia(n sexp.Helper, ordering int) (Via, error) {
 			case "micro":
 				v.ViaType = ViaMicro
 			default:
-				return v, errors.New("via invalid type " + t)
+				return v, c.Errorf("via invalid type %q", t)
 			}
 		}
 	}
 	return v, nil
 }
 
-func parseZone(n sexp.Helper, ordering int) (*Zone, error) {
+func parseZone(n sreader.Node, ordering int) (*Zone, error) {
 	z := Zone{order: ordering}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "net":
@@ -167,7 +171,7 @@ func parseZone(n sexp.Helper, ordering int) (*Zone, error) {
 			z.Layers = []string{c.Child(1).MustString()}
 		case "layers":
 			z.Layers = nil
-			for j := 1; j < c.MustNode().NumChildren(); j++ {
+			for j := 1; j < c.NumChildren(); j++ {
 				z.Layers = append(z.Layers, c.Child(j).MustString())
 			}
 		case "tstamp":
@@ -184,7 +188,7 @@ func parseZone(n sexp.Helper, ordering int) (*Zone, error) {
 			z.FilledAreaThickness = c.Child(1).MustString() == "yes"
 
 		case "connect_pads":
-			for y := 1; y < c.MustNode().NumChildren(); y++ {
+			for y := 1; y < c.NumChildren(); y++ {
 				c2 := c.Child(y)
 				if c2.IsList() {
 					switch c2.Child(0).MustString() {
@@ -196,14 +200,14 @@ func parseZone(n sexp.Helper, ordering int) (*Zone, error) {
 				}
 			}
 		case "fill":
-			for y := 1; y < c.MustNode().NumChildren(); y++ {
+			for y := 1; y < c.NumChildren(); y++ {
 				c2 := c.Child(y)
 				if c2.IsScalar() {
-					switch c2.MustNode().Value {
+					switch c2.Value() {
 					case "yes":
 						z.Fill.IsFilled = true
 					default:
-						return nil, fmt.Errorf("unhandled scalar in zone fill: %v", c2.MustNode().Value)
+						return nil, c2.Errorf("unhandled scalar in zone fill: %v", c2.Value())
 					}
 					continue
 				}
@@ -222,7 +226,7 @@ func parseZone(n sexp.Helper, ordering int) (*Zone, error) {
 
 		case "keepout":
 			z.IsKeepout = true
-			for y := 1; y < c.MustNode().NumChildren(); y++ {
+			for y := 1; y < c.NumChildren(); y++ {
 				c2 := c.Child(y)
 
 				switch c2.Child(0).MustString() {
@@ -237,11 +241,11 @@ func parseZone(n sexp.Helper, ordering int) (*Zone, error) {
 
 		case "polygon":
 			var points []XY
-			for y := 1; y < c.Child(1).MustNode().NumChildren(); y++ {
+			for y := 1; y < c.Child(1).NumChildren(); y++ {
 				pt := c.Child(1).Child(y)
 				ptType, err2 := pt.Child(0).String()
 				if err2 != nil || ptType != "xy" {
-					return nil, errors.New("zone.polygon point is not xy point")
+					return nil, pt.Errorf("zone.polygon point is not xy point")
 				}
 				points = append(points, XY{X: pt.Child(1).MustFloat64(), Y: pt.Child(2).MustFloat64()})
 			}
@@ -249,11 +253,11 @@ func parseZone(n sexp.Helper, ordering int) (*Zone, error) {
 
 		case "filled_polygon":
 			var points []XY
-			for y := 1; y < c.Child(1).MustNode().NumChildren(); y++ {
+			for y := 1; y < c.Child(1).NumChildren(); y++ {
 				pt := c.Child(1).Child(y)
 				ptType, err2 := pt.Child(0).String()
 				if err2 != nil || ptType != "xy" {
-					return nil, errors.New("zone.filled_polygon point is not xy point")
+					return nil, pt.Errorf("zone.filled_polygon point is not xy point")
 				}
 				points = append(points, XY{X: pt.Child(1).MustFloat64(), Y: pt.Child(2).MustFloat64()})
 			}
@@ -263,9 +267,9 @@ func parseZone(n sexp.Helper, ordering int) (*Zone, error) {
 	return &z, nil
 }
 
-func parseSegment(n sexp.Helper, ordering int) (Track, error) {
+func parseSegment(n sreader.Node, ordering int) (Track, error) {
 	t := Track{order: ordering}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "width":
@@ -120,8 +120,21 @@ func (z *Zone) write(sw *swriter.SExpWriter) error {
 	}
 	sw.Newlines(1)
 
+	if z.Priority > 0 {
+		sw.StartList(false)
+		sw.StringScalar("priority")
+		sw.IntScalar(z.Priority)
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+		sw.Newlines(1)
+	}
+
 	sw.StartList(false)
 	sw.StringScalar("connect_pads")
+	if z.ConnectPads.Mode != "" {
+		sw.StringScalar(z.ConnectPads.Mode)
+	}
 	sw.StartList(false)
 	sw.StringScalar("clearance")
 	sw.StringScalar(f(z.ConnectPads.Clearance))
@@ -175,6 +188,14 @@ func (z *Zone) write(sw *swriter.SExpWriter) error {
 	if z.Fill.IsFilled {
 		sw.StringScalar("yes")
 	}
+	if z.Fill.Mode != "" {
+		sw.StartList(false)
+		sw.StringScalar("mode")
+		sw.StringScalar(z.Fill.Mode)
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
 	sw.StartList(false)
 	sw.StringScalar("arc_segments")
 	sw.IntScalar(z.Fill.Segments)
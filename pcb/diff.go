@@ -0,0 +1,693 @@
+package pcb
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// diffTolerance is the largest difference between two dimensions, in
+// millimeters, which is treated as no difference.
+const diffTolerance = 1e-6
+
+// ChangeKind describes whether an element was added, removed or changed.
+type ChangeKind string
+
+// Valid ChangeKind values.
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change describes a semantic difference between two modules or boards,
+// found by DiffModules or DiffPCBs.
+type Change struct {
+	// Element identifies the changed element, such as pad[2] or
+	// module[R1]/graphics[F.SilkS]. Modules are identified by their
+	// reference, pads by their number, and graphics, tracks and drawings
+	// by their layer.
+	Element string     `json:"element"`
+	Kind    ChangeKind `json:"kind"`
+	Msg     string     `json:"msg"`
+}
+
+func (c Change) String() string {
+	return c.Element + ": " + c.Msg
+}
+
+// DiffModules reports the semantic differences between two footprints.
+// Differences in formatting, element order and timestamps are ignored.
+func DiffModules(a, b *Module) []Change {
+	d := differ{}
+	d.diffModule("", a, b, false)
+	return d.changes
+}
+
+// DiffPCBs reports the semantic differences between two boards. Modules
+// are matched by reference, and nets by name, so renumbering nets is not
+// reported. Differences in formatting, element order and timestamps are
+// ignored.
+func DiffPCBs(a, b *PCB) []Change {
+	d := differ{a: a, b: b}
+	d.diffBoard()
+	d.diffNets()
+	d.diffNetClasses()
+	d.diffModules()
+	d.diffSegments()
+	d.diffDrawings()
+	d.diffZones()
+	return d.changes
+}
+
+type differ struct {
+	// a and b are the boards being compared, if any.
+	a, b    *PCB
+	changes []Change
+}
+
+func (d *differ) addf(element string, kind ChangeKind, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{Element: element, Kind: kind, Msg: fmt.Sprintf(format, args...)})
+}
+
+// changedf reports a changed value, if it differs.
+func (d *differ) changedf(element, what string, a, b interface{}) {
+	if fa, ok := a.(float64); ok {
+		if fb := b.(float64); !floatEq(fa, fb) {
+			d.addf(element, Changed, "%s %s -> %s", what, fmtNum(fa), fmtNum(fb))
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		d.addf(element, Changed, "%s %v -> %v", what, a, b)
+	}
+}
+
+// setChangedf reports the members added to and removed from a set.
+func (d *differ) setChangedf(element, what string, a, b []string) {
+	added, removed := setDiff(a, b)
+	if len(added)+len(removed) == 0 {
+		return
+	}
+	var parts []string
+	for _, s := range added {
+		parts = append(parts, "+"+s)
+	}
+	for _, s := range removed {
+		parts = append(parts, "-"+s)
+	}
+	d.addf(element, Changed, "%s %s", what, strings.Join(parts, " "))
+}
+
+func (d *differ) diffModule(prefix string, a, b *Module, onBoard bool) {
+	elem := "module"
+	if prefix != "" {
+		elem = prefix
+		prefix += "/"
+	}
+
+	d.changedf(elem, "footprint", a.Name, b.Name)
+	d.changedf(elem, "layer", a.Layer, b.Layer)
+	d.changedf(elem, "description", strconv.Quote(a.Description), strconv.Quote(b.Description))
+	d.setChangedf(elem, "tags", a.Tags, b.Tags)
+	d.setChangedf(elem, "attributes", a.Attrs, b.Attrs)
+	d.changedf(elem, "zone connection", a.ZoneConnect, b.ZoneConnect)
+	d.changedf(elem, "solder mask margin", a.SolderMaskMargin, b.SolderMaskMargin)
+	d.changedf(elem, "solder paste margin", a.SolderPasteMargin, b.SolderPasteMargin)
+	d.changedf(elem, "solder paste ratio", a.SolderPasteRatio, b.SolderPasteRatio)
+	d.changedf(elem, "clearance", a.Clearance, b.Clearance)
+	// Orientations of pads and text include the module rotation, which
+	// is removed so rotating a module is reported once.
+	var rotA, rotB float64
+	if onBoard {
+		d.diffPosition(elem, a.Placement.At, b.Placement.At)
+		d.changedf(elem, "locked", a.Locked, b.Locked)
+		rotA, rotB = a.Placement.At.Z, b.Placement.At.Z
+	}
+
+	for _, kind := range []ModTextKind{RefText, ValueText} {
+		ta, tb := modText(a, kind), modText(b, kind)
+		if ta == nil || tb == nil {
+			if ta != tb {
+				d.addf(prefix+kind.String(), Changed, "present %v -> %v", ta != nil, tb != nil)
+			}
+			continue
+		}
+		d.diffModText(prefix+kind.String(), ta, tb, rotA, rotB)
+	}
+
+	d.diffPads(prefix, a, b, rotA, rotB, onBoard)
+	d.diffMultiset(prefix+"graphics", modGraphicKeys(a, rotA), modGraphicKeys(b, rotB))
+	d.diffModels(prefix, a.Models, b.Models)
+}
+
+func (d *differ) diffModText(elem string, a, b *ModText, rotA, rotB float64) {
+	d.changedf(elem, "text", strconv.Quote(a.Text), strconv.Quote(b.Text))
+	d.changedf(elem, "layer", a.Layer, b.Layer)
+	d.changedf(elem, "hidden", a.Hidden, b.Hidden)
+	d.diffPosition(elem, unrotate(a.At, rotA), unrotate(b.At, rotB))
+	d.changedf(elem, "size", fmtXY(a.Effects.FontSize), fmtXY(b.Effects.FontSize))
+	d.changedf(elem, "thickness", a.Effects.Thickness, b.Effects.Thickness)
+}
+
+// diffPosition reports a move or rotation.
+func (d *differ) diffPosition(elem string, a, b XYZ) {
+	if !floatEq(a.X, b.X) || !floatEq(a.Y, b.Y) {
+		d.addf(elem, Changed, "moved by %s to %s", fmtXY(XY{X: b.X - a.X, Y: b.Y - a.Y}), fmtXY(XY{X: b.X, Y: b.Y}))
+	}
+	d.changedf(elem, "rotation", normalizeAnglePos(a.Z), normalizeAnglePos(b.Z))
+}
+
+func (d *differ) diffPads(prefix string, a, b *Module, rotA, rotB float64, onBoard bool) {
+	byNum := func(m *Module) (map[string][]*Pad, []string) {
+		out := map[string][]*Pad{}
+		var order []string
+		for i := range m.Pads {
+			p := &m.Pads[i]
+			if _, ok := out[p.Ident]; !ok {
+				order = append(order, p.Ident)
+			}
+			out[p.Ident] = append(out[p.Ident], p)
+		}
+		return out, order
+	}
+	padsA, orderA := byNum(a)
+	padsB, orderB := byNum(b)
+	nums := append(orderA, orderB...)
+	sort.Slice(nums, func(i, j int) bool { return padLess(nums[i], nums[j]) })
+
+	for i, num := range nums {
+		if i > 0 && nums[i-1] == num {
+			continue
+		}
+		elem := fmt.Sprintf("%spad[%s]", prefix, num)
+		pa, pb := padsA[num], padsB[num]
+
+		// Pads sharing a number, such as thermal vias, are paired by
+		// proximity.
+		used := make([]bool, len(pb))
+		for _, p := range pa {
+			best := -1
+			for j, q := range pb {
+				if used[j] {
+					continue
+				}
+				if best < 0 || padDist(p, q) < padDist(p, pb[best]) {
+					best = j
+				}
+			}
+			if best < 0 {
+				d.addf(elem, Removed, "removed from %s", fmtXY(XY{X: p.At.X, Y: p.At.Y}))
+				continue
+			}
+			used[best] = true
+			d.diffPad(elem, p, pb[best], rotA, rotB, onBoard)
+		}
+		for j, q := range pb {
+			if !used[j] {
+				d.addf(elem, Added, "added at %s", fmtXY(XY{X: q.At.X, Y: q.At.Y}))
+			}
+		}
+	}
+}
+
+func (d *differ) diffPad(elem string, a, b *Pad, rotA, rotB float64, onBoard bool) {
+	d.diffPosition(elem, unrotate(a.At, rotA), unrotate(b.At, rotB))
+	d.changedf(elem, "type", a.Surface, b.Surface)
+	d.changedf(elem, "shape", a.Shape, b.Shape)
+	d.changedf(elem, "size", fmtXY(a.Size, "x"), fmtXY(b.Size, "x"))
+	d.changedf(elem, "drill", fmtDrill(a), fmtDrill(b))
+	d.changedf(elem, "drill offset", fmtXY(a.DrillOffset), fmtXY(b.DrillOffset))
+	d.setChangedf(elem, "layers", a.Layers, b.Layers)
+	d.changedf(elem, "trapezoid delta", fmtXY(a.RectDelta), fmtXY(b.RectDelta))
+	d.changedf(elem, "roundrect ratio", a.RoundRectRRatio, b.RoundRectRRatio)
+	d.changedf(elem, "chamfer ratio", a.ChamferRatio, b.ChamferRatio)
+	d.changedf(elem, "die length", a.DieLength, b.DieLength)
+	d.changedf(elem, "zone connection", a.ZoneConnect, b.ZoneConnect)
+	d.changedf(elem, "thermal width", a.ThermalWidth, b.ThermalWidth)
+	d.changedf(elem, "thermal gap", a.ThermalGap, b.ThermalGap)
+	d.changedf(elem, "solder mask margin", a.SolderMaskMargin, b.SolderMaskMargin)
+	d.changedf(elem, "solder paste margin", a.SolderPasteMargin, b.SolderPasteMargin)
+	d.changedf(elem, "solder paste ratio", a.SolderPasteMarginRatio, b.SolderPasteMarginRatio)
+	d.changedf(elem, "clearance", a.Clearance, b.Clearance)
+	if onBoard {
+		d.changedf(elem, "net", a.NetName, b.NetName)
+	}
+	d.diffMultiset(elem+"/primitives", graphicKeys(a.Primitives, 0), graphicKeys(b.Primitives, 0))
+}
+
+func (d *differ) diffModels(prefix string, a, b []ModModel) {
+	byPath := map[string]*ModModel{}
+	for i := range b {
+		byPath[b[i].Path] = &b[i]
+	}
+	for i := range a {
+		elem := fmt.Sprintf("%smodel[%s]", prefix, a[i].Path)
+		mb, ok := byPath[a[i].Path]
+		if !ok {
+			d.addf(elem, Removed, "removed")
+			continue
+		}
+		delete(byPath, a[i].Path)
+		d.changedf(elem, "offset", fmtXYZ(a[i].Offset), fmtXYZ(mb.Offset))
+		d.changedf(elem, "position", fmtXYZ(a[i].At), fmtXYZ(mb.At))
+		d.changedf(elem, "scale", fmtXYZ(a[i].Scale), fmtXYZ(mb.Scale))
+		d.changedf(elem, "rotation", fmtXYZ(a[i].Rotate), fmtXYZ(mb.Rotate))
+	}
+	for i := range b {
+		if _, ok := byPath[b[i].Path]; ok {
+			d.addf(fmt.Sprintf("%smodel[%s]", prefix, b[i].Path), Added, "added")
+		}
+	}
+}
+
+// layerKey identifies an element for multiset comparison. Elements are
+// grouped by layer when reported.
+type layerKey struct {
+	layer, desc string
+}
+
+// diffMultiset reports elements which are present a different number of
+// times in a and b, grouped by layer.
+func (d *differ) diffMultiset(elem string, a, b []layerKey) {
+	counts := map[layerKey]int{}
+	for _, k := range a {
+		counts[k]--
+	}
+	for _, k := range b {
+		counts[k]++
+	}
+	keys := make([]layerKey, 0, len(counts))
+	for k, n := range counts {
+		if n != 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].layer != keys[j].layer {
+			return keys[i].layer < keys[j].layer
+		}
+		return keys[i].desc < keys[j].desc
+	})
+
+	for _, k := range keys {
+		e := elem
+		if k.layer != "" {
+			e = fmt.Sprintf("%s[%s]", elem, k.layer)
+		}
+		for n := counts[k]; n < 0; n++ {
+			d.addf(e, Removed, "removed %s", k.desc)
+		}
+		for n := counts[k]; n > 0; n-- {
+			d.addf(e, Added, "added %s", k.desc)
+		}
+	}
+}
+
+// modGraphicKeys returns the keys of the graphics of a module, other than
+// its reference and value.
+func modGraphicKeys(m *Module, rot float64) []layerKey {
+	var out []layerKey
+	for _, g := range m.Graphics {
+		if t, ok := g.Renderable.(*ModText); ok && t.Kind != UserText {
+			continue
+		}
+		out = append(out, graphicKey(g.Renderable, rot))
+	}
+	return out
+}
+
+func graphicKeys(gs []ModGraphic, rot float64) []layerKey {
+	out := make([]layerKey, len(gs))
+	for i, g := range gs {
+		out[i] = graphicKey(g.Renderable, rot)
+	}
+	return out
+}
+
+func graphicKey(g modDrawable, rot float64) layerKey {
+	switch g := g.(type) {
+	case *ModText:
+		return layerKey{g.Layer, fmt.Sprintf("text %q at %s", g.Text, fmtXYZ(unrotate(g.At, rot)))}
+	case *ModLine:
+		return layerKey{g.Layer, fmt.Sprintf("line %s width %s", fmtSegment(g.Start, g.End), fmtNum(g.Width))}
+	case *ModCircle:
+		r := math.Hypot(g.End.X-g.Center.X, g.End.Y-g.Center.Y)
+		return layerKey{g.Layer, fmt.Sprintf("circle at %s radius %s width %s", fmtXY(g.Center), fmtNum(r), fmtNum(g.Width))}
+	case *ModArc:
+		return layerKey{g.Layer, fmt.Sprintf("arc %s-%s angle %s width %s", fmtXY(g.Start), fmtXY(g.End), fmtNum(g.Angle), fmtNum(g.Width))}
+	case *ModPolygon:
+		pts := make([]string, len(g.Points))
+		for i, p := range g.Points {
+			pts[i] = fmtXY(XY{X: p.X + g.At.X, Y: p.Y + g.At.Y})
+		}
+		return layerKey{g.Layer, fmt.Sprintf("polygon %s width %s", strings.Join(pts, " "), fmtNum(g.Width))}
+	}
+	return layerKey{"", fmt.Sprintf("%+v", g)}
+}
+
+func modText(m *Module, kind ModTextKind) *ModText {
+	for _, g := range m.Graphics {
+		if t, ok := g.Renderable.(*ModText); ok && t.Kind == kind {
+			return t
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffBoard() {
+	a, b := d.a, d.b
+	d.changedf("board", "thickness", a.Thickness, b.Thickness)
+	d.changedf("board", "page", fmt.Sprintf("%+v", a.Page), fmt.Sprintf("%+v", b.Page))
+
+	var layersA, layersB []string
+	for _, l := range a.Layers {
+		layersA = append(layersA, l.Name)
+	}
+	for _, l := range b.Layers {
+		layersB = append(layersB, l.Name)
+	}
+	d.setChangedf("board", "layers", layersA, layersB)
+
+	ta, tb := a.TitleInfo, b.TitleInfo
+	if ta == nil {
+		ta = &TitleInfo{}
+	}
+	if tb == nil {
+		tb = &TitleInfo{}
+	}
+	d.changedf("title", "title", strconv.Quote(ta.Title), strconv.Quote(tb.Title))
+	d.changedf("title", "date", strconv.Quote(ta.Date), strconv.Quote(tb.Date))
+	d.changedf("title", "revision", strconv.Quote(ta.Revision), strconv.Quote(tb.Revision))
+	d.changedf("title", "company", strconv.Quote(ta.Company), strconv.Quote(tb.Company))
+	for i := range ta.Comments {
+		d.changedf("title", fmt.Sprintf("comment %d", i+1), strconv.Quote(ta.Comments[i]), strconv.Quote(tb.Comments[i]))
+	}
+
+	d.diffSetup(&a.EditorSetup, &b.EditorSetup)
+}
+
+// diffSetup reports changed design rules and editor settings.
+func (d *differ) diffSetup(a, b *EditorSetup) {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < va.NumField(); i++ {
+		f := va.Type().Field(i)
+		if f.PkgPath != "" || f.Name == "PlotParams" || f.Name == "Unrecognised" {
+			continue
+		}
+		d.changedf("setup", f.Name, va.Field(i).Interface(), vb.Field(i).Interface())
+	}
+
+	names := map[string]bool{}
+	for n := range a.PlotParams {
+		names[n] = true
+	}
+	for n := range b.PlotParams {
+		names[n] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for n := range names {
+		sorted = append(sorted, n)
+	}
+	sort.Strings(sorted)
+	for _, n := range sorted {
+		d.changedf("setup/pcbplotparams", n, strings.Join(a.PlotParams[n].values, " "), strings.Join(b.PlotParams[n].values, " "))
+	}
+}
+
+func (d *differ) diffNets() {
+	var a, b []string
+	for _, n := range d.a.Nets {
+		if n.Name != "" {
+			a = append(a, n.Name)
+		}
+	}
+	for _, n := range d.b.Nets {
+		if n.Name != "" {
+			b = append(b, n.Name)
+		}
+	}
+	added, removed := setDiff(a, b)
+	for _, n := range removed {
+		d.addf(fmt.Sprintf("net[%s]", n), Removed, "removed")
+	}
+	for _, n := range added {
+		d.addf(fmt.Sprintf("net[%s]", n), Added, "added")
+	}
+}
+
+func (d *differ) diffNetClasses() {
+	byName := map[string]*NetClass{}
+	for i := range d.b.NetClasses {
+		byName[d.b.NetClasses[i].Name] = &d.b.NetClasses[i]
+	}
+	for i := range d.a.NetClasses {
+		a := &d.a.NetClasses[i]
+		elem := fmt.Sprintf("net_class[%s]", a.Name)
+		b, ok := byName[a.Name]
+		if !ok {
+			d.addf(elem, Removed, "removed")
+			continue
+		}
+		delete(byName, a.Name)
+		d.changedf(elem, "description", strconv.Quote(a.Description), strconv.Quote(b.Description))
+		d.changedf(elem, "clearance", a.Clearance, b.Clearance)
+		d.changedf(elem, "trace width", a.TraceWidth, b.TraceWidth)
+		d.changedf(elem, "via diameter", a.ViaDiameter, b.ViaDiameter)
+		d.changedf(elem, "via drill", a.ViaDrill, b.ViaDrill)
+		d.changedf(elem, "uvia diameter", a.UViaDiameter, b.UViaDiameter)
+		d.changedf(elem, "uvia drill", a.UViaDrill, b.UViaDrill)
+		d.changedf(elem, "diff pair width", a.DiffPairWidth, b.DiffPairWidth)
+		d.changedf(elem, "diff pair gap", a.DiffPairGap, b.DiffPairGap)
+		d.setChangedf(elem, "nets", a.Nets, b.Nets)
+	}
+	for i := range d.b.NetClasses {
+		if _, ok := byName[d.b.NetClasses[i].Name]; ok {
+			d.addf(fmt.Sprintf("net_class[%s]", d.b.NetClasses[i].Name), Added, "added")
+		}
+	}
+}
+
+// moduleKeys identifies the modules of a board by reference. Modules with
+// the same reference are numbered in the order they appear.
+func moduleKeys(p *PCB) ([]string, map[string]*Module) {
+	var keys []string
+	out := map[string]*Module{}
+	seen := map[string]int{}
+	for i := range p.Modules {
+		m := &p.Modules[i]
+		key := m.Reference()
+		if n := seen[key]; n > 0 {
+			key = fmt.Sprintf("%s#%d", key, n+1)
+		}
+		seen[m.Reference()]++
+		keys = append(keys, key)
+		out[key] = m
+	}
+	return keys, out
+}
+
+func (d *differ) diffModules() {
+	keysA, modsA := moduleKeys(d.a)
+	keysB, modsB := moduleKeys(d.b)
+	for _, key := range keysA {
+		elem := fmt.Sprintf("module[%s]", key)
+		b, ok := modsB[key]
+		if !ok {
+			d.addf(elem, Removed, "removed %s", modsA[key].Name)
+			continue
+		}
+		d.diffModule(elem, modsA[key], b, true)
+	}
+	for _, key := range keysB {
+		if _, ok := modsA[key]; !ok {
+			m := modsB[key]
+			d.addf(fmt.Sprintf("module[%s]", key), Added, "added %s at %s", m.Name, fmtXY(XY{X: m.Placement.At.X, Y: m.Placement.At.Y}))
+		}
+	}
+}
+
+func (d *differ) diffSegments() {
+	keys := func(p *PCB) (tracks, vias []layerKey) {
+		for _, s := range p.Segments {
+			switch s := s.(type) {
+			case *Track:
+				tracks = append(tracks, layerKey{s.Layer, fmt.Sprintf("%s width %s net %q", fmtSegment(s.Start, s.End), fmtNum(s.Width), p.Nets[s.NetIndex].Name)})
+			case *Via:
+				vias = append(vias, layerKey{"", fmt.Sprintf("at %s size %s drill %s layers %s net %q", fmtXY(s.At), fmtNum(s.Size), fmtNum(s.Drill), strings.Join(s.Layers, "-"), p.Nets[s.NetIndex].Name)})
+			}
+		}
+		return tracks, vias
+	}
+	tracksA, viasA := keys(d.a)
+	tracksB, viasB := keys(d.b)
+	d.diffMultiset("track", tracksA, tracksB)
+	d.diffMultiset("via", viasA, viasB)
+}
+
+func (d *differ) diffDrawings() {
+	keys := func(p *PCB) []layerKey {
+		var out []layerKey
+		for _, dr := range p.Drawings {
+			switch dr := dr.(type) {
+			case *Line:
+				out = append(out, layerKey{dr.Layer, fmt.Sprintf("line %s width %s", fmtSegment(dr.Start, dr.End), fmtNum(dr.Width))})
+			case *Arc:
+				out = append(out, layerKey{dr.Layer, fmt.Sprintf("arc %s-%s angle %s width %s", fmtXY(dr.Start), fmtXY(dr.End), fmtNum(dr.Angle), fmtNum(dr.Width))})
+			case *Text:
+				out = append(out, layerKey{dr.Layer, fmt.Sprintf("text %q at %s", dr.Text, fmtXYZ(dr.At))})
+			case *Dimension:
+				out = append(out, layerKey{dr.Layer, fmt.Sprintf("dimension %q at %s", dr.Text.Text, fmtXYZ(dr.Text.At))})
+			default:
+				out = append(out, layerKey{"", fmt.Sprintf("%+v", dr)})
+			}
+		}
+		return out
+	}
+	d.diffMultiset("drawing", keys(d.a), keys(d.b))
+}
+
+func (d *differ) diffZones() {
+	key := func(z *Zone) layerKey {
+		var polys []string
+		for _, poly := range z.BasePolys {
+			pts := make([]string, len(poly))
+			for i, p := range poly {
+				pts[i] = fmtXY(p)
+			}
+			polys = append(polys, strings.Join(pts, " "))
+		}
+		kind := "zone"
+		if z.IsKeepout {
+			kind = "keepout"
+		}
+		return layerKey{strings.Join(z.Layers, " "), fmt.Sprintf("%s net %q priority %d outline %s", kind, z.NetName, z.Priority, strings.Join(polys, "; "))}
+	}
+
+	var a, b []layerKey
+	filledA, filledB := map[layerKey]string{}, map[layerKey]string{}
+	for i := range d.a.Zones {
+		k := key(&d.a.Zones[i])
+		a = append(a, k)
+		filledA[k] = fmt.Sprint(d.a.Zones[i].Polys)
+	}
+	for i := range d.b.Zones {
+		k := key(&d.b.Zones[i])
+		b = append(b, k)
+		filledB[k] = fmt.Sprint(d.b.Zones[i].Polys)
+	}
+	d.diffMultiset("zone", a, b)
+
+	// Zones with the same outline may have been refilled.
+	var refilled []layerKey
+	for k, fa := range filledA {
+		if fb, ok := filledB[k]; ok && fa != fb {
+			refilled = append(refilled, k)
+		}
+	}
+	sort.Slice(refilled, func(i, j int) bool { return refilled[i].desc < refilled[j].desc })
+	for _, k := range refilled {
+		d.addf(fmt.Sprintf("zone[%s]", k.layer), Changed, "filled area changed: %s", k.desc)
+	}
+}
+
+// setDiff returns the sorted members of b which are not in a, and of a
+// which are not in b.
+func setDiff(a, b []string) (added, removed []string) {
+	inA, inB := map[string]bool{}, map[string]bool{}
+	for _, s := range a {
+		inA[s] = true
+	}
+	for _, s := range b {
+		inB[s] = true
+	}
+	for s := range inB {
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+	for s := range inA {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// padLess orders pad numbers numerically where possible.
+func padLess(a, b string) bool {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil && na != nb:
+		return na < nb
+	case errA == nil && errB != nil:
+		return true
+	case errB == nil && errA != nil:
+		return false
+	}
+	return a < b
+}
+
+func padDist(a, b *Pad) float64 {
+	return math.Hypot(a.At.X-b.At.X, a.At.Y-b.At.Y)
+}
+
+func floatEq(a, b float64) bool {
+	return math.Abs(a-b) <= diffTolerance
+}
+
+// unrotate removes the module rotation from the orientation of a pad or
+// text.
+func unrotate(at XYZ, rot float64) XYZ {
+	at.Z = normalizeAnglePos(at.Z - rot)
+	return at
+}
+
+// fmtNum formats a dimension, rounding away floating point noise.
+func fmtNum(f float64) string {
+	s := strings.TrimRight(strconv.FormatFloat(f, 'f', 6, 64), "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func fmtXY(p XY, sep ...string) string {
+	if len(sep) > 0 {
+		return fmtNum(p.X) + sep[0] + fmtNum(p.Y)
+	}
+	return "(" + fmtNum(p.X) + ", " + fmtNum(p.Y) + ")"
+}
+
+func fmtXYZ(p XYZ) string {
+	if p.Z == 0 {
+		return fmtXY(XY{X: p.X, Y: p.Y})
+	}
+	return "(" + fmtNum(p.X) + ", " + fmtNum(p.Y) + ", " + fmtNum(p.Z) + ")"
+}
+
+// fmtSegment formats a line segment with its endpoints in a canonical
+// order, so reversed segments compare equal.
+func fmtSegment(start, end XY) string {
+	if end.X < start.X || (end.X == start.X && end.Y < start.Y) {
+		start, end = end, start
+	}
+	return fmtXY(start) + "-" + fmtXY(end)
+}
+
+func fmtDrill(p *Pad) string {
+	switch {
+	case p.DrillSize.X == 0 && p.DrillSize.Y == 0:
+		return "none"
+	case p.DrillShape == ShapeDrillOblong || !floatEq(p.DrillSize.X, p.DrillSize.Y):
+		return "oval " + fmtXY(p.DrillSize, "x")
+	}
+	return fmtNum(p.DrillSize.X)
+}
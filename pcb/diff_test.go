@@ -0,0 +1,180 @@
+package pcb
+
+import (
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const diffTestModule = `(module R_0805 (layer F.Cu) (tedit 5DB1A000)
+  (descr "Resistor SMD 0805")
+  (tags "resistor")
+  (attr smd)
+  (fp_text reference REF** (at 0 -1.65) (layer F.SilkS)
+    (effects (font (size 1 1) (thickness 0.15)))
+  )
+  (fp_text value R_0805 (at 0 1.65) (layer F.Fab)
+    (effects (font (size 1 1) (thickness 0.15)))
+  )
+  (fp_line (start -1 -0.6) (end 1 -0.6) (layer F.Fab) (width 0.1))
+  (fp_line (start -0.26 0.71) (end 0.26 0.71) (layer F.SilkS) (width 0.12))
+  (pad 1 smd roundrect (at -0.9375 0) (size 0.975 1.4) (layers F.Cu F.Paste F.Mask) (roundrect_rratio 0.25))
+  (pad 2 smd roundrect (at 0.9375 0) (size 0.975 1.4) (layers F.Cu F.Paste F.Mask) (roundrect_rratio 0.25))
+)`
+
+func TestDiffModules(t *testing.T) {
+	a, err := decodeModule([]byte(diffTestModule), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reordered elements, reversed lines and reformatted numbers are not
+	// differences.
+	same, err := decodeModule([]byte(`(module R_0805 (layer F.Cu) (tedit 5EEEEEEE)
+  (pad 2 smd roundrect (at 0.93750 0) (size 0.975 1.40) (layers F.Mask F.Paste F.Cu) (roundrect_rratio 0.25))
+  (pad 1 smd roundrect (at -0.9375 0) (size 0.975 1.4) (layers F.Cu F.Paste F.Mask) (roundrect_rratio 0.25))
+  (fp_line (start 0.26 0.71) (end -0.26 0.71) (layer F.SilkS) (width 0.12))
+  (fp_line (start -1 -0.6) (end 1 -0.6) (layer F.Fab) (width 0.1))
+  (fp_text value R_0805 (at 0 1.65) (layer F.Fab)
+    (effects (font (size 1 1) (thickness 0.15)))
+  )
+  (fp_text reference REF** (at 0 -1.65) (layer F.SilkS)
+    (effects (font (size 1 1) (thickness 0.15)))
+  )
+  (descr "Resistor SMD 0805")
+  (tags "resistor")
+  (attr smd)
+)`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := DiffModules(a, same); len(changes) > 0 {
+		t.Errorf("DiffModules() of equivalent modules = %v, want none", changes)
+	}
+
+	b, err := decodeModule([]byte(strings.NewReplacer(
+		`(tags "resistor")`, `(tags "resistor smd")`,
+		`(at 0.9375 0) (size 0.975 1.4) (layers F.Cu F.Paste F.Mask)`, `(at 1 0.1) (size 1 1.4) (layers F.Cu F.Mask)`,
+		`(fp_line (start -0.26 0.71) (end 0.26 0.71) (layer F.SilkS) (width 0.12))`, `(fp_line (start -0.26 -0.71) (end 0.26 -0.71) (layer F.SilkS) (width 0.12))`,
+		`(pad 1 smd`, `(pad 3 thru_hole circle (at 0 0) (size 1 1) (drill 0.5) (layers *.Cu *.Mask))
+  (pad 1 smd`,
+	).Replace(diffTestModule)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"module: tags +smd",
+		"pad[2]: moved by (0.0625, 0.1) to (1, 0.1)",
+		"pad[2]: size 0.975x1.4 -> 1x1.4",
+		"pad[2]: layers -F.Paste",
+		"pad[3]: added at (0, 0)",
+		"graphics[F.SilkS]: added line (-0.26, -0.71)-(0.26, -0.71) width 0.12",
+		"graphics[F.SilkS]: removed line (-0.26, 0.71)-(0.26, 0.71) width 0.12",
+	}
+	var got []string
+	for _, c := range DiffModules(a, b) {
+		got = append(got, c.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffModules() = %q, want %q", got, want)
+	}
+}
+
+// permuteNets renumbers the nets of a board, reversing their order.
+func permuteNets(p *PCB) {
+	perm := map[int]int{0: 0}
+	nets := map[int]Net{0: p.Nets[0]}
+	for num, n := range p.Nets {
+		if num != 0 {
+			perm[num] = len(p.Nets) - num
+			nets[perm[num]] = n
+		}
+	}
+	p.Nets = nets
+	for _, s := range p.Segments {
+		switch s := s.(type) {
+		case *Track:
+			s.NetIndex = perm[s.NetIndex]
+		case *Via:
+			s.NetIndex = perm[s.NetIndex]
+		}
+	}
+	for i := range p.Zones {
+		p.Zones[i].NetNum = perm[p.Zones[i].NetNum]
+	}
+	for i := range p.Modules {
+		for j := range p.Modules[i].Pads {
+			p.Modules[i].Pads[j].NetNum = perm[p.Modules[i].Pads[j].NetNum]
+		}
+	}
+}
+
+func TestDiffPCBs(t *testing.T) {
+	a, err := DecodeFile(path.Join("testdata", "t1.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := DecodeFile(path.Join("testdata", "t1.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, j := 0, len(b.Modules)-1; i < j; i, j = i+1, j-1 {
+		b.Modules[i], b.Modules[j] = b.Modules[j], b.Modules[i]
+	}
+	for i, j := 0, len(b.Segments)-1; i < j; i, j = i+1, j-1 {
+		b.Segments[i], b.Segments[j] = b.Segments[j], b.Segments[i]
+	}
+	permuteNets(b)
+	if changes := DiffPCBs(a, b); len(changes) > 0 {
+		t.Fatalf("DiffPCBs() after reordering = %v, want none", changes)
+	}
+
+	for i := range b.Modules {
+		if m := &b.Modules[i]; m.Reference() == "R1" {
+			m.Placement.At.X += 1
+			m.Placement.At.Z += 90
+			for j := range m.Pads {
+				m.Pads[j].At.Z += 90
+			}
+			for _, g := range m.Graphics {
+				if txt, ok := g.Renderable.(*ModText); ok {
+					txt.At.Z += 90
+				}
+			}
+		}
+	}
+	b.Segments[0].(*Track).Width = 0.5
+	b.EditorSetup.TraceClearance = 0.3
+	b.AddNet("SPARE")
+
+	var got []string
+	for _, c := range DiffPCBs(a, b) {
+		got = append(got, c.String())
+	}
+	for _, want := range []string{
+		"setup: TraceClearance 0.2 -> 0.3",
+		"net[SPARE]: added",
+		"module[R1]: moved by (1, 0) to (134.604, 96.266)",
+		"module[R1]: rotation 90 -> 180",
+	} {
+		found := false
+		for _, g := range got {
+			found = found || g == want
+		}
+		if !found {
+			t.Errorf("DiffPCBs() = %q, missing %q", got, want)
+		}
+	}
+	var trackChanges int
+	for _, g := range got {
+		if strings.HasPrefix(g, "track[") {
+			trackChanges++
+		}
+		if strings.HasPrefix(g, "module[R1]/") {
+			t.Errorf("DiffPCBs() reported rotation of the module contents: %q", g)
+		}
+	}
+	if trackChanges != 2 {
+		t.Errorf("got %d track changes, want 2 (one removed, one added)", trackChanges)
+	}
+}
@@ -0,0 +1,144 @@
+package pcb
+
+import (
+	"fmt"
+	"math"
+)
+
+// DimensionUnits describes the units a dimension is displayed in.
+type DimensionUnits string
+
+// Valid DimensionUnits values.
+const (
+	DimensionMillimeters DimensionUnits = "mm"
+	DimensionInches      DimensionUnits = "in"
+	DimensionMils        DimensionUnits = "mils"
+)
+
+// scale returns the number of units per millimeter.
+func (u DimensionUnits) scale() (float64, error) {
+	switch u {
+	case DimensionMillimeters:
+		return 1, nil
+	case DimensionInches:
+		return 1 / 25.4, nil
+	case DimensionMils:
+		return 1000 / 25.4, nil
+	}
+	return 0, fmt.Errorf("unknown dimension units %q", string(u))
+}
+
+const (
+	// dimensionArrowLength is the length of the arrowhead lines, 50 mils.
+	dimensionArrowLength = 1.27
+	// dimensionArrowAngle is the angle between the arrowhead lines and
+	// the crossbar, in degrees.
+	dimensionArrowAngle = 27.5
+)
+
+// NewDimension returns a dimension measuring the distance from start to
+// end, on the given layer. The crossbar is drawn offset from the measured
+// points, perpendicular to the line between them. Precision is the number
+// of decimal places the measurement is displayed with.
+func NewDimension(start, end XY, offset float64, layer string, units DimensionUnits, precision int) (*Dimension, error) {
+	d := &Dimension{
+		Width: 0.3,
+		Layer: layer,
+		Text: Text{
+			Effects: TextEffects{
+				FontSize:  XY{X: 1.5, Y: 1.5},
+				Thickness: 0.3,
+			},
+		},
+	}
+	if err := d.Layout(start, end, offset, units, precision); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Layout computes the measurement, text and graphical features of the
+// dimension, matching the geometry generated by pcbnew. The current
+// width and text size are used to size the feature lines.
+func (d *Dimension) Layout(start, end XY, offset float64, units DimensionUnits, precision int) error {
+	scale, err := units.scale()
+	if err != nil {
+		return err
+	}
+
+	dx, dy := end.X-start.X, end.Y-start.Y
+	measure := math.Hypot(dx, dy)
+	angle := math.Atan2(dy, dx)
+
+	// The crossbar runs parallel to the measured points, offset
+	// perpendicular to them.
+	ox := roundNM(offset * math.Cos(angle+math.Pi/2))
+	oy := roundNM(offset * math.Sin(angle+math.Pi/2))
+	crossStart := XY{X: start.X + ox, Y: start.Y + oy}
+	crossEnd := XY{X: end.X + ox, Y: end.Y + oy}
+
+	// Feature lines extend past the crossbar by enough to fit the text.
+	var fx, fy float64
+	var arrowUp, arrowDown XY
+	if measure > 0 {
+		ext := d.Text.Effects.FontSize.Y + d.Text.Effects.Thickness + 3*d.Width
+		fx = roundNM(math.Abs(dy * ext / measure))
+		fy = roundNM(math.Abs(dx * ext / measure))
+		if start.X > crossStart.X {
+			fx = -fx
+		} else if start.X == crossStart.X {
+			fx = 0
+		}
+		if start.Y > crossStart.Y {
+			fy = -fy
+		} else if start.Y == crossStart.Y {
+			fy = 0
+		}
+
+		a := dimensionArrowAngle * math.Pi / 180
+		arrowUp = XY{
+			X: roundNM(dimensionArrowLength * math.Cos(angle+a)),
+			Y: roundNM(dimensionArrowLength * math.Sin(angle+a)),
+		}
+		arrowDown = XY{
+			X: roundNM(dimensionArrowLength * math.Cos(angle-a)),
+			Y: roundNM(dimensionArrowLength * math.Sin(angle-a)),
+		}
+	}
+	featureStart := XY{X: crossStart.X + fx, Y: crossStart.Y + fy}
+	featureEnd := XY{X: crossEnd.X + fx, Y: crossEnd.Y + fy}
+
+	d.Features = []DimensionFeature{
+		{Feature: "feature1", Points: []XY{end, featureEnd}},
+		{Feature: "feature2", Points: []XY{start, featureStart}},
+		{Feature: "crossbar", Points: []XY{crossStart, crossEnd}},
+		{Feature: "arrow1a", Points: []XY{crossEnd, {X: crossEnd.X - arrowDown.X, Y: crossEnd.Y - arrowDown.Y}}},
+		{Feature: "arrow1b", Points: []XY{crossEnd, {X: crossEnd.X - arrowUp.X, Y: crossEnd.Y - arrowUp.Y}}},
+		{Feature: "arrow2a", Points: []XY{crossStart, {X: crossStart.X + arrowUp.X, Y: crossStart.Y + arrowUp.Y}}},
+		{Feature: "arrow2b", Points: []XY{crossStart, {X: crossStart.X + arrowDown.X, Y: crossStart.Y + arrowDown.Y}}},
+	}
+
+	// Text sits centered beyond the crossbar, reading left-to-right or
+	// bottom-to-top.
+	textAngle := math.Mod(-angle*180/math.Pi+360, 360)
+	if textAngle > 90 && textAngle < 270 {
+		textAngle -= 180
+	}
+	d.Text.At = XYZ{
+		X:        roundNM((crossEnd.X + featureStart.X) / 2),
+		Y:        roundNM((crossEnd.Y + featureStart.Y) / 2),
+		Z:        textAngle,
+		ZPresent: textAngle != 0,
+	}
+	d.Text.Layer = d.Layer
+
+	d.CurrentMeasurement = roundNM(measure)
+	d.Text.Text = fPrecise(d.CurrentMeasurement*scale, precision) + " " + string(units)
+	return nil
+}
+
+// roundNM rounds a distance in millimeters to the nearest nanometer, the
+// internal resolution of pcbnew.
+func roundNM(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
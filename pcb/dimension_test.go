@@ -0,0 +1,84 @@
+package pcb
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestNewDimensionMatchesPcbnew(t *testing.T) {
+	board, err := DecodeFile("testdata/dimension_equality.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []*Dimension
+	for _, d := range board.Drawings {
+		if dim, ok := d.(*Dimension); ok {
+			want = append(want, dim)
+		}
+	}
+	if len(want) != 2 {
+		t.Fatalf("got %d dimensions in testdata, want 2", len(want))
+	}
+
+	tcs := []struct {
+		name       string
+		start, end XY
+		offset     float64
+		want       *Dimension
+	}{
+		{
+			name:   "vertical",
+			start:  XY{X: 132.08, Y: 100.076},
+			end:    XY{X: 132.08, Y: 87.63},
+			offset: -5.334,
+			want:   want[0],
+		},
+		{
+			name:   "horizontal",
+			start:  XY{X: 132.08, Y: 100.076},
+			end:    XY{X: 173.736, Y: 100.076},
+			offset: 3.81,
+			want:   want[1],
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewDimension(tc.start, tc.end, tc.offset, "F.Fab", DimensionMillimeters, 4)
+			if err != nil {
+				t.Fatalf("NewDimension() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreUnexported(Dimension{}, Text{}), cmpopts.EquateApprox(0, 1e-6)); diff != "" {
+				t.Errorf("NewDimension() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewDimensionUnits(t *testing.T) {
+	tcs := []struct {
+		units     DimensionUnits
+		precision int
+		want      string
+	}{
+		{DimensionMillimeters, 2, "25.4 mm"},
+		{DimensionInches, 4, "1 in"},
+		{DimensionMils, 0, "1000 mils"},
+	}
+
+	for _, tc := range tcs {
+		d, err := NewDimension(XY{}, XY{X: 25.4}, 5, "Dwgs.User", tc.units, tc.precision)
+		if err != nil {
+			t.Fatalf("NewDimension(%q) failed: %v", tc.units, err)
+		}
+		if d.Text.Text != tc.want {
+			t.Errorf("NewDimension(%q).Text.Text = %q, want %q", tc.units, d.Text.Text, tc.want)
+		}
+	}
+
+	if _, err := NewDimension(XY{}, XY{X: 1}, 5, "Dwgs.User", "furlongs", 2); err == nil {
+		t.Error("NewDimension() with unknown units did not fail")
+	}
+}
@@ -0,0 +1,382 @@
+package pcb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/sreader"
+)
+
+// FPLib describes a footprint library in a library table.
+type FPLib struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	URI         string `json:"uri"`
+	Options     string `json:"options,omitempty"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+// FPLibTable is a footprint library table, as stored in fp-lib-table files.
+// KiCad consults the table in the project directory first, then the
+// global table.
+type FPLibTable struct {
+	Libs []FPLib `json:"libs"`
+
+	// Dir is the directory containing the table. It is the value of
+	// ${KIPRJMOD} when library URIs are expanded.
+	Dir string `json:"-"`
+	// Fallback is searched for libraries which are not in this table.
+	Fallback *FPLibTable `json:"-"`
+}
+
+// DecodeFPLibTableFile reads a fp-lib-table file at fpath, returning a
+// parsed representation.
+func DecodeFPLibTableFile(fpath string) (*FPLibTable, error) {
+	f, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	t, err := decodeFPLibTable(f, fpath)
+	if err != nil {
+		return nil, err
+	}
+	t.Dir = filepath.Dir(fpath)
+	return t, nil
+}
+
+// DecodeFPLibTable parses the provided fp-lib-table content. Malformed
+// input is reported as a *ParseError.
+func DecodeFPLibTable(f []byte) (*FPLibTable, error) {
+	return decodeFPLibTable(f, "")
+}
+
+func decodeFPLibTable(f []byte, fname string) (t *FPLibTable, err error) {
+	ast, err := sreader.Parse(f, fname)
+	if err != nil {
+		return nil, err
+	}
+	n := ast.Child(0)
+	if n.Name() != "fp_lib_table" {
+		return nil, n.Errorf("invalid format: missing leading element fp_lib_table")
+	}
+
+	defer sreader.Recover(&err)
+	t = &FPLibTable{}
+	for x := 1; x < n.NumChildren(); x++ {
+		c := n.Child(x)
+		switch c.Name() {
+		case "lib":
+			l, err := parseFPLib(c)
+			if err != nil {
+				return nil, err
+			}
+			t.Libs = append(t.Libs, l)
+		case "version":
+		default:
+			return nil, c.Errorf("unexpected element %q", c.Name())
+		}
+	}
+	return t, nil
+}
+
+func parseFPLib(n sreader.Node) (FPLib, error) {
+	out := FPLib{}
+	for x := 1; x < n.NumChildren(); x++ {
+		c := n.Child(x)
+		switch c.Name() {
+		case "name":
+			out.Name = c.Child(1).MustString()
+		case "type":
+			out.Type = c.Child(1).MustString()
+		case "uri":
+			out.URI = c.Child(1).MustString()
+		case "options":
+			out.Options = c.Child(1).MustString()
+		case "descr":
+			out.Description = c.Child(1).MustString()
+		case "disabled":
+			out.Disabled = true
+		}
+	}
+	if out.Name == "" {
+		return out, n.Errorf("library has no name")
+	}
+	return out, nil
+}
+
+// Lib returns the library with the given nickname, searching the fallback
+// tables if it is not in this one.
+func (t *FPLibTable) Lib(name string) (*FPLib, bool) {
+	for tbl := t; tbl != nil; tbl = tbl.Fallback {
+		for i := range tbl.Libs {
+			if tbl.Libs[i].Name == name {
+				return &tbl.Libs[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Libraries returns the enabled libraries in the table and its fallbacks,
+// in the order they are searched. Libraries shadowed by one of the same
+// name in an earlier table are omitted.
+func (t *FPLibTable) Libraries() []FPLib {
+	var out []FPLib
+	seen := map[string]bool{}
+	for tbl := t; tbl != nil; tbl = tbl.Fallback {
+		for _, l := range tbl.Libs {
+			if !seen[l.Name] && !l.Disabled {
+				out = append(out, l)
+			}
+			seen[l.Name] = true
+		}
+	}
+	return out
+}
+
+// ResolveURI returns the URI of the library with variables such as
+// ${KISYSMOD} expanded as by LookupEnv. ${KIPRJMOD} expands to the
+// directory of the table. Variables which are not set are left unexpanded.
+func (t *FPLibTable) ResolveURI(l *FPLib) string {
+	return expandEnv(l.URI, func(name string) (string, bool) {
+		if name == "KIPRJMOD" && t.Dir != "" {
+			return t.Dir, true
+		}
+		return LookupEnv(name)
+	})
+}
+
+// LoadFootprint loads a footprint given its library identifier, such as
+// Resistor_SMD:R_0805_2012Metric.
+func (t *FPLibTable) LoadFootprint(id string) (*Module, error) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("footprint %q has no library nickname", id)
+	}
+	return t.Loader()(parts[0], parts[1])
+}
+
+// Loader returns a FootprintLoader which resolves libraries using the table.
+func (t *FPLibTable) Loader() FootprintLoader {
+	return func(lib, name string) (*Module, error) {
+		l, ok := t.Lib(lib)
+		if !ok || l.Disabled {
+			return nil, &footprintNotFoundError{lib: lib, name: name}
+		}
+		if l.Type != "" && !strings.EqualFold(l.Type, "KiCad") {
+			return nil, fmt.Errorf("footprint library %s: unsupported library type %s", lib, l.Type)
+		}
+		path := filepath.Join(t.ResolveURI(l), name+".kicad_mod")
+		if _, err := os.Stat(path); err != nil {
+			return nil, &footprintNotFoundError{lib: lib, name: name}
+		}
+		return DecodeModuleFile(path)
+	}
+}
+
+// ConfigDir returns the KiCad configuration directory, which may be
+// overridden with $KICAD_CONFIG_HOME.
+func ConfigDir() string {
+	if dir := os.Getenv("KICAD_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	var dir string
+	switch runtime.GOOS {
+	case "windows":
+		dir = os.Getenv("APPDATA")
+	case "darwin":
+		dir = filepath.Join(os.Getenv("HOME"), "Library", "Preferences")
+	default:
+		if dir = os.Getenv("XDG_CONFIG_HOME"); dir == "" {
+			dir = filepath.Join(os.Getenv("HOME"), ".config")
+		}
+	}
+	return filepath.Join(dir, "kicad")
+}
+
+// GlobalFPLibTablePath returns the path of the global footprint library
+// table in the KiCad configuration directory.
+func GlobalFPLibTablePath() string {
+	return filepath.Join(ConfigDir(), "fp-lib-table")
+}
+
+// LookupEnv returns the value of a path variable such as KISYSMOD the way
+// KiCad does: from the environment, then from the variables configured in
+// kicad_common in the configuration directory, then from the default
+// install location. Empty values are treated as unset.
+func LookupEnv(name string) (string, bool) {
+	if v := os.Getenv(name); v != "" {
+		return v, true
+	}
+	if v := readKicadCommonEnv(filepath.Join(ConfigDir(), "kicad_common"))[name]; v != "" {
+		return v, true
+	}
+	v, ok := defaultEnv()[name]
+	return v, ok
+}
+
+// readKicadCommonEnv returns the variables in the [EnvironmentVariables]
+// section of a kicad_common file. A missing or unreadable file has no
+// variables.
+func readKicadCommonEnv(fpath string) map[string]string {
+	f, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil
+	}
+	vars := map[string]string{}
+	var inSection bool
+	for _, line := range strings.Split(string(f), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "["):
+			inSection = line == "[EnvironmentVariables]"
+		case inSection:
+			if eq := strings.IndexByte(line, '='); eq > 0 {
+				vars[strings.TrimSpace(line[:eq])] = unescapeConfigValue(strings.TrimSpace(line[eq+1:]))
+			}
+		}
+	}
+	return vars
+}
+
+// unescapeConfigValue reverses the escaping wxWidgets applies to values
+// in configuration files.
+func unescapeConfigValue(v string) string {
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = v[1 : len(v)-1]
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			i++
+			switch v[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(v[i])
+			}
+			continue
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
+
+// defaultEnv returns the values KiCad gives its path variables when they
+// are not configured, based on the default install location.
+func defaultEnv() map[string]string {
+	var share string
+	switch runtime.GOOS {
+	case "windows":
+		share = `C:\Program Files\KiCad\share\kicad`
+	case "darwin":
+		share = "/Library/Application Support/kicad"
+	default:
+		share = "/usr/share/kicad"
+	}
+	return map[string]string{
+		"KISYSMOD":           filepath.Join(share, "modules"),
+		"KISYS3DMOD":         filepath.Join(share, "modules", "packages3d"),
+		"KICAD_SYMBOL_DIR":   filepath.Join(share, "library"),
+		"KICAD_TEMPLATE_DIR": filepath.Join(share, "template"),
+	}
+}
+
+// LoadFPLibTables loads the fp-lib-table in the project directory, falling
+// back to the global table. Tables which do not exist are skipped.
+func LoadFPLibTables(projectDir string) (*FPLibTable, error) {
+	global := &FPLibTable{}
+	if _, err := os.Stat(GlobalFPLibTablePath()); err == nil {
+		if global, err = DecodeFPLibTableFile(GlobalFPLibTablePath()); err != nil {
+			return nil, err
+		}
+	}
+
+	path := filepath.Join(projectDir, "fp-lib-table")
+	if _, err := os.Stat(path); err != nil {
+		global.Dir = projectDir
+		return global, nil
+	}
+	t, err := DecodeFPLibTableFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t.Fallback = global
+	return t, nil
+}
+
+// DefaultFootprintLoader returns a FootprintLoader which resolves libraries
+// the way pcbnew does for a project in projectDir: through the project and
+// global library tables, then in the KISYSMOD directory given by
+// LookupEnv. The tables are read when the first footprint is loaded.
+func DefaultFootprintLoader(projectDir string) FootprintLoader {
+	var tables FootprintLoader
+	return func(lib, name string) (*Module, error) {
+		if tables == nil {
+			t, err := LoadFPLibTables(projectDir)
+			if err != nil {
+				return nil, err
+			}
+			tables = t.Loader()
+		}
+		loaders := []FootprintLoader{tables}
+		if dir, ok := LookupEnv("KISYSMOD"); ok {
+			loaders = append(loaders, DirFootprintLoader(dir))
+		}
+		return FootprintLoaders(loaders...)(lib, name)
+	}
+}
+
+// FootprintLoaders returns a FootprintLoader which tries each loader in
+// turn, until one finds the footprint.
+func FootprintLoaders(loaders ...FootprintLoader) FootprintLoader {
+	return func(lib, name string) (*Module, error) {
+		for _, l := range loaders {
+			m, err := l(lib, name)
+			if _, notFound := err.(*footprintNotFoundError); notFound {
+				continue
+			}
+			return m, err
+		}
+		return nil, &footprintNotFoundError{lib: lib, name: name}
+	}
+}
+
+type footprintNotFoundError struct {
+	lib, name string
+}
+
+func (e *footprintNotFoundError) Error() string {
+	return fmt.Sprintf("footprint %s:%s not found", e.lib, e.name)
+}
+
+// expandEnv replaces ${VAR} and $(VAR) references in s, as KiCad does.
+func expandEnv(s string, lookup func(string) (string, bool)) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '$' && i+1 < len(s) && (s[i+1] == '{' || s[i+1] == '(') {
+			closing := byte('}')
+			if s[i+1] == '(' {
+				closing = ')'
+			}
+			if end := strings.IndexByte(s[i+2:], closing); end >= 0 {
+				if v, ok := lookup(s[i+2 : i+2+end]); ok {
+					b.WriteString(v)
+					i += end + 2
+					continue
+				}
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package pcb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setTestEnv sets environment variables for the duration of a test.
+func setTestEnv(t *testing.T, vars map[string]string) func() {
+	t.Helper()
+	old := map[string]*string{}
+	for k, v := range vars {
+		if prev, ok := os.LookupEnv(k); ok {
+			old[k] = &prev
+		} else {
+			old[k] = nil
+		}
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range old {
+			if v == nil {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *v)
+			}
+		}
+	}
+}
+
+func TestDecodeFPLibTable(t *testing.T) {
+	tbl, err := DecodeFPLibTableFile("testdata/fplib/config/fp-lib-table")
+	if err != nil {
+		t.Fatalf("DecodeFPLibTableFile() failed: %v", err)
+	}
+	want := []FPLib{
+		{Name: "Button_Switch_SMD", Type: "KiCad", URI: "$(KCGEN_TEST_LIBS)/Button_Switch_SMD.pretty", Description: "Surface mount switches"},
+		{Name: "Project", Type: "KiCad", URI: "/nonexistent/Project.pretty", Description: "Shadowed by the project table"},
+		{Name: "Disabled", Type: "KiCad", URI: "${KCGEN_TEST_LIBS}/Button_Switch_SMD.pretty", Disabled: true},
+	}
+	if !reflect.DeepEqual(tbl.Libs, want) {
+		t.Errorf("Libs = %+v, want %+v", tbl.Libs, want)
+	}
+	if got, want := tbl.Dir, filepath.Join("testdata", "fplib", "config"); got != want {
+		t.Errorf("Dir = %q, want %q", got, want)
+	}
+
+	if _, err := DecodeFPLibTable([]byte("(sym_lib_table)")); err == nil {
+		t.Error("DecodeFPLibTable() accepted a symbol library table")
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	vars := map[string]string{"KISYSMOD": "/usr/share/kicad/modules", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+	tcs := map[string]string{
+		"${KISYSMOD}/Resistor_SMD.pretty": "/usr/share/kicad/modules/Resistor_SMD.pretty",
+		"$(KISYSMOD)/x":                   "/usr/share/kicad/modules/x",
+		"${UNSET}/x":                      "${UNSET}/x",
+		"a${EMPTY}b":                      "ab",
+		"${KISYSMOD":                      "${KISYSMOD",
+		"$$5":                             "$$5",
+	}
+	for in, want := range tcs {
+		if got := expandEnv(in, lookup); got != want {
+			t.Errorf("expandEnv(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDefaultFootprintLoader(t *testing.T) {
+	libs, err := filepath.Abs("testdata/fplib/shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer setTestEnv(t, map[string]string{
+		"KICAD_CONFIG_HOME": "testdata/fplib/config",
+		"KCGEN_TEST_LIBS":   libs,
+		"KISYSMOD":          "",
+	})()
+
+	load := DefaultFootprintLoader("testdata/fplib/project")
+	for _, tc := range []struct {
+		lib, name string
+		wantErr   bool
+	}{
+		{lib: "Project", name: "Logo"},
+		{lib: "Button_Switch_SMD", name: "SW_SPST_PTS645"},
+		{lib: "Button_Switch_SMD", name: "Missing", wantErr: true},
+		{lib: "Disabled", name: "SW_SPST_PTS645", wantErr: true},
+		{lib: "Old", name: "Logo", wantErr: true},
+	} {
+		m, err := load(tc.lib, tc.name)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s:%s loaded, want error", tc.lib, tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s:%s failed to load: %v", tc.lib, tc.name, err)
+			continue
+		}
+		if m.Name != tc.name {
+			t.Errorf("%s:%s loaded module %q", tc.lib, tc.name, m.Name)
+		}
+	}
+
+	tbl, err := LoadFPLibTables("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tbl.Lib("Project"); !ok {
+		t.Error("global table not used when the project has none")
+	}
+
+	// Libraries missing from the tables are found in $KISYSMOD.
+	defer setTestEnv(t, map[string]string{"KISYSMOD": "testdata/fplib/project/footprints"})()
+	if _, err := DefaultFootprintLoader("testdata")("Project", "Logo"); err != nil {
+		t.Errorf("loading from $KISYSMOD failed: %v", err)
+	}
+}
+
+func TestLookupEnvKicadCommon(t *testing.T) {
+	defer setTestEnv(t, map[string]string{
+		"KICAD_CONFIG_HOME": "testdata/fplib/common",
+		"KISYSMOD":          "",
+		"KISYS3DMOD":        "",
+	})()
+
+	if got, want := readKicadCommonEnv("testdata/fplib/common/kicad_common")["KISYSMOD"], "testdata/fplib/shared"; got != want {
+		t.Errorf("kicad_common KISYSMOD = %q, want %q", got, want)
+	}
+	// The nickname is only resolvable through ${KISYSMOD} in the global
+	// table, which is configured in kicad_common.
+	m, err := DefaultFootprintLoader("testdata")("Switches", "SW_SPST_PTS645")
+	if err != nil {
+		t.Fatalf("Switches:SW_SPST_PTS645 failed to load: %v", err)
+	}
+	if m.Name != "SW_SPST_PTS645" {
+		t.Errorf("loaded module %q", m.Name)
+	}
+
+	// Variables missing from kicad_common take the default install path.
+	if got, want := mustLookupEnv(t, "KISYS3DMOD"), defaultEnv()["KISYS3DMOD"]; got != want {
+		t.Errorf("LookupEnv(KISYS3DMOD) = %q, want %q", got, want)
+	}
+	defer setTestEnv(t, map[string]string{"KICAD_CONFIG_HOME": "testdata/fplib/config"})()
+	if got, want := mustLookupEnv(t, "KISYSMOD"), defaultEnv()["KISYSMOD"]; got != want {
+		t.Errorf("LookupEnv(KISYSMOD) = %q, want %q", got, want)
+	}
+	if _, ok := LookupEnv("KCGEN_UNSET"); ok {
+		t.Error("LookupEnv(KCGEN_UNSET) returned ok = true")
+	}
+}
+
+func mustLookupEnv(t *testing.T, name string) string {
+	t.Helper()
+	v, ok := LookupEnv(name)
+	if !ok {
+		t.Fatalf("LookupEnv(%s) returned ok = false", name)
+	}
+	return v
+}
@@ -0,0 +1,308 @@
+package pcb
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/twitchyliquid64/kcgen/sreader"
+	"github.com/twitchyliquid64/kcgen/swriter"
+)
+
+// DefaultThickness is the board thickness pcbnew assumes, in millimeters.
+const DefaultThickness = 1.6
+
+// Page describes the size of the drawing sheet.
+type Page struct {
+	// Size is a standard paper size such as A4 or USLetter, or User for a
+	// custom sheet described by Width and Height. Other sizes read from a
+	// file are kept as they are.
+	Size     string  `json:"size"`
+	Width    float64 `json:"width,omitempty"`
+	Height   float64 `json:"height,omitempty"`
+	Portrait bool    `json:"portrait,omitempty"`
+
+	// rest holds the arguments after a size kcgen does not know, which are
+	// written back unchanged.
+	rest string
+}
+
+// pageSizes are the paper sizes understood by pcbnew.
+var pageSizes = map[string]bool{
+	"A4": true, "A3": true, "A2": true, "A1": true, "A0": true,
+	"A": true, "B": true, "C": true, "D": true, "E": true,
+	"GERBER": true, "USLetter": true, "USLegal": true, "USLedger": true,
+	"User": true,
+}
+
+func parsePage(n sreader.Node) (Page, error) {
+	var p Page
+	var err error
+	if p.Size, err = n.Child(1).String(); err != nil {
+		return p, err
+	}
+	if !pageSizes[p.Size] {
+		var rest []string
+		for i := 2; i < n.NumChildren(); i++ {
+			rest = append(rest, n.Child(i).Value())
+		}
+		p.rest = strings.Join(rest, " ")
+		return p, nil
+	}
+	if p.Size == "User" {
+		if p.Width, err = n.Child(2).Float64(); err != nil {
+			return p, err
+		}
+		if p.Height, err = n.Child(3).Float64(); err != nil {
+			return p, err
+		}
+		return p, nil
+	}
+	p.Portrait = n.Child(2).Value() == "portrait"
+	return p, nil
+}
+
+func (p Page) write(sw *swriter.SExpWriter) error {
+	sw.StartList(false)
+	sw.StringScalar("page")
+	switch {
+	case p.Size == "":
+		sw.StringScalar("A4")
+	case p.Size == "User":
+		sw.StringScalar(p.Size)
+		sw.StringScalar(f(p.Width))
+		sw.StringScalar(f(p.Height))
+	case !pageSizes[p.Size]:
+		sw.StringScalar(p.Size)
+		for _, a := range strings.Fields(p.rest) {
+			sw.StringScalar(a)
+		}
+	default:
+		sw.StringScalar(p.Size)
+		if p.Portrait {
+			sw.StringScalar("portrait")
+		}
+	}
+	return sw.CloseList(false)
+}
+
+// generalCounts returns the element counts pcbnew records in the general
+// section, in the order they are written.
+func (p *PCB) generalCounts() [][2]string {
+	return [][2]string{
+		{"drawings", strconv.Itoa(len(p.Drawings))},
+		{"tracks", strconv.Itoa(len(p.Segments))},
+		// pcbnew counts legacy zone segments here rather than zones, which
+		// kcgen never produces.
+		{"zones", "0"},
+		{"modules", strconv.Itoa(len(p.Modules))},
+		{"nets", strconv.Itoa(len(p.Nets))},
+	}
+}
+
+func (p *PCB) writeGeneral(sw *swriter.SExpWriter) error {
+	sw.StartList(false)
+	sw.StringScalar("general")
+
+	// Connectivity summaries written by older versions of pcbnew are
+	// preserved as-is.
+	for _, section := range p.legacyGeneral {
+		sw.StartList(true)
+		for _, v := range section {
+			sw.StringScalar(v)
+		}
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
+	if p.hasArea {
+		if min, max, ok := p.BoundingBox(); ok {
+			sw.StartList(true)
+			sw.StringScalar("area")
+			sw.StringScalar(f(min.X))
+			sw.StringScalar(f(min.Y))
+			sw.StringScalar(f(max.X))
+			sw.StringScalar(f(max.Y))
+			if err := sw.CloseList(false); err != nil {
+				return err
+			}
+		}
+	}
+
+	thickness := p.Thickness
+	if thickness == 0 {
+		thickness = DefaultThickness
+	}
+	sw.StartList(true)
+	sw.StringScalar("thickness")
+	sw.StringScalar(f(thickness))
+	if err := sw.CloseList(false); err != nil {
+		return err
+	}
+	for _, c := range p.generalCounts() {
+		sw.StartList(true)
+		sw.StringScalar(c[0])
+		sw.StringScalar(c[1])
+		if err := sw.CloseList(false); err != nil {
+			return err
+		}
+	}
+	return sw.CloseList(true)
+}
+
+// BoundingBox returns the extent of the drawings, tracks, zones and
+// modules on the board, computed the same way as pcbnew. The extent of
+// text is estimated, as pcbnew measures it with its stroke font. ok is
+// false if the board is empty.
+func (p *PCB) BoundingBox() (min, max XY, ok bool) {
+	var b bbox
+	for _, d := range p.Drawings {
+		switch d := d.(type) {
+		case *Line:
+			b.merge(segmentBox(d.Start, d.End, d.Width))
+		case *Arc:
+			b.merge(arcBox(d.Start, d.End, d.Angle, d.Width))
+		case *Text:
+			b.merge(textBox(d.Text, d.At, d.Effects, true))
+		case *Dimension:
+			b.merge(d.boundingBox())
+		}
+	}
+	for i := range p.Modules {
+		b.merge(p.Modules[i].boundingBox())
+	}
+	for _, s := range p.Segments {
+		switch s := s.(type) {
+		case *Track:
+			b.merge(segmentBox(s.Start, s.End, s.Width))
+		case *Via:
+			b.merge(circleBox(s.At, 0, s.Size))
+		}
+	}
+	for _, z := range p.Zones {
+		for _, poly := range z.BasePolys {
+			for _, pt := range poly {
+				b.add(pt)
+			}
+		}
+	}
+	return b.min, b.max, b.ok
+}
+
+// boundingBox returns the extent of the module graphics and pads, in
+// board coordinates. Like pcbnew, the box always includes a small area
+// around the module position.
+func (m *Module) boundingBox() bbox {
+	toBoard := m.ToBoard
+
+	var b bbox
+	b.add(XY{X: m.Placement.At.X, Y: m.Placement.At.Y})
+	b.inflate(0.25)
+	for _, g := range m.Graphics {
+		switch r := g.Renderable.(type) {
+		case *ModLine:
+			b.merge(segmentBox(toBoard(r.Start), toBoard(r.End), r.Width))
+		case *ModCircle:
+			b.merge(circleBox(toBoard(r.Center), r.Center.Distance(r.End), r.Width))
+		case *ModArc:
+			b.merge(arcBox(toBoard(r.Start), toBoard(r.End), r.Angle, r.Width))
+		case *ModPolygon:
+			var pb bbox
+			for _, pt := range r.Points {
+				pb.add(toBoard(pt))
+			}
+			pb.inflate(strokeInflation(r.Width))
+			b.merge(pb)
+		case *ModText:
+			// Like pcbnew, hidden text counts towards the extent.
+			at := toBoard(XY{X: r.At.X, Y: r.At.Y})
+			b.merge(textBox(r.Text, XYZ{X: at.X, Y: at.Y, Z: r.At.Z}, r.Effects, true))
+		}
+	}
+	for _, pad := range m.Pads {
+		at := m.PadPosition(&pad)
+		center := XY{X: at.X, Y: at.Y}
+		var pb bbox
+		pb.add(center)
+		if pad.Shape == ShapeCircle {
+			pb.inflate(pad.Size.X / 2)
+		} else {
+			c1 := XY{X: pad.Size.X / 2, Y: pad.Size.Y / 2}.Rotate(pad.At.Z)
+			c2 := XY{X: -pad.Size.X / 2, Y: pad.Size.Y / 2}.Rotate(pad.At.Z)
+			dx := math.Max(math.Abs(c1.X), math.Abs(c2.X))
+			dy := math.Max(math.Abs(c1.Y), math.Abs(c2.Y))
+			pb.add(XY{X: center.X - dx, Y: center.Y - dy})
+			pb.add(XY{X: center.X + dx, Y: center.Y + dy})
+		}
+		b.merge(pb)
+	}
+	return b
+}
+
+// boundingBox returns the extent of the dimension as pcbnew computes it:
+// the crossbar, the second feature line and the unrotated box of the text.
+func (d *Dimension) boundingBox() bbox {
+	b := textBox(d.Text.Text, d.Text.At, d.Text.Effects, false)
+	for _, f := range d.Features {
+		if f.Feature == "crossbar" || f.Feature == "feature2" {
+			for _, pt := range f.Points {
+				b.add(pt)
+			}
+		}
+	}
+	return b
+}
+
+// textAdvance is the estimated advance of a character of the pcbnew
+// stroke font, as a fraction of the font width. kcgen does not carry the
+// font, so the extent of text is approximate.
+const textAdvance = 0.9
+
+// textInterline is the ratio of the line pitch to the font height used
+// by pcbnew.
+const textInterline = 1.61
+
+// textBox returns the estimated extent of text drawn at the given
+// position, laid out the way pcbnew lays out multi-line text, and
+// optionally rotated by the angle of the position.
+func textBox(text string, at XYZ, e TextEffects, rotate bool) bbox {
+	size := e.FontSize
+	if size == (XY{}) {
+		size = XY{X: 1, Y: 1}
+	}
+	lines := strings.Split(text, "\n")
+	var w float64
+	for _, l := range lines {
+		w = math.Max(w, float64(utf8.RuneCountInString(l))*textAdvance*size.X)
+	}
+	w += e.Thickness
+	interline := size.Y * textInterline
+	h := size.Y + float64(len(lines)-1)*interline
+
+	origin := XY{X: at.X, Y: at.Y}
+	switch e.Justify {
+	case JustifyLeft:
+	case JustifyRight, JustifyMirror:
+		origin.X -= w
+	default:
+		origin.X -= w / 2
+	}
+	switch e.Justify {
+	case JustifyTop:
+	case JustifyBottom:
+		origin.Y -= size.Y + e.Thickness + float64(len(lines)-1)*interline
+	default:
+		origin.Y -= (size.Y + e.Thickness + float64(len(lines)-1)*interline) / 2
+	}
+
+	var b bbox
+	for _, c := range []XY{origin, {X: origin.X + w, Y: origin.Y}, {X: origin.X, Y: origin.Y + h}, {X: origin.X + w, Y: origin.Y + h}} {
+		if rotate {
+			c = XY{X: c.X - at.X, Y: c.Y - at.Y}.Rotate(at.Z)
+			c = XY{X: at.X + c.X, Y: at.Y + c.Y}
+		}
+		b.add(XY{X: roundNM(c.X), Y: roundNM(c.Y)})
+	}
+	return b
+}
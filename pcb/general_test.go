@@ -0,0 +1,151 @@
+package pcb
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGeneralRecomputed(t *testing.T) {
+	p := EmptyPCB()
+	p.Thickness = 1.2
+	p.Nets[1] = Net{Name: "GND"}
+	p.Segments = append(p.Segments, &Track{Start: XY{X: 10, Y: 10}, End: XY{X: 20, Y: 10}, Width: 0.25, Layer: "F.Cu", NetIndex: 1})
+	p.Drawings = append(p.Drawings,
+		&Line{Start: XY{X: 0, Y: 0}, End: XY{X: 30, Y: 0}, Layer: "Edge.Cuts", Width: 0.05},
+		&Line{Start: XY{X: 30, Y: 0}, End: XY{X: 30, Y: 20}, Layer: "Edge.Cuts", Width: 0.05},
+	)
+	p.Modules = append(p.Modules, Module{Name: "R1", Layer: "F.Cu"})
+
+	var b bytes.Buffer
+	if err := p.Write(&b); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	want := "  (general\n    (thickness 1.2)\n    (drawings 2)\n    (tracks 1)\n    (zones 0)\n    (modules 1)\n    (nets 2)\n  )\n"
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("output does not contain general section %q:\n%s", want, b.String())
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	p := &PCB{
+		Drawings: []Drawing{
+			&Line{Start: XY{X: 0, Y: 0}, End: XY{X: 10, Y: 0}, Width: 0.15},
+		},
+		Modules: []Module{
+			{
+				Placement: ModPlacement{At: XYZ{X: 20, Y: 5, Z: 90}},
+				Pads: []Pad{
+					{At: XYZ{X: 2, Y: 0, Z: 90}, Size: XY{X: 1, Y: 2}, Shape: ShapeRect},
+				},
+			},
+		},
+	}
+	min, max, ok := p.BoundingBox()
+	if !ok {
+		t.Fatal("BoundingBox() returned ok = false")
+	}
+	if want := (XY{X: -0.075001, Y: -0.075001}); min != want {
+		t.Errorf("min = %+v, want %+v", min, want)
+	}
+	if want := (XY{X: 21, Y: 5.25}); max != want {
+		t.Errorf("max = %+v, want %+v", max, want)
+	}
+	// The pad is rotated with the module to sit above its position.
+	if b := p.Modules[0].boundingBox(); b.min != (XY{X: 19, Y: 2.5}) {
+		t.Errorf("module min = %+v, want {X:19 Y:2.5}", b.min)
+	}
+
+	if _, _, ok := (&PCB{}).BoundingBox(); ok {
+		t.Error("BoundingBox() of empty board returned ok = true")
+	}
+}
+
+func TestTextBoundingBox(t *testing.T) {
+	p := &PCB{
+		Drawings: []Drawing{
+			&Text{Text: "AB", At: XYZ{X: 10, Y: 10, Z: 90}, Effects: TextEffects{FontSize: XY{X: 1, Y: 1}, Thickness: 0.1}},
+		},
+	}
+	min, max, _ := p.BoundingBox()
+	if want := (XY{X: 9.45, Y: 9.05}); min != want {
+		t.Errorf("min = %+v, want %+v", min, want)
+	}
+	if want := (XY{X: 10.45, Y: 10.95}); max != want {
+		t.Errorf("max = %+v, want %+v", max, want)
+	}
+
+	// The text of a dimension is not rotated, and the crossbar counts
+	// towards its extent.
+	p.Drawings = []Drawing{
+		&Dimension{
+			Text: Text{Text: "AB", At: XYZ{X: 10, Y: 10, Z: 90}, Effects: TextEffects{FontSize: XY{X: 1, Y: 1}, Thickness: 0.1}},
+			Features: []DimensionFeature{
+				{Feature: "crossbar", Points: []XY{{X: 12, Y: 0}, {X: 12, Y: 20}}},
+				{Feature: "arrow1a", Points: []XY{{X: 12, Y: 0}, {X: 13, Y: 1}}},
+			},
+		},
+	}
+	min, max, _ = p.BoundingBox()
+	if want := (XY{X: 9.05, Y: 0}); min != want {
+		t.Errorf("dimension min = %+v, want %+v", min, want)
+	}
+	if want := (XY{X: 12, Y: 20}); max != want {
+		t.Errorf("dimension max = %+v, want %+v", max, want)
+	}
+}
+
+func TestGeneralMatchesPcbnew(t *testing.T) {
+	p, err := DecodeFile("testdata/cseduino-v4.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"drawings", "43"}, {"tracks", "129"}, {"zones", "0"}, {"modules", "22"}, {"nets", "31"}}
+	if got := p.generalCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("generalCounts() = %v, want %v", got, want)
+	}
+
+	// The extent of text is estimated, so the area only approximates the
+	// one written by pcbnew.
+	min, max, _ := p.BoundingBox()
+	for _, c := range []struct{ got, want float64 }{
+		{min.X, 19.940001}, {min.Y, 17.47}, {max.X, 87.538046}, {max.Y, 84.95},
+	} {
+		if math.Abs(c.got-c.want) > 1 {
+			t.Errorf("area (%v, %v, %v, %v) is not within 1mm of (19.940001 17.47 87.538046 84.95)", min.X, min.Y, max.X, max.Y)
+			break
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	tcs := []struct {
+		in   string
+		want Page
+	}{
+		{"(page A4)", Page{Size: "A4"}},
+		{"(page A3 portrait)", Page{Size: "A3", Portrait: true}},
+		{"(page User 200 150.5)", Page{Size: "User", Width: 200, Height: 150.5}},
+		{"(page A5)", Page{Size: "A5"}},
+		{"(page USLetterPlus 228.6 322.58 portrait)", Page{Size: "USLetterPlus", rest: "228.6 322.58 portrait"}},
+	}
+
+	for _, tc := range tcs {
+		p, err := Decode([]byte("(kicad_pcb (version 20171130) (host pcbnew 5.1.4) (general) (layers) " + tc.in + ")"))
+		if err != nil {
+			t.Fatalf("Decode(%q) failed: %v", tc.in, err)
+		}
+		if p.Page != tc.want {
+			t.Errorf("Decode(%q).Page = %+v, want %+v", tc.in, p.Page, tc.want)
+		}
+		var b bytes.Buffer
+		if err := p.Write(&b); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(b.String(), "\n  "+tc.in+"\n") {
+			t.Errorf("Write() output does not contain %q:\n%s", tc.in, b.String())
+		}
+	}
+}
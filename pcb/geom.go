@@ -0,0 +1,148 @@
+package pcb
+
+import "math"
+
+// Rotate returns p rotated around the origin by the given angle in
+// degrees, in the same direction pcbnew rotates footprints and pads:
+// positive angles are counter-clockwise when viewed with the Y axis
+// pointing down. The result is rounded to the nanometer, as pcbnew stores
+// positions.
+func (p XY) Rotate(deg float64) XY {
+	if deg == 0 {
+		return p
+	}
+	s, c := math.Sincos(deg * math.Pi / 180)
+	return XY{
+		X: roundNM(p.X*c + p.Y*s),
+		Y: roundNM(p.Y*c - p.X*s),
+	}
+}
+
+// Add returns the sum of p and q.
+func (p XY) Add(q XY) XY {
+	return XY{X: p.X + q.X, Y: p.Y + q.Y}
+}
+
+// ClosestOnSegment returns the point on the segment a-b closest to p.
+func ClosestOnSegment(p, a, b XY) XY {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	l2 := dx*dx + dy*dy
+	if l2 == 0 {
+		return a
+	}
+	t := math.Max(0, math.Min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/l2))
+	return XY{X: a.X + t*dx, Y: a.Y + t*dy}
+}
+
+// SegmentDistance returns the distance between the segments a-b and c-d,
+// and the point midway between their closest points.
+func SegmentDistance(a, b, c, d XY) (float64, XY) {
+	if p, ok := segmentIntersection(a, b, c, d); ok {
+		return 0, p
+	}
+	best, at := math.Inf(1), XY{}
+	try := func(p, q XY) {
+		if d := p.Distance(q); d < best {
+			best, at = d, XY{X: (p.X + q.X) / 2, Y: (p.Y + q.Y) / 2}
+		}
+	}
+	try(a, ClosestOnSegment(a, c, d))
+	try(b, ClosestOnSegment(b, c, d))
+	try(c, ClosestOnSegment(c, a, b))
+	try(d, ClosestOnSegment(d, a, b))
+	return best, at
+}
+
+// segmentIntersection returns the point where the segments a-b and c-d
+// cross, if they do.
+func segmentIntersection(a, b, c, d XY) (XY, bool) {
+	cross := func(o, p, q XY) float64 {
+		return (p.X-o.X)*(q.Y-o.Y) - (p.Y-o.Y)*(q.X-o.X)
+	}
+	d1, d2 := cross(c, d, a), cross(c, d, b)
+	d3, d4 := cross(a, b, c), cross(a, b, d)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		t := d1 / (d1 - d2)
+		return XY{X: a.X + t*(b.X-a.X), Y: a.Y + t*(b.Y-a.Y)}, true
+	}
+	return XY{}, false
+}
+
+// bbox accumulates an axis-aligned bounding box.
+type bbox struct {
+	min, max XY
+	ok       bool
+}
+
+func (b *bbox) add(p XY) {
+	if !b.ok {
+		b.min, b.max, b.ok = p, p, true
+		return
+	}
+	b.min.X = math.Min(b.min.X, p.X)
+	b.min.Y = math.Min(b.min.Y, p.Y)
+	b.max.X = math.Max(b.max.X, p.X)
+	b.max.Y = math.Max(b.max.Y, p.Y)
+}
+
+func (b *bbox) merge(o bbox) {
+	if o.ok {
+		b.add(o.min)
+		b.add(o.max)
+	}
+}
+
+func (b *bbox) inflate(d float64) {
+	if b.ok {
+		b.min = XY{X: b.min.X - d, Y: b.min.Y - d}
+		b.max = XY{X: b.max.X + d, Y: b.max.Y + d}
+	}
+}
+
+// strokeInflation returns how far pcbnew extends the bounding box of a
+// graphic drawn with the given line width: half the width rounded up to
+// the nearest nanometer, plus a nanometer.
+func strokeInflation(width float64) float64 {
+	nm := int64(math.Round(width * 1e6))
+	return float64((nm+1)/2+1) / 1e6
+}
+
+// segmentBox returns the bounding box of a line of the given width.
+func segmentBox(start, end XY, width float64) bbox {
+	var b bbox
+	b.add(start)
+	b.add(end)
+	b.inflate(strokeInflation(width))
+	return b
+}
+
+// arcBox returns the bounding box of an arc of the given width, centered
+// on center and sweeping angle degrees clockwise from start.
+func arcBox(center, start XY, angle, width float64) bbox {
+	var b bbox
+	r := center.Distance(start)
+	a0 := math.Atan2(start.Y-center.Y, start.X-center.X) * 180 / math.Pi
+	end := XY{X: start.X - center.X, Y: start.Y - center.Y}.Rotate(-angle)
+	b.add(start)
+	b.add(XY{X: center.X + end.X, Y: center.Y + end.Y})
+
+	// Include each axis crossing within the sweep.
+	lo, hi := a0, a0+angle
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	for q := math.Ceil(lo/90) * 90; q <= hi; q += 90 {
+		s, c := math.Sincos(q * math.Pi / 180)
+		b.add(XY{X: roundNM(center.X + r*c), Y: roundNM(center.Y + r*s)})
+	}
+	b.inflate(strokeInflation(width))
+	return b
+}
+
+// circleBox returns the bounding box of a circle of the given width.
+func circleBox(center XY, radius, width float64) bbox {
+	var b bbox
+	b.add(center)
+	b.inflate(radius + strokeInflation(width))
+	return b
+}
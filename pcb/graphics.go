@@ -2,9 +2,8 @@ package pcb
 
 import (
 	"crypto/sha256"
-	"fmt"
 
-	"github.com/nsf/sexp"
+	"github.com/twitchyliquid64/kcgen/sreader"
 	"go.starlark.net/starlark"
 )
 
@@ -78,6 +77,8 @@ type TextEffects struct {
 	FontSize  XY          `json:"size"`
 	Thickness float64     `json:"thickness"`
 	Justify   TextJustify `json:"justify"`
+	// Mirrored is set for text drawn as seen from the back of the board.
+	Mirrored bool `json:"mirrored,omitempty"`
 
 	Bold   bool `json:"bold"`
 	Italic bool `json:"italic"`
@@ -129,12 +130,12 @@ type DimensionFeature struct {
 	Points  []XY   `json:"points,omitempty"`
 }
 
-func parseDimension(n sexp.Helper, ordering int) (Dimension, error) {
+func parseDimension(n sreader.Node, ordering int) (Dimension, error) {
 	d := Dimension{
 		CurrentMeasurement: n.Child(1).MustFloat64(),
 		order:              ordering,
 	}
-	for x := 2; x < n.MustNode().NumChildren(); x++ {
+	for x := 2; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "width":
@@ -151,11 +152,11 @@ func parseDimension(n sexp.Helper, ordering int) (Dimension, error) {
 			f := DimensionFeature{
 				Feature: c.Child(0).MustString(),
 			}
-			for y := 1; y < c.MustNode().NumChildren(); y++ {
+			for y := 1; y < c.NumChildren(); y++ {
 				c := c.Child(y)
 				switch c.Child(0).MustString() {
 				case "pts":
-					for z := 1; z < c.MustNode().NumChildren(); z++ {
+					for z := 1; z < c.NumChildren(); z++ {
 						c := c.Child(z)
 						switch c.Child(0).MustString() {
 						case "xy":
@@ -171,18 +172,18 @@ func parseDimension(n sexp.Helper, ordering int) (Dimension, error) {
 	return d, nil
 }
 
-func parseGRText(n sexp.Helper, ordering int) (Text, error) {
+func parseGRText(n sreader.Node, ordering int) (Text, error) {
 	t := Text{
 		Text:  n.Child(1).MustString(),
 		order: ordering,
 	}
-	for x := 2; x < n.MustNode().NumChildren(); x++ {
+	for x := 2; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "at":
 			t.At.X = c.Child(1).MustFloat64()
 			t.At.Y = c.Child(2).MustFloat64()
-			if c.MustNode().NumChildren() >= 4 {
+			if c.NumChildren() >= 4 {
 				if f, err := c.Child(3).Float64(); err == nil {
 					t.At.Z = f
 					t.At.ZPresent = true
@@ -207,23 +208,23 @@ func parseGRText(n sexp.Helper, ordering int) (Text, error) {
 	return t, nil
 }
 
-func parseTextEffects(n sexp.Helper) (TextEffects, error) {
+func parseTextEffects(n sreader.Node) (TextEffects, error) {
 	var e TextEffects
-	for y := 1; y < n.MustNode().NumChildren(); y++ {
+	for y := 1; y < n.NumChildren(); y++ {
 		c := n.Child(y)
 		switch c.Child(0).MustString() {
 		case "font":
-			for z := 1; z < c.MustNode().NumChildren(); z++ {
+			for z := 1; z < c.NumChildren(); z++ {
 				c := c.Child(z)
 
 				if c.IsScalar() {
-					switch c.MustNode().Value {
+					switch c.Value() {
 					case "italic":
 						e.Italic = true
 					case "bold":
 						e.Bold = true
 					default:
-						return TextEffects{}, fmt.Errorf("unhandled scalar in text effects: %v", c.MustNode().Value)
+						return TextEffects{}, c.Errorf("unhandled scalar in text effects: %v", c.Value())
 					}
 					continue
 				}
@@ -237,28 +238,30 @@ func parseTextEffects(n sexp.Helper) (TextEffects, error) {
 				}
 			}
 		case "justify":
-			switch c.Child(1).MustString() {
-			case "mirror":
-				e.Justify = JustifyMirror
-			case "top":
-				e.Justify = JustifyTop
-			case "bottom":
-				e.Justify = JustifyBottom
-			case "left":
-				e.Justify = JustifyLeft
-			case "right":
-				e.Justify = JustifyRight
-			default:
-				return TextEffects{}, fmt.Errorf("unknown justify value: %q", c.Child(1).MustString())
+			for z := 1; z < c.NumChildren(); z++ {
+				switch c.Child(z).MustString() {
+				case "mirror":
+					e.Mirrored = true
+				case "top":
+					e.Justify = JustifyTop
+				case "bottom":
+					e.Justify = JustifyBottom
+				case "left":
+					e.Justify = JustifyLeft
+				case "right":
+					e.Justify = JustifyRight
+				default:
+					return TextEffects{}, c.Child(z).Errorf("unknown justify value: %q", c.Child(z).MustString())
+				}
 			}
 		}
 	}
 	return e, nil
 }
 
-func parseGRLine(n sexp.Helper, ordering int) (Line, error) {
+func parseGRLine(n sreader.Node, ordering int) (Line, error) {
 	l := Line{order: ordering}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "start":
@@ -280,9 +283,9 @@ func parseGRLine(n sexp.Helper, ordering int) (Line, error) {
 	return l, nil
 }
 
-func parseGRArc(n sexp.Helper, ordering int) (Arc, error) {
+func parseGRArc(n sreader.Node, ordering int) (Arc, error) {
 	l := Arc{order: ordering}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "start":
@@ -0,0 +1,128 @@
+package library
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// pitchTolerance is how far the pitch of a footprint may be from the
+// queried pitch, in millimeters.
+const pitchTolerance = 0.005
+
+// Query describes the footprints to be returned by a search. Criteria
+// which are left unset match every footprint.
+type Query struct {
+	// Keywords must each appear in the name, description or tags of the
+	// footprint. Matching is case-insensitive.
+	Keywords []string
+	// Tags must each be a tag of the footprint.
+	Tags []string
+	// Pads is the number of numbered pads.
+	Pads int
+	// Pitch is the smallest pad pitch, in millimeters.
+	Pitch float64
+}
+
+// Index is an in-memory index of footprints from one or more libraries.
+type Index struct {
+	entries []Entry
+	// text holds the lowercased searchable text of each entry.
+	text []string
+	tags map[string][]int
+}
+
+// NewIndex indexes the footprints in the given libraries.
+func NewIndex(libs ...*Library) *Index {
+	ix := &Index{tags: map[string][]int{}}
+	for _, l := range libs {
+		ix.Add(l.Footprints()...)
+	}
+	return ix
+}
+
+// Add adds footprint summaries to the index. Footprints which could not
+// be summarized are skipped.
+func (ix *Index) Add(entries ...Entry) {
+	for _, e := range entries {
+		if e.Error != "" {
+			continue
+		}
+		i := len(ix.entries)
+		ix.entries = append(ix.entries, e)
+		ix.text = append(ix.text, strings.ToLower(e.ID()+" "+e.Description+" "+strings.Join(e.Tags, " ")))
+		for t := range lowerSet(e.Tags) {
+			ix.tags[t] = append(ix.tags[t], i)
+		}
+	}
+}
+
+// Len returns the number of footprints in the index.
+func (ix *Index) Len() int {
+	return len(ix.entries)
+}
+
+// Search returns the footprints matching the query, sorted by identifier.
+func (ix *Index) Search(q Query) []Entry {
+	candidates := ix.candidates(q.Tags)
+	var out []Entry
+	for _, i := range candidates {
+		e := ix.entries[i]
+		if q.Pads != 0 && e.Pads != q.Pads {
+			continue
+		}
+		if q.Pitch != 0 && math.Abs(e.Pitch-q.Pitch) > pitchTolerance {
+			continue
+		}
+		if !containsAll(ix.text[i], q.Keywords) {
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID() < out[j].ID() })
+	return out
+}
+
+// candidates returns the indices of entries with all the given tags.
+func (ix *Index) candidates(tags []string) []int {
+	if len(tags) == 0 {
+		out := make([]int, len(ix.entries))
+		for i := range out {
+			out[i] = i
+		}
+		return out
+	}
+
+	want := lowerSet(tags)
+	counts := map[int]int{}
+	for t := range want {
+		for _, i := range ix.tags[t] {
+			counts[i]++
+		}
+	}
+	var out []int
+	for i, n := range counts {
+		if n == len(want) {
+			out = append(out, i)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+func containsAll(text string, keywords []string) bool {
+	for _, k := range keywords {
+		if !strings.Contains(text, strings.ToLower(k)) {
+			return false
+		}
+	}
+	return true
+}
+
+func lowerSet(in []string) map[string]bool {
+	out := make(map[string]bool, len(in))
+	for _, s := range in {
+		out[strings.ToLower(s)] = true
+	}
+	return out
+}
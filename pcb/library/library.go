@@ -0,0 +1,235 @@
+// Package library manages KiCad footprint libraries: .pretty directories
+// containing a .kicad_mod file for each footprint.
+package library
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Ext is the extension of footprint files.
+const Ext = ".kicad_mod"
+
+// Entry summarizes a footprint in a library.
+type Entry struct {
+	Name        string   `json:"name"`
+	Library     string   `json:"library"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Pads is the number of distinct pad numbers. Unnumbered pads, such as
+	// mounting holes, are not counted.
+	Pads int `json:"pads"`
+	// Pitch is the smallest distance between the centers of pads with
+	// different numbers, in millimeters. It is zero if there are fewer
+	// than two numbered pads.
+	Pitch float64 `json:"pitch,omitempty"`
+	// Error describes why the footprint could not be summarized, such as
+	// a malformed file. The other fields are then unset.
+	Error string `json:"error,omitempty"`
+}
+
+// ID returns the library identifier of the footprint, such as
+// Resistor_SMD:R_0805_2012Metric.
+func (e *Entry) ID() string {
+	return e.Library + ":" + e.Name
+}
+
+// Library is a footprint library stored in a .pretty directory.
+type Library struct {
+	// Name is the nickname of the library. It defaults to the name of
+	// the directory without the .pretty extension.
+	Name string
+	Path string
+
+	entries map[string]Entry
+}
+
+// Open reads the footprint summaries of the library at path. Footprints
+// which cannot be read are listed with their Error set.
+func Open(path string) (*Library, error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &Library{
+		Name:    strings.TrimSuffix(filepath.Base(path), ".pretty"),
+		Path:    path,
+		entries: map[string]Entry{},
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), Ext) {
+			continue
+		}
+		l.scan(strings.TrimSuffix(f.Name(), Ext))
+	}
+	return l, nil
+}
+
+// Create makes an empty library at path. The directory must not exist.
+func Create(path string) (*Library, error) {
+	if err := os.Mkdir(path, 0755); err != nil {
+		return nil, err
+	}
+	return Open(path)
+}
+
+func (l *Library) fpath(name string) string {
+	return filepath.Join(l.Path, name+Ext)
+}
+
+// scan reads the summary of the named footprint into the index. If the
+// footprint cannot be read, it is indexed with the error, which is also
+// returned.
+func (l *Library) scan(name string) error {
+	e, err := l.summarize(name)
+	if err != nil {
+		e = Entry{Name: name, Error: err.Error()}
+	}
+	l.entries[name] = e
+	return err
+}
+
+func (l *Library) summarize(name string) (Entry, error) {
+	d, err := ioutil.ReadFile(l.fpath(name))
+	if err != nil {
+		return Entry{}, err
+	}
+	e, err := scanFootprint(d)
+	if err != nil {
+		return Entry{}, fmt.Errorf("%s: %v", l.fpath(name), err)
+	}
+	// pcbnew identifies footprints by file name, which takes precedence
+	// over the name inside the file.
+	e.Name = name
+	return e, nil
+}
+
+// Len returns the number of footprints in the library.
+func (l *Library) Len() int {
+	return len(l.entries)
+}
+
+// Footprints returns the summaries of the footprints in the library,
+// sorted by name.
+func (l *Library) Footprints() []Entry {
+	out := make([]Entry, 0, len(l.entries))
+	for _, e := range l.entries {
+		e.Library = l.Name
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Entry returns the summary of the named footprint.
+func (l *Library) Entry(name string) (Entry, bool) {
+	e, ok := l.entries[name]
+	e.Library = l.Name
+	return e, ok
+}
+
+// Load fully parses the named footprint.
+func (l *Library) Load(name string) (*pcb.Module, error) {
+	if _, ok := l.entries[name]; !ok {
+		return nil, fmt.Errorf("footprint %s:%s not found", l.Name, name)
+	}
+	return pcb.DecodeModuleFile(l.fpath(name))
+}
+
+// Add writes a new footprint to the library. The footprint is named after
+// the module, without any library nickname.
+func (l *Library) Add(m *pcb.Module) error {
+	name, err := footprintName(m.Name)
+	if err != nil {
+		return err
+	}
+	if _, ok := l.entries[name]; ok {
+		return fmt.Errorf("footprint %s:%s already exists", l.Name, name)
+	}
+	return l.write(name, m)
+}
+
+// Replace overwrites an existing footprint with the module.
+func (l *Library) Replace(m *pcb.Module) error {
+	name, err := footprintName(m.Name)
+	if err != nil {
+		return err
+	}
+	if _, ok := l.entries[name]; !ok {
+		return fmt.Errorf("footprint %s:%s not found", l.Name, name)
+	}
+	return l.write(name, m)
+}
+
+// Remove deletes the named footprint from the library.
+func (l *Library) Remove(name string) error {
+	if _, ok := l.entries[name]; !ok {
+		return fmt.Errorf("footprint %s:%s not found", l.Name, name)
+	}
+	if err := os.Remove(l.fpath(name)); err != nil {
+		return err
+	}
+	delete(l.entries, name)
+	return nil
+}
+
+// Rename changes the name of a footprint, updating the name stored in the
+// file to match.
+func (l *Library) Rename(from, to string) error {
+	if _, err := footprintName(to); err != nil {
+		return err
+	}
+	if _, ok := l.entries[to]; ok {
+		return fmt.Errorf("footprint %s:%s already exists", l.Name, to)
+	}
+	m, err := l.Load(from)
+	if err != nil {
+		return err
+	}
+	m.Name = to
+	if err := l.write(to, m); err != nil {
+		return err
+	}
+	return l.Remove(from)
+}
+
+// Search returns the footprints in the library matching the query.
+func (l *Library) Search(q Query) []Entry {
+	return NewIndex(l).Search(q)
+}
+
+func (l *Library) write(name string, m *pcb.Module) error {
+	saved := m.Name
+	m.Name = name
+	var buf bytes.Buffer
+	err := m.WriteModule(&buf)
+	m.Name = saved
+	if err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+
+	if err := ioutil.WriteFile(l.fpath(name), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return l.scan(name)
+}
+
+// footprintName returns the footprint name of a module name, which may
+// include a library nickname.
+func footprintName(name string) (string, error) {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid footprint name %q", name)
+	}
+	return name, nil
+}
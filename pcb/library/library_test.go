@@ -0,0 +1,223 @@
+package library
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// copyLibrary copies the test library into a temporary directory.
+func copyLibrary(t *testing.T) (string, func()) {
+	t.Helper()
+	tmp, err := ioutil.TempDir("", "kcgen-library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(tmp, "Test.pretty")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir("testdata/Test.pretty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		d, err := ioutil.ReadFile(filepath.Join("testdata/Test.pretty", f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, f.Name()), d, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, func() { os.RemoveAll(tmp) }
+}
+
+func TestOpen(t *testing.T) {
+	l, err := Open("testdata/Test.pretty")
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if l.Name != "Test" {
+		t.Errorf("Name = %q, want Test", l.Name)
+	}
+
+	want := []Entry{
+		{Name: "MountingHole_3.2mm_M3", Library: "Test", Description: "Mounting Hole 3.2mm, no annular, M3", Tags: []string{"mounting", "hole", "3.2mm", "no", "annular", "m3"}},
+		{Name: "R_0805", Library: "Test", Description: "Resistor SMD 0805 (2012 Metric), square (rectangular) end terminal", Tags: []string{"resistor"}, Pads: 2, Pitch: 1.875},
+		{Name: "SOIC-8_3.9x4.9mm_P1.27mm", Library: "Test", Description: "SOIC, 8 Pin (JEDEC MS-012AA, https://www.analog.com/media/en/package-pcb-resources/package/pkg_pdf/soic_narrow-r/r_8.pdf), generated with kicad-footprint-generator", Tags: []string{"SOIC", "SO"}, Pads: 8, Pitch: 1.27},
+	}
+	if got := l.Footprints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Footprints() = %+v, want %+v", got, want)
+	}
+
+	// The summary must agree with a full parse.
+	m, err := l.Load("SOIC-8_3.9x4.9mm_P1.27mm")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(m.Pads) != 8 || m.Description != want[2].Description {
+		t.Errorf("Load() = %d pads, description %q", len(m.Pads), m.Description)
+	}
+	if _, err := l.Load("Missing"); err == nil {
+		t.Error("Load() of a missing footprint succeeded")
+	}
+}
+
+func TestOpenBrokenFootprint(t *testing.T) {
+	dir, cleanup := copyLibrary(t)
+	defer cleanup()
+	if err := ioutil.WriteFile(filepath.Join(dir, "Broken"+Ext), []byte("(module Broken (layer F.Cu)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	fps := l.Footprints()
+	if len(fps) != 4 {
+		t.Fatalf("Footprints() returned %d entries, want 4", len(fps))
+	}
+	if e := fps[0]; e.Name != "Broken" || e.Error == "" {
+		t.Errorf("Footprints()[0] = %+v, want Broken with an error", e)
+	}
+	for _, e := range fps[1:] {
+		if e.Error != "" {
+			t.Errorf("%s: unexpected error %q", e.Name, e.Error)
+		}
+	}
+	if res := NewIndex(l).Search(Query{Keywords: []string{"broken"}}); len(res) != 0 {
+		t.Errorf("Search() = %+v, want no results", res)
+	}
+	if _, err := l.Load("Broken"); err == nil {
+		t.Error("Load() of a broken footprint succeeded")
+	}
+}
+
+func TestScanFootprint(t *testing.T) {
+	e, err := scanFootprint([]byte(`(module "Quoted \"name\"" locked (layer F.Cu)
+  (descr "a \"quoted\" (description)")
+  (pad 1 thru_hole circle (at 0 0 90) (size 1.7 1.7) (drill 1) (layers *.Cu *.Mask))
+  (pad 1 thru_hole circle (at 0 5) (size 1.7 1.7) (drill 1) (layers *.Cu *.Mask))
+  (pad 2 thru_hole circle (at 2.54 0) (size 1.7 1.7) (drill 1) (layers *.Cu *.Mask)))`))
+	if err != nil {
+		t.Fatalf("scanFootprint() failed: %v", err)
+	}
+	want := Entry{Name: `Quoted "name"`, Description: `a "quoted" (description)`, Pads: 2, Pitch: 2.54}
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("scanFootprint() = %+v, want %+v", e, want)
+	}
+
+	for _, bad := range []string{"", "(kicad_pcb)", "(module x (layer F.Cu)", `(module x (descr "unterminated))`} {
+		if _, err := scanFootprint([]byte(bad)); err == nil {
+			t.Errorf("scanFootprint(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	dir, cleanup := copyLibrary(t)
+	defer cleanup()
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := l.Load("R_0805")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Name = "Resistor_SMD:R_0603"
+	m.Description = "Resistor SMD 0603"
+	if err := l.Add(m); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := l.Add(m); err == nil {
+		t.Error("Add() of an existing footprint succeeded")
+	}
+	if e, ok := l.Entry("R_0603"); !ok || e.Description != "Resistor SMD 0603" || e.Pads != 2 {
+		t.Errorf("Entry(R_0603) = %+v, %v", e, ok)
+	}
+
+	m.Description = "Replaced"
+	if err := l.Replace(m); err != nil {
+		t.Fatalf("Replace() failed: %v", err)
+	}
+	m.Name = "R_0402"
+	if err := l.Replace(m); err == nil {
+		t.Error("Replace() of a missing footprint succeeded")
+	}
+
+	if err := l.Rename("R_0603", "R_0603_1608Metric"); err != nil {
+		t.Fatalf("Rename() failed: %v", err)
+	}
+	if err := l.Rename("R_0805", "R_0603_1608Metric"); err == nil {
+		t.Error("Rename() over an existing footprint succeeded")
+	}
+	if err := l.Remove("MountingHole_3.2mm_M3"); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+
+	// Reopen to check the index matches the files.
+	l, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range l.Footprints() {
+		names = append(names, e.Name)
+	}
+	if want := []string{"R_0603_1608Metric", "R_0805", "SOIC-8_3.9x4.9mm_P1.27mm"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("footprints = %v, want %v", names, want)
+	}
+	renamed, err := l.Load("R_0603_1608Metric")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Name != "R_0603_1608Metric" || renamed.Description != "Replaced" {
+		t.Errorf("renamed footprint = %q (%q)", renamed.Name, renamed.Description)
+	}
+
+	if err := l.Add(&pcb.Module{Name: "../escape"}); err == nil {
+		t.Error("Add() accepted a name containing a path separator")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	l, err := Open("testdata/Test.pretty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix := NewIndex(l)
+
+	tcs := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{name: "all", q: Query{}, want: []string{"Test:MountingHole_3.2mm_M3", "Test:R_0805", "Test:SOIC-8_3.9x4.9mm_P1.27mm"}},
+		{name: "keyword", q: Query{Keywords: []string{"jedec"}}, want: []string{"Test:SOIC-8_3.9x4.9mm_P1.27mm"}},
+		{name: "keywords", q: Query{Keywords: []string{"smd", "0805"}}, want: []string{"Test:R_0805"}},
+		{name: "tag", q: Query{Tags: []string{"soic"}}, want: []string{"Test:SOIC-8_3.9x4.9mm_P1.27mm"}},
+		{name: "tags", q: Query{Tags: []string{"SOIC", "resistor"}}},
+		{name: "pads", q: Query{Pads: 2}, want: []string{"Test:R_0805"}},
+		{name: "pitch", q: Query{Pitch: 1.27}, want: []string{"Test:SOIC-8_3.9x4.9mm_P1.27mm"}},
+		{name: "pitch mismatch", q: Query{Pitch: 1.27, Pads: 2}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, e := range ix.Search(tc.q) {
+				got = append(got, e.ID())
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Search(%+v) = %v, want %v", tc.q, got, tc.want)
+			}
+		})
+	}
+}
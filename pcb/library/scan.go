@@ -0,0 +1,239 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokOpen
+	tokClose
+	tokAtom
+)
+
+// scanner tokenizes s-expressions, without building a tree.
+type scanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *scanner) next() (tokenKind, string, error) {
+	for s.pos < len(s.data) && isSpace(s.data[s.pos]) {
+		s.pos++
+	}
+	if s.pos >= len(s.data) {
+		return tokEOF, "", nil
+	}
+
+	switch c := s.data[s.pos]; c {
+	case '(':
+		s.pos++
+		return tokOpen, "", nil
+	case ')':
+		s.pos++
+		return tokClose, "", nil
+	case '"':
+		var b strings.Builder
+		for s.pos++; s.pos < len(s.data); s.pos++ {
+			switch c := s.data[s.pos]; c {
+			case '\\':
+				if s.pos++; s.pos < len(s.data) {
+					b.WriteByte(s.data[s.pos])
+				}
+			case '"':
+				s.pos++
+				return tokAtom, b.String(), nil
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return tokEOF, "", errors.New("unterminated string")
+	}
+
+	start := s.pos
+	for s.pos < len(s.data) && !isSpace(s.data[s.pos]) && s.data[s.pos] != '(' && s.data[s.pos] != ')' {
+		s.pos++
+	}
+	return tokAtom, string(s.data[start:s.pos]), nil
+}
+
+// skip consumes tokens up to and including the end of the current list.
+func (s *scanner) skip() error {
+	for depth := 1; depth > 0; {
+		k, _, err := s.next()
+		if err != nil {
+			return err
+		}
+		switch k {
+		case tokEOF:
+			return errors.New("unexpected end of file")
+		case tokOpen:
+			depth++
+		case tokClose:
+			depth--
+		}
+	}
+	return nil
+}
+
+// atom reads a token which must be an atom.
+func (s *scanner) atom() (string, error) {
+	k, v, err := s.next()
+	if err != nil {
+		return "", err
+	}
+	if k != tokAtom {
+		return "", fmt.Errorf("expected a value at offset %d", s.pos)
+	}
+	return v, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+type scannedPad struct {
+	num  string
+	x, y float64
+}
+
+// scanFootprint reads the summary of a footprint from the contents of its
+// .kicad_mod file. Only the elements needed for the summary are examined,
+// which is much faster than parsing the whole module.
+func scanFootprint(data []byte) (Entry, error) {
+	s := scanner{data: data}
+	if k, _, err := s.next(); err != nil || k != tokOpen {
+		return Entry{}, errors.New("invalid format: expected a module")
+	}
+	if head, err := s.atom(); err != nil || (head != "module" && head != "footprint") {
+		return Entry{}, errors.New("invalid format: missing leading element module")
+	}
+	name, err := s.atom()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	e := Entry{Name: name}
+	var pads []scannedPad
+	for {
+		k, _, err := s.next()
+		if err != nil {
+			return Entry{}, err
+		}
+		switch k {
+		case tokEOF:
+			return Entry{}, errors.New("unexpected end of file")
+		case tokClose:
+			e.Pads, e.Pitch = summarizePads(pads)
+			return e, nil
+		case tokAtom:
+			continue
+		}
+
+		head, err := s.atom()
+		if err != nil {
+			return Entry{}, err
+		}
+		switch head {
+		case "descr":
+			if e.Description, err = s.atom(); err != nil {
+				return Entry{}, err
+			}
+		case "tags":
+			tags, err := s.atom()
+			if err != nil {
+				return Entry{}, err
+			}
+			e.Tags = strings.Fields(tags)
+		case "pad":
+			p, err := s.scanPad()
+			if err != nil {
+				return Entry{}, err
+			}
+			pads = append(pads, p)
+			continue
+		}
+		if err := s.skip(); err != nil {
+			return Entry{}, err
+		}
+	}
+}
+
+// scanPad reads the number and position of a pad, consuming the rest of it.
+func (s *scanner) scanPad() (scannedPad, error) {
+	num, err := s.atom()
+	if err != nil {
+		return scannedPad{}, err
+	}
+	p := scannedPad{num: num}
+	for {
+		k, _, err := s.next()
+		if err != nil {
+			return p, err
+		}
+		switch k {
+		case tokEOF:
+			return p, errors.New("unexpected end of file")
+		case tokClose:
+			return p, nil
+		case tokAtom:
+			continue
+		}
+
+		if head, err := s.atom(); err != nil {
+			return p, err
+		} else if head == "at" {
+			x, err := s.atom()
+			if err != nil {
+				return p, err
+			}
+			y, err := s.atom()
+			if err != nil {
+				return p, err
+			}
+			if p.x, err = strconv.ParseFloat(x, 64); err != nil {
+				return p, err
+			}
+			if p.y, err = strconv.ParseFloat(y, 64); err != nil {
+				return p, err
+			}
+		}
+		if err := s.skip(); err != nil {
+			return p, err
+		}
+	}
+}
+
+// summarizePads returns the number of distinct numbered pads, and the
+// smallest distance between pads with different numbers.
+func summarizePads(pads []scannedPad) (count int, pitch float64) {
+	nums := map[string]bool{}
+	for _, p := range pads {
+		if p.num != "" && !nums[p.num] {
+			nums[p.num] = true
+			count++
+		}
+	}
+
+	pitch = math.Inf(1)
+	for i, a := range pads {
+		for _, b := range pads[i+1:] {
+			if a.num == "" || b.num == "" || a.num == b.num {
+				continue
+			}
+			if d := math.Hypot(a.x-b.x, a.y-b.y); d > 0 && d < pitch {
+				pitch = d
+			}
+		}
+	}
+	if math.IsInf(pitch, 1) {
+		return count, 0
+	}
+	return count, math.Round(pitch*1e4) / 1e4
+}
@@ -0,0 +1,221 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"go.starlark.net/starlark"
+)
+
+// MakeLibrary opens a library from starlark.
+var MakeLibrary = starlark.NewBuiltin("open", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path starlark.String
+	if err := starlark.UnpackArgs("open", args, kwargs, "path", &path); err != nil {
+		return starlark.None, err
+	}
+	return Open(string(path))
+})
+
+// String implements starlark.Value.
+func (l *Library) String() string {
+	return fmt.Sprintf("Library{%s, %s, %d footprints}", l.Name, l.Path, len(l.entries))
+}
+
+// Type implements starlark.Value.
+func (l *Library) Type() string {
+	return "Library"
+}
+
+// Freeze implements starlark.Value.
+func (l *Library) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (l *Library) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (l *Library) Hash() (uint32, error) {
+	h := fnv.New32a()
+	h.Write([]byte(l.Path))
+	return h.Sum32(), nil
+}
+
+// Attr implements starlark.Value.
+func (l *Library) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "name":
+		return starlark.String(l.Name), nil
+	case "path":
+		return starlark.String(l.Path), nil
+	case "footprints":
+		return entryList(l.Footprints()), nil
+	}
+
+	if m, ok := libraryMethods[name]; ok {
+		return m.BindReceiver(l), nil
+	}
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", l.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (l *Library) AttrNames() []string {
+	return []string{"name", "path", "footprints",
+		"load_mod", "add", "replace", "remove", "rename", "search"}
+}
+
+// SetField implements starlark.HasSetField.
+func (l *Library) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "name":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to name using type %T", val)
+		}
+		l.Name = string(v)
+		return nil
+	}
+	return errors.New("no such assignable field: " + name)
+}
+
+func entryList(entries []Entry) *starlark.List {
+	l := starlark.NewList(nil)
+	for i := range entries {
+		l.Append(&entries[i])
+	}
+	return l
+}
+
+// libraryMethods are the methods of a Library value.
+var libraryMethods = map[string]*starlark.Builtin{
+	"load_mod": starlark.NewBuiltin("load_mod", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var name starlark.String
+		if err := starlark.UnpackArgs("load_mod", args, kwargs, "name", &name); err != nil {
+			return starlark.None, err
+		}
+		return f.Receiver().(*Library).Load(string(name))
+	}),
+	"add": starlark.NewBuiltin("add", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var m *pcb.Module
+		if err := starlark.UnpackArgs("add", args, kwargs, "mod", &m); err != nil {
+			return starlark.None, err
+		}
+		return starlark.None, f.Receiver().(*Library).Add(m)
+	}),
+	"replace": starlark.NewBuiltin("replace", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var m *pcb.Module
+		if err := starlark.UnpackArgs("replace", args, kwargs, "mod", &m); err != nil {
+			return starlark.None, err
+		}
+		return starlark.None, f.Receiver().(*Library).Replace(m)
+	}),
+	"remove": starlark.NewBuiltin("remove", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var name starlark.String
+		if err := starlark.UnpackArgs("remove", args, kwargs, "name", &name); err != nil {
+			return starlark.None, err
+		}
+		return starlark.None, f.Receiver().(*Library).Remove(string(name))
+	}),
+	"rename": starlark.NewBuiltin("rename", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var from, to starlark.String
+		if err := starlark.UnpackArgs("rename", args, kwargs, "from", &from, "to", &to); err != nil {
+			return starlark.None, err
+		}
+		return starlark.None, f.Receiver().(*Library).Rename(string(from), string(to))
+	}),
+	"search": starlark.NewBuiltin("search", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var (
+			keywords starlark.String
+			tags     *starlark.List
+			pads     starlark.Int
+			pitch    starlark.Value = starlark.Float(0)
+		)
+		if err := starlark.UnpackArgs("search", args, kwargs, "keywords?", &keywords, "tags?", &tags, "pads?", &pads, "pitch?", &pitch); err != nil {
+			return starlark.None, err
+		}
+		q := Query{Keywords: strings.Fields(string(keywords))}
+		if tags != nil {
+			for i := 0; i < tags.Len(); i++ {
+				s, ok := tags.Index(i).(starlark.String)
+				if !ok {
+					return starlark.None, fmt.Errorf("search: tags must be strings, got %s", tags.Index(i).Type())
+				}
+				q.Tags = append(q.Tags, string(s))
+			}
+		}
+		n, ok := pads.Int64()
+		if !ok {
+			return starlark.None, fmt.Errorf("search: pads out of range")
+		}
+		q.Pads = int(n)
+		p, ok := starlark.AsFloat(pitch)
+		if !ok {
+			return starlark.None, fmt.Errorf("search: pitch must be a number, got %s", pitch.Type())
+		}
+		q.Pitch = p
+		return entryList(f.Receiver().(*Library).Search(q)), nil
+	}),
+}
+
+// String implements starlark.Value.
+func (e *Entry) String() string {
+	return fmt.Sprintf("Entry{%s, %d pads}", e.ID(), e.Pads)
+}
+
+// Type implements starlark.Value.
+func (e *Entry) Type() string {
+	return "LibraryEntry"
+}
+
+// Freeze implements starlark.Value.
+func (e *Entry) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (e *Entry) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (e *Entry) Hash() (uint32, error) {
+	return starlark.String(e.ID()).Hash()
+}
+
+// Attr implements starlark.Value.
+func (e *Entry) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "name":
+		return starlark.String(e.Name), nil
+	case "library":
+		return starlark.String(e.Library), nil
+	case "id":
+		return starlark.String(e.ID()), nil
+	case "description":
+		return starlark.String(e.Description), nil
+	case "tags":
+		l := make([]starlark.Value, len(e.Tags))
+		for i, t := range e.Tags {
+			l[i] = starlark.String(t)
+		}
+		return starlark.NewList(l), nil
+	case "pads":
+		return starlark.MakeInt(e.Pads), nil
+	case "pitch":
+		return starlark.Float(e.Pitch), nil
+	case "error":
+		if e.Error == "" {
+			return starlark.None, nil
+		}
+		return starlark.String(e.Error), nil
+	}
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", e.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (e *Entry) AttrNames() []string {
+	return []string{"name", "library", "id", "description", "tags", "pads", "pitch", "error"}
+}
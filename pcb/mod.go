@@ -5,11 +5,13 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 
-	"github.com/nsf/sexp"
+	"github.com/twitchyliquid64/kcgen/sreader"
 	"github.com/twitchyliquid64/kcgen/swriter"
 	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
 )
 
 // Module describes a KiCad module.
@@ -42,6 +44,17 @@ type Module struct {
 	Models   []ModModel   `json:"models,omitempty"`
 }
 
+// Reference returns the text of the module's reference designator, such
+// as R1, or the empty string if it has none.
+func (m *Module) Reference() string {
+	for _, g := range m.Graphics {
+		if t, ok := g.Renderable.(*ModText); ok && t.Kind == RefText {
+			return t.Text
+		}
+	}
+	return ""
+}
+
 // ModPlacement describes the positioning of a module on a PCB.
 type ModPlacement struct {
 	At XYZ `json:"position"`
@@ -197,6 +210,17 @@ func (p *PadSurface) Hash() (uint32, error) {
 	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
 }
 
+// CompareSameType implements starlark.Comparable.
+func (p *PadSurface) CompareSameType(op syntax.Token, y starlark.Value, depth int) (bool, error) {
+	switch op {
+	case syntax.EQL:
+		return *p == *y.(*PadSurface), nil
+	case syntax.NEQ:
+		return *p != *y.(*PadSurface), nil
+	}
+	return false, fmt.Errorf("%s %s %s not implemented", p.Type(), op, y.Type())
+}
+
 type PadShape uint8
 
 func (s PadShape) String() string {
@@ -245,6 +269,17 @@ func (p *PadShape) Hash() (uint32, error) {
 	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
 }
 
+// CompareSameType implements starlark.Comparable.
+func (p *PadShape) CompareSameType(op syntax.Token, y starlark.Value, depth int) (bool, error) {
+	switch op {
+	case syntax.EQL:
+		return *p == *y.(*PadShape), nil
+	case syntax.NEQ:
+		return *p != *y.(*PadShape), nil
+	}
+	return false, fmt.Errorf("%s %s %s not implemented", p.Type(), op, y.Type())
+}
+
 // Pad constants
 const (
 	ShapeInvalid PadShape = iota
@@ -304,30 +339,66 @@ type PadOptions struct {
 	Anchor    string `json:"anchor"`
 }
 
+// ParseModule decodes a module in kicad_mod format. Malformed input is
+// reported as a *ParseError.
 func ParseModule(r io.RuneReader) (*Module, error) {
-	ast, err := sexp.Parse(r, nil)
+	var b strings.Builder
+	for {
+		c, _, err := r.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		b.WriteRune(c)
+	}
+	return decodeModule([]byte(b.String()), "")
+}
+
+// DecodeModuleFile reads a .kicad_mod file at fpath, returning a parsed
+// representation.
+func DecodeModuleFile(fpath string) (*Module, error) {
+	f, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return decodeModule(f, fpath)
+}
+
+func decodeModule(f []byte, fname string) (m *Module, err error) {
+	ast, err := sreader.Parse(f, fname)
 	if err != nil {
 		return nil, err
 	}
-	return parseModule(sexp.Help(ast).Child(0), 0)
+	n := ast.Child(0)
+	if n.Name() != "module" {
+		return nil, n.Errorf("invalid format: missing leading element module")
+	}
+
+	defer sreader.Recover(&err)
+	if m, err = parseModule(n, 0); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func parseModule(n sexp.Helper, ordering int) (*Module, error) {
+func parseModule(n sreader.Node, ordering int) (*Module, error) {
 	m := Module{
 		Name:        n.Child(1).MustString(),
 		ZoneConnect: ZoneConnectInherited,
 		order:       ordering,
 	}
-	for x := 2; x < n.MustNode().NumChildren(); x++ {
+	for x := 2; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		if c.IsScalar() {
-			switch c.MustNode().Value {
+			switch c.Value() {
 			case "locked":
 				m.Locked = true
 			case "placed":
 				m.Placed = true
 			default:
-				return nil, fmt.Errorf("unknown scalar value in module: %v", c.MustNode().Value)
+				return nil, c.Errorf("unknown scalar value in module: %v", c.Value())
 			}
 			continue
 		}
@@ -352,7 +423,7 @@ func parseModule(n sexp.Helper, ordering int) (*Module, error) {
 		case "at":
 			m.Placement.At.X = c.Child(1).MustFloat64()
 			m.Placement.At.Y = c.Child(2).MustFloat64()
-			if c.MustNode().NumChildren() >= 4 {
+			if c.NumChildren() >= 4 {
 				m.Placement.At.Z = c.Child(3).MustFloat64()
 				m.Placement.At.ZPresent = true
 			}
@@ -438,7 +509,7 @@ func parseModule(n sexp.Helper, ordering int) (*Module, error) {
 	return &m, nil
 }
 
-func parseModText(n sexp.Helper) (*ModText, error) {
+func parseModText(n sreader.Node) (*ModText, error) {
 	t := ModText{
 		Text: n.Child(2).MustString(),
 	}
@@ -451,12 +522,12 @@ func parseModText(n sexp.Helper) (*ModText, error) {
 	case "user":
 		t.Kind = UserText
 	default:
-		return nil, fmt.Errorf("unknown fp_text type: %v", n.Child(1).MustString())
+		return nil, n.Child(1).Errorf("unknown fp_text type: %v", n.Child(1).MustString())
 	}
 
-	for x := 3; x < n.MustNode().NumChildren(); x++ {
+	for x := 3; x < n.NumChildren(); x++ {
 		c := n.Child(x)
-		if c.MustNode().Value == "hide" {
+		if c.Value() == "hide" {
 			t.Hidden = true
 			continue
 		}
@@ -465,9 +536,9 @@ func parseModText(n sexp.Helper) (*ModText, error) {
 		case "at":
 			t.At.X = c.Child(1).MustFloat64()
 			t.At.Y = c.Child(2).MustFloat64()
-			for z := 3; z < c.MustNode().NumChildren(); z++ {
-				c := c.Child(x)
-				switch c.MustNode().Value {
+			for z := 3; z < c.NumChildren(); z++ {
+				c := c.Child(z)
+				switch c.Value() {
 				case "unlocked":
 					t.At.Unlocked = true
 				default:
@@ -489,9 +560,9 @@ func parseModText(n sexp.Helper) (*ModText, error) {
 	return &t, nil
 }
 
-func parseModLine(n sexp.Helper) (*ModLine, error) {
+func parseModLine(n sreader.Node) (*ModLine, error) {
 	l := ModLine{}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "start":
@@ -510,19 +581,19 @@ func parseModLine(n sexp.Helper) (*ModLine, error) {
 	return &l, nil
 }
 
-func parseModPolygon(n sexp.Helper) (*ModPolygon, error) {
+func parseModPolygon(n sreader.Node) (*ModPolygon, error) {
 	p := ModPolygon{}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "at":
 			p.At.X = c.Child(1).MustFloat64()
 			p.At.Y = c.Child(2).MustFloat64()
 		case "pts":
-			for j := 1; j < c.MustNode().NumChildren(); j++ {
+			for j := 1; j < c.NumChildren(); j++ {
 				c := c.Child(j)
 				if marker := c.Child(0).MustString(); marker != "xy" {
-					return nil, fmt.Errorf("expected 'xy', got %q", marker)
+					return nil, c.Errorf("expected 'xy', got %q", marker)
 				}
 				p.Points = append(p.Points, XY{X: c.Child(1).MustFloat64(), Y: c.Child(2).MustFloat64()})
 			}
@@ -536,9 +607,9 @@ func parseModPolygon(n sexp.Helper) (*ModPolygon, error) {
 	return &p, nil
 }
 
-func parseModArc(n sexp.Helper) (*ModArc, error) {
+func parseModArc(n sreader.Node) (*ModArc, error) {
 	a := ModArc{}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "start":
@@ -559,9 +630,9 @@ func parseModArc(n sexp.Helper) (*ModArc, error) {
 	return &a, nil
 }
 
-func parseModCircle(n sexp.Helper) (*ModCircle, error) {
+func parseModCircle(n sreader.Node) (*ModCircle, error) {
 	a := ModCircle{}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "center":
@@ -580,7 +651,7 @@ func parseModCircle(n sexp.Helper) (*ModCircle, error) {
 	return &a, nil
 }
 
-func parseModPad(n sexp.Helper) (*Pad, error) {
+func parseModPad(n sreader.Node) (*Pad, error) {
 	p := Pad{
 		Ident:       n.Child(1).MustString(),
 		ZoneConnect: ZoneConnectInherited,
@@ -612,13 +683,13 @@ func parseModPad(n sexp.Helper) (*Pad, error) {
 		p.Shape = ShapeCustom
 	}
 
-	for x := 4; x < n.MustNode().NumChildren(); x++ {
+	for x := 4; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "at":
 			p.At.X = c.Child(1).MustFloat64()
 			p.At.Y = c.Child(2).MustFloat64()
-			if c.MustNode().NumChildren() >= 4 {
+			if c.NumChildren() >= 4 {
 				p.At.Z = c.Child(3).MustFloat64()
 				p.At.ZPresent = true
 			}
@@ -626,7 +697,7 @@ func parseModPad(n sexp.Helper) (*Pad, error) {
 			p.Size.X = c.Child(1).MustFloat64()
 			p.Size.Y = c.Child(2).MustFloat64()
 		case "layers":
-			for j := 1; j < c.MustNode().NumChildren(); j++ {
+			for j := 1; j < c.NumChildren(); j++ {
 				p.Layers = append(p.Layers, c.Child(j).MustString())
 			}
 
@@ -636,7 +707,7 @@ func parseModPad(n sexp.Helper) (*Pad, error) {
 
 		case "drill":
 			readWidth := false
-			for z := 1; z < c.MustNode().NumChildren(); z++ {
+			for z := 1; z < c.NumChildren(); z++ {
 				c := c.Child(z)
 				if c.IsList() {
 					switch c.Child(0).MustString() {
@@ -695,7 +766,7 @@ func parseModPad(n sexp.Helper) (*Pad, error) {
 			p.Options = o
 
 		case "primitives":
-			for y := 1; y < c.MustNode().NumChildren(); y++ {
+			for y := 1; y < c.NumChildren(); y++ {
 				c2 := c.Child(y)
 				switch c2.Child(0).MustString() {
 				case "gr_poly":
@@ -744,9 +815,9 @@ func parseModPad(n sexp.Helper) (*Pad, error) {
 	return &p, nil
 }
 
-func parsePadOptions(n sexp.Helper) (*PadOptions, error) {
+func parsePadOptions(n sreader.Node) (*PadOptions, error) {
 	o := PadOptions{}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "clearance":
@@ -758,39 +829,39 @@ func parsePadOptions(n sexp.Helper) (*PadOptions, error) {
 	return &o, nil
 }
 
-func parseModModel(n sexp.Helper) (*ModModel, error) {
+func parseModModel(n sreader.Node) (*ModModel, error) {
 	m := ModModel{
 		Path: n.Child(1).MustString(),
 	}
 
-	for x := 2; x < n.MustNode().NumChildren(); x++ {
+	for x := 2; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "at":
 			m.At.X = c.Child(1).Child(1).MustFloat64()
 			m.At.Y = c.Child(1).Child(2).MustFloat64()
-			if c.Child(1).MustNode().NumChildren() >= 4 {
+			if c.Child(1).NumChildren() >= 4 {
 				m.At.Z = c.Child(1).Child(3).MustFloat64()
 				m.At.ZPresent = true
 			}
 		case "offset":
 			m.Offset.X = c.Child(1).Child(1).MustFloat64()
 			m.Offset.Y = c.Child(1).Child(2).MustFloat64()
-			if c.Child(1).MustNode().NumChildren() >= 4 {
+			if c.Child(1).NumChildren() >= 4 {
 				m.Offset.Z = c.Child(1).Child(3).MustFloat64()
 				m.Offset.ZPresent = true
 			}
 		case "scale":
 			m.Scale.X = c.Child(1).Child(1).MustFloat64()
 			m.Scale.Y = c.Child(1).Child(2).MustFloat64()
-			if c.Child(1).MustNode().NumChildren() >= 4 {
+			if c.Child(1).NumChildren() >= 4 {
 				m.Scale.Z = c.Child(1).Child(3).MustFloat64()
 				m.Scale.ZPresent = true
 			}
 		case "rotate":
 			m.Rotate.X = c.Child(1).Child(1).MustFloat64()
 			m.Rotate.Y = c.Child(1).Child(2).MustFloat64()
-			if c.Child(1).MustNode().NumChildren() >= 4 {
+			if c.Child(1).NumChildren() >= 4 {
 				m.Rotate.Z = c.Child(1).Child(3).MustFloat64()
 				m.Rotate.ZPresent = true
 			}
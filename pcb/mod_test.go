@@ -299,3 +299,18 @@ func TestParseMod(t *testing.T) {
 		})
 	}
 }
+
+func TestParseModErrors(t *testing.T) {
+	_, err := ParseModule(strings.NewReader(`(module R_0805 (layer F.Cu)
+  (pad 1 smd rect (at -1 0) (size 1 1.2) (layers F.Cu F.Paste F.Mask))
+  (pad 2 smd rect (at 1) (size 1 1.2) (layers F.Cu F.Paste F.Mask))
+)`))
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("ParseModule() returned %T (%v), want *ParseError", err, err)
+	}
+	want := ParseError{Line: 3, Column: 19, Path: "module/pad[1]/at", Msg: "missing number at position 2, at has 2 elements"}
+	if !reflect.DeepEqual(*pe, want) {
+		t.Errorf("ParseModule() error = %+v, want %+v", *pe, want)
+	}
+}
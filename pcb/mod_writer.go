@@ -301,13 +301,8 @@ func (t *ModText) write(sw *swriter.SExpWriter, ident string) error {
 	if err := sw.CloseList(false); err != nil {
 		return err
 	}
-	if t.Effects.Justify != JustifyNone {
-		sw.StartList(false)
-		sw.StringScalar("justify")
-		sw.StringScalar(t.Effects.Justify.String())
-		if err := sw.CloseList(false); err != nil {
-			return err
-		}
+	if err := t.Effects.writeJustify(sw); err != nil {
+		return err
 	}
 	if err := sw.CloseList(false); err != nil {
 		return err
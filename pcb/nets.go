@@ -0,0 +1,262 @@
+package pcb
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AddNet returns the number of the net with the given name, adding it to
+// the board with the next free number if it does not already exist.
+func (p *PCB) AddNet(name string) int {
+	if num, ok := p.NetByName(name); ok {
+		return num
+	}
+	if p.Nets == nil {
+		p.Nets = map[int]Net{}
+	}
+	num := 0
+	for n := range p.Nets {
+		if n >= num {
+			num = n + 1
+		}
+	}
+	p.Nets[num] = Net{Name: name}
+	return num
+}
+
+// NetByName returns the number of the net with the given name. If
+// several nets share the name, the lowest numbered is returned.
+func (p *PCB) NetByName(name string) (int, bool) {
+	found, ok := 0, false
+	for num, n := range p.Nets {
+		if n.Name == name && (!ok || num < found) {
+			found, ok = num, true
+		}
+	}
+	return found, ok
+}
+
+// RenameNet changes the name of a net, updating the zones, pads and net
+// classes which refer to it.
+func (p *PCB) RenameNet(num int, name string) error {
+	n, ok := p.Nets[num]
+	if !ok {
+		return fmt.Errorf("net %d does not exist", num)
+	}
+	if num == 0 {
+		return fmt.Errorf("cannot rename the unconnected net")
+	}
+	if other, ok := p.NetByName(name); ok && other != num {
+		return fmt.Errorf("net %q already exists with number %d", name, other)
+	}
+
+	old := n.Name
+	n.Name = name
+	p.Nets[num] = n
+	for i := range p.Zones {
+		if p.Zones[i].NetNum == num {
+			p.Zones[i].NetName = name
+		}
+	}
+	for i := range p.Modules {
+		for j := range p.Modules[i].Pads {
+			if pad := &p.Modules[i].Pads[j]; pad.NetNum == num {
+				pad.NetName = name
+			}
+		}
+	}
+	for i := range p.NetClasses {
+		for j, member := range p.NetClasses[i].Nets {
+			if member == old {
+				p.NetClasses[i].Nets[j] = name
+			}
+		}
+	}
+	return nil
+}
+
+// MergeNets connects everything on net from to net into, and removes net
+// from from the board.
+func (p *PCB) MergeNets(from, into int) error {
+	if from == 0 {
+		return fmt.Errorf("cannot merge the unconnected net")
+	}
+	return p.DeleteNet(from, into)
+}
+
+// DeleteNet removes a net from the board. Tracks, vias, zones and pads on
+// the net are moved to the net numbered reassign, which is usually zero,
+// the unconnected net.
+func (p *PCB) DeleteNet(num, reassign int) error {
+	if _, ok := p.Nets[num]; !ok {
+		return fmt.Errorf("net %d does not exist", num)
+	}
+	if num == 0 {
+		return fmt.Errorf("cannot delete the unconnected net")
+	}
+	if num == reassign {
+		return fmt.Errorf("cannot reassign net %d to itself", num)
+	}
+	if _, ok := p.Nets[reassign]; !ok {
+		return fmt.Errorf("net %d does not exist", reassign)
+	}
+
+	name := p.Nets[num].Name
+	delete(p.Nets, num)
+	p.renumberNets(map[int]int{num: reassign})
+	for i := range p.NetClasses {
+		nc := &p.NetClasses[i]
+		for j := 0; j < len(nc.Nets); j++ {
+			if nc.Nets[j] == name {
+				nc.Nets = append(nc.Nets[:j], nc.Nets[j+1:]...)
+				j--
+			}
+		}
+	}
+	return nil
+}
+
+// CompactNets renumbers the nets so they are numbered consecutively from
+// zero, preserving their order. References to nets are updated.
+func (p *PCB) CompactNets() {
+	nums := make([]int, 0, len(p.Nets))
+	for num := range p.Nets {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	mapping := map[int]int{}
+	nets := make(map[int]Net, len(p.Nets))
+	for i, num := range nums {
+		nets[i] = p.Nets[num]
+		if i != num {
+			mapping[num] = i
+		}
+	}
+	p.Nets = nets
+	p.renumberNets(mapping)
+}
+
+// renumberNets updates references to nets, moving elements on each net
+// in mapping to the corresponding net.
+func (p *PCB) renumberNets(mapping map[int]int) {
+	if len(mapping) == 0 {
+		return
+	}
+	for _, s := range p.Segments {
+		switch s := s.(type) {
+		case *Track:
+			if to, ok := mapping[s.NetIndex]; ok {
+				s.NetIndex = to
+			}
+		case *Via:
+			if to, ok := mapping[s.NetIndex]; ok {
+				s.NetIndex = to
+			}
+		}
+	}
+	for i := range p.Zones {
+		z := &p.Zones[i]
+		if to, ok := mapping[z.NetNum]; ok && !z.IsKeepout {
+			z.NetNum, z.NetName = to, p.Nets[to].Name
+		}
+	}
+	for i := range p.Modules {
+		for j := range p.Modules[i].Pads {
+			pad := &p.Modules[i].Pads[j]
+			if to, ok := mapping[pad.NetNum]; ok {
+				pad.NetNum, pad.NetName = to, p.Nets[to].Name
+			}
+		}
+	}
+}
+
+// resolveNetNames assigns net numbers to tracks, vias, zones and pads
+// which were created referring to a net by name, adding the nets to the
+// board as needed.
+func (p *PCB) resolveNetNames() {
+	for _, s := range p.Segments {
+		switch s := s.(type) {
+		case *Track:
+			if s.netName != "" {
+				s.NetIndex, s.netName = p.AddNet(s.netName), ""
+			}
+		case *Via:
+			if s.netName != "" {
+				s.NetIndex, s.netName = p.AddNet(s.netName), ""
+			}
+		}
+	}
+	for i := range p.Zones {
+		if z := &p.Zones[i]; !z.IsKeepout && z.NetNum == 0 && z.NetName != "" {
+			z.NetNum = p.AddNet(z.NetName)
+		}
+	}
+	for i := range p.Modules {
+		for j := range p.Modules[i].Pads {
+			if pad := &p.Modules[i].Pads[j]; pad.NetNum == 0 && pad.NetName != "" {
+				pad.NetNum = p.AddNet(pad.NetName)
+			}
+		}
+	}
+}
+
+// hasNetNames returns true if any track, via, zone or pad refers to a net
+// by name and has not yet been assigned a net number.
+func (p *PCB) hasNetNames() bool {
+	for _, s := range p.Segments {
+		switch s := s.(type) {
+		case *Track:
+			if s.netName != "" {
+				return true
+			}
+		case *Via:
+			if s.netName != "" {
+				return true
+			}
+		}
+	}
+	for i := range p.Zones {
+		if z := &p.Zones[i]; !z.IsKeepout && z.NetNum == 0 && z.NetName != "" {
+			return true
+		}
+	}
+	for i := range p.Modules {
+		for j := range p.Modules[i].Pads {
+			if pad := &p.Modules[i].Pads[j]; pad.NetNum == 0 && pad.NetName != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyNetRefs returns a copy of the board which can have its net
+// references resolved without modifying p. Elements which do not refer
+// to nets are shared with p.
+func (p *PCB) copyNetRefs() *PCB {
+	out := *p
+	out.Nets = make(map[int]Net, len(p.Nets))
+	for num, n := range p.Nets {
+		out.Nets[num] = n
+	}
+	out.Segments = make([]NetSegment, len(p.Segments))
+	for i, s := range p.Segments {
+		switch s := s.(type) {
+		case *Track:
+			t := *s
+			out.Segments[i] = &t
+		case *Via:
+			v := *s
+			out.Segments[i] = &v
+		default:
+			out.Segments[i] = s
+		}
+	}
+	out.Zones = append([]Zone(nil), p.Zones...)
+	out.Modules = append([]Module(nil), p.Modules...)
+	for i := range out.Modules {
+		out.Modules[i].Pads = append([]Pad(nil), out.Modules[i].Pads...)
+	}
+	return &out
+}
@@ -0,0 +1,176 @@
+package pcb
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func netTestPCB() *PCB {
+	p := EmptyPCB()
+	p.Nets[1] = Net{Name: "GND"}
+	p.Nets[2] = Net{Name: "VCC"}
+	p.Nets[4] = Net{Name: "SDA"}
+	p.NetClasses[0].Nets = []string{"GND", "VCC", "SDA"}
+	p.Segments = []NetSegment{
+		&Track{Layer: "F.Cu", Width: 0.25, NetIndex: 2},
+		&Via{Layers: []string{"F.Cu", "B.Cu"}, Size: 0.8, NetIndex: 4},
+	}
+	p.Zones = []Zone{{NetNum: 1, NetName: "GND", Layers: []string{"B.Cu"}, BasePolys: [][]XY{{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}}}}}
+	p.Modules = []Module{{Layer: "F.Cu", Pads: []Pad{
+		{Ident: "1", NetNum: 2, NetName: "VCC"},
+		{Ident: "2", NetNum: 4, NetName: "SDA"},
+	}}}
+	return p
+}
+
+func TestAddNet(t *testing.T) {
+	p := netTestPCB()
+	if got, want := p.AddNet("VCC"), 2; got != want {
+		t.Errorf("AddNet(VCC) = %d, want %d", got, want)
+	}
+	if got, want := p.AddNet("SCL"), 5; got != want {
+		t.Errorf("AddNet(SCL) = %d, want %d", got, want)
+	}
+	if num, ok := p.NetByName("SCL"); !ok || num != 5 {
+		t.Errorf("NetByName(SCL) = %d, %v, want 5, true", num, ok)
+	}
+	if _, ok := p.NetByName("MISO"); ok {
+		t.Error("NetByName(MISO) found a net")
+	}
+}
+
+func TestRenameNet(t *testing.T) {
+	p := netTestPCB()
+	if err := p.RenameNet(2, "3V3"); err != nil {
+		t.Fatalf("RenameNet() failed: %v", err)
+	}
+	if got, want := p.Modules[0].Pads[0].NetName, "3V3"; got != want {
+		t.Errorf("pad net name = %q, want %q", got, want)
+	}
+	if got, want := p.NetClasses[0].Nets, []string{"GND", "3V3", "SDA"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("net class nets = %v, want %v", got, want)
+	}
+	if err := p.RenameNet(2, "GND"); err == nil {
+		t.Error("RenameNet() to an existing name did not fail")
+	}
+	if err := p.RenameNet(0, "X"); err == nil {
+		t.Error("RenameNet(0) did not fail")
+	}
+	if issues := Validate(p); len(issues) > 0 {
+		t.Errorf("Validate() = %v", issues)
+	}
+}
+
+func TestMergeNets(t *testing.T) {
+	p := netTestPCB()
+	if err := p.MergeNets(2, 1); err != nil {
+		t.Fatalf("MergeNets() failed: %v", err)
+	}
+	if _, ok := p.Nets[2]; ok {
+		t.Error("net 2 still exists")
+	}
+	if got := p.Segments[0].(*Track).NetIndex; got != 1 {
+		t.Errorf("track net = %d, want 1", got)
+	}
+	if got, want := p.Modules[0].Pads[0], (Pad{Ident: "1", NetNum: 1, NetName: "GND"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("pad = %+v, want %+v", got, want)
+	}
+	if got, want := p.NetClasses[0].Nets, []string{"GND", "SDA"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("net class nets = %v, want %v", got, want)
+	}
+	if err := p.MergeNets(1, 9); err == nil {
+		t.Error("MergeNets() into a missing net did not fail")
+	}
+	if issues := Validate(p); len(issues) > 0 {
+		t.Errorf("Validate() = %v", issues)
+	}
+}
+
+func TestDeleteNet(t *testing.T) {
+	p := netTestPCB()
+	if err := p.DeleteNet(4, 0); err != nil {
+		t.Fatalf("DeleteNet() failed: %v", err)
+	}
+	if got := p.Segments[1].(*Via).NetIndex; got != 0 {
+		t.Errorf("via net = %d, want 0", got)
+	}
+	if got, want := p.Modules[0].Pads[1], (Pad{Ident: "2"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("pad = %+v, want %+v", got, want)
+	}
+	if err := p.DeleteNet(0, 1); err == nil {
+		t.Error("DeleteNet(0) did not fail")
+	}
+	if err := p.DeleteNet(1, 1); err == nil {
+		t.Error("DeleteNet() reassigning to itself did not fail")
+	}
+}
+
+func TestCompactNets(t *testing.T) {
+	p := netTestPCB()
+	p.CompactNets()
+	names := map[int]string{}
+	for num, n := range p.Nets {
+		names[num] = n.Name
+	}
+	if want := map[int]string{0: "", 1: "GND", 2: "VCC", 3: "SDA"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Nets = %v, want %v", names, want)
+	}
+	if got := p.Segments[1].(*Via).NetIndex; got != 3 {
+		t.Errorf("via net = %d, want 3", got)
+	}
+	if got := p.Modules[0].Pads[1].NetNum; got != 3 {
+		t.Errorf("pad net = %d, want 3", got)
+	}
+	if issues := Validate(p); len(issues) > 0 {
+		t.Errorf("Validate() = %v", issues)
+	}
+}
+
+func TestResolveNetNames(t *testing.T) {
+	p := netTestPCB()
+	p.Segments = append(p.Segments, &Track{Layer: "F.Cu", Width: 0.25, netName: "VCC"}, &Via{Layers: []string{"F.Cu", "B.Cu"}, Size: 0.8, netName: "SCL"})
+	p.Modules[0].Pads = append(p.Modules[0].Pads, Pad{Ident: "3", NetName: "SCL"})
+	p.resolveNetNames()
+
+	if got := p.Segments[2].(*Track).NetIndex; got != 2 {
+		t.Errorf("track net = %d, want 2", got)
+	}
+	if got := p.Segments[3].(*Via).NetIndex; got != 5 {
+		t.Errorf("via net = %d, want 5", got)
+	}
+	if got := p.Modules[0].Pads[2].NetNum; got != 5 {
+		t.Errorf("pad net = %d, want 5", got)
+	}
+	if issues := Validate(p); len(issues) > 0 {
+		t.Errorf("Validate() = %v", issues)
+	}
+}
+
+func TestWriteResolvesNetNamesOnCopy(t *testing.T) {
+	p := netTestPCB()
+	track := &Track{Layer: "F.Cu", Width: 0.25, netName: "SCL"}
+	p.Segments = append(p.Segments, track)
+	p.Modules[0].Pads = append(p.Modules[0].Pads, Pad{Ident: "3", NetName: "SCL"})
+
+	var b bytes.Buffer
+	if err := p.Write(&b); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	for _, want := range []string{"(net 5 SCL)", "(layer F.Cu) (net 5))", "(net 5 SCL) (zone_connect"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, b.String())
+		}
+	}
+
+	if _, ok := p.Nets[5]; ok {
+		t.Error("Write() added net 5 to the board")
+	}
+	if track.NetIndex != 0 || track.netName != "SCL" {
+		t.Errorf("track net = (%d, %q), want it left referring to SCL by name", track.NetIndex, track.netName)
+	}
+	if got := p.Modules[0].Pads[2].NetNum; got != 0 {
+		t.Errorf("pad net = %d, want 0", got)
+	}
+}
@@ -4,15 +4,16 @@ package pcb
 
 import (
 	"crypto/sha256"
-	"errors"
 	"io/ioutil"
-	"strings"
 
-	"github.com/nsf/sexp"
+	"github.com/twitchyliquid64/kcgen/sreader"
 	"github.com/twitchyliquid64/kcgen/swriter"
 	"go.starlark.net/starlark"
 )
 
+// ParseError describes malformed input, and where it was encountered.
+type ParseError = sreader.ParseError
+
 // Layer describes the attributes of a layer.
 type Layer struct {
 	Num    int    `json:"num"`
@@ -28,6 +29,10 @@ type Net struct {
 	Name string `json:"name"`
 
 	order int
+	// board and num are set on nets handed out by the nets attribute of a
+	// PCB, so that edits are written back to the net table of the board.
+	board *PCB
+	num   int
 }
 
 // NetClass represents a net class.
@@ -61,6 +66,13 @@ type PCB struct {
 	FormatVersion int          `json:"format_version"`
 	CreatedBy     PCBCreatedBy `json:"created_by"`
 
+	// Thickness is the overall thickness of the board in millimeters. If
+	// zero, DefaultThickness is written.
+	Thickness float64 `json:"thickness"`
+	// Page describes the drawing sheet. If the size is empty, an A4 sheet
+	// is written.
+	Page Page `json:"page"`
+
 	TitleInfo   *TitleInfo  `json:"title_info"`
 	EditorSetup EditorSetup `json:"editor_setup"`
 
@@ -75,8 +87,12 @@ type PCB struct {
 	Zones      []Zone      `json:"zones"`
 	Modules    []Module    `json:"modules"`
 
-	// TODO(twitchyliquid64): Compute these & expose them.
-	generalFields [][]string
+	// legacyGeneral holds fields of the general section which are not
+	// recomputed, such as the connectivity summary written by pcbnew 4.
+	legacyGeneral [][]string
+	// hasArea is true if the general section records the board extent,
+	// which is recomputed when written.
+	hasArea bool
 }
 
 // Drawing represents a drawable element.
@@ -143,7 +159,7 @@ type EditorSetup struct {
 
 	PlotParams map[string]PlotParam
 
-	Unrecognised map[string]sexp.Helper
+	Unrecognised map[string]sreader.Node
 	order        int
 }
 
@@ -166,6 +182,10 @@ func (j ZoneConnectMode) String() string {
 		return "none"
 	case ZoneConnectThermal:
 		return "thermal"
+	case ZoneConnectSolid:
+		return "solid"
+	case ZoneConnectThroughHole:
+		return "thru_hole_only"
 	}
 	return "???????"
 }
@@ -195,6 +215,10 @@ const (
 	ZoneConnectInherited ZoneConnectMode = iota - 1
 	ZoneConnectNone
 	ZoneConnectThermal
+	ZoneConnectSolid
+	// ZoneConnectThroughHole uses thermal reliefs for through-hole pads,
+	// and connects other pads solidly.
+	ZoneConnectThroughHole
 )
 
 // DecodeFile reads a .kicad_pcb file at fpath, returning a parsed representation.
@@ -203,54 +227,62 @@ func DecodeFile(fpath string) (*PCB, error) {
 	if err != nil {
 		return nil, err
 	}
-	return Decode(f)
+	return decode(f, fpath)
 }
 
-// Decode parses the provided kicad_pcb content.
+// Decode parses the provided kicad_pcb content. Malformed input is reported
+// as a *ParseError.
 func Decode(f []byte) (*PCB, error) {
-	ast, err := sexp.Parse(strings.NewReader(string(f)), nil)
+	return decode(f, "")
+}
+
+func decode(f []byte, fname string) (*PCB, error) {
+	ast, err := sreader.Parse(f, fname)
 	if err != nil {
 		return nil, err
 	}
 
-	if !ast.IsList() {
-		return nil, errors.New("invalid format: expected s-expression list at top level")
-	}
 	if ast.NumChildren() != 1 {
-		return nil, errors.New("invalid format: top level list of size 1")
+		return nil, ast.Errorf("invalid format: expected a single top-level expression, got %d", ast.NumChildren())
 	}
-	mainAST, _ := ast.Nth(0)
+	mainAST := ast.Child(0)
 	if !mainAST.IsList() {
-		return nil, errors.New("invalid format: expected s-expression list at 1st level")
+		return nil, mainAST.Errorf("invalid format: expected s-expression list at 1st level")
 	}
 
 	if mainAST.NumChildren() < 5 {
-		return nil, errors.New("invalid format: expected at least 5 nodes in main expression")
+		return nil, mainAST.Errorf("invalid format: expected at least 5 nodes in main expression")
 	}
-	if mainAST.Children.Value != "kicad_pcb" {
-		return nil, errors.New("invalid format: missing leading element kicad_pcb")
+	if mainAST.Name() != "kicad_pcb" {
+		return nil, mainAST.Errorf("invalid format: missing leading element kicad_pcb")
 	}
 
-	pcb := &PCB{LayersByName: map[string]*Layer{}, Nets: map[int]Net{}}
+	pcb, err := parsePCB(mainAST)
+	if err != nil {
+		return nil, err
+	}
+	return pcb, nil
+}
+
+func parsePCB(mainAST sreader.Node) (pcb *PCB, err error) {
+	defer sreader.Recover(&err)
+	pcb = &PCB{LayersByName: map[string]*Layer{}, Nets: map[int]Net{}}
 	var ordering int
 
 	for i := 1; i < mainAST.NumChildren(); i++ {
-		n := sexp.Help(mainAST).Child(i)
+		n := mainAST.Child(i)
 		if n.IsList() && n.Child(1).IsValid() {
 			switch n.Child(0).MustString() {
 			case "version":
-				pcb.FormatVersion, err = n.Child(1).Int()
-				if err != nil {
-					return nil, errors.New("invalid format: version value must be an int")
+				if pcb.FormatVersion, err = n.Child(1).Int(); err != nil {
+					return nil, err
 				}
 			case "host":
-				pcb.CreatedBy.Tool, err = n.Child(1).String()
-				if err != nil {
-					return nil, errors.New("invalid format: host value[1] must be a string")
+				if pcb.CreatedBy.Tool, err = n.Child(1).String(); err != nil {
+					return nil, err
 				}
-				pcb.CreatedBy.Version, err = n.Child(2).String()
-				if err != nil {
-					return nil, errors.New("invalid format: host value[2] must be a string")
+				if pcb.CreatedBy.Version, err = n.Child(2).String(); err != nil {
+					return nil, err
 				}
 			case "setup":
 				s, err := parseSetup(n, ordering)
@@ -267,20 +299,36 @@ func Decode(f []byte) (*PCB, error) {
 				pcb.TitleInfo = t
 
 			case "general":
-				for y := 1; y < n.MustNode().NumChildren(); y++ {
+				for y := 1; y < n.NumChildren(); y++ {
 					c := n.Child(y)
-					var params []string
-					for z := 0; z < c.MustNode().NumChildren(); z++ {
-						params = append(params, c.Child(z).MustString())
+					switch c.Name() {
+					case "thickness":
+						if pcb.Thickness, err = c.Child(1).Float64(); err != nil {
+							return nil, err
+						}
+					case "area":
+						pcb.hasArea = true
+					case "drawings", "tracks", "zones", "modules", "nets":
+						// Recomputed when written.
+					default:
+						var params []string
+						for z := 0; z < c.NumChildren(); z++ {
+							params = append(params, c.Child(z).MustString())
+						}
+						pcb.legacyGeneral = append(pcb.legacyGeneral, params)
 					}
-					pcb.generalFields = append(pcb.generalFields, params)
+				}
+
+			case "page":
+				if pcb.Page, err = parsePage(n); err != nil {
+					return nil, err
 				}
 
 			case "layers":
-				for x := 1; x < n.MustNode().NumChildren(); x++ {
+				for x := 1; x < n.NumChildren(); x++ {
 					c := n.Child(x)
-					num, err2 := c.Child(0).Int()
-					if err2 != nil {
+					num, err := c.Child(0).Int()
+					if err != nil {
 						return nil, err
 					}
 					l := &Layer{
@@ -290,7 +338,7 @@ func Decode(f []byte) (*PCB, error) {
 						order: ordering,
 					}
 
-					if c.MustNode().NumChildren() > 3 && c.Child(3).IsScalar() &&
+					if c.NumChildren() > 3 && c.Child(3).IsScalar() &&
 						c.Child(3).MustString() == "hide" {
 						l.Hidden = true
 					}
@@ -300,8 +348,8 @@ func Decode(f []byte) (*PCB, error) {
 					ordering++
 				}
 			case "net":
-				num, err2 := n.Child(1).Int()
-				if err2 != nil {
+				num, err := n.Child(1).Int()
+				if err != nil {
 					return nil, err
 				}
 				pcb.Nets[num] = Net{Name: n.Child(2).MustString(), order: ordering}
@@ -376,13 +424,13 @@ func Decode(f []byte) (*PCB, error) {
 	return pcb, nil
 }
 
-func parseNetClass(n sexp.Helper, ordering int) (*NetClass, error) {
+func parseNetClass(n sreader.Node, ordering int) (*NetClass, error) {
 	nc := NetClass{
 		order:       ordering,
 		Name:        n.Child(1).MustString(),
 		Description: n.Child(2).MustString(),
 	}
-	for x := 3; x < n.MustNode().NumChildren(); x++ {
+	for x := 3; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "clearance":
@@ -408,9 +456,9 @@ func parseNetClass(n sexp.Helper, ordering int) (*NetClass, error) {
 	return &nc, nil
 }
 
-func parseTitleBlock(n sexp.Helper, ordering int) (*TitleInfo, error) {
+func parseTitleBlock(n sreader.Node, ordering int) (*TitleInfo, error) {
 	t := TitleInfo{order: ordering}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "title":
@@ -423,7 +471,7 @@ func parseTitleBlock(n sexp.Helper, ordering int) (*TitleInfo, error) {
 			t.Company = c.Child(1).MustString()
 		case "comment":
 			idx := c.Child(1).MustInt() - 1
-			if idx < len(t.Comments) {
+			if idx >= 0 && idx < len(t.Comments) {
 				t.Comments[idx] = c.Child(2).MustString()
 			}
 		}
@@ -431,12 +479,12 @@ func parseTitleBlock(n sexp.Helper, ordering int) (*TitleInfo, error) {
 	return &t, nil
 }
 
-func parseSetup(n sexp.Helper, ordering int) (*EditorSetup, error) {
+func parseSetup(n sreader.Node, ordering int) (*EditorSetup, error) {
 	e := EditorSetup{
 		order:        ordering,
-		Unrecognised: map[string]sexp.Helper{},
+		Unrecognised: map[string]sreader.Node{},
 	}
-	for x := 1; x < n.MustNode().NumChildren(); x++ {
+	for x := 1; x < n.NumChildren(); x++ {
 		c := n.Child(x)
 		switch c.Child(0).MustString() {
 		case "last_trace_width":
@@ -478,21 +526,21 @@ func parseSetup(n sexp.Helper, ordering int) (*EditorSetup, error) {
 		case "pcb_text_width":
 			e.TextWidth = c.Child(1).MustFloat64()
 		case "pcb_text_size":
-			for y := 1; y < c.MustNode().NumChildren(); y++ {
+			for y := 1; y < c.NumChildren(); y++ {
 				e.TextSize = append(e.TextSize, c.Child(y).MustFloat64())
 			}
 
 		case "mod_edge_width":
 			e.ModEdgeWidth = c.Child(1).MustFloat64()
 		case "mod_text_size":
-			for y := 1; y < c.MustNode().NumChildren(); y++ {
+			for y := 1; y < c.NumChildren(); y++ {
 				e.ModTextSize = append(e.ModTextSize, c.Child(y).MustFloat64())
 			}
 		case "mod_text_width":
 			e.ModTextWidth = c.Child(1).MustFloat64()
 
 		case "pad_size":
-			for y := 1; y < c.MustNode().NumChildren(); y++ {
+			for y := 1; y < c.NumChildren(); y++ {
 				e.PadSize = append(e.PadSize, c.Child(y).MustFloat64())
 			}
 		case "pad_drill":
@@ -503,7 +551,7 @@ func parseSetup(n sexp.Helper, ordering int) (*EditorSetup, error) {
 			e.SolderMaskMinWidth = c.Child(1).MustFloat64()
 
 		case "aux_axis_origin":
-			for y := 1; y < c.MustNode().NumChildren(); y++ {
+			for y := 1; y < c.NumChildren(); y++ {
 				e.AuxAxisOrigin = append(e.AuxAxisOrigin, c.Child(y).MustFloat64())
 			}
 		case "visible_elements":
@@ -518,13 +566,13 @@ func parseSetup(n sexp.Helper, ordering int) (*EditorSetup, error) {
 
 		case "pcbplotparams":
 			e.PlotParams = map[string]PlotParam{}
-			for y := 1; y < c.MustNode().NumChildren(); y++ {
+			for y := 1; y < c.NumChildren(); y++ {
 				c := c.Child(y)
 				param := PlotParam{
 					name:  c.Child(0).MustString(),
 					order: y,
 				}
-				for z := 1; z < c.MustNode().NumChildren(); z++ {
+				for z := 1; z < c.NumChildren(); z++ {
 					param.values = append(param.values, c.Child(z).MustString())
 				}
 				e.PlotParams[param.name] = param
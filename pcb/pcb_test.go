@@ -1,7 +1,9 @@
 package pcb
 
 import (
+	"io/ioutil"
 	"reflect"
+	"regexp"
 	"testing"
 )
 
@@ -157,3 +159,101 @@ func TestPCB(t *testing.T) {
 		t.Errorf("p.Drawings[0].Features[1].Feature = %v, want %v", got, want)
 	}
 }
+
+func TestDecodeErrors(t *testing.T) {
+	tcs := []struct {
+		name  string
+		input string
+		want  ParseError
+	}{
+		{
+			name:  "syntax",
+			input: "(kicad_pcb (version 4)\n  (host pcbnew \"4.0.7)\n",
+			want:  ParseError{Line: 2, Column: 16, Msg: "newline is not allowed within '\"' strings"},
+		},
+		{
+			name:  "version",
+			input: "(kicad_pcb (version four) (host pcbnew 4.0.7)\n  (general) (page A4) (layers))",
+			want:  ParseError{Line: 1, Column: 21, Path: "kicad_pcb/version", Msg: "expected integer, got \"four\""},
+		},
+		{
+			name: "pad drill",
+			input: `(kicad_pcb (version 4) (host pcbnew 4.0.7) (general) (page A4)
+  (module A (layer F.Cu) (at 0 0))
+  (module B (layer F.Cu) (at 0 0)
+    (pad 1 thru_hole circle (at 0 0) (size 1 1) (drill 0.8) (layers *.Cu))
+    (pad 2 thru_hole circle (at 0 0) (size 1 1) (drill 0.8x) (layers *.Cu))
+  )
+)`,
+			want: ParseError{Line: 5, Column: 56, Path: "kicad_pcb/module[1]/pad[1]/drill", Msg: "expected number, got \"0.8x\""},
+		},
+		{
+			name:  "missing value",
+			input: "(kicad_pcb (version 4) (host pcbnew 4.0.7) (general) (page A4)\n  (net_class Default))",
+			want:  ParseError{Line: 2, Column: 3, Path: "kicad_pcb/net_class", Msg: "missing string at position 2, net_class has 2 elements"},
+		},
+		{
+			name:  "list instead of scalar",
+			input: "(kicad_pcb (version 4) (host pcbnew 4.0.7) (general) (page A4)\n  (segment (start 0 0) (end 1 1) (width (0.25))))",
+			want:  ParseError{Line: 2, Column: 41, Path: "kicad_pcb/segment/width", Msg: "expected number, got list"},
+		},
+		{
+			name:  "wrong root",
+			input: "(kicad_sch (version 4) (host eeschema 4.0.7) (general) (page A4))",
+			want:  ParseError{Line: 1, Column: 1, Path: "kicad_sch", Msg: "invalid format: missing leading element kicad_pcb"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode([]byte(tc.input))
+			if err == nil {
+				t.Fatal("Decode() returned no error")
+			}
+			pe, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("Decode() returned %T (%v), want *ParseError", err, err)
+			}
+			if !reflect.DeepEqual(*pe, tc.want) {
+				t.Errorf("Decode() error = %+v, want %+v", *pe, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeFileErrorHasFilename(t *testing.T) {
+	_, err := DecodeFile("testdata/README.md")
+	if err == nil {
+		t.Fatal("DecodeFile() returned no error")
+	}
+	if pe, ok := err.(*ParseError); !ok || pe.File != "testdata/README.md" {
+		t.Errorf("DecodeFile() error = %v, want *ParseError for testdata/README.md", err)
+	}
+}
+
+// TestDecodeMalformedDoesNotPanic corrupts each value in a board in turn,
+// checking that the decoder reports an error rather than panicking.
+func TestDecodeMalformedDoesNotPanic(t *testing.T) {
+	d, err := ioutil.ReadFile("testdata/sci2c-a7001.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutations := []string{"(x)", "", "bogus"}
+	values := regexp.MustCompile(`[ (]-?[0-9.]+[ )]`).FindAllIndex(d, -1)
+	for i, loc := range values {
+		mutated := make([]byte, 0, len(d))
+		mutated = append(mutated, d[:loc[0]+1]...)
+		mutated = append(mutated, mutations[i%len(mutations)]...)
+		mutated = append(mutated, d[loc[1]-1:]...)
+
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("Decode() panicked on value %d at offset %d: %v", i, loc[0], r)
+				}
+			}()
+			Decode(mutated)
+		}()
+	}
+}
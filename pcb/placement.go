@@ -0,0 +1,152 @@
+package pcb
+
+import (
+	"math"
+	"strings"
+)
+
+// Positions of module graphics and pads are stored relative to the module
+// position, before the module rotation is applied. Orientations of pads and
+// text are stored as they appear on the board, already including the module
+// rotation. Modules on the back of the board store their geometry already
+// mirrored.
+
+// OnBack returns true if the module is placed on the back of the board.
+func (m *Module) OnBack() bool {
+	return strings.HasPrefix(m.Layer, "B.")
+}
+
+// ToBoard converts a position relative to the module into board
+// coordinates, applying the module position and rotation.
+func (m *Module) ToBoard(p XY) XY {
+	p = p.Rotate(m.Placement.At.Z)
+	return XY{X: m.Placement.At.X + p.X, Y: m.Placement.At.Y + p.Y}
+}
+
+// PadPosition returns the position of the pad in board coordinates. Z is
+// set to the orientation of the pad on the board.
+func (m *Module) PadPosition(pad *Pad) XYZ {
+	at := m.ToBoard(XY{X: pad.At.X, Y: pad.At.Y})
+	return XYZ{X: at.X, Y: at.Y, Z: pad.At.Z, ZPresent: pad.At.Z != 0}
+}
+
+// Flip moves the module to the other side of the board, mirroring it
+// about its position as pcbnew does: geometry is mirrored top-to-bottom,
+// front and back layers are exchanged, orientations are negated and text
+// on the back is mirrored.
+func (m *Module) Flip() {
+	m.Layer = flipLayer(m.Layer)
+	m.Placement.At.Z = normalizeAngle180(-m.Placement.At.Z)
+
+	for i := range m.Graphics {
+		flipModDrawable(m.Graphics[i].Renderable)
+	}
+	for i := range m.Pads {
+		m.Pads[i].flip()
+	}
+}
+
+// Rotate rotates the module about its position by deg degrees
+// counter-clockwise, as pcbnew does: the orientations of its pads and text
+// turn with it.
+func (m *Module) Rotate(deg float64) {
+	m.Placement.At.rotate(deg, normalizeAngle180)
+
+	for i := range m.Graphics {
+		if t, ok := m.Graphics[i].Renderable.(*ModText); ok {
+			t.At.rotate(deg, normalizeAnglePos)
+		}
+	}
+	for i := range m.Pads {
+		m.Pads[i].At.rotate(deg, normalizeAnglePos)
+	}
+}
+
+// rotate adds deg to the orientation, normalized by norm.
+func (p *XYZ) rotate(deg float64, norm func(float64) float64) {
+	p.Z = norm(p.Z + deg)
+	p.ZPresent = p.ZPresent || p.Z != 0
+}
+
+func (p *Pad) flip() {
+	p.At.Y = negate(p.At.Y)
+	p.At.Z = normalizeAnglePos(-p.At.Z)
+	p.RectDelta.Y = negate(p.RectDelta.Y)
+	p.DrillOffset.Y = negate(p.DrillOffset.Y)
+	for i, l := range p.Layers {
+		p.Layers[i] = flipLayer(l)
+	}
+	for i := range p.Primitives {
+		flipModDrawable(p.Primitives[i].Renderable)
+	}
+}
+
+func flipModDrawable(d modDrawable) {
+	switch d := d.(type) {
+	case *ModText:
+		d.At.Y = negate(d.At.Y)
+		d.At.Z = normalizeAnglePos(-d.At.Z)
+		d.Layer = flipLayer(d.Layer)
+		d.Effects.Mirrored = strings.HasPrefix(d.Layer, "B.")
+		if d.Effects.Justify == JustifyMirror {
+			d.Effects.Justify = JustifyNone
+		}
+	case *ModLine:
+		d.Start.Y, d.End.Y = negate(d.Start.Y), negate(d.End.Y)
+		d.Layer = flipLayer(d.Layer)
+	case *ModCircle:
+		d.Center.Y, d.End.Y = negate(d.Center.Y), negate(d.End.Y)
+		d.Layer = flipLayer(d.Layer)
+	case *ModArc:
+		d.Start.Y, d.End.Y = negate(d.Start.Y), negate(d.End.Y)
+		d.Angle = -d.Angle
+		d.Layer = flipLayer(d.Layer)
+	case *ModPolygon:
+		d.At.Y = negate(d.At.Y)
+		for i := range d.Points {
+			d.Points[i].Y = negate(d.Points[i].Y)
+		}
+		d.Layer = flipLayer(d.Layer)
+	}
+}
+
+// flipLayer returns the layer on the opposite side of the board to the
+// given layer. Inner and non-sided layers are returned unchanged.
+func flipLayer(l string) string {
+	switch {
+	case strings.HasPrefix(l, "F."):
+		return "B." + l[2:]
+	case strings.HasPrefix(l, "B."):
+		return "F." + l[2:]
+	}
+	return l
+}
+
+// negate returns -v, avoiding negative zero which would be written as -0.
+func negate(v float64) float64 {
+	if v == 0 {
+		return 0
+	}
+	return -v
+}
+
+// normalizeAngle180 returns the equivalent angle in (-180, 180] degrees.
+func normalizeAngle180(deg float64) float64 {
+	deg = normalizeAnglePos(deg)
+	if deg > 180 {
+		deg -= 360
+	}
+	return deg
+}
+
+// normalizeAnglePos returns the equivalent angle in [0, 360) degrees.
+func normalizeAnglePos(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	if deg == 0 {
+		return 0
+	}
+	return deg
+}
@@ -0,0 +1,85 @@
+package pcb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const placementTestMod = `
+(module R_0805 (layer F.Cu)
+  (at 10 20 90)
+  (fp_text reference R1 (at 0 -1.65 90) (layer F.SilkS)
+    (effects (font (size 1 1) (thickness 0.15)))
+  )
+  (fp_text value 10k (at 0 1.65 90) (layer F.Fab)
+    (effects (font (size 1 1) (thickness 0.15)) (justify left))
+  )
+  (fp_line (start -1 0.6) (end 1 0.6) (layer F.Fab) (width 0.1))
+  (fp_arc (start 0 0) (end 0.5 0.5) (angle 90) (layer F.CrtYd) (width 0.05))
+  (pad 1 smd roundrect (at -0.95 0.1 90) (size 1 1.45) (layers F.Cu F.Paste F.Mask) (roundrect_rratio 0.25))
+  (pad 2 thru_hole circle (at 0.95 0 90) (size 1.2 1.2) (drill 0.6 (offset 0 0.1)) (layers *.Cu *.Mask))
+)
+`
+
+func TestPadPosition(t *testing.T) {
+	m, err := ParseModule(strings.NewReader(placementTestMod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []XYZ{
+		{X: 10.1, Y: 20.95, Z: 90, ZPresent: true},
+		{X: 10, Y: 19.05, Z: 90, ZPresent: true},
+	}
+	for i := range m.Pads {
+		if got := m.PadPosition(&m.Pads[i]); got != want[i] {
+			t.Errorf("PadPosition(%s) = %+v, want %+v", m.Pads[i].Ident, got, want[i])
+		}
+	}
+}
+
+func TestFlip(t *testing.T) {
+	m, err := ParseModule(strings.NewReader(placementTestMod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig, err := ParseModule(strings.NewReader(placementTestMod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Flip()
+
+	if !m.OnBack() || m.Placement.At.Z != -90 {
+		t.Errorf("flipped module on %s at %v, want B.Cu at -90", m.Layer, m.Placement.At.Z)
+	}
+	ref := m.Graphics[0].Renderable.(*ModText)
+	if ref.Layer != "B.SilkS" || ref.At.Y != 1.65 || ref.At.Z != 270 || !ref.Effects.Mirrored {
+		t.Errorf("reference = %+v, want mirrored on B.SilkS at (0, 1.65, 270)", ref)
+	}
+	if got, want := *m.Graphics[2].Renderable.(*ModLine), (ModLine{Start: XY{X: -1, Y: -0.6}, End: XY{X: 1, Y: -0.6}, Layer: "B.Fab", Width: 0.1}); got != want {
+		t.Errorf("line = %+v, want %+v", got, want)
+	}
+	if got := m.Graphics[3].Renderable.(*ModArc); got.Angle != -90 || got.End.Y != -0.5 || got.Layer != "B.CrtYd" {
+		t.Errorf("arc = %+v, want angle -90 ending at y=-0.5 on B.CrtYd", got)
+	}
+	if got, want := m.Pads[0].Layers, []string{"B.Cu", "B.Paste", "B.Mask"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pad 1 layers = %v, want %v", got, want)
+	}
+	if got := m.Pads[1]; got.DrillOffset.Y != -0.1 || got.At.Z != 270 || !reflect.DeepEqual(got.Layers, []string{"*.Cu", "*.Mask"}) {
+		t.Errorf("pad 2 = %+v, want drill offset -0.1, orientation 270 and unchanged layers", got)
+	}
+
+	// Flipping keeps the pads in place relative to the module position,
+	// mirrored about it.
+	for i := range m.Pads {
+		before, after := orig.PadPosition(&orig.Pads[i]), m.PadPosition(&m.Pads[i])
+		if after.X != before.X || after.Y-20 != 20-before.Y {
+			t.Errorf("pad %s moved from %+v to %+v", m.Pads[i].Ident, before, after)
+		}
+	}
+
+	m.Flip()
+	if !reflect.DeepEqual(m, orig) {
+		t.Errorf("flipping twice = %+v, want %+v", m, orig)
+	}
+}
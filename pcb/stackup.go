@@ -0,0 +1,157 @@
+package pcb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// frontCopperNum and backCopperNum are the layer numbers of the outer
+	// copper layers. Inner copper layers are numbered in between.
+	frontCopperNum = 0
+	backCopperNum  = 31
+
+	// MaxCopperLayers is the largest number of copper layers a board can have.
+	MaxCopperLayers = 32
+)
+
+// Thicknesses used when computing a default stackup, matching pcbnew.
+const (
+	stackupCopperThickness = 0.035
+	stackupMaskThickness   = 0.01
+)
+
+// InnerCopperLayer returns the name of the n'th inner copper layer,
+// counting from the front.
+func InnerCopperLayer(n int) string {
+	return fmt.Sprintf("In%d.Cu", n)
+}
+
+// IsCopper returns true if the layer carries copper.
+func (l *Layer) IsCopper() bool {
+	return strings.HasSuffix(l.Name, ".Cu") && l.Num >= frontCopperNum && l.Num <= backCopperNum
+}
+
+// CopperLayers returns the copper layers of the board, ordered from the
+// front to the back.
+func (p *PCB) CopperLayers() []*Layer {
+	var out []*Layer
+	for _, l := range p.Layers {
+		if l.IsCopper() {
+			out = append(out, l)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Num < out[j].Num
+	})
+	return out
+}
+
+// SetCopperLayers rebuilds the layer table so the board has n copper
+// layers. Existing copper layers keep their type and visibility, and
+// non-copper layers are left untouched.
+func (p *PCB) SetCopperLayers(n int) error {
+	if n < 2 || n > MaxCopperLayers || n%2 != 0 {
+		return fmt.Errorf("invalid number of copper layers %d: must be an even number between 2 and %d", n, MaxCopperLayers)
+	}
+
+	existing := map[string]*Layer{}
+	var others []*Layer
+	for _, l := range p.Layers {
+		if l.IsCopper() {
+			existing[l.Name] = l
+		} else {
+			others = append(others, l)
+		}
+	}
+	copper := func(num int, name string) *Layer {
+		if l, ok := existing[name]; ok {
+			l.Num = num
+			return l
+		}
+		return &Layer{Num: num, Name: name, Typ: "signal"}
+	}
+
+	layers := []*Layer{copper(frontCopperNum, "F.Cu")}
+	for i := 1; i <= n-2; i++ {
+		layers = append(layers, copper(i, InnerCopperLayer(i)))
+	}
+	layers = append(layers, copper(backCopperNum, "B.Cu"))
+	p.Layers = append(layers, others...)
+
+	p.LayersByName = make(map[string]*Layer, len(p.Layers))
+	for _, l := range p.Layers {
+		p.LayersByName[l.Name] = l
+	}
+	return nil
+}
+
+// StackupLayer describes a physical layer of the board.
+type StackupLayer struct {
+	Name string `json:"name"`
+	// Typ is one of "copper", "core", "prepreg" or "solder mask".
+	Typ       string  `json:"type"`
+	Thickness float64 `json:"thickness"`
+}
+
+// Stackup describes the physical layers of a board, from front to back.
+type Stackup struct {
+	Thickness float64        `json:"thickness"`
+	Layers    []StackupLayer `json:"layers"`
+}
+
+// DefaultStackup returns the stackup pcbnew assumes for a board with the
+// given number of copper layers and overall thickness: 35um copper and
+// 10um solder mask, with the remaining thickness split evenly between
+// alternating core and prepreg dielectrics.
+func DefaultStackup(copperLayers int, thickness float64) (*Stackup, error) {
+	if copperLayers < 1 || copperLayers > MaxCopperLayers {
+		return nil, fmt.Errorf("invalid number of copper layers %d", copperLayers)
+	}
+
+	var dielectric float64
+	if copperLayers > 1 {
+		dielectric = (thickness - float64(copperLayers)*stackupCopperThickness - 2*stackupMaskThickness) / float64(copperLayers-1)
+		if dielectric <= 0 {
+			return nil, fmt.Errorf("board thickness %v is too thin for %d copper layers", thickness, copperLayers)
+		}
+	}
+
+	s := &Stackup{Thickness: thickness}
+	s.Layers = append(s.Layers, StackupLayer{Name: "F.Mask", Typ: "solder mask", Thickness: stackupMaskThickness})
+	for i := 0; i < copperLayers; i++ {
+		name := InnerCopperLayer(i)
+		switch i {
+		case 0:
+			name = "F.Cu"
+		case copperLayers - 1:
+			name = "B.Cu"
+		}
+		s.Layers = append(s.Layers, StackupLayer{Name: name, Typ: "copper", Thickness: stackupCopperThickness})
+
+		if i < copperLayers-1 {
+			typ := "core"
+			if i%2 == 1 {
+				typ = "prepreg"
+			}
+			s.Layers = append(s.Layers, StackupLayer{
+				Name:      fmt.Sprintf("Dielectric %d", i+1),
+				Typ:       typ,
+				Thickness: roundNM(dielectric),
+			})
+		}
+	}
+	s.Layers = append(s.Layers, StackupLayer{Name: "B.Mask", Typ: "solder mask", Thickness: stackupMaskThickness})
+	return s, nil
+}
+
+// Stackup returns the default stackup for the copper layers and
+// thickness of the board.
+func (p *PCB) Stackup() (*Stackup, error) {
+	thickness := p.Thickness
+	if thickness == 0 {
+		thickness = DefaultThickness
+	}
+	return DefaultStackup(len(p.CopperLayers()), thickness)
+}
@@ -0,0 +1,94 @@
+package pcb
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSetCopperLayers(t *testing.T) {
+	p := EmptyPCB()
+	numLayers := len(p.Layers)
+	p.LayersByName["B.Cu"].Hidden = true
+
+	if err := p.SetCopperLayers(6); err != nil {
+		t.Fatalf("SetCopperLayers(6) failed: %v", err)
+	}
+	var got []string
+	for _, l := range p.CopperLayers() {
+		got = append(got, l.Name)
+	}
+	want := []string{"F.Cu", "In1.Cu", "In2.Cu", "In3.Cu", "In4.Cu", "B.Cu"}
+	if len(got) != len(want) {
+		t.Fatalf("copper layers = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("copper layer %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if l := p.LayersByName["In4.Cu"]; l == nil || l.Num != 4 || l.Typ != "signal" {
+		t.Errorf("In4.Cu = %+v, want signal layer 4", l)
+	}
+	if !p.LayersByName["B.Cu"].Hidden {
+		t.Error("B.Cu was not hidden after SetCopperLayers, want existing attributes preserved")
+	}
+	if got, want := len(p.Layers), numLayers+4; got != want {
+		t.Errorf("len(Layers) = %d, want %d", got, want)
+	}
+
+	if err := p.SetCopperLayers(2); err != nil {
+		t.Fatalf("SetCopperLayers(2) failed: %v", err)
+	}
+	if got, want := len(p.Layers), numLayers; got != want {
+		t.Errorf("len(Layers) = %d, want %d", got, want)
+	}
+	if _, ok := p.LayersByName["In1.Cu"]; ok {
+		t.Error("In1.Cu still present after SetCopperLayers(2)")
+	}
+
+	for _, n := range []int{0, 3, 34} {
+		if err := p.SetCopperLayers(n); err == nil {
+			t.Errorf("SetCopperLayers(%d) did not fail", n)
+		}
+	}
+}
+
+func TestDefaultStackup(t *testing.T) {
+	p := EmptyPCB()
+	if err := p.SetCopperLayers(4); err != nil {
+		t.Fatal(err)
+	}
+	s, err := p.Stackup()
+	if err != nil {
+		t.Fatalf("Stackup() failed: %v", err)
+	}
+
+	want := []struct{ name, typ string }{
+		{"F.Mask", "solder mask"},
+		{"F.Cu", "copper"},
+		{"Dielectric 1", "core"},
+		{"In1.Cu", "copper"},
+		{"Dielectric 2", "prepreg"},
+		{"In2.Cu", "copper"},
+		{"Dielectric 3", "core"},
+		{"B.Cu", "copper"},
+		{"B.Mask", "solder mask"},
+	}
+	if len(s.Layers) != len(want) {
+		t.Fatalf("stackup has %d layers, want %d: %+v", len(s.Layers), len(want), s.Layers)
+	}
+	var total float64
+	for i, l := range s.Layers {
+		if l.Name != want[i].name || l.Typ != want[i].typ {
+			t.Errorf("stackup layer %d = {%q, %q}, want {%q, %q}", i, l.Name, l.Typ, want[i].name, want[i].typ)
+		}
+		total += l.Thickness
+	}
+	if math.Abs(total-1.6) > 1e-6 || s.Thickness != 1.6 {
+		t.Errorf("stackup thickness = %v (layers sum to %v), want 1.6", s.Thickness, total)
+	}
+
+	if _, err := DefaultStackup(32, 0.5); err == nil {
+		t.Error("DefaultStackup(32, 0.5) did not fail")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"errors"
 	"fmt"
+	"strings"
 
 	"go.starlark.net/starlark"
 )
@@ -260,13 +261,30 @@ func (t ViaType) Hash() (uint32, error) {
 	return uint32(t), nil
 }
 
+// unpackNet interprets v as a reference to a net, either by number or by
+// name. Nets referred to by name are resolved to a number once the
+// element is added to a PCB.
+func unpackNet(field string, v starlark.Value) (int, string, error) {
+	switch v := v.(type) {
+	case starlark.Int:
+		i, ok := v.Int64()
+		if !ok {
+			return 0, "", fmt.Errorf("cannot convert %v to int64", v)
+		}
+		return int(i), "", nil
+	case starlark.String:
+		return 0, string(v), nil
+	}
+	return 0, "", fmt.Errorf("cannot assign to %s using type %T", field, v)
+}
+
 var MakeVia = starlark.NewBuiltin("Via", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var (
 		f0 *XY
 		f1 starlark.Float
 		f2 starlark.Float
 		f3 *starlark.List
-		f4 starlark.Int
+		f4 starlark.Value
 		f5 starlark.String
 		f6 ViaType
 	)
@@ -302,8 +320,11 @@ var MakeVia = starlark.NewBuiltin("Via", func(t *starlark.Thread, f *starlark.Bu
 		}
 	}
 
-	if v, ok := f4.Int64(); ok {
-		out.NetIndex = int(v)
+	if f4 != nil {
+		var err error
+		if out.NetIndex, out.netName, err = unpackNet("net_index", f4); err != nil {
+			return starlark.None, err
+		}
 	}
 	out.StatusFlags = string(f5)
 	out.ViaType = f6
@@ -355,6 +376,9 @@ func (p *Via) Attr(name string) (starlark.Value, error) {
 		return l, nil
 
 	case "net_index":
+		if p.netName != "" {
+			return starlark.String(p.netName), nil
+		}
 		return starlark.MakeInt(p.NetIndex), nil
 
 	case "status_flags":
@@ -378,6 +402,7 @@ func (p *Via) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to at using type %T", val)
 		}
 		p.At = *v
+		return nil
 
 	case "size":
 		v, ok := val.(starlark.Float)
@@ -385,6 +410,7 @@ func (p *Via) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to size using type %T", val)
 		}
 		p.Size = float64(v)
+		return nil
 
 	case "drill":
 		v, ok := val.(starlark.Float)
@@ -392,6 +418,7 @@ func (p *Via) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to drill using type %T", val)
 		}
 		p.Drill = float64(v)
+		return nil
 
 	case "layers":
 		v, ok := val.(*starlark.List)
@@ -399,6 +426,7 @@ func (p *Via) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to layers using type %T", val)
 		}
 
+		p.Layers = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(starlark.String)
 			if !ok {
@@ -406,17 +434,15 @@ func (p *Via) SetField(name string, val starlark.Value) error {
 			}
 			p.Layers = append(p.Layers, string(s))
 		}
+		return nil
 
 	case "net_index":
-		v, ok := val.(*starlark.Int)
-		if !ok {
-			return fmt.Errorf("cannot assign to net_index using type %T", val)
-		}
-		i, ok := v.Int64()
-		if !ok {
-			return fmt.Errorf("cannot convert %v to int64", v)
+		num, name, err := unpackNet("net_index", val)
+		if err != nil {
+			return err
 		}
-		p.NetIndex = int(i)
+		p.NetIndex, p.netName = num, name
+		return nil
 
 	case "status_flags":
 		v, ok := val.(starlark.String)
@@ -424,6 +450,7 @@ func (p *Via) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to status_flags using type %T", val)
 		}
 		p.StatusFlags = string(v)
+		return nil
 
 	}
 
@@ -436,7 +463,7 @@ var MakeTrack = starlark.NewBuiltin("Track", func(t *starlark.Thread, f *starlar
 		f1 *XY
 		f2 starlark.Float
 		f3 starlark.String
-		f4 starlark.Int
+		f4 starlark.Value
 		f5 starlark.String
 		f6 starlark.String
 	)
@@ -466,8 +493,11 @@ var MakeTrack = starlark.NewBuiltin("Track", func(t *starlark.Thread, f *starlar
 	out.Width = float64(f2)
 	out.Layer = string(f3)
 
-	if v, ok := f4.Int64(); ok {
-		out.NetIndex = int(v)
+	if f4 != nil {
+		var err error
+		if out.NetIndex, out.netName, err = unpackNet("net_index", f4); err != nil {
+			return starlark.None, err
+		}
 	}
 	out.Tstamp = string(f5)
 	out.StatusFlags = string(f6)
@@ -514,6 +544,9 @@ func (p *Track) Attr(name string) (starlark.Value, error) {
 		return starlark.String(p.Layer), nil
 
 	case "net_index":
+		if p.netName != "" {
+			return starlark.String(p.netName), nil
+		}
 		return starlark.MakeInt(p.NetIndex), nil
 
 	case "tstamp":
@@ -540,6 +573,7 @@ func (p *Track) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to start using type %T", val)
 		}
 		p.Start = *v
+		return nil
 
 	case "end":
 		v, ok := val.(*XY)
@@ -547,6 +581,7 @@ func (p *Track) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to end using type %T", val)
 		}
 		p.End = *v
+		return nil
 
 	case "width":
 		v, ok := val.(starlark.Float)
@@ -554,6 +589,7 @@ func (p *Track) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to width using type %T", val)
 		}
 		p.Width = float64(v)
+		return nil
 
 	case "layer":
 		v, ok := val.(starlark.String)
@@ -561,17 +597,15 @@ func (p *Track) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to layer using type %T", val)
 		}
 		p.Layer = string(v)
+		return nil
 
 	case "net_index":
-		v, ok := val.(*starlark.Int)
-		if !ok {
-			return fmt.Errorf("cannot assign to net_index using type %T", val)
-		}
-		i, ok := v.Int64()
-		if !ok {
-			return fmt.Errorf("cannot convert %v to int64", v)
+		num, name, err := unpackNet("net_index", val)
+		if err != nil {
+			return err
 		}
-		p.NetIndex = int(i)
+		p.NetIndex, p.netName = num, name
+		return nil
 
 	case "tstamp":
 		v, ok := val.(starlark.String)
@@ -579,6 +613,7 @@ func (p *Track) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to tstamp using type %T", val)
 		}
 		p.Tstamp = string(v)
+		return nil
 
 	case "status_flags":
 		v, ok := val.(starlark.String)
@@ -586,228 +621,1808 @@ func (p *Track) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to status_flags using type %T", val)
 		}
 		p.StatusFlags = string(v)
+		return nil
 
 	}
 
 	return errors.New("no such assignable field: " + name)
 }
 
-var MakeLayer = starlark.NewBuiltin("Layer", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+var MakeZoneHatch = starlark.NewBuiltin("ZoneHatch", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var (
-		f0 starlark.Int
+		f0 starlark.String = "edge"
+		f1 starlark.Float  = 0.508
+	)
+	unpackErr := starlark.UnpackArgs(
+		"ZoneHatch",
+		args,
+		kwargs,
+		"mode?", &f0,
+		"pitch?", &f1,
+	)
+	if unpackErr != nil {
+		return starlark.None, unpackErr
+	}
+	out := ZoneHatch{}
+
+	out.Mode = string(f0)
+	out.Pitch = float64(f1)
+	return &out, nil
+})
+
+func (p *ZoneHatch) String() string {
+	return fmt.Sprintf("ZoneHatch{%v, %v}", p.Mode, p.Pitch)
+}
+
+// Type implements starlark.Value.
+func (p *ZoneHatch) Type() string {
+	return "ZoneHatch"
+}
+
+// Freeze implements starlark.Value.
+func (p *ZoneHatch) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *ZoneHatch) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *ZoneHatch) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *ZoneHatch) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "mode":
+		return starlark.String(p.Mode), nil
+
+	case "pitch":
+		return starlark.Float(p.Pitch), nil
+	}
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *ZoneHatch) AttrNames() []string {
+	return []string{"mode", "pitch"}
+}
+
+// SetField implements starlark.HasSetField.
+func (p *ZoneHatch) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "mode":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to mode using type %T", val)
+		}
+		p.Mode = string(v)
+		return nil
+
+	case "pitch":
+		v, ok := val.(starlark.Float)
+		if !ok {
+			return fmt.Errorf("cannot assign to pitch using type %T", val)
+		}
+		p.Pitch = float64(v)
+		return nil
+	}
+
+	return errors.New("no such assignable field: " + name)
+}
+
+var MakeZoneConnectPads = starlark.NewBuiltin("ZoneConnectPads", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		f0 starlark.Float = 0.508
 		f1 starlark.String
-		f2 starlark.String
-		f3 starlark.Bool
 	)
 	unpackErr := starlark.UnpackArgs(
-		"Layer",
+		"ZoneConnectPads",
 		args,
 		kwargs,
-		"num?", &f0,
-		"name?", &f1,
-		"typ?", &f2,
-		"hidden?", &f3,
+		"clearance?", &f0,
+		"mode?", &f1,
 	)
 	if unpackErr != nil {
 		return starlark.None, unpackErr
 	}
-	out := Layer{}
+	out := ZoneConnectPads{}
 
-	if v, ok := f0.Int64(); ok {
-		out.Num = int(v)
+	out.Clearance = float64(f0)
+	out.Mode = string(f1)
+	return &out, nil
+})
+
+func (p *ZoneConnectPads) String() string {
+	return fmt.Sprintf("ZoneConnectPads{%v, %v}", p.Clearance, p.Mode)
+}
+
+// Type implements starlark.Value.
+func (p *ZoneConnectPads) Type() string {
+	return "ZoneConnectPads"
+}
+
+// Freeze implements starlark.Value.
+func (p *ZoneConnectPads) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *ZoneConnectPads) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *ZoneConnectPads) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *ZoneConnectPads) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "clearance":
+		return starlark.Float(p.Clearance), nil
+
+	case "mode":
+		return starlark.String(p.Mode), nil
 	}
-	out.Name = string(f1)
-	out.Typ = string(f2)
-	out.Hidden = bool(f3)
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *ZoneConnectPads) AttrNames() []string {
+	return []string{"clearance", "mode"}
+}
+
+// SetField implements starlark.HasSetField.
+func (p *ZoneConnectPads) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "clearance":
+		v, ok := val.(starlark.Float)
+		if !ok {
+			return fmt.Errorf("cannot assign to clearance using type %T", val)
+		}
+		p.Clearance = float64(v)
+		return nil
+
+	case "mode":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to mode using type %T", val)
+		}
+		p.Mode = string(v)
+		return nil
+	}
+
+	return errors.New("no such assignable field: " + name)
+}
+
+var MakeZoneFill = starlark.NewBuiltin("ZoneFill", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		f0 starlark.Bool
+		f1 starlark.String
+		f2 starlark.Int   = starlark.MakeInt(32)
+		f3 starlark.Float = 0.508
+		f4 starlark.Float = 0.508
+	)
+	unpackErr := starlark.UnpackArgs(
+		"ZoneFill",
+		args,
+		kwargs,
+		"is_filled?", &f0,
+		"mode?", &f1,
+		"segments?", &f2,
+		"thermal_gap?", &f3,
+		"thermal_bridge_width?", &f4,
+	)
+	if unpackErr != nil {
+		return starlark.None, unpackErr
+	}
+	out := ZoneFill{}
+
+	out.IsFilled = bool(f0)
+	out.Mode = string(f1)
+	if v, ok := f2.Int64(); ok {
+		out.Segments = int(v)
+	}
+	out.ThermalGap = float64(f3)
+	out.ThermalBridgeWidth = float64(f4)
 	return &out, nil
 })
 
-func (p *Layer) String() string {
-	return fmt.Sprintf("Layer{%v, %v, %v, %v}", p.Num, p.Name, p.Typ, p.Hidden)
-}
+func (p *ZoneFill) String() string {
+	return fmt.Sprintf("ZoneFill{%v, %v, %v, %v, %v}", p.IsFilled, p.Mode, p.Segments, p.ThermalGap, p.ThermalBridgeWidth)
+}
+
+// Type implements starlark.Value.
+func (p *ZoneFill) Type() string {
+	return "ZoneFill"
+}
+
+// Freeze implements starlark.Value.
+func (p *ZoneFill) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *ZoneFill) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *ZoneFill) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *ZoneFill) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "is_filled":
+		return starlark.Bool(p.IsFilled), nil
+
+	case "mode":
+		return starlark.String(p.Mode), nil
+
+	case "segments":
+		return starlark.MakeInt(p.Segments), nil
+
+	case "thermal_gap":
+		return starlark.Float(p.ThermalGap), nil
+
+	case "thermal_bridge_width":
+		return starlark.Float(p.ThermalBridgeWidth), nil
+	}
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *ZoneFill) AttrNames() []string {
+	return []string{"is_filled", "mode", "segments", "thermal_gap", "thermal_bridge_width"}
+}
+
+// SetField implements starlark.HasSetField.
+func (p *ZoneFill) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "is_filled":
+		v, ok := val.(starlark.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign to is_filled using type %T", val)
+		}
+		p.IsFilled = bool(v)
+		return nil
+
+	case "mode":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to mode using type %T", val)
+		}
+		p.Mode = string(v)
+		return nil
+
+	case "segments":
+		v, ok := val.(starlark.Int)
+		if !ok {
+			return fmt.Errorf("cannot assign to segments using type %T", val)
+		}
+		i, ok := v.Int64()
+		if !ok {
+			return fmt.Errorf("cannot convert %v to int64", v)
+		}
+		p.Segments = int(i)
+		return nil
+
+	case "thermal_gap":
+		v, ok := val.(starlark.Float)
+		if !ok {
+			return fmt.Errorf("cannot assign to thermal_gap using type %T", val)
+		}
+		p.ThermalGap = float64(v)
+		return nil
+
+	case "thermal_bridge_width":
+		v, ok := val.(starlark.Float)
+		if !ok {
+			return fmt.Errorf("cannot assign to thermal_bridge_width using type %T", val)
+		}
+		p.ThermalBridgeWidth = float64(v)
+		return nil
+	}
+
+	return errors.New("no such assignable field: " + name)
+}
+
+var MakeZoneKeepout = starlark.NewBuiltin("ZoneKeepout", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		f0 starlark.Bool
+		f1 starlark.Bool
+		f2 starlark.Bool
+	)
+	unpackErr := starlark.UnpackArgs(
+		"ZoneKeepout",
+		args,
+		kwargs,
+		"tracks_allowed?", &f0,
+		"vias_allowed?", &f1,
+		"copperpour_allowed?", &f2,
+	)
+	if unpackErr != nil {
+		return starlark.None, unpackErr
+	}
+	out := ZoneKeepout{}
+
+	out.TracksAllowed = bool(f0)
+	out.ViasAllowed = bool(f1)
+	out.CopperPourAllowed = bool(f2)
+	return &out, nil
+})
+
+func (p *ZoneKeepout) String() string {
+	return fmt.Sprintf("ZoneKeepout{%v, %v, %v}", p.TracksAllowed, p.ViasAllowed, p.CopperPourAllowed)
+}
+
+// Type implements starlark.Value.
+func (p *ZoneKeepout) Type() string {
+	return "ZoneKeepout"
+}
+
+// Freeze implements starlark.Value.
+func (p *ZoneKeepout) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *ZoneKeepout) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *ZoneKeepout) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *ZoneKeepout) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "tracks_allowed":
+		return starlark.Bool(p.TracksAllowed), nil
+
+	case "vias_allowed":
+		return starlark.Bool(p.ViasAllowed), nil
+
+	case "copperpour_allowed":
+		return starlark.Bool(p.CopperPourAllowed), nil
+	}
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *ZoneKeepout) AttrNames() []string {
+	return []string{"tracks_allowed", "vias_allowed", "copperpour_allowed"}
+}
+
+// SetField implements starlark.HasSetField.
+func (p *ZoneKeepout) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "tracks_allowed":
+		v, ok := val.(starlark.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign to tracks_allowed using type %T", val)
+		}
+		p.TracksAllowed = bool(v)
+		return nil
+
+	case "vias_allowed":
+		v, ok := val.(starlark.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign to vias_allowed using type %T", val)
+		}
+		p.ViasAllowed = bool(v)
+		return nil
+
+	case "copperpour_allowed":
+		v, ok := val.(starlark.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign to copperpour_allowed using type %T", val)
+		}
+		p.CopperPourAllowed = bool(v)
+		return nil
+	}
+
+	return errors.New("no such assignable field: " + name)
+}
+
+// unpackPoly converts a list of XY into a polygon.
+func unpackPoly(name string, v *starlark.List) ([]XY, error) {
+	var out []XY
+	for i := 0; i < v.Len(); i++ {
+		s, ok := v.Index(i).(*XY)
+		if !ok {
+			return nil, fmt.Errorf("%s element is not a XY", name)
+		}
+		out = append(out, *s)
+	}
+	return out, nil
+}
+
+// unpackPolys converts a list of lists of XY into polygons.
+func unpackPolys(name string, v *starlark.List) ([][]XY, error) {
+	var out [][]XY
+	for i := 0; i < v.Len(); i++ {
+		pl, ok := v.Index(i).(*starlark.List)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a list", name)
+		}
+		poly, err := unpackPoly(name, pl)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, poly)
+	}
+	return out, nil
+}
+
+// makeZone implements the Zone and Keepout builtins, which differ only
+// in which arguments they accept.
+func makeZone(fnName string, args starlark.Tuple, kwargs []starlark.Tuple, isKeepout bool) (starlark.Value, error) {
+	var (
+		netNum      starlark.Value
+		netName     starlark.String
+		layers      *starlark.List
+		tstamp      starlark.String = "0"
+		priority    starlark.Int
+		hatch       *ZoneHatch
+		connectPads *ZoneConnectPads
+		fill        *ZoneFill
+		keepout     *ZoneKeepout
+		minThick    starlark.Float = 0.254
+		outline     *starlark.List
+		basePolys   *starlark.List
+	)
+	params := []interface{}{
+		"layers?", &layers,
+		"outline?", &outline,
+		"base_polys?", &basePolys,
+		"tstamp?", &tstamp,
+		"priority?", &priority,
+		"hatch?", &hatch,
+	}
+	if isKeepout {
+		params = append(params, "keepout?", &keepout)
+	} else {
+		params = append(params,
+			"net_num?", &netNum,
+			"net_name?", &netName,
+			"connect_pads?", &connectPads,
+			"fill?", &fill,
+			"min_thickness?", &minThick)
+	}
+	if err := starlark.UnpackArgs(fnName, args, kwargs, params...); err != nil {
+		return starlark.None, err
+	}
+
+	out := Zone{
+		IsKeepout:    isKeepout,
+		NetName:      string(netName),
+		Tstamp:       string(tstamp),
+		Hatch:        ZoneHatch{Mode: "edge", Pitch: 0.508},
+		ConnectPads:  ZoneConnectPads{Clearance: 0.508},
+		Fill:         ZoneFill{Segments: 32, ThermalGap: 0.508, ThermalBridgeWidth: 0.508},
+		MinThickness: float64(minThick),
+	}
+	if netNum != nil {
+		num, name, err := unpackNet("net_num", netNum)
+		if err != nil {
+			return starlark.None, err
+		}
+		out.NetNum = num
+		if name != "" {
+			out.NetName = name
+		}
+	}
+	if v, ok := priority.Int64(); ok {
+		out.Priority = int(v)
+	}
+	if layers != nil {
+		for i := 0; i < layers.Len(); i++ {
+			s, ok := layers.Index(i).(starlark.String)
+			if !ok {
+				return starlark.None, errors.New("layers is not a string")
+			}
+			out.Layers = append(out.Layers, string(s))
+		}
+	}
+	if len(out.Layers) == 0 {
+		return starlark.None, fmt.Errorf("%s: at least one layer must be specified", fnName)
+	}
+	if hatch != nil {
+		out.Hatch = *hatch
+	}
+	if connectPads != nil {
+		out.ConnectPads = *connectPads
+	}
+	if fill != nil {
+		out.Fill = *fill
+	}
+	if keepout != nil {
+		out.Keepout = *keepout
+	}
+	if outline != nil {
+		poly, err := unpackPoly("outline", outline)
+		if err != nil {
+			return starlark.None, err
+		}
+		out.BasePolys = append(out.BasePolys, poly)
+	}
+	if basePolys != nil {
+		polys, err := unpackPolys("base_polys", basePolys)
+		if err != nil {
+			return starlark.None, err
+		}
+		out.BasePolys = append(out.BasePolys, polys...)
+	}
+	return &out, nil
+}
+
+// MakeZone creates a copper zone.
+var MakeZone = starlark.NewBuiltin("Zone", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return makeZone("Zone", args, kwargs, false)
+})
+
+// MakeKeepout creates a keepout zone, which by default prohibits tracks,
+// vias and copper pours.
+var MakeKeepout = starlark.NewBuiltin("Keepout", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return makeZone("Keepout", args, kwargs, true)
+})
+
+func (p *Zone) String() string {
+	return fmt.Sprintf("Zone{%v, %v, %v, %v, %v, %v, %v, %v, %v, %v, %v, %v, %v, %v}", p.IsKeepout, p.NetNum, p.NetName, p.Layers, p.Tstamp, p.Priority, p.Hatch, p.ConnectPads, p.Fill, p.Keepout, p.MinThickness, p.FilledAreaThickness, p.Polys, p.BasePolys)
+}
+
+// Type implements starlark.Value.
+func (p *Zone) Type() string {
+	return "Zone"
+}
+
+// Freeze implements starlark.Value.
+func (p *Zone) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *Zone) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *Zone) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *Zone) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "is_keepout":
+		return starlark.Bool(p.IsKeepout), nil
+
+	case "net_num":
+		return starlark.MakeInt(p.NetNum), nil
+
+	case "net_name":
+		return starlark.String(p.NetName), nil
+
+	case "layers":
+		l := starlark.NewList(nil)
+		for _, e := range p.Layers {
+			l.Append(starlark.String(e))
+		}
+		return l, nil
+
+	case "tstamp":
+		return starlark.String(p.Tstamp), nil
+
+	case "priority":
+		return starlark.MakeInt(p.Priority), nil
+
+	case "hatch":
+		return &p.Hatch, nil
+
+	case "connect_pads":
+		return &p.ConnectPads, nil
+
+	case "fill":
+		return &p.Fill, nil
+
+	case "keepout":
+		return &p.Keepout, nil
+
+	case "min_thickness":
+		return starlark.Float(p.MinThickness), nil
+
+	case "filled_area_thickness":
+		return starlark.Bool(p.FilledAreaThickness), nil
+
+	case "polys":
+		l := starlark.NewList(nil)
+		for i := range p.Polys {
+			poly := starlark.NewList(nil)
+			for j := range p.Polys[i] {
+				poly.Append(&p.Polys[i][j])
+			}
+			l.Append(poly)
+		}
+		return l, nil
+
+	case "base_polys":
+		l := starlark.NewList(nil)
+		for i := range p.BasePolys {
+			poly := starlark.NewList(nil)
+			for j := range p.BasePolys[i] {
+				poly.Append(&p.BasePolys[i][j])
+			}
+			l.Append(poly)
+		}
+		return l, nil
+	}
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *Zone) AttrNames() []string {
+	return []string{"is_keepout", "net_num", "net_name", "layers", "tstamp", "priority", "hatch", "connect_pads", "fill", "keepout", "min_thickness", "filled_area_thickness", "polys", "base_polys"}
+}
+
+// SetField implements starlark.HasSetField.
+func (p *Zone) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "is_keepout":
+		v, ok := val.(starlark.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign to is_keepout using type %T", val)
+		}
+		p.IsKeepout = bool(v)
+		return nil
+
+	case "net_num":
+		num, name, err := unpackNet("net_num", val)
+		if err != nil {
+			return err
+		}
+		p.NetNum = num
+		if name != "" {
+			p.NetName = name
+		}
+		return nil
+
+	case "net_name":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to net_name using type %T", val)
+		}
+		p.NetName = string(v)
+		return nil
+
+	case "layers":
+		v, ok := val.(*starlark.List)
+		if !ok {
+			return fmt.Errorf("cannot assign to layers using type %T", val)
+		}
+
+		p.Layers = nil
+		for i := 0; i < v.Len(); i++ {
+			s, ok := v.Index(i).(starlark.String)
+			if !ok {
+				return errors.New("layers is not a string")
+			}
+			p.Layers = append(p.Layers, string(s))
+		}
+		return nil
+
+	case "tstamp":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to tstamp using type %T", val)
+		}
+		p.Tstamp = string(v)
+		return nil
+
+	case "priority":
+		v, ok := val.(starlark.Int)
+		if !ok {
+			return fmt.Errorf("cannot assign to priority using type %T", val)
+		}
+		i, ok := v.Int64()
+		if !ok {
+			return fmt.Errorf("cannot convert %v to int64", v)
+		}
+		p.Priority = int(i)
+		return nil
+
+	case "hatch":
+		v, ok := val.(*ZoneHatch)
+		if !ok {
+			return fmt.Errorf("cannot assign to hatch using type %T", val)
+		}
+		p.Hatch = *v
+		return nil
+
+	case "connect_pads":
+		v, ok := val.(*ZoneConnectPads)
+		if !ok {
+			return fmt.Errorf("cannot assign to connect_pads using type %T", val)
+		}
+		p.ConnectPads = *v
+		return nil
+
+	case "fill":
+		v, ok := val.(*ZoneFill)
+		if !ok {
+			return fmt.Errorf("cannot assign to fill using type %T", val)
+		}
+		p.Fill = *v
+		return nil
+
+	case "keepout":
+		v, ok := val.(*ZoneKeepout)
+		if !ok {
+			return fmt.Errorf("cannot assign to keepout using type %T", val)
+		}
+		p.Keepout = *v
+		return nil
+
+	case "min_thickness":
+		v, ok := val.(starlark.Float)
+		if !ok {
+			return fmt.Errorf("cannot assign to min_thickness using type %T", val)
+		}
+		p.MinThickness = float64(v)
+		return nil
+
+	case "filled_area_thickness":
+		v, ok := val.(starlark.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign to filled_area_thickness using type %T", val)
+		}
+		p.FilledAreaThickness = bool(v)
+		return nil
+
+	case "polys":
+		v, ok := val.(*starlark.List)
+		if !ok {
+			return fmt.Errorf("cannot assign to polys using type %T", val)
+		}
+
+		polys, err := unpackPolys("polys", v)
+		if err != nil {
+			return err
+		}
+		p.Polys = polys
+		return nil
+
+	case "base_polys":
+		v, ok := val.(*starlark.List)
+		if !ok {
+			return fmt.Errorf("cannot assign to base_polys using type %T", val)
+		}
+
+		polys, err := unpackPolys("base_polys", v)
+		if err != nil {
+			return err
+		}
+		p.BasePolys = polys
+		return nil
+	}
+
+	return errors.New("no such assignable field: " + name)
+}
+
+var MakeLayer = starlark.NewBuiltin("Layer", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		f0 starlark.Int
+		f1 starlark.String
+		f2 starlark.String
+		f3 starlark.Bool
+	)
+	unpackErr := starlark.UnpackArgs(
+		"Layer",
+		args,
+		kwargs,
+		"num?", &f0,
+		"name?", &f1,
+		"typ?", &f2,
+		"hidden?", &f3,
+	)
+	if unpackErr != nil {
+		return starlark.None, unpackErr
+	}
+	out := Layer{}
+
+	if v, ok := f0.Int64(); ok {
+		out.Num = int(v)
+	}
+	out.Name = string(f1)
+	out.Typ = string(f2)
+	out.Hidden = bool(f3)
+	return &out, nil
+})
+
+func (p *Layer) String() string {
+	return fmt.Sprintf("Layer{%v, %v, %v, %v}", p.Num, p.Name, p.Typ, p.Hidden)
+}
+
+// Type implements starlark.Value.
+func (p *Layer) Type() string {
+	return "Layer"
+}
+
+// Freeze implements starlark.Value.
+func (p *Layer) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *Layer) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *Layer) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *Layer) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "num":
+		return starlark.MakeInt(p.Num), nil
+
+	case "name":
+		return starlark.String(p.Name), nil
+
+	case "type":
+		return starlark.String(p.Typ), nil
+
+	case "hidden":
+		return starlark.Bool(p.Hidden), nil
+	}
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *Layer) AttrNames() []string {
+	return []string{"num", "name", "type", "hidden"}
+}
+
+// SetField implements starlark.HasSetField.
+func (p *Layer) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "num":
+		v, ok := val.(starlark.Int)
+		if !ok {
+			return fmt.Errorf("cannot assign to num using type %T", val)
+		}
+		i, ok := v.Int64()
+		if !ok {
+			return fmt.Errorf("cannot convert %v to int64", v)
+		}
+		p.Num = int(i)
+		return nil
+
+	case "name":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to name using type %T", val)
+		}
+		p.Name = string(v)
+		return nil
+
+	case "type":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to typ using type %T", val)
+		}
+		p.Typ = string(v)
+		return nil
+
+	case "hidden":
+		v, ok := val.(starlark.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign to hidden using type %T", val)
+		}
+		p.Hidden = bool(v)
+		return nil
+
+	}
+
+	return errors.New("no such assignable field: " + name)
+}
+
+func (p *StackupLayer) String() string {
+	return fmt.Sprintf("StackupLayer{%v, %v, %v}", p.Name, p.Typ, p.Thickness)
+}
+
+// Type implements starlark.Value.
+func (p *StackupLayer) Type() string {
+	return "StackupLayer"
+}
+
+// Freeze implements starlark.Value.
+func (p *StackupLayer) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *StackupLayer) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *StackupLayer) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *StackupLayer) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "name":
+		return starlark.String(p.Name), nil
+
+	case "type":
+		return starlark.String(p.Typ), nil
+
+	case "thickness":
+		return starlark.Float(p.Thickness), nil
+	}
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *StackupLayer) AttrNames() []string {
+	return []string{"name", "type", "thickness"}
+}
+
+var MakePage = starlark.NewBuiltin("Page", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		f0 starlark.String = "A4"
+		f1 starlark.Value
+		f2 starlark.Value
+		f3 starlark.Bool
+	)
+	unpackErr := starlark.UnpackArgs(
+		"Page",
+		args,
+		kwargs,
+		"size?", &f0,
+		"width?", &f1,
+		"height?", &f2,
+		"portrait?", &f3,
+	)
+	if unpackErr != nil {
+		return starlark.None, unpackErr
+	}
+	out := Page{Size: string(f0), Portrait: bool(f3)}
+	if !pageSizes[out.Size] {
+		return starlark.None, fmt.Errorf("Page: unknown size %q", out.Size)
+	}
+
+	if f1 != nil {
+		if err := out.SetField("width", f1); err != nil {
+			return starlark.None, err
+		}
+	}
+	if f2 != nil {
+		if err := out.SetField("height", f2); err != nil {
+			return starlark.None, err
+		}
+	}
+	if out.Size == "User" && (out.Width <= 0 || out.Height <= 0) {
+		return starlark.None, errors.New("Page: width and height must be specified for a User page")
+	}
+	return &out, nil
+})
+
+func (p *Page) String() string {
+	return fmt.Sprintf("Page{%v, %v, %v, %v}", p.Size, p.Width, p.Height, p.Portrait)
+}
+
+// Type implements starlark.Value.
+func (p *Page) Type() string {
+	return "Page"
+}
+
+// Freeze implements starlark.Value.
+func (p *Page) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *Page) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *Page) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *Page) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "size":
+		return starlark.String(p.Size), nil
+
+	case "width":
+		return starlark.Float(p.Width), nil
+
+	case "height":
+		return starlark.Float(p.Height), nil
+
+	case "portrait":
+		return starlark.Bool(p.Portrait), nil
+	}
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *Page) AttrNames() []string {
+	return []string{"size", "width", "height", "portrait"}
+}
+
+// SetField implements starlark.HasSetField.
+func (p *Page) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "size":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to size using type %T", val)
+		}
+		if !pageSizes[string(v)] {
+			return fmt.Errorf("unknown page size %q", string(v))
+		}
+		p.Size = string(v)
+		return nil
+
+	case "width":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to width using type %T", val)
+		}
+		p.Width = v
+		return nil
+
+	case "height":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to height using type %T", val)
+		}
+		p.Height = v
+		return nil
+
+	case "portrait":
+		v, ok := val.(starlark.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign to portrait using type %T", val)
+		}
+		p.Portrait = bool(v)
+		return nil
+	}
+
+	return errors.New("no such assignable field: " + name)
+}
+
+var MakePCBCreatedBy = starlark.NewBuiltin("PCBCreatedBy", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		f0 starlark.String
+		f1 starlark.String
+	)
+	unpackErr := starlark.UnpackArgs(
+		"PCBCreatedBy",
+		args,
+		kwargs,
+		"tool?", &f0,
+		"version?", &f1,
+	)
+	if unpackErr != nil {
+		return starlark.None, unpackErr
+	}
+	out := PCBCreatedBy{}
+
+	out.Tool = string(f0)
+	out.Version = string(f1)
+	return &out, nil
+})
+
+func (p *PCBCreatedBy) String() string {
+	return fmt.Sprintf("PCBCreatedBy{%v, %v}", p.Tool, p.Version)
+}
+
+// Type implements starlark.Value.
+func (p *PCBCreatedBy) Type() string {
+	return "PCBCreatedBy"
+}
+
+// Freeze implements starlark.Value.
+func (p *PCBCreatedBy) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *PCBCreatedBy) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *PCBCreatedBy) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *PCBCreatedBy) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "tool":
+		return starlark.String(p.Tool), nil
+
+	case "version":
+		return starlark.String(p.Version), nil
+	}
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *PCBCreatedBy) AttrNames() []string {
+	return []string{"tool", "version"}
+}
+
+// SetField implements starlark.HasSetField.
+func (p *PCBCreatedBy) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "tool":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to tool using type %T", val)
+		}
+		p.Tool = string(v)
+		return nil
+
+	case "version":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to version using type %T", val)
+		}
+		p.Version = string(v)
+		return nil
+
+	}
+
+	return errors.New("no such assignable field: " + name)
+}
+
+var MakeTitleInfo = starlark.NewBuiltin("TitleInfo", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		f0 starlark.String
+		f1 starlark.String
+		f2 starlark.String
+		f3 starlark.String
+		f4 *starlark.List
+	)
+	unpackErr := starlark.UnpackArgs(
+		"TitleInfo",
+		args,
+		kwargs,
+		"title?", &f0,
+		"date?", &f1,
+		"revision?", &f2,
+		"company?", &f3,
+		"comments?", &f4,
+	)
+	if unpackErr != nil {
+		return starlark.None, unpackErr
+	}
+	out := TitleInfo{}
+
+	out.Title = string(f0)
+	out.Date = string(f1)
+	out.Revision = string(f2)
+	out.Company = string(f3)
+	if f4 != nil {
+		if err := out.SetField("comments", f4); err != nil {
+			return starlark.None, err
+		}
+	}
+	return &out, nil
+})
+
+func (p *TitleInfo) String() string {
+	return fmt.Sprintf("TitleInfo{%v, %v, %v, %v, %v}", p.Title, p.Date, p.Revision, p.Company, p.Comments)
+}
+
+// Type implements starlark.Value.
+func (p *TitleInfo) Type() string {
+	return "TitleInfo"
+}
+
+// Freeze implements starlark.Value.
+func (p *TitleInfo) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *TitleInfo) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *TitleInfo) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *TitleInfo) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "title":
+		return starlark.String(p.Title), nil
+
+	case "date":
+		return starlark.String(p.Date), nil
+
+	case "revision":
+		return starlark.String(p.Revision), nil
+
+	case "company":
+		return starlark.String(p.Company), nil
+
+	case "comments":
+		l := starlark.NewList(nil)
+		for _, e := range p.Comments {
+			l.Append(starlark.String(e))
+		}
+		return l, nil
+	}
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *TitleInfo) AttrNames() []string {
+	return []string{"title", "date", "revision", "company", "comments"}
+}
+
+// SetField implements starlark.HasSetField.
+func (p *TitleInfo) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "title":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to title using type %T", val)
+		}
+		p.Title = string(v)
+		return nil
+
+	case "date":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to date using type %T", val)
+		}
+		p.Date = string(v)
+		return nil
+
+	case "revision":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to revision using type %T", val)
+		}
+		p.Revision = string(v)
+		return nil
+
+	case "company":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to company using type %T", val)
+		}
+		p.Company = string(v)
+		return nil
+
+	case "comments":
+		v, ok := val.(*starlark.List)
+		if !ok {
+			return fmt.Errorf("cannot assign to comments using type %T", val)
+		}
+		if v.Len() > len(p.Comments) {
+			return fmt.Errorf("at most %d comments are allowed, got %d", len(p.Comments), v.Len())
+		}
+
+		p.Comments = [4]string{}
+		for i := 0; i < v.Len(); i++ {
+			s, ok := v.Index(i).(starlark.String)
+			if !ok {
+				return errors.New("comments is not a string")
+			}
+			p.Comments[i] = string(s)
+		}
+		return nil
+	}
+
+	return errors.New("no such assignable field: " + name)
+}
+
+// MakeEditorSetup creates editor settings, starting from the defaults used
+// by EmptyPCB and applying any keyword arguments.
+var MakeEditorSetup = starlark.NewBuiltin("EditorSetup", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if len(args) > 0 {
+		return starlark.None, errors.New("EditorSetup: unexpected positional arguments")
+	}
+	out := EmptyPCB().EditorSetup
+	for _, kw := range kwargs {
+		name := string(kw[0].(starlark.String))
+		if err := out.SetField(name, kw[1]); err != nil {
+			return starlark.None, fmt.Errorf("EditorSetup: %v", err)
+		}
+	}
+	return &out, nil
+})
+
+func (p *EditorSetup) String() string {
+	return fmt.Sprintf("EditorSetup{%v, %v, %v, %v}", p.TraceClearance, p.TraceMin, p.ViaSize, p.ViaDrill)
+}
+
+// Type implements starlark.Value.
+func (p *EditorSetup) Type() string {
+	return "EditorSetup"
+}
+
+// Freeze implements starlark.Value.
+func (p *EditorSetup) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *EditorSetup) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *EditorSetup) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *EditorSetup) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "last_trace_width":
+		return starlark.Float(p.LastTraceWidth), nil
+
+	case "user_trace_widths":
+		l := starlark.NewList(nil)
+		for _, e := range p.UserTraceWidths {
+			l.Append(starlark.Float(e))
+		}
+		return l, nil
+
+	case "trace_clearance":
+		return starlark.Float(p.TraceClearance), nil
+
+	case "zone_clearance":
+		return starlark.Float(p.ZoneClearance), nil
+
+	case "zone_45_only":
+		return starlark.Bool(p.Zone45Only), nil
+
+	case "trace_min":
+		return starlark.Float(p.TraceMin), nil
+
+	case "text_width":
+		return starlark.Float(p.TextWidth), nil
+
+	case "text_size":
+		return xyFromSlice(p.TextSize), nil
+
+	case "segment_width":
+		return starlark.Float(p.SegmentWidth), nil
+
+	case "edge_width":
+		return starlark.Float(p.EdgeWidth), nil
+
+	case "via_size":
+		return starlark.Float(p.ViaSize), nil
+
+	case "via_min_size":
+		return starlark.Float(p.ViaMinSize), nil
+
+	case "via_drill":
+		return starlark.Float(p.ViaDrill), nil
+
+	case "via_min_drill":
+		return starlark.Float(p.ViaMinDrill), nil
+
+	case "uvia_size":
+		return starlark.Float(p.UViaSize), nil
+
+	case "uvia_min_size":
+		return starlark.Float(p.UViaMinSize), nil
+
+	case "uvia_drill":
+		return starlark.Float(p.UViaDrill), nil
+
+	case "uvia_min_drill":
+		return starlark.Float(p.UViaMinDrill), nil
+
+	case "allow_uvias":
+		return starlark.Bool(p.AllowUVias), nil
+
+	case "blind_buried_vias_allowed":
+		return starlark.Bool(p.BlindBuriedViasAllowed), nil
+
+	case "grid_origin":
+		return &XY{X: p.GridOrigin[0], Y: p.GridOrigin[1]}, nil
 
-// Type implements starlark.Value.
-func (p *Layer) Type() string {
-	return "Layer"
-}
+	case "mod_edge_width":
+		return starlark.Float(p.ModEdgeWidth), nil
 
-// Freeze implements starlark.Value.
-func (p *Layer) Freeze() {
-}
+	case "mod_text_size":
+		return xyFromSlice(p.ModTextSize), nil
 
-// Truth implements starlark.Value.
-func (p *Layer) Truth() starlark.Bool {
-	return starlark.Bool(true)
-}
+	case "mod_text_width":
+		return starlark.Float(p.ModTextWidth), nil
 
-// Hash implements starlark.Value.
-func (p *Layer) Hash() (uint32, error) {
-	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
-	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
-}
+	case "pad_size":
+		return xyFromSlice(p.PadSize), nil
 
-// Attr implements starlark.Value.
-func (p *Layer) Attr(name string) (starlark.Value, error) {
-	switch name {
-	case "num":
-		return starlark.MakeInt(p.Num), nil
+	case "pad_drill":
+		return starlark.Float(p.PadDrill), nil
 
-	case "name":
-		return starlark.String(p.Name), nil
+	case "pad_to_mask_clearance":
+		return starlark.Float(p.PadToMaskClearance), nil
 
-	case "type":
-		return starlark.String(p.Typ), nil
+	case "solder_mask_min_width":
+		return starlark.Float(p.SolderMaskMinWidth), nil
 
-	case "hidden":
-		return starlark.Bool(p.Hidden), nil
+	case "aux_axis_origin":
+		return xyFromSlice(p.AuxAxisOrigin), nil
+
+	case "visible_elements":
+		return starlark.String(p.VisibleElements), nil
+
+	case "plot_params":
+		d := starlark.NewDict(len(p.PlotParams))
+		for name, pp := range p.PlotParams {
+			if err := d.SetKey(starlark.String(name), starlark.String(strings.Join(pp.values, " "))); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
 	}
 
 	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
 }
 
 // AttrNames implements starlark.Value.
-func (p *Layer) AttrNames() []string {
-	return []string{"num", "name", "type", "hidden"}
+func (p *EditorSetup) AttrNames() []string {
+	return []string{"last_trace_width", "user_trace_widths", "trace_clearance", "zone_clearance", "zone_45_only", "trace_min", "text_width", "text_size", "segment_width", "edge_width", "via_size", "via_min_size", "via_drill", "via_min_drill", "uvia_size", "uvia_min_size", "uvia_drill", "uvia_min_drill", "allow_uvias", "blind_buried_vias_allowed", "grid_origin", "mod_edge_width", "mod_text_size", "mod_text_width", "pad_size", "pad_drill", "pad_to_mask_clearance", "solder_mask_min_width", "aux_axis_origin", "visible_elements", "plot_params"}
 }
 
 // SetField implements starlark.HasSetField.
-func (p *Layer) SetField(name string, val starlark.Value) error {
+func (p *EditorSetup) SetField(name string, val starlark.Value) error {
 	switch name {
-	case "num":
-		v, ok := val.(*starlark.Int)
+	case "last_trace_width":
+		v, ok := starlark.AsFloat(val)
 		if !ok {
-			return fmt.Errorf("cannot assign to num using type %T", val)
+			return fmt.Errorf("cannot assign to last_trace_width using type %T", val)
 		}
-		i, ok := v.Int64()
+		p.LastTraceWidth = v
+		return nil
+
+	case "user_trace_widths":
+		v, ok := val.(*starlark.List)
 		if !ok {
-			return fmt.Errorf("cannot convert %v to int64", v)
+			return fmt.Errorf("cannot assign to user_trace_widths using type %T", val)
 		}
-		p.Num = int(i)
 
-	case "name":
-		v, ok := val.(starlark.String)
+		p.UserTraceWidths = nil
+		for i := 0; i < v.Len(); i++ {
+			f, ok := starlark.AsFloat(v.Index(i))
+			if !ok {
+				return errors.New("user_trace_widths is not a number")
+			}
+			p.UserTraceWidths = append(p.UserTraceWidths, f)
+		}
+		return nil
+
+	case "trace_clearance":
+		v, ok := starlark.AsFloat(val)
 		if !ok {
-			return fmt.Errorf("cannot assign to name using type %T", val)
+			return fmt.Errorf("cannot assign to trace_clearance using type %T", val)
 		}
-		p.Name = string(v)
+		p.TraceClearance = v
+		return nil
 
-	case "type":
-		v, ok := val.(starlark.String)
+	case "zone_clearance":
+		v, ok := starlark.AsFloat(val)
 		if !ok {
-			return fmt.Errorf("cannot assign to typ using type %T", val)
+			return fmt.Errorf("cannot assign to zone_clearance using type %T", val)
 		}
-		p.Typ = string(v)
+		p.ZoneClearance = v
+		return nil
 
-	case "hidden":
+	case "zone_45_only":
 		v, ok := val.(starlark.Bool)
 		if !ok {
-			return fmt.Errorf("cannot assign to hidden using type %T", val)
+			return fmt.Errorf("cannot assign to zone_45_only using type %T", val)
 		}
-		p.Hidden = bool(v)
+		p.Zone45Only = bool(v)
+		return nil
 
-	}
+	case "trace_min":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to trace_min using type %T", val)
+		}
+		p.TraceMin = v
+		return nil
 
-	return errors.New("no such assignable field: " + name)
-}
+	case "text_width":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to text_width using type %T", val)
+		}
+		p.TextWidth = v
+		return nil
 
-var MakePCBCreatedBy = starlark.NewBuiltin("PCBCreatedBy", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
-	var (
-		f0 starlark.String
-		f1 starlark.String
-	)
-	unpackErr := starlark.UnpackArgs(
-		"PCBCreatedBy",
-		args,
-		kwargs,
-		"tool?", &f0,
-		"version?", &f1,
-	)
-	if unpackErr != nil {
-		return starlark.None, unpackErr
-	}
-	out := PCBCreatedBy{}
+	case "text_size":
+		v, ok := val.(*XY)
+		if !ok {
+			return fmt.Errorf("cannot assign to text_size using type %T", val)
+		}
+		p.TextSize = []float64{v.X, v.Y}
+		return nil
 
-	out.Tool = string(f0)
-	out.Version = string(f1)
-	return &out, nil
-})
+	case "segment_width":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to segment_width using type %T", val)
+		}
+		p.SegmentWidth = v
+		return nil
 
-func (p *PCBCreatedBy) String() string {
-	return fmt.Sprintf("PCBCreatedBy{%v, %v}", p.Tool, p.Version)
-}
+	case "edge_width":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to edge_width using type %T", val)
+		}
+		p.EdgeWidth = v
+		return nil
 
-// Type implements starlark.Value.
-func (p *PCBCreatedBy) Type() string {
-	return "PCBCreatedBy"
-}
+	case "via_size":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to via_size using type %T", val)
+		}
+		p.ViaSize = v
+		return nil
 
-// Freeze implements starlark.Value.
-func (p *PCBCreatedBy) Freeze() {
-}
+	case "via_min_size":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to via_min_size using type %T", val)
+		}
+		p.ViaMinSize = v
+		return nil
 
-// Truth implements starlark.Value.
-func (p *PCBCreatedBy) Truth() starlark.Bool {
-	return starlark.Bool(true)
-}
+	case "via_drill":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to via_drill using type %T", val)
+		}
+		p.ViaDrill = v
+		return nil
 
-// Hash implements starlark.Value.
-func (p *PCBCreatedBy) Hash() (uint32, error) {
-	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
-	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
-}
+	case "via_min_drill":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to via_min_drill using type %T", val)
+		}
+		p.ViaMinDrill = v
+		return nil
 
-// Attr implements starlark.Value.
-func (p *PCBCreatedBy) Attr(name string) (starlark.Value, error) {
-	switch name {
-	case "tool":
-		return starlark.String(p.Tool), nil
+	case "uvia_size":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to uvia_size using type %T", val)
+		}
+		p.UViaSize = v
+		return nil
 
-	case "version":
-		return starlark.String(p.Version), nil
-	}
+	case "uvia_min_size":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to uvia_min_size using type %T", val)
+		}
+		p.UViaMinSize = v
+		return nil
 
-	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
-}
+	case "uvia_drill":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to uvia_drill using type %T", val)
+		}
+		p.UViaDrill = v
+		return nil
 
-// AttrNames implements starlark.Value.
-func (p *PCBCreatedBy) AttrNames() []string {
-	return []string{"tool", "version"}
-}
+	case "uvia_min_drill":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to uvia_min_drill using type %T", val)
+		}
+		p.UViaMinDrill = v
+		return nil
 
-// SetField implements starlark.HasSetField.
-func (p *PCBCreatedBy) SetField(name string, val starlark.Value) error {
-	switch name {
-	case "tool":
-		v, ok := val.(starlark.String)
+	case "allow_uvias":
+		v, ok := val.(starlark.Bool)
 		if !ok {
-			return fmt.Errorf("cannot assign to tool using type %T", val)
+			return fmt.Errorf("cannot assign to allow_uvias using type %T", val)
 		}
-		p.Tool = string(v)
+		p.AllowUVias = bool(v)
+		return nil
 
-	case "version":
+	case "blind_buried_vias_allowed":
+		v, ok := val.(starlark.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign to blind_buried_vias_allowed using type %T", val)
+		}
+		p.BlindBuriedViasAllowed = bool(v)
+		return nil
+
+	case "grid_origin":
+		v, ok := val.(*XY)
+		if !ok {
+			return fmt.Errorf("cannot assign to grid_origin using type %T", val)
+		}
+		p.GridOrigin = [2]float64{v.X, v.Y}
+		return nil
+
+	case "mod_edge_width":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to mod_edge_width using type %T", val)
+		}
+		p.ModEdgeWidth = v
+		return nil
+
+	case "mod_text_size":
+		v, ok := val.(*XY)
+		if !ok {
+			return fmt.Errorf("cannot assign to mod_text_size using type %T", val)
+		}
+		p.ModTextSize = []float64{v.X, v.Y}
+		return nil
+
+	case "mod_text_width":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to mod_text_width using type %T", val)
+		}
+		p.ModTextWidth = v
+		return nil
+
+	case "pad_size":
+		v, ok := val.(*XY)
+		if !ok {
+			return fmt.Errorf("cannot assign to pad_size using type %T", val)
+		}
+		p.PadSize = []float64{v.X, v.Y}
+		return nil
+
+	case "pad_drill":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to pad_drill using type %T", val)
+		}
+		p.PadDrill = v
+		return nil
+
+	case "pad_to_mask_clearance":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to pad_to_mask_clearance using type %T", val)
+		}
+		p.PadToMaskClearance = v
+		return nil
+
+	case "solder_mask_min_width":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to solder_mask_min_width using type %T", val)
+		}
+		p.SolderMaskMinWidth = v
+		return nil
+
+	case "aux_axis_origin":
+		v, ok := val.(*XY)
+		if !ok {
+			return fmt.Errorf("cannot assign to aux_axis_origin using type %T", val)
+		}
+		p.AuxAxisOrigin = []float64{v.X, v.Y}
+		return nil
+
+	case "visible_elements":
 		v, ok := val.(starlark.String)
 		if !ok {
-			return fmt.Errorf("cannot assign to version using type %T", val)
+			return fmt.Errorf("cannot assign to visible_elements using type %T", val)
+		}
+		p.VisibleElements = string(v)
+		return nil
+
+	case "plot_params":
+		v, ok := val.(*starlark.Dict)
+		if !ok {
+			return fmt.Errorf("cannot assign to plot_params using type %T", val)
+		}
+		if p.PlotParams == nil {
+			p.PlotParams = map[string]PlotParam{}
 		}
-		p.Version = string(v)
 
+		for _, e := range v.Items() {
+			k, ok := starlark.AsString(e[0])
+			if !ok {
+				return errors.New("plot_params key is not a string")
+			}
+			s, ok := starlark.AsString(e[1])
+			if !ok {
+				return fmt.Errorf("plot_params %s is not a string", k)
+			}
+			pp, exists := p.PlotParams[k]
+			if !exists {
+				pp = PlotParam{name: k, order: len(p.PlotParams) + 1}
+			}
+			pp.values = strings.Fields(s)
+			p.PlotParams[k] = pp
+		}
+		return nil
 	}
 
 	return errors.New("no such assignable field: " + name)
 }
 
+// xyFromSlice returns the first two elements of the slice as an XY.
+func xyFromSlice(s []float64) *XY {
+	out := &XY{}
+	if len(s) > 0 {
+		out.X = s[0]
+	}
+	if len(s) > 1 {
+		out.Y = s[1]
+	}
+	return out
+}
+
+// MakeNetClass creates a net class. Unspecified design rules are taken
+// from the default net class of EmptyPCB.
 var MakeNetClass = starlark.NewBuiltin("NetClass", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var def NetClass
+	if p := EmptyPCB(); len(p.NetClasses) > 0 {
+		def = p.NetClasses[0]
+	}
 	var (
 		f0  starlark.String
 		f1  starlark.String
-		f2  starlark.Float
-		f3  starlark.Float
-		f4  starlark.Float
-		f5  starlark.Float
-		f6  starlark.Float
-		f7  starlark.Float
-		f8  starlark.Float
-		f9  starlark.Float
+		f2  = starlark.Float(def.Clearance)
+		f3  = starlark.Float(def.TraceWidth)
+		f4  = starlark.Float(def.ViaDiameter)
+		f5  = starlark.Float(def.ViaDrill)
+		f6  = starlark.Float(def.UViaDiameter)
+		f7  = starlark.Float(def.UViaDrill)
+		f8  = starlark.Float(def.DiffPairWidth)
+		f9  = starlark.Float(def.DiffPairGap)
 		f10 *starlark.List
 	)
 	unpackErr := starlark.UnpackArgs(
@@ -935,6 +2550,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to name using type %T", val)
 		}
 		p.Name = string(v)
+		return nil
 
 	case "description":
 		v, ok := val.(starlark.String)
@@ -942,6 +2558,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to description using type %T", val)
 		}
 		p.Description = string(v)
+		return nil
 
 	case "clearance":
 		v, ok := val.(starlark.Float)
@@ -949,6 +2566,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to clearance using type %T", val)
 		}
 		p.Clearance = float64(v)
+		return nil
 
 	case "trace_width":
 		v, ok := val.(starlark.Float)
@@ -956,6 +2574,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to trace_width using type %T", val)
 		}
 		p.TraceWidth = float64(v)
+		return nil
 
 	case "via_diameter":
 		v, ok := val.(starlark.Float)
@@ -963,6 +2582,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to via_diameter using type %T", val)
 		}
 		p.ViaDiameter = float64(v)
+		return nil
 
 	case "via_drill":
 		v, ok := val.(starlark.Float)
@@ -970,6 +2590,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to via_drill using type %T", val)
 		}
 		p.ViaDrill = float64(v)
+		return nil
 
 	case "u_via_diameter":
 		v, ok := val.(starlark.Float)
@@ -977,6 +2598,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to u_via_diameter using type %T", val)
 		}
 		p.UViaDiameter = float64(v)
+		return nil
 
 	case "u_via_drill":
 		v, ok := val.(starlark.Float)
@@ -984,6 +2606,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to u_via_drill using type %T", val)
 		}
 		p.UViaDrill = float64(v)
+		return nil
 
 	case "diff_pair_width":
 		v, ok := val.(starlark.Float)
@@ -991,6 +2614,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to diff_pair_width using type %T", val)
 		}
 		p.DiffPairWidth = float64(v)
+		return nil
 
 	case "diff_pair_gap":
 		v, ok := val.(starlark.Float)
@@ -998,6 +2622,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to diff_pair_gap using type %T", val)
 		}
 		p.DiffPairGap = float64(v)
+		return nil
 
 	case "nets":
 		v, ok := val.(*starlark.List)
@@ -1005,6 +2630,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to nets using type %T", val)
 		}
 
+		p.Nets = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(starlark.String)
 			if !ok {
@@ -1012,6 +2638,7 @@ func (p *NetClass) SetField(name string, val starlark.Value) error {
 			}
 			p.Nets = append(p.Nets, string(s))
 		}
+		return nil
 	}
 
 	return errors.New("no such assignable field: " + name)
@@ -1083,7 +2710,13 @@ func (p *Net) SetField(name string, val starlark.Value) error {
 		if !ok {
 			return fmt.Errorf("cannot assign to name using type %T", val)
 		}
+		if p.board != nil {
+			if err := p.board.RenameNet(p.num, string(v)); err != nil {
+				return err
+			}
+		}
 		p.Name = string(v)
+		return nil
 
 	}
 
@@ -1198,6 +2831,7 @@ func (p *ModPolygon) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to points using type %T", val)
 		}
 
+		p.Points = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(*XY)
 			if !ok {
@@ -1234,6 +2868,7 @@ var MakeTextEffects = starlark.NewBuiltin("TextEffects", func(t *starlark.Thread
 		f2 TextJustify
 		f3 starlark.Bool
 		f4 starlark.Bool
+		f5 starlark.Bool
 	)
 	unpackErr := starlark.UnpackArgs(
 		"TextEffects",
@@ -1244,6 +2879,7 @@ var MakeTextEffects = starlark.NewBuiltin("TextEffects", func(t *starlark.Thread
 		"justify?", &f2,
 		"bold?", &f3,
 		"italic?", &f4,
+		"mirrored?", &f5,
 	)
 	if unpackErr != nil {
 		return starlark.None, unpackErr
@@ -1257,11 +2893,12 @@ var MakeTextEffects = starlark.NewBuiltin("TextEffects", func(t *starlark.Thread
 	out.Justify = f2
 	out.Bold = bool(f3)
 	out.Italic = bool(f4)
+	out.Mirrored = bool(f5)
 	return &out, nil
 })
 
 func (p *TextEffects) String() string {
-	return fmt.Sprintf("TextEffects{%v, %v, %v, %v, %v}", p.FontSize, p.Thickness, p.Justify, p.Bold, p.Italic)
+	return fmt.Sprintf("TextEffects{%v, %v, %v, %v, %v, %v}", p.FontSize, p.Thickness, p.Justify, p.Bold, p.Italic, p.Mirrored)
 }
 
 // Type implements starlark.Value.
@@ -1301,6 +2938,9 @@ func (p *TextEffects) Attr(name string) (starlark.Value, error) {
 
 	case "italic":
 		return starlark.Bool(p.Italic), nil
+
+	case "mirrored":
+		return starlark.Bool(p.Mirrored), nil
 	}
 
 	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
@@ -1308,7 +2948,7 @@ func (p *TextEffects) Attr(name string) (starlark.Value, error) {
 
 // AttrNames implements starlark.Value.
 func (p *TextEffects) AttrNames() []string {
-	return []string{"font_size", "thickness", "justify", "bold", "italic"}
+	return []string{"font_size", "thickness", "justify", "bold", "italic", "mirrored"}
 }
 
 // SetField implements starlark.HasSetField.
@@ -1353,6 +2993,14 @@ func (p *TextEffects) SetField(name string, val starlark.Value) error {
 		}
 		p.Italic = bool(v)
 		return nil
+
+	case "mirrored":
+		v, ok := val.(starlark.Bool)
+		if !ok {
+			return fmt.Errorf("cannot assign to mirrored using type %T", val)
+		}
+		p.Mirrored = bool(v)
+		return nil
 	}
 
 	return errors.New("no such assignable field: " + name)
@@ -2085,6 +3733,7 @@ func (p *PadOptions) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to clearance using type %T", val)
 		}
 		p.Clearance = string(v)
+		return nil
 
 	case "anchor":
 		v, ok := val.(starlark.String)
@@ -2092,6 +3741,7 @@ func (p *PadOptions) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to anchor using type %T", val)
 		}
 		p.Anchor = string(v)
+		return nil
 
 	}
 
@@ -2188,7 +3838,7 @@ func (p *ModGraphic) SetField(name string, val starlark.Value) error {
 var MakePad = starlark.NewBuiltin("Pad", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var (
 		f0  starlark.String
-		f1  starlark.Int
+		f1  starlark.Value
 		f2  starlark.String
 		f3  starlark.Value
 		f4  *XY
@@ -2248,10 +3898,17 @@ var MakePad = starlark.NewBuiltin("Pad", func(t *starlark.Thread, f *starlark.Bu
 
 	out.Ident = string(f0)
 
-	if v, ok := f1.Int64(); ok {
-		out.NetNum = int(v)
-	}
 	out.NetName = string(f2)
+	if f1 != nil {
+		num, name, err := unpackNet("net_num", f1)
+		if err != nil {
+			return starlark.None, err
+		}
+		out.NetNum = num
+		if name != "" {
+			out.NetName = name
+		}
+	}
 	if f3 != nil {
 		if xy, ok := f3.(*XY); ok {
 			out.At = XYZ{X: xy.X, Y: xy.Y}
@@ -2447,17 +4104,18 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to ident using type %T", val)
 		}
 		p.Ident = string(v)
+		return nil
 
 	case "net_num":
-		v, ok := val.(*starlark.Int)
-		if !ok {
-			return fmt.Errorf("cannot assign to net_num using type %T", val)
+		num, name, err := unpackNet("net_num", val)
+		if err != nil {
+			return err
 		}
-		i, ok := v.Int64()
-		if !ok {
-			return fmt.Errorf("cannot convert %v to int64", v)
+		p.NetNum = num
+		if name != "" {
+			p.NetName = name
 		}
-		p.NetNum = int(i)
+		return nil
 
 	case "net_name":
 		v, ok := val.(starlark.String)
@@ -2465,6 +4123,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to net_name using type %T", val)
 		}
 		p.NetName = string(v)
+		return nil
 
 	case "at":
 		v, ok := val.(*XYZ)
@@ -2472,6 +4131,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to at using type %T", val)
 		}
 		p.At = *v
+		return nil
 
 	case "size":
 		v, ok := val.(*XY)
@@ -2479,6 +4139,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to size using type %T", val)
 		}
 		p.Size = *v
+		return nil
 
 	case "layers":
 		v, ok := val.(*starlark.List)
@@ -2486,6 +4147,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to layers using type %T", val)
 		}
 
+		p.Layers = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(starlark.String)
 			if !ok {
@@ -2493,6 +4155,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			}
 			p.Layers = append(p.Layers, string(s))
 		}
+		return nil
 
 	case "rect_delta":
 		v, ok := val.(*XY)
@@ -2500,6 +4163,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to rect_delta using type %T", val)
 		}
 		p.RectDelta = *v
+		return nil
 
 	case "drill_offset":
 		v, ok := val.(*XY)
@@ -2507,6 +4171,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to drill_offset using type %T", val)
 		}
 		p.DrillOffset = *v
+		return nil
 
 	case "drill_size":
 		v, ok := val.(*XY)
@@ -2514,6 +4179,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to drill_size using type %T", val)
 		}
 		p.DrillSize = *v
+		return nil
 
 	case "drill_shape":
 		v, ok := val.(*PadShape)
@@ -2521,6 +4187,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to drill_shape using type %T", val)
 		}
 		p.DrillShape = *v
+		return nil
 
 	case "die_length":
 		v, ok := val.(starlark.Float)
@@ -2528,6 +4195,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to die_length using type %T", val)
 		}
 		p.DieLength = float64(v)
+		return nil
 
 	case "zone_connect":
 		v, ok := val.(*ZoneConnectMode)
@@ -2535,6 +4203,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to zone_connect using type %T", val)
 		}
 		p.ZoneConnect = *v
+		return nil
 
 	case "thermal_width":
 		v, ok := val.(starlark.Float)
@@ -2542,6 +4211,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to thermal_width using type %T", val)
 		}
 		p.ThermalWidth = float64(v)
+		return nil
 
 	case "thermal_gap":
 		v, ok := val.(starlark.Float)
@@ -2549,6 +4219,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to thermal_gap using type %T", val)
 		}
 		p.ThermalGap = float64(v)
+		return nil
 
 	case "round_rect_r_ratio":
 		v, ok := val.(starlark.Float)
@@ -2556,6 +4227,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to round_rect_r_ratio using type %T", val)
 		}
 		p.RoundRectRRatio = float64(v)
+		return nil
 
 	case "chamfer_ratio":
 		v, ok := val.(starlark.Float)
@@ -2563,6 +4235,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to chamfer_ratio using type %T", val)
 		}
 		p.ChamferRatio = float64(v)
+		return nil
 
 	case "solder_mask_margin":
 		v, ok := val.(starlark.Float)
@@ -2570,6 +4243,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to solder_mask_margin using type %T", val)
 		}
 		p.SolderMaskMargin = float64(v)
+		return nil
 
 	case "solder_paste_margin":
 		v, ok := val.(starlark.Float)
@@ -2577,6 +4251,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to solder_paste_margin using type %T", val)
 		}
 		p.SolderPasteMargin = float64(v)
+		return nil
 
 	case "solder_paste_margin_ratio":
 		v, ok := val.(starlark.Float)
@@ -2584,6 +4259,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to solder_paste_margin_ratio using type %T", val)
 		}
 		p.SolderPasteMarginRatio = float64(v)
+		return nil
 
 	case "clearance":
 		v, ok := val.(starlark.Float)
@@ -2591,6 +4267,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to clearance using type %T", val)
 		}
 		p.Clearance = float64(v)
+		return nil
 
 	case "surface":
 		v, ok := val.(*PadSurface)
@@ -2598,6 +4275,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to surface using type %T", val)
 		}
 		p.Surface = *v
+		return nil
 
 	case "shape":
 		v, ok := val.(*PadShape)
@@ -2605,6 +4283,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to shape using type %T", val)
 		}
 		p.Shape = *v
+		return nil
 
 	case "options":
 		v, ok := val.(*PadOptions)
@@ -2612,6 +4291,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to options using type %T", val)
 		}
 		p.Options = v
+		return nil
 
 	case "primitives":
 		v, ok := val.(*starlark.List)
@@ -2619,6 +4299,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to primitives using type %T", val)
 		}
 
+		p.Primitives = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(*ModGraphic)
 			if !ok {
@@ -2626,6 +4307,7 @@ func (p *Pad) SetField(name string, val starlark.Value) error {
 			}
 			p.Primitives = append(p.Primitives, *s)
 		}
+		return nil
 	}
 
 	return errors.New("no such assignable field: " + name)
@@ -2700,6 +4382,7 @@ func (p *ModPlacement) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to at using type %T", val)
 		}
 		p.At = *v
+		return nil
 
 	}
 
@@ -2921,27 +4604,69 @@ func (p *Module) Attr(name string) (starlark.Value, error) {
 
 	case "pads":
 		l := starlark.NewList(nil)
-		for _, e := range p.Pads {
-			dupe := e
-			l.Append(&dupe)
+		for i := range p.Pads {
+			l.Append(&p.Pads[i])
 		}
 		return l, nil
 
 	case "models":
 		l := starlark.NewList(nil)
-		for _, e := range p.Models {
-			dupe := e
-			l.Append(&dupe)
+		for i := range p.Models {
+			l.Append(&p.Models[i])
 		}
 		return l, nil
 	}
 
+	if m, ok := moduleMethods[name]; ok {
+		return m.BindReceiver(p), nil
+	}
 	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
 }
 
 // AttrNames implements starlark.Value.
 func (p *Module) AttrNames() []string {
-	return []string{"name", "placement", "placed", "locked", "layer", "zone_connect", "solder_mask_margin", "solder_paste_margin", "solder_paste_ratio", "clearance", "tedit", "tstamp", "path", "description", "tags", "attrs", "graphics", "pads", "models"}
+	return []string{"name", "placement", "placed", "locked", "layer", "zone_connect", "solder_mask_margin", "solder_paste_margin", "solder_paste_ratio", "clearance", "tedit", "tstamp", "path", "description", "tags", "attrs", "graphics", "pads", "models",
+		"flip", "to_board", "pad_position"}
+}
+
+// moduleMethods are the methods of a Mod value.
+var moduleMethods = map[string]*starlark.Builtin{
+	"flip": starlark.NewBuiltin("flip", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackArgs("flip", args, kwargs); err != nil {
+			return starlark.None, err
+		}
+		f.Receiver().(*Module).Flip()
+		return starlark.None, nil
+	}),
+	"to_board": starlark.NewBuiltin("to_board", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var pos *XY
+		if err := starlark.UnpackArgs("to_board", args, kwargs, "pos", &pos); err != nil {
+			return starlark.None, err
+		}
+		out := f.Receiver().(*Module).ToBoard(*pos)
+		return &out, nil
+	}),
+	"pad_position": starlark.NewBuiltin("pad_position", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var pad starlark.Value
+		if err := starlark.UnpackArgs("pad_position", args, kwargs, "pad", &pad); err != nil {
+			return starlark.None, err
+		}
+		m := f.Receiver().(*Module)
+		switch v := pad.(type) {
+		case *Pad:
+			out := m.PadPosition(v)
+			return &out, nil
+		case starlark.String:
+			for i := range m.Pads {
+				if m.Pads[i].Ident == string(v) {
+					out := m.PadPosition(&m.Pads[i])
+					return &out, nil
+				}
+			}
+			return starlark.None, fmt.Errorf("pad_position: no pad %q", string(v))
+		}
+		return starlark.None, fmt.Errorf("pad_position: cannot find pad using type %T", pad)
+	}),
 }
 
 // SetField implements starlark.HasSetField.
@@ -3065,6 +4790,7 @@ func (p *Module) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to tags using type %T", val)
 		}
 
+		p.Tags = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(starlark.String)
 			if !ok {
@@ -3080,6 +4806,7 @@ func (p *Module) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to attrs using type %T", val)
 		}
 
+		p.Attrs = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(starlark.String)
 			if !ok {
@@ -3095,6 +4822,7 @@ func (p *Module) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to graphics using type %T", val)
 		}
 
+		p.Graphics = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(*ModGraphic)
 			if !ok {
@@ -3110,6 +4838,7 @@ func (p *Module) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to pads using type %T", val)
 		}
 
+		p.Pads = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(*Pad)
 			if !ok {
@@ -3125,6 +4854,7 @@ func (p *Module) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to models using type %T", val)
 		}
 
+		p.Models = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(*ModModel)
 			if !ok {
@@ -3140,9 +4870,17 @@ func (p *Module) SetField(name string, val starlark.Value) error {
 
 var MakePCB = starlark.NewBuiltin("PCB", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var (
-		segments *starlark.List
-		drawings *starlark.List
-		modules  *starlark.List
+		segments   *starlark.List
+		drawings   *starlark.List
+		modules    *starlark.List
+		zones      *starlark.List
+		nets       *starlark.List
+		netClasses *starlark.List
+		titleInfo  *TitleInfo
+		setup      *EditorSetup
+		copper     = 2
+		thickness  starlark.Value
+		page       starlark.Value
 	)
 	unpackErr := starlark.UnpackArgs(
 		"PCB",
@@ -3154,11 +4892,30 @@ var MakePCB = starlark.NewBuiltin("PCB", func(t *starlark.Thread, f *starlark.Bu
 		&drawings,
 		"modules?",
 		&modules,
+		"zones?",
+		&zones,
+		"nets?",
+		&nets,
+		"net_classes?",
+		&netClasses,
+		"title_info?",
+		&titleInfo,
+		"setup?",
+		&setup,
+		"copper_layers?",
+		&copper,
+		"thickness?",
+		&thickness,
+		"page?",
+		&page,
 	)
 	if unpackErr != nil {
 		return starlark.None, unpackErr
 	}
 	out := EmptyPCB()
+	if err := out.SetCopperLayers(copper); err != nil {
+		return starlark.None, err
+	}
 
 	if segments != nil {
 		for i := 0; i < segments.Len(); i++ {
@@ -3187,6 +4944,59 @@ var MakePCB = starlark.NewBuiltin("PCB", func(t *starlark.Thread, f *starlark.Bu
 			out.Modules = append(out.Modules, *m)
 		}
 	}
+	if zones != nil {
+		for i := 0; i < zones.Len(); i++ {
+			z, ok := zones.Index(i).(*Zone)
+			if !ok {
+				return starlark.None, errors.New("zones element is not a Zone")
+			}
+			out.Zones = append(out.Zones, *z)
+		}
+	}
+	if nets != nil {
+		// Nets are numbered in the order given, after the unconnected net.
+		for i := 0; i < nets.Len(); i++ {
+			n, ok := nets.Index(i).(*Net)
+			if !ok {
+				return starlark.None, errors.New("nets element is not a Net")
+			}
+			out.Nets[len(out.Nets)] = *n
+		}
+	}
+	if netClasses != nil {
+		// Classes replace any default class of the same name.
+	classLoop:
+		for i := 0; i < netClasses.Len(); i++ {
+			nc, ok := netClasses.Index(i).(*NetClass)
+			if !ok {
+				return starlark.None, errors.New("net_classes element is not a NetClass")
+			}
+			for j := range out.NetClasses {
+				if out.NetClasses[j].Name == nc.Name {
+					out.NetClasses[j] = *nc
+					continue classLoop
+				}
+			}
+			out.NetClasses = append(out.NetClasses, *nc)
+		}
+	}
+	if titleInfo != nil {
+		out.TitleInfo = titleInfo
+	}
+	if setup != nil {
+		out.EditorSetup = *setup
+	}
+	if thickness != nil {
+		if err := out.SetField("thickness", thickness); err != nil {
+			return starlark.None, err
+		}
+	}
+	if page != nil {
+		if err := out.SetField("page", page); err != nil {
+			return starlark.None, err
+		}
+	}
+	out.resolveNetNames()
 
 	return out, nil
 })
@@ -3250,33 +5060,184 @@ func (p *PCB) Attr(name string) (starlark.Value, error) {
 				l.Append(d)
 			case *Arc:
 				l.Append(d)
+			case *Dimension:
+				l.Append(d)
 			default:
 				return nil, fmt.Errorf("cannot process drawing of type %T", d)
 			}
 		}
 		return l, nil
 
-	// case "zones":
-	// 	l := starlark.NewList(nil)
-	// 	for _, e := range p.Zones {
-	// 		l.Append(e)
-	// 	}
-	// 	return l, nil
+	case "zones":
+		l := starlark.NewList(nil)
+		for i := range p.Zones {
+			l.Append(&p.Zones[i])
+		}
+		return l, nil
+
+	case "nets":
+		d := starlark.NewDict(len(p.Nets))
+		for num, e := range p.Nets {
+			n := e
+			n.board, n.num = p, num
+			if err := d.SetKey(starlark.MakeInt(num), &n); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+
+	case "net_classes":
+		l := starlark.NewList(nil)
+		for i := range p.NetClasses {
+			l.Append(&p.NetClasses[i])
+		}
+		return l, nil
+
+	case "title_info":
+		if p.TitleInfo == nil {
+			p.TitleInfo = &TitleInfo{}
+		}
+		return p.TitleInfo, nil
+
+	case "setup":
+		return &p.EditorSetup, nil
+
+	case "thickness":
+		if p.Thickness == 0 {
+			return starlark.Float(DefaultThickness), nil
+		}
+		return starlark.Float(p.Thickness), nil
+
+	case "page":
+		if p.Page.Size == "" {
+			p.Page.Size = "A4"
+		}
+		return &p.Page, nil
+
+	case "copper_layers":
+		return starlark.MakeInt(len(p.CopperLayers())), nil
+
+	case "stackup":
+		stackup, err := p.Stackup()
+		if err != nil {
+			return nil, err
+		}
+		l := starlark.NewList(nil)
+		for i := range stackup.Layers {
+			l.Append(&stackup.Layers[i])
+		}
+		return l, nil
 
 	case "modules":
 		l := starlark.NewList(nil)
-		for _, e := range p.Modules {
-			l.Append(&e)
+		for i := range p.Modules {
+			l.Append(&p.Modules[i])
 		}
 		return l, nil
 	}
 
+	if m, ok := pcbMethods[name]; ok {
+		return m.BindReceiver(p), nil
+	}
 	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
 }
 
 // AttrNames implements starlark.Value.
 func (p *PCB) AttrNames() []string {
-	return []string{"layers", "segments", "drawings", "modules"}
+	return []string{"layers", "segments", "drawings", "zones", "nets", "net_classes", "title_info", "setup", "thickness", "page", "copper_layers", "stackup", "modules",
+		"add_net", "net", "rename_net", "merge_nets", "delete_net", "compact_nets"}
+}
+
+// pcbMethods are the methods of a PCB value.
+var pcbMethods = map[string]*starlark.Builtin{
+	"add_net": starlark.NewBuiltin("add_net", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var name starlark.String
+		if err := starlark.UnpackArgs("add_net", args, kwargs, "name", &name); err != nil {
+			return starlark.None, err
+		}
+		return starlark.MakeInt(f.Receiver().(*PCB).AddNet(string(name))), nil
+	}),
+	"net": starlark.NewBuiltin("net", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var name starlark.String
+		if err := starlark.UnpackArgs("net", args, kwargs, "name", &name); err != nil {
+			return starlark.None, err
+		}
+		num, ok := f.Receiver().(*PCB).NetByName(string(name))
+		if !ok {
+			return starlark.None, nil
+		}
+		return starlark.MakeInt(num), nil
+	}),
+	"rename_net": starlark.NewBuiltin("rename_net", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var (
+			net  starlark.Value
+			name starlark.String
+		)
+		if err := starlark.UnpackArgs("rename_net", args, kwargs, "net", &net, "name", &name); err != nil {
+			return starlark.None, err
+		}
+		p := f.Receiver().(*PCB)
+		num, err := p.lookupNet("net", net)
+		if err != nil {
+			return starlark.None, err
+		}
+		return starlark.None, p.RenameNet(num, string(name))
+	}),
+	"merge_nets": starlark.NewBuiltin("merge_nets", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var from, into starlark.Value
+		if err := starlark.UnpackArgs("merge_nets", args, kwargs, "from", &from, "into", &into); err != nil {
+			return starlark.None, err
+		}
+		p := f.Receiver().(*PCB)
+		fromNum, err := p.lookupNet("from", from)
+		if err != nil {
+			return starlark.None, err
+		}
+		intoNum, err := p.lookupNet("into", into)
+		if err != nil {
+			return starlark.None, err
+		}
+		return starlark.None, p.MergeNets(fromNum, intoNum)
+	}),
+	"delete_net": starlark.NewBuiltin("delete_net", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var (
+			net      starlark.Value
+			reassign starlark.Value = starlark.MakeInt(0)
+		)
+		if err := starlark.UnpackArgs("delete_net", args, kwargs, "net", &net, "reassign?", &reassign); err != nil {
+			return starlark.None, err
+		}
+		p := f.Receiver().(*PCB)
+		num, err := p.lookupNet("net", net)
+		if err != nil {
+			return starlark.None, err
+		}
+		reassignNum, err := p.lookupNet("reassign", reassign)
+		if err != nil {
+			return starlark.None, err
+		}
+		return starlark.None, p.DeleteNet(num, reassignNum)
+	}),
+	"compact_nets": starlark.NewBuiltin("compact_nets", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackArgs("compact_nets", args, kwargs); err != nil {
+			return starlark.None, err
+		}
+		f.Receiver().(*PCB).CompactNets()
+		return starlark.None, nil
+	}),
+}
+
+// lookupNet returns the number of the net referred to by v, which may be
+// a net number or the name of an existing net.
+func (p *PCB) lookupNet(field string, v starlark.Value) (int, error) {
+	num, name, err := unpackNet(field, v)
+	if err != nil || name == "" {
+		return num, err
+	}
+	if num, ok := p.NetByName(name); ok {
+		return num, nil
+	}
+	return 0, fmt.Errorf("%s: no net named %q", field, name)
 }
 
 // SetField implements starlark.HasSetField.
@@ -3289,6 +5250,7 @@ func (p *PCB) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to layers using type %T", val)
 		}
 
+		p.Layers = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(*Layer)
 			if !ok {
@@ -3296,6 +5258,7 @@ func (p *PCB) SetField(name string, val starlark.Value) error {
 			}
 			p.Layers = append(p.Layers, s)
 		}
+		return nil
 
 	case "segments":
 		v, ok := val.(*starlark.List)
@@ -3303,6 +5266,7 @@ func (p *PCB) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to segments using type %T", val)
 		}
 
+		p.Segments = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(NetSegment)
 			if !ok {
@@ -3310,6 +5274,8 @@ func (p *PCB) SetField(name string, val starlark.Value) error {
 			}
 			p.Segments = append(p.Segments, NetSegment(s))
 		}
+		p.resolveNetNames()
+		return nil
 
 	case "drawings":
 		v, ok := val.(*starlark.List)
@@ -3317,6 +5283,7 @@ func (p *PCB) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to drawings using type %T", val)
 		}
 
+		p.Drawings = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(Drawing)
 			if !ok {
@@ -3324,6 +5291,110 @@ func (p *PCB) SetField(name string, val starlark.Value) error {
 			}
 			p.Drawings = append(p.Drawings, Drawing(s))
 		}
+		return nil
+
+	case "zones":
+		v, ok := val.(*starlark.List)
+		if !ok {
+			return fmt.Errorf("cannot assign to zones using type %T", val)
+		}
+
+		p.Zones = nil
+		for i := 0; i < v.Len(); i++ {
+			s, ok := v.Index(i).(*Zone)
+			if !ok {
+				return errors.New("zones is not a Zone")
+			}
+			p.Zones = append(p.Zones, *s)
+		}
+		p.resolveNetNames()
+		return nil
+
+	case "nets":
+		v, ok := val.(*starlark.Dict)
+		if !ok {
+			return fmt.Errorf("cannot assign to nets using type %T", val)
+		}
+
+		p.Nets = make(map[int]Net, v.Len())
+		for _, e := range v.Items() {
+			num, err := starlark.AsInt32(e[0])
+			if err != nil {
+				return fmt.Errorf("nets key: %v", err)
+			}
+			n, ok := e[1].(*Net)
+			if !ok {
+				return errors.New("nets is not a Net")
+			}
+			n2 := *n
+			n2.board = nil
+			p.Nets[num] = n2
+		}
+		return nil
+
+	case "net_classes":
+		v, ok := val.(*starlark.List)
+		if !ok {
+			return fmt.Errorf("cannot assign to net_classes using type %T", val)
+		}
+
+		p.NetClasses = nil
+		for i := 0; i < v.Len(); i++ {
+			s, ok := v.Index(i).(*NetClass)
+			if !ok {
+				return errors.New("net_classes is not a NetClass")
+			}
+			p.NetClasses = append(p.NetClasses, *s)
+		}
+		return nil
+
+	case "title_info":
+		v, ok := val.(*TitleInfo)
+		if !ok {
+			return fmt.Errorf("cannot assign to title_info using type %T", val)
+		}
+		p.TitleInfo = v
+		return nil
+
+	case "setup":
+		v, ok := val.(*EditorSetup)
+		if !ok {
+			return fmt.Errorf("cannot assign to setup using type %T", val)
+		}
+		p.EditorSetup = *v
+		return nil
+
+	case "thickness":
+		v, ok := starlark.AsFloat(val)
+		if !ok {
+			return fmt.Errorf("cannot assign to thickness using type %T", val)
+		}
+		if v <= 0 {
+			return fmt.Errorf("invalid board thickness %v", v)
+		}
+		p.Thickness = v
+		return nil
+
+	case "page":
+		switch v := val.(type) {
+		case starlark.String:
+			if !pageSizes[string(v)] || v == "User" {
+				return fmt.Errorf("cannot assign page size %q: use Page() for custom sizes", string(v))
+			}
+			p.Page = Page{Size: string(v)}
+		case *Page:
+			p.Page = *v
+		default:
+			return fmt.Errorf("cannot assign to page using type %T", val)
+		}
+		return nil
+
+	case "copper_layers":
+		v, err := starlark.AsInt32(val)
+		if err != nil {
+			return fmt.Errorf("cannot assign to copper_layers: %v", err)
+		}
+		return p.SetCopperLayers(v)
 
 	case "modules":
 		v, ok := val.(*starlark.List)
@@ -3331,6 +5402,7 @@ func (p *PCB) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to modules using type %T", val)
 		}
 
+		p.Modules = nil
 		for i := 0; i < v.Len(); i++ {
 			s, ok := v.Index(i).(*Module)
 			if !ok {
@@ -3338,6 +5410,8 @@ func (p *PCB) SetField(name string, val starlark.Value) error {
 			}
 			p.Modules = append(p.Modules, *s)
 		}
+		p.resolveNetNames()
+		return nil
 	}
 
 	return errors.New("no such assignable field: " + name)
@@ -3444,6 +5518,7 @@ func (p *Line) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to start using type %T", val)
 		}
 		p.Start = *v
+		return nil
 
 	case "end":
 		v, ok := val.(*XY)
@@ -3451,6 +5526,7 @@ func (p *Line) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to end using type %T", val)
 		}
 		p.End = *v
+		return nil
 
 	case "angle":
 		v, ok := val.(starlark.Float)
@@ -3458,6 +5534,7 @@ func (p *Line) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to angle using type %T", val)
 		}
 		p.Angle = float64(v)
+		return nil
 
 	case "layer":
 		v, ok := val.(starlark.String)
@@ -3465,6 +5542,7 @@ func (p *Line) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to layer using type %T", val)
 		}
 		p.Layer = string(v)
+		return nil
 
 	case "width":
 		v, ok := val.(starlark.Float)
@@ -3472,6 +5550,7 @@ func (p *Line) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to width using type %T", val)
 		}
 		p.Width = float64(v)
+		return nil
 
 	case "tstamp":
 		v, ok := val.(starlark.String)
@@ -3479,6 +5558,7 @@ func (p *Line) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to tstamp using type %T", val)
 		}
 		p.Tstamp = string(v)
+		return nil
 
 	}
 
@@ -3586,6 +5666,7 @@ func (p *Arc) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to start using type %T", val)
 		}
 		p.Start = *v
+		return nil
 
 	case "end":
 		v, ok := val.(*XY)
@@ -3593,6 +5674,7 @@ func (p *Arc) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to end using type %T", val)
 		}
 		p.End = *v
+		return nil
 
 	case "angle":
 		v, ok := val.(starlark.Float)
@@ -3600,6 +5682,7 @@ func (p *Arc) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to angle using type %T", val)
 		}
 		p.Angle = float64(v)
+		return nil
 
 	case "tstamp":
 		v, ok := val.(starlark.String)
@@ -3607,6 +5690,7 @@ func (p *Arc) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to tstamp using type %T", val)
 		}
 		p.Tstamp = string(v)
+		return nil
 
 	case "layer":
 		v, ok := val.(starlark.String)
@@ -3614,6 +5698,7 @@ func (p *Arc) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to layer using type %T", val)
 		}
 		p.Layer = string(v)
+		return nil
 
 	case "width":
 		v, ok := val.(starlark.Float)
@@ -3621,6 +5706,7 @@ func (p *Arc) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to width using type %T", val)
 		}
 		p.Width = float64(v)
+		return nil
 
 	}
 
@@ -3734,6 +5820,7 @@ func (p *Text) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to text using type %T", val)
 		}
 		p.Text = string(v)
+		return nil
 
 	case "layer":
 		v, ok := val.(starlark.String)
@@ -3741,6 +5828,7 @@ func (p *Text) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to layer using type %T", val)
 		}
 		p.Layer = string(v)
+		return nil
 
 	case "tstamp":
 		v, ok := val.(starlark.String)
@@ -3748,6 +5836,7 @@ func (p *Text) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to tstamp using type %T", val)
 		}
 		p.Tstamp = string(v)
+		return nil
 
 	case "at":
 		v, ok := val.(*XYZ)
@@ -3755,6 +5844,7 @@ func (p *Text) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to at using type %T", val)
 		}
 		p.At = *v
+		return nil
 
 	case "unlocked":
 		v, ok := val.(starlark.Bool)
@@ -3762,6 +5852,7 @@ func (p *Text) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to unlocked using type %T", val)
 		}
 		p.Unlocked = bool(v)
+		return nil
 
 	case "effects":
 		v, ok := val.(*TextEffects)
@@ -3769,6 +5860,7 @@ func (p *Text) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to effects using type %T", val)
 		}
 		p.Effects = *v
+		return nil
 
 	case "hidden":
 		v, ok := val.(starlark.Bool)
@@ -3776,8 +5868,159 @@ func (p *Text) SetField(name string, val starlark.Value) error {
 			return fmt.Errorf("cannot assign to hidden using type %T", val)
 		}
 		p.Hidden = bool(v)
+		return nil
+
+	}
+
+	return errors.New("no such assignable field: " + name)
+}
+
+var MakeDimension = starlark.NewBuiltin("Dimension", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		start     *XY
+		end       *XY
+		offset    starlark.Float  = 5
+		layer     starlark.String = "Dwgs.User"
+		units     starlark.String = starlark.String(DimensionMillimeters)
+		precision starlark.Int    = starlark.MakeInt(4)
+		width     starlark.Float  = 0.3
+		textSize  *XY
+		thickness starlark.Float = 0.3
+	)
+	unpackErr := starlark.UnpackArgs(
+		"Dimension",
+		args,
+		kwargs,
+		"start", &start,
+		"end", &end,
+		"offset?", &offset,
+		"layer?", &layer,
+		"units?", &units,
+		"precision?", &precision,
+		"width?", &width,
+		"text_size?", &textSize,
+		"thickness?", &thickness,
+	)
+	if unpackErr != nil {
+		return starlark.None, unpackErr
+	}
+	prec, err := starlark.AsInt32(precision)
+	if err != nil {
+		return starlark.None, fmt.Errorf("precision: %v", err)
+	}
+
+	out := Dimension{
+		Width: float64(width),
+		Layer: string(layer),
+		Text: Text{
+			Effects: TextEffects{
+				FontSize:  XY{X: 1.5, Y: 1.5},
+				Thickness: float64(thickness),
+			},
+		},
+	}
+	if textSize != nil {
+		out.Text.Effects.FontSize = *textSize
+	}
+	if err := out.Layout(*start, *end, float64(offset), DimensionUnits(units), prec); err != nil {
+		return starlark.None, err
+	}
+	return &out, nil
+})
+
+func (p *Dimension) String() string {
+	return fmt.Sprintf("Dimension{%v, %v, %v, %v}", p.CurrentMeasurement, p.Text.Text, p.Width, p.Layer)
+}
+
+// Type implements starlark.Value.
+func (p *Dimension) Type() string {
+	return "Dimension"
+}
+
+// Freeze implements starlark.Value.
+func (p *Dimension) Freeze() {
+}
+
+// Truth implements starlark.Value.
+func (p *Dimension) Truth() starlark.Bool {
+	return starlark.Bool(true)
+}
+
+// Hash implements starlark.Value.
+func (p *Dimension) Hash() (uint32, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v", p)))
+	return uint32(uint32(h[0]) + uint32(h[1])<<8 + uint32(h[2])<<16 + uint32(h[3])<<24), nil
+}
+
+// Attr implements starlark.Value.
+func (p *Dimension) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "value":
+		return starlark.Float(p.CurrentMeasurement), nil
+
+	case "text":
+		return &p.Text, nil
+
+	case "width":
+		return starlark.Float(p.Width), nil
+
+	case "layer":
+		return starlark.String(p.Layer), nil
+	}
+
+	return nil, starlark.NoSuchAttrError(fmt.Sprintf("%s has no attribute %s", p.Type(), name))
+}
+
+// AttrNames implements starlark.Value.
+func (p *Dimension) AttrNames() []string {
+	return []string{"value", "text", "width", "layer"}
+}
+
+// SetField implements starlark.HasSetField.
+func (p *Dimension) SetField(name string, val starlark.Value) error {
+	switch name {
+	case "width":
+		v, ok := val.(starlark.Float)
+		if !ok {
+			return fmt.Errorf("cannot assign to width using type %T", val)
+		}
+		p.Width = float64(v)
+		return nil
 
+	case "layer":
+		v, ok := val.(starlark.String)
+		if !ok {
+			return fmt.Errorf("cannot assign to layer using type %T", val)
+		}
+		p.Layer = string(v)
+		p.Text.Layer = string(v)
+		return nil
 	}
 
 	return errors.New("no such assignable field: " + name)
 }
+
+// FloatArg is an optional numeric argument of a builtin, for use with
+// UnpackFloats.
+type FloatArg struct {
+	Name  string
+	Value starlark.Value
+	Out   *float64
+}
+
+// UnpackFloats stores the numeric arguments of the builtin fn which were
+// given, leaving the others at their defaults. It is an error for an
+// argument to be given a value which is not a number.
+func UnpackFloats(fn string, args ...FloatArg) error {
+	for _, a := range args {
+		if a.Value == nil {
+			continue
+		}
+		f, ok := starlark.AsFloat(a.Value)
+		if !ok {
+			return fmt.Errorf("%s: %s must be a number, got %s", fn, a.Name, a.Value.Type())
+		}
+		*a.Out = f
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package pcb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Issue describes a structural problem with a PCB, found by Validate.
+type Issue struct {
+	// Element identifies the offending element, such as track[3] or
+	// module[R1]/pad[2]. Modules are identified by their reference and
+	// pads by their number when possible, other elements by their index.
+	Element string `json:"element"`
+	Msg     string `json:"msg"`
+}
+
+func (i Issue) String() string {
+	return i.Element + ": " + i.Msg
+}
+
+// Validate checks the board for inconsistencies which would cause pcbnew
+// to reject or misinterpret it, such as references to nets or layers which
+// do not exist. An empty result means no problems were found.
+func Validate(p *PCB) []Issue {
+	v := validator{p: p}
+	v.checkSegments()
+	v.checkDrawings()
+	v.checkZones()
+	v.checkModules()
+	v.checkNetClasses()
+	return v.issues
+}
+
+type validator struct {
+	p      *PCB
+	issues []Issue
+}
+
+func (v *validator) addf(element, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Element: element, Msg: fmt.Sprintf(format, args...)})
+}
+
+// checkNet reports a reference to a net which is not in the net table.
+func (v *validator) checkNet(element string, num int) {
+	if _, ok := v.p.Nets[num]; !ok {
+		v.addf(element, "references net %d, which does not exist", num)
+	}
+}
+
+// checkNetName reports a reference to a net by both number and name,
+// which do not agree with the net table.
+func (v *validator) checkNetName(element string, num int, name string) {
+	n, ok := v.p.Nets[num]
+	switch {
+	case !ok:
+		v.addf(element, "references net %d (%q), which does not exist", num, name)
+	case n.Name != name:
+		v.addf(element, "references net %d as %q, but it is named %q", num, name, n.Name)
+	}
+}
+
+// checkLayer reports a reference to a layer which is not in the layer
+// table. Wildcards such as *.Cu are satisfied by any matching layer.
+func (v *validator) checkLayer(element, layer string) {
+	if _, ok := v.p.LayersByName[layer]; ok {
+		return
+	}
+	var suffix string
+	switch {
+	case strings.HasPrefix(layer, "*."):
+		suffix = layer[1:]
+	case strings.HasPrefix(layer, "F&B."):
+		suffix = layer[3:]
+	}
+	if suffix != "" {
+		for _, l := range v.p.Layers {
+			if strings.HasSuffix(l.Name, suffix) {
+				return
+			}
+		}
+	}
+	v.addf(element, "is on layer %q, which does not exist", layer)
+}
+
+func (v *validator) checkSegments() {
+	for i, s := range v.p.Segments {
+		switch s := s.(type) {
+		case *Track:
+			el := fmt.Sprintf("track[%d]", i)
+			v.checkNet(el, s.NetIndex)
+			v.checkLayer(el, s.Layer)
+			if s.Width <= 0 {
+				v.addf(el, "has width %v", s.Width)
+			}
+		case *Via:
+			el := fmt.Sprintf("via[%d]", i)
+			v.checkNet(el, s.NetIndex)
+			for _, l := range s.Layers {
+				v.checkLayer(el, l)
+			}
+			if s.Size <= 0 {
+				v.addf(el, "has size %v", s.Size)
+			}
+			// A drill of zero uses the default from the net class.
+			if s.Drill != 0 && s.Drill >= s.Size {
+				v.addf(el, "has drill %v, which is not smaller than its size %v", s.Drill, s.Size)
+			}
+		}
+	}
+}
+
+func (v *validator) checkDrawings() {
+	for i, d := range v.p.Drawings {
+		switch d := d.(type) {
+		case *Line:
+			v.checkLayer(fmt.Sprintf("gr_line[%d]", i), d.Layer)
+		case *Arc:
+			v.checkLayer(fmt.Sprintf("gr_arc[%d]", i), d.Layer)
+		case *Text:
+			v.checkLayer(fmt.Sprintf("gr_text[%d]", i), d.Layer)
+		case *Dimension:
+			v.checkLayer(fmt.Sprintf("dimension[%d]", i), d.Layer)
+		}
+	}
+}
+
+func (v *validator) checkZones() {
+	for i, z := range v.p.Zones {
+		el := fmt.Sprintf("zone[%d]", i)
+		if !z.IsKeepout {
+			v.checkNetName(el, z.NetNum, z.NetName)
+		}
+		if len(z.Layers) == 0 {
+			v.addf(el, "has no layers")
+		}
+		for _, l := range z.Layers {
+			v.checkLayer(el, l)
+		}
+
+		var hasOutline bool
+		for _, poly := range z.BasePolys {
+			if len(poly) >= 3 {
+				hasOutline = true
+			}
+		}
+		if !hasOutline {
+			v.addf(el, "has an empty outline")
+		}
+	}
+}
+
+func (v *validator) checkModules() {
+	seen := map[string]int{}
+	for i := range v.p.Modules {
+		m := &v.p.Modules[i]
+		ref := m.Reference()
+		el := fmt.Sprintf("module[%d]", i)
+		if ref != "" {
+			el = fmt.Sprintf("module[%s]", ref)
+			// Footprints which are not part of the schematic, such as
+			// logos and mounting holes, conventionally share REF**.
+			if first, ok := seen[ref]; !ok {
+				seen[ref] = i
+			} else if !strings.HasSuffix(ref, "**") {
+				v.addf(el, "has the same reference as module[%d]", first)
+			}
+		}
+
+		v.checkLayer(el, m.Layer)
+		for _, g := range m.Graphics {
+			if l := modDrawableLayer(g.Renderable); l != "" {
+				v.checkLayer(el+"/"+g.Ident, l)
+			}
+		}
+		for j, pad := range m.Pads {
+			pel := fmt.Sprintf("%s/pad[%d]", el, j)
+			if pad.Ident != "" {
+				pel = fmt.Sprintf("%s/pad[%s]", el, pad.Ident)
+			}
+			if pad.NetNum != 0 || pad.NetName != "" {
+				v.checkNetName(pel, pad.NetNum, pad.NetName)
+			}
+			for _, l := range pad.Layers {
+				v.checkLayer(pel, l)
+			}
+		}
+	}
+}
+
+func (v *validator) checkNetClasses() {
+	names := make(map[string]bool, len(v.p.Nets))
+	for _, n := range v.p.Nets {
+		names[n.Name] = true
+	}
+	for _, nc := range v.p.NetClasses {
+		for _, n := range nc.Nets {
+			if !names[n] {
+				v.addf(fmt.Sprintf("net_class[%s]", nc.Name), "contains net %q, which does not exist", n)
+			}
+		}
+	}
+}
+
+// modDrawableLayer returns the layer a module graphic is drawn on.
+func modDrawableLayer(d modDrawable) string {
+	switch d := d.(type) {
+	case *ModText:
+		return d.Layer
+	case *ModLine:
+		return d.Layer
+	case *ModCircle:
+		return d.Layer
+	case *ModArc:
+		return d.Layer
+	case *ModPolygon:
+		return d.Layer
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package pcb
+
+import (
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestValidateTestdata(t *testing.T) {
+	for _, fname := range []string{"t1.kicad_pcb", "cseduino-v4.kicad_pcb", "anavi-fume-extractor.kicad_pcb", "hp34401a_oled.kicad_pcb"} {
+		p, err := DecodeFile(path.Join("testdata", fname))
+		if err != nil {
+			t.Fatalf("DecodeFile(%q) failed: %v", fname, err)
+		}
+		if issues := Validate(p); len(issues) > 0 {
+			t.Errorf("Validate(%q) = %v, want no issues", fname, issues)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	p := EmptyPCB()
+	p.Nets[1] = Net{Name: "GND"}
+	p.Segments = []NetSegment{
+		&Track{Start: XY{X: 0, Y: 0}, End: XY{X: 1, Y: 0}, Width: 0.25, Layer: "F.Cu", NetIndex: 1},
+		&Track{Start: XY{X: 0, Y: 0}, End: XY{X: 1, Y: 0}, Width: 0, Layer: "In1.Cu", NetIndex: 7},
+		&Via{At: XY{X: 1, Y: 0}, Size: 0.6, Drill: 0.6, Layers: []string{"F.Cu", "B.Cu"}, NetIndex: 1},
+	}
+	p.Zones = []Zone{
+		{NetNum: 1, NetName: "VCC", Layers: []string{"B.Cu"}},
+	}
+	p.NetClasses[0].Nets = []string{"GND", "VBUS"}
+	ref := func(r string) ModGraphic {
+		return ModGraphic{Ident: "fp_text", Renderable: &ModText{Kind: RefText, Text: r, Layer: "F.SilkS"}}
+	}
+	p.Modules = []Module{
+		{Layer: "F.Cu", Graphics: []ModGraphic{ref("R1")}, Pads: []Pad{
+			{Ident: "1", NetNum: 1, NetName: "GND", Layers: []string{"*.Cu", "*.Mask"}},
+			{Ident: "2", NetNum: 2, NetName: "VCC", Layers: []string{"F.Cu"}},
+		}},
+		{Layer: "F.Cu", Graphics: []ModGraphic{ref("R1")}},
+		{Layer: "F.Cu", Graphics: []ModGraphic{ref("REF**")}},
+		{Layer: "F.Cu", Graphics: []ModGraphic{ref("REF**")}},
+	}
+
+	want := []Issue{
+		{Element: "track[1]", Msg: "references net 7, which does not exist"},
+		{Element: "track[1]", Msg: `is on layer "In1.Cu", which does not exist`},
+		{Element: "track[1]", Msg: "has width 0"},
+		{Element: "via[2]", Msg: "has drill 0.6, which is not smaller than its size 0.6"},
+		{Element: "zone[0]", Msg: `references net 1 as "VCC", but it is named "GND"`},
+		{Element: "zone[0]", Msg: "has an empty outline"},
+		{Element: "module[R1]/pad[2]", Msg: `references net 2 ("VCC"), which does not exist`},
+		{Element: "module[R1]", Msg: "has the same reference as module[0]"},
+		{Element: "net_class[Default]", Msg: `contains net "VBUS", which does not exist`},
+	}
+	if got := Validate(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %v\nwant %v", got, want)
+	}
+}
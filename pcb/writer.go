@@ -8,8 +8,15 @@ import (
 	"github.com/twitchyliquid64/kcgen/swriter"
 )
 
-// Write produces the file on disk.
+// Write produces the file on disk. Elements which refer to a net by name
+// are written on that net, which is added to the written board if
+// necessary. p itself is not modified.
 func (p *PCB) Write(w io.Writer) error {
+	if p.hasNetNames() {
+		p = p.copyNetRefs()
+		p.resolveNetNames()
+	}
+
 	sw, err := swriter.NewSExpWriter(w)
 	if err != nil {
 		return err
@@ -43,34 +50,14 @@ func (p *PCB) Write(w io.Writer) error {
 	}
 	sw.Newlines(2)
 
-	// EG: general (no_connects 0) ...
-	sw.StartList(false)
-	sw.StringScalar("general")
-	if len(p.generalFields) > 0 {
-		for _, section := range p.generalFields {
-			sw.StartList(true)
-			for _, v := range section {
-				sw.StringScalar(v)
-			}
-			if err := sw.CloseList(false); err != nil {
-				return err
-			}
-		}
-		if err := sw.CloseList(true); err != nil {
-			return err
-		}
-	} else {
-		if err := sw.CloseList(false); err != nil {
-			return err
-		}
+	// EG: general (thickness 1.6) (drawings 0) ...
+	if err := p.writeGeneral(sw); err != nil {
+		return err
 	}
 	sw.Separator()
 
 	// EG: page A4
-	sw.StartList(false)
-	sw.StringScalar("page")
-	sw.StringScalar("A4")
-	if err := sw.CloseList(false); err != nil {
+	if err := p.Page.write(sw); err != nil {
 		return err
 	}
 	sw.Newlines(1)
@@ -150,7 +137,7 @@ func (p *PCB) Write(w io.Writer) error {
 			sw.Newlines(1)
 		}
 	}
-	if len(p.Drawings) > 0 && len(p.Segments) > 0 {
+	if len(p.Drawings) > 0 && (len(p.Segments) > 0 || len(p.Zones) > 0) {
 		sw.Separator()
 	}
 
@@ -229,6 +216,24 @@ func (l *Layer) write(sw *swriter.SExpWriter) error {
 	return sw.CloseList(false)
 }
 
+// writeJustify writes the justification of text, if it is not centered
+// or is mirrored.
+func (e *TextEffects) writeJustify(sw *swriter.SExpWriter) error {
+	mirrored := e.Mirrored || e.Justify == JustifyMirror
+	if e.Justify == JustifyNone && !mirrored {
+		return nil
+	}
+	sw.StartList(false)
+	sw.StringScalar("justify")
+	if e.Justify != JustifyNone && e.Justify != JustifyMirror {
+		sw.StringScalar(e.Justify.String())
+	}
+	if mirrored {
+		sw.StringScalar("mirror")
+	}
+	return sw.CloseList(false)
+}
+
 func f(f float64) string {
 	t := fmt.Sprintf("%f", f)
 	if t[len(t)-1] != '0' {
@@ -248,7 +253,7 @@ func f(f float64) string {
 
 func fPrecise(f float64, precision int) string {
 	t := fmt.Sprintf("%."+fmt.Sprint(precision)+"f", f)
-	if t[len(t)-1] != '0' {
+	if precision <= 0 || t[len(t)-1] != '0' {
 		return t
 	}
 
@@ -437,13 +442,8 @@ func (t *Text) write(sw *swriter.SExpWriter) error {
 	if err := sw.CloseList(false); err != nil {
 		return err
 	}
-	if t.Effects.Justify != JustifyNone {
-		sw.StartList(false)
-		sw.StringScalar("justify")
-		sw.StringScalar(t.Effects.Justify.String())
-		if err := sw.CloseList(false); err != nil {
-			return err
-		}
+	if err := t.Effects.writeJustify(sw); err != nil {
+		return err
 	}
 	if err := sw.CloseList(false); err != nil {
 		return err
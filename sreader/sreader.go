@@ -0,0 +1,312 @@
+// Package sreader decodes s-expressions, keeping track of where each
+// element came from so malformed input can be reported precisely.
+package sreader
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nsf/sexp"
+)
+
+// ParseError describes malformed input. Line and Column are 1-based, and
+// are zero if the location is unknown.
+type ParseError struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+	// Path identifies the element which could not be decoded, such as
+	// kicad_pcb/module[3]/pad[7]/drill. Indexes are zero-based, and only
+	// present when the parent has more than one element of that name.
+	Path string `json:"path,omitempty"`
+	Msg  string `json:"msg"`
+}
+
+// Error implements error.
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		b.WriteByte(':')
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", e.Line, e.Column)
+	}
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+type document struct {
+	fname string
+	src   []byte
+	ctx   sexp.SourceContext
+}
+
+// position returns the line and column of the given location.
+func (d *document) position(loc sexp.SourceLoc) (line, col int) {
+	l := d.ctx.Decode(loc)
+	if l.Offset > len(d.src) || l.LineOffset > l.Offset {
+		return l.Line, 0
+	}
+	return l.Line, utf8.RuneCount(d.src[l.LineOffset:l.Offset]) + 1
+}
+
+// Node is an element in a parsed document. The zero value is not valid.
+//
+// Accessors never panic on malformed input: the Must* variants panic with
+// a *ParseError, which is converted back into an error by Recover.
+type Node struct {
+	n   *sexp.Node
+	doc *document
+
+	parent *Node
+	idx    int // position within parent, or requested position if n is nil.
+}
+
+// Parse decodes the s-expressions in data. The returned node is a list
+// containing each top-level expression. fname is only used when reporting
+// errors, and may be empty.
+func Parse(data []byte, fname string) (Node, error) {
+	d := &document{fname: fname, src: data}
+	f := d.ctx.AddFile(fname, len(data))
+	ast, err := sexp.Parse(bytes.NewReader(data), f)
+	if err != nil {
+		if pe, ok := err.(*sexp.ParseError); ok {
+			line, col := d.position(pe.Location)
+			return Node{}, &ParseError{File: fname, Line: line, Column: col, Msg: pe.Error()}
+		}
+		return Node{}, &ParseError{File: fname, Msg: err.Error()}
+	}
+	return Node{n: ast, doc: d, idx: -1}, nil
+}
+
+// IsValid returns true if the node exists.
+func (n Node) IsValid() bool {
+	return n.n != nil
+}
+
+// IsList returns true if the node is a list.
+func (n Node) IsList() bool {
+	return n.n != nil && n.n.IsList()
+}
+
+// IsScalar returns true if the node is a scalar value.
+func (n Node) IsScalar() bool {
+	return n.n != nil && n.n.IsScalar()
+}
+
+// NumChildren returns the number of elements in the list, or zero if the
+// node is not a list.
+func (n Node) NumChildren() int {
+	if n.n == nil {
+		return 0
+	}
+	return n.n.NumChildren()
+}
+
+// Child returns the i'th element of the list. If there is no such element,
+// an invalid node is returned which reports the omission when accessed.
+func (n Node) Child(i int) Node {
+	parent := n
+	out := Node{doc: n.doc, parent: &parent, idx: i}
+	if n.n == nil {
+		return out
+	}
+	c := n.n.Children
+	for x := 0; x < i && c != nil; x++ {
+		c = c.Next
+	}
+	out.n = c
+	return out
+}
+
+// Value returns the raw value of a scalar, or the empty string if the node
+// is not a scalar.
+func (n Node) Value() string {
+	if !n.IsScalar() {
+		return ""
+	}
+	return n.n.Value
+}
+
+// Name returns the leading scalar of a list, such as 'pad' for
+// (pad 1 smd rect ...), or the empty string if there is none.
+func (n Node) Name() string {
+	if !n.IsList() {
+		return ""
+	}
+	return n.Child(0).Value()
+}
+
+// String returns the value of the scalar.
+func (n Node) String() (string, error) {
+	if err := n.expectScalar("string"); err != nil {
+		return "", err
+	}
+	return n.n.Value, nil
+}
+
+// Int returns the value of the scalar as an integer.
+func (n Node) Int() (int, error) {
+	if err := n.expectScalar("integer"); err != nil {
+		return 0, err
+	}
+	v, err := strconv.Atoi(n.n.Value)
+	if err != nil {
+		return 0, n.Errorf("expected integer, got %q", n.n.Value)
+	}
+	return v, nil
+}
+
+// Float64 returns the value of the scalar as a number.
+func (n Node) Float64() (float64, error) {
+	if err := n.expectScalar("number"); err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(n.n.Value, 64)
+	if err != nil {
+		return 0, n.Errorf("expected number, got %q", n.n.Value)
+	}
+	return v, nil
+}
+
+// MustString is like String, but panics with a *ParseError on failure.
+func (n Node) MustString() string {
+	v, err := n.String()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustInt is like Int, but panics with a *ParseError on failure.
+func (n Node) MustInt() int {
+	v, err := n.Int()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustFloat64 is like Float64, but panics with a *ParseError on failure.
+func (n Node) MustFloat64() float64 {
+	v, err := n.Float64()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (n Node) expectScalar(want string) error {
+	switch {
+	case n.n == nil:
+		if n.parent != nil && n.parent.IsScalar() {
+			return n.parent.Errorf("expected list, got %q", n.parent.Value())
+		}
+		if n.parent == nil {
+			return n.Errorf("missing %s", want)
+		}
+		return n.Errorf("missing %s at position %d, %s has %d elements", want, n.idx, n.parent.describe(), n.parent.NumChildren())
+	case n.n.IsList():
+		e := n.Errorf("expected %s, got list", want)
+		if n.parent != nil {
+			e.Path = n.parent.Path()
+		}
+		return e
+	}
+	return nil
+}
+
+func (n Node) describe() string {
+	if name := n.Name(); name != "" {
+		return name
+	}
+	return "list"
+}
+
+// Errorf returns a *ParseError describing a problem with the node.
+func (n Node) Errorf(format string, args ...interface{}) *ParseError {
+	e := &ParseError{
+		Path: n.Path(),
+		Msg:  fmt.Sprintf(format, args...),
+	}
+	if n.doc == nil {
+		return e
+	}
+	e.File = n.doc.fname
+
+	// Missing nodes are reported at the position of their parent.
+	at := &n
+	for at != nil && at.n == nil {
+		at = at.parent
+	}
+	if at != nil {
+		e.Line, e.Column = n.doc.position(at.n.Location)
+	}
+	return e
+}
+
+// Path returns the slash-separated names of the lists enclosing the node,
+// such as kicad_pcb/module[3]/pad[7]/drill.
+func (n Node) Path() string {
+	var parts []string
+	for at := &n; at != nil; at = at.parent {
+		if at.n == nil || !at.n.IsList() || at.parent == nil {
+			continue
+		}
+		parts = append(parts, at.pathElement())
+	}
+
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, "/")
+}
+
+// pathElement returns the name of the list qualified by its index among
+// siblings of the same name, if there are any.
+func (n *Node) pathElement() string {
+	name := n.describe()
+	var idx, count int
+	for c := n.parent.n.Children; c != nil; c = c.Next {
+		if c == n.n {
+			idx = count
+		}
+		if c.IsList() && c.Children != nil && c.Children.IsScalar() && c.Children.Value == name {
+			count++
+		}
+	}
+	if count > 1 {
+		return fmt.Sprintf("%s[%d]", name, idx)
+	}
+	return name
+}
+
+// Recover converts a *ParseError panic raised by a Must* accessor into an
+// error, for use in a deferred call:
+//
+//	func decode(n sreader.Node) (out *Thing, err error) {
+//		defer sreader.Recover(&err)
+//		...
+//	}
+//
+// Other panics are propagated.
+func Recover(err *error) {
+	if r := recover(); r != nil {
+		if pe, ok := r.(*ParseError); ok {
+			*err = pe
+			return
+		}
+		panic(r)
+	}
+}
@@ -0,0 +1,218 @@
+package stitch
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/twitchyliquid64/kcgen/drc"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// FenceOptions describes a fence of vias along a path, such as an RF
+// track or the board outline.
+type FenceOptions struct {
+	// Net is the name of the net of the vias.
+	Net string `json:"net"`
+	// Offset is the distance from the path to the centers of the vias, on
+	// each side of it.
+	Offset float64 `json:"offset"`
+	// Pitch is the distance between vias along the path.
+	Pitch float64 `json:"pitch"`
+	// ViaSize and ViaDrill default to those of the net class of the net.
+	ViaSize  float64 `json:"via_size,omitempty"`
+	ViaDrill float64 `json:"via_drill,omitempty"`
+	// Clearance is kept from copper of other nets and the board edge, if
+	// it is larger than the clearance of the net classes.
+	Clearance float64 `json:"clearance,omitempty"`
+}
+
+// DefaultFenceOptions returns options to fence a path with GND vias 1mm
+// apart, 1mm to each side.
+func DefaultFenceOptions() FenceOptions {
+	return FenceOptions{Net: "GND", Offset: 1, Pitch: 1}
+}
+
+// NetPaths returns the tracks of the named net joined into paths.
+func NetPaths(p *pcb.PCB, net string) ([][]pcb.XY, error) {
+	num, ok := p.NetByName(net)
+	if !ok || num == 0 {
+		return nil, fmt.Errorf("no net named %q", net)
+	}
+	var tracks []*pcb.Track
+	for _, s := range p.Segments {
+		if t, ok := s.(*pcb.Track); ok && t.NetIndex == num {
+			tracks = append(tracks, t)
+		}
+	}
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("net %s has no tracks", net)
+	}
+	return TrackPaths(tracks), nil
+}
+
+// TrackPaths joins tracks which share ends into paths, whatever their
+// layer.
+func TrackPaths(tracks []*pcb.Track) [][]pcb.XY {
+	segs := make([][2]pcb.XY, len(tracks))
+	for i, t := range tracks {
+		segs[i] = [2]pcb.XY{t.Start, t.End}
+	}
+	return drc.Chain(segs)
+}
+
+// Fence places vias along the paths as PlanFence does, and adds them to
+// the board.
+func Fence(p *pcb.PCB, paths [][]pcb.XY, opts FenceOptions) ([]*pcb.Via, error) {
+	vias, err := PlanFence(p, paths, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vias {
+		p.Segments = append(p.Segments, v)
+	}
+	return vias, nil
+}
+
+// PlanFence returns through vias of the net on both sides of the paths,
+// following their corners. Paths which end where they start are closed.
+// Positions which are off the board, too close to copper of other nets,
+// pads, holes or other vias of the net, or in keepouts which do not allow
+// vias, are skipped.
+func PlanFence(p *pcb.PCB, paths [][]pcb.XY, opts FenceOptions) ([]*pcb.Via, error) {
+	net, ok := p.NetByName(opts.Net)
+	if !ok || net == 0 {
+		return nil, fmt.Errorf("no net named %q", opts.Net)
+	}
+	if opts.Offset <= 0 {
+		return nil, fmt.Errorf("offset must be positive, got %v", opts.Offset)
+	}
+	if opts.Pitch <= 0 {
+		return nil, fmt.Errorf("pitch must be positive, got %v", opts.Pitch)
+	}
+	s, err := newStitcher(p, net, opts.Pitch, opts.ViaSize, opts.ViaDrill, opts.Clearance)
+	if err != nil {
+		return nil, err
+	}
+
+	s.index()
+	var out []*pcb.Via
+	for _, path := range paths {
+		path = dedupe(path)
+		if len(path) < 2 {
+			continue
+		}
+		for _, side := range []float64{1, -1} {
+			for _, at := range spaced(offsetPath(path, side*opts.Offset), opts.Pitch) {
+				at = pcb.XY{X: math.Round(at.X*1e3) / 1e3, Y: math.Round(at.Y*1e3) / 1e3}
+				if s.fits(at) {
+					out = append(out, s.place(at))
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// dedupe removes repeated points from a path.
+func dedupe(path []pcb.XY) []pcb.XY {
+	var out []pcb.XY
+	for _, pt := range path {
+		if len(out) == 0 || out[len(out)-1].Distance(pt) > 1e-6 {
+			out = append(out, pt)
+		}
+	}
+	return out
+}
+
+// offsetPath returns the path moved sideways by offset, to the left of
+// its direction if positive. The outsides of corners are rounded, and
+// their insides are mitred.
+func offsetPath(path []pcb.XY, offset float64) []pcb.XY {
+	closed := len(path) > 2 && path[0].Distance(path[len(path)-1]) < 1e-6
+	if closed {
+		path = path[:len(path)-1]
+	}
+	n := len(path)
+	normal := func(i int) pcb.XY {
+		a, b := path[i], path[(i+1)%n]
+		l := a.Distance(b)
+		return pcb.XY{X: -(b.Y - a.Y) / l * offset, Y: (b.X - a.X) / l * offset}
+	}
+	shift := func(p, d pcb.XY) pcb.XY {
+		return pcb.XY{X: p.X + d.X, Y: p.Y + d.Y}
+	}
+
+	var out []pcb.XY
+	segs := n - 1
+	if closed {
+		segs = n
+	}
+	for i := 0; i < n; i++ {
+		var prev, next pcb.XY
+		switch {
+		case !closed && i == 0:
+			out = append(out, shift(path[0], normal(0)))
+			continue
+		case !closed && i == n-1:
+			out = append(out, shift(path[i], normal(segs-1)))
+			continue
+		default:
+			prev, next = normal((i+n-1)%n), normal(i)
+		}
+
+		// The inside of the corner is on the offset side where the path
+		// turns towards it, and sharp corners are not mitred.
+		turn := prev.X*next.Y - prev.Y*next.X
+		dot := (prev.X*next.X + prev.Y*next.Y) / (offset * offset)
+		switch {
+		case math.Abs(turn) < 1e-9*offset*offset && dot > 0:
+			out = append(out, shift(path[i], next))
+		case turn*offset > 0 && dot > -0.9:
+			// Inside of the corner: where the offset segments meet.
+			k := 1 / (1 + dot)
+			out = append(out, shift(path[i], pcb.XY{X: (prev.X + next.X) * k, Y: (prev.Y + next.Y) * k}))
+		case turn*offset > 0:
+			out = append(out, shift(path[i], prev), shift(path[i], next))
+		default:
+			// Outside of the corner: an arc around it.
+			a0 := math.Atan2(prev.Y, prev.X)
+			sweep := math.Atan2(turn, dot*offset*offset)
+			steps := int(math.Ceil(math.Abs(sweep) / (math.Pi / 16)))
+			for j := 0; j <= steps; j++ {
+				a := a0 + sweep*float64(j)/float64(steps)
+				out = append(out, shift(path[i], pcb.XY{X: math.Abs(offset) * math.Cos(a), Y: math.Abs(offset) * math.Sin(a)}))
+			}
+		}
+	}
+	if closed {
+		out = append(out, out[0])
+	}
+	return out
+}
+
+// spaced returns points evenly spaced along the path, no more than pitch
+// apart, including its ends.
+func spaced(path []pcb.XY, pitch float64) []pcb.XY {
+	var length float64
+	for i := 1; i < len(path); i++ {
+		length += path[i-1].Distance(path[i])
+	}
+	n := int(math.Ceil(length/pitch - 1e-6))
+	if n < 1 {
+		return []pcb.XY{path[0]}
+	}
+	step := length / float64(n)
+
+	out := []pcb.XY{path[0]}
+	along := step
+	for i := 1; i < len(path) && len(out) <= n; i++ {
+		a, b := path[i-1], path[i]
+		l := a.Distance(b)
+		for ; along <= l+1e-9 && len(out) <= n; along += step {
+			t := along / l
+			out = append(out, pcb.XY{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t})
+		}
+		along -= l
+	}
+	return out
+}
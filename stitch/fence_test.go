@@ -0,0 +1,119 @@
+package stitch
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/drc"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+func pathDistance(at pcb.XY, paths [][]pcb.XY) float64 {
+	near := math.Inf(1)
+	for _, path := range paths {
+		for i := 1; i < len(path); i++ {
+			near = math.Min(near, at.Distance(pcb.ClosestOnSegment(at, path[i-1], path[i])))
+		}
+	}
+	return near
+}
+
+func TestFenceNet(t *testing.T) {
+	p := loadBoard(t, "fence.kicad_pcb")
+	paths, err := NetPaths(p, "RF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || len(paths[0]) != 4 {
+		t.Fatalf("NetPaths() = %v, want the RF tracks joined into one path", paths)
+	}
+
+	opts := DefaultFenceOptions()
+	vias, err := Fence(p, paths, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var above, below int
+	for _, v := range vias {
+		if v.NetIndex != 1 {
+			t.Errorf("via at %v has net %d, want GND", v.At, v.NetIndex)
+		}
+		// Corners are followed, so every via is the offset from the path.
+		if d := pathDistance(v.At, paths); math.Abs(d-opts.Offset) > 0.002 {
+			t.Errorf("via at %v is %v from the path, want %v", v.At, d, opts.Offset)
+		}
+		if v.At.X > 21-0.3 && v.At.X < 23+0.3 && v.At.Y > 2.5-0.3 && v.At.Y < 3.5+0.3 {
+			t.Errorf("via at %v is in the keepout", v.At)
+		}
+		if d := v.At.Distance(pcb.XY{X: 20, Y: 5}); d < opts.Pitch/2 {
+			t.Errorf("via at %v is %v from the existing via at (20, 5)", v.At, d)
+		}
+		if v.At.Y > 10 && v.At.X < 12 {
+			below++
+		}
+		if v.At.Y < 10 && v.At.X < 12 {
+			above++
+		}
+	}
+	// The SIG track beneath the first run of the path leaves a gap in the
+	// fence on that side.
+	if below == 0 || above <= below {
+		t.Errorf("got %d vias above and %d below the first run, want a gap below", above, below)
+	}
+
+	if vs := drc.Check(p, drc.Options{}); len(vs) > 0 {
+		t.Errorf("Check() = %v, want no violations", vs)
+	}
+}
+
+func TestFenceOutline(t *testing.T) {
+	p := loadBoard(t, "fence.kicad_pcb")
+	outline := drc.Outline(p)
+	if len(outline) != 1 || outline[0][0].Distance(outline[0][len(outline[0])-1]) > 1e-6 {
+		t.Fatalf("Outline() = %v, want one closed loop", outline)
+	}
+
+	opts := DefaultFenceOptions()
+	opts.Pitch = 2
+	vias, err := PlanFence(p, outline, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vias) < 40 {
+		t.Errorf("PlanFence() placed %d vias, want the outline fenced", len(vias))
+	}
+	for _, v := range vias {
+		if v.At.X < 0 || v.At.Y < 0 || v.At.X > 30 || v.At.Y > 20 {
+			t.Errorf("via at %v is off the board", v.At)
+		}
+		if d := pathDistance(v.At, outline); math.Abs(d-opts.Offset) > 0.002 {
+			t.Errorf("via at %v is %v from the outline, want %v", v.At, d, opts.Offset)
+		}
+	}
+}
+
+func TestFenceErrors(t *testing.T) {
+	tcs := []struct {
+		name   string
+		modify func(*FenceOptions)
+		want   string
+	}{
+		{"unknown net", func(o *FenceOptions) { o.Net = "NOPE" }, `no net named "NOPE"`},
+		{"offset", func(o *FenceOptions) { o.Offset = 0 }, "offset must be positive"},
+		{"pitch", func(o *FenceOptions) { o.Pitch = -1 }, "pitch must be positive"},
+	}
+	for _, tc := range tcs {
+		p := loadBoard(t, "fence.kicad_pcb")
+		opts := DefaultFenceOptions()
+		tc.modify(&opts)
+		if _, err := PlanFence(p, [][]pcb.XY{{{X: 2, Y: 10}, {X: 12, Y: 10}}}, opts); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: PlanFence() error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+
+	p := loadBoard(t, "fence.kicad_pcb")
+	if _, err := NetPaths(p, "GND"); err == nil || !strings.Contains(err.Error(), "net GND has no tracks") {
+		t.Errorf("NetPaths() error = %v, want no tracks", err)
+	}
+}
@@ -0,0 +1,124 @@
+package stitch
+
+import (
+	"fmt"
+
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"go.starlark.net/starlark"
+)
+
+// MakeStitch places stitching vias on a board from starlark, returning a
+// list of the vias added to the board.
+var MakeStitch = starlark.NewBuiltin("stitch", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	opts := DefaultOptions()
+	var (
+		p        *pcb.PCB
+		net      = starlark.String(opts.Net)
+		strategy = starlark.String(opts.Strategy)
+	)
+	var spacing, viaSize, viaDrill, clearance starlark.Value
+	if err := starlark.UnpackArgs("stitch", args, kwargs, "pcb", &p, "net?", &net, "strategy?", &strategy,
+		"spacing?", &spacing, "via_size?", &viaSize, "via_drill?", &viaDrill, "clearance?", &clearance); err != nil {
+		return starlark.None, err
+	}
+	opts.Net, opts.Strategy = string(net), string(strategy)
+	if err := pcb.UnpackFloats("stitch",
+		pcb.FloatArg{Name: "spacing", Value: spacing, Out: &opts.Spacing},
+		pcb.FloatArg{Name: "via_size", Value: viaSize, Out: &opts.ViaSize},
+		pcb.FloatArg{Name: "via_drill", Value: viaDrill, Out: &opts.ViaDrill},
+		pcb.FloatArg{Name: "clearance", Value: clearance, Out: &opts.Clearance},
+	); err != nil {
+		return starlark.None, err
+	}
+
+	vias, err := Stitch(p, opts)
+	if err != nil {
+		return starlark.None, err
+	}
+	out := make([]starlark.Value, len(vias))
+	for i, v := range vias {
+		out[i] = v
+	}
+	return starlark.NewList(out), nil
+})
+
+// MakeFence places a fence of vias along a path from starlark, returning
+// a list of the vias added to the board. The path is the name of a net
+// whose tracks are fenced, a list of tracks, a list of XY points such as
+// a line of the board outline, or a list of such lists.
+var MakeFence = starlark.NewBuiltin("fence", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	opts := DefaultFenceOptions()
+	var (
+		p     *pcb.PCB
+		along starlark.Value
+		net   = starlark.String(opts.Net)
+	)
+	var offset, pitch, viaSize, viaDrill, clearance starlark.Value
+	if err := starlark.UnpackArgs("fence", args, kwargs, "pcb", &p, "along", &along, "net?", &net, "offset?", &offset,
+		"pitch?", &pitch, "via_size?", &viaSize, "via_drill?", &viaDrill, "clearance?", &clearance); err != nil {
+		return starlark.None, err
+	}
+	opts.Net = string(net)
+	if err := pcb.UnpackFloats("fence",
+		pcb.FloatArg{Name: "offset", Value: offset, Out: &opts.Offset},
+		pcb.FloatArg{Name: "pitch", Value: pitch, Out: &opts.Pitch},
+		pcb.FloatArg{Name: "via_size", Value: viaSize, Out: &opts.ViaSize},
+		pcb.FloatArg{Name: "via_drill", Value: viaDrill, Out: &opts.ViaDrill},
+		pcb.FloatArg{Name: "clearance", Value: clearance, Out: &opts.Clearance},
+	); err != nil {
+		return starlark.None, err
+	}
+
+	paths, err := unpackPaths(p, along)
+	if err != nil {
+		return starlark.None, err
+	}
+	vias, err := Fence(p, paths, opts)
+	if err != nil {
+		return starlark.None, err
+	}
+	out := make([]starlark.Value, len(vias))
+	for i, v := range vias {
+		out[i] = v
+	}
+	return starlark.NewList(out), nil
+})
+
+func unpackPaths(p *pcb.PCB, along starlark.Value) ([][]pcb.XY, error) {
+	if net, ok := along.(starlark.String); ok {
+		return NetPaths(p, string(net))
+	}
+	l, ok := along.(*starlark.List)
+	if !ok {
+		return nil, fmt.Errorf("fence: along must be a net name or a list, got %s", along.Type())
+	}
+
+	var (
+		tracks []*pcb.Track
+		path   []pcb.XY
+		paths  [][]pcb.XY
+	)
+	for i := 0; i < l.Len(); i++ {
+		switch v := l.Index(i).(type) {
+		case *pcb.Track:
+			tracks = append(tracks, v)
+		case *pcb.XY:
+			path = append(path, *v)
+		case *starlark.List:
+			sub, err := unpackPaths(p, v)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, sub...)
+		default:
+			return nil, fmt.Errorf("fence: along must hold tracks, points or lists of points, got %s", v.Type())
+		}
+	}
+	if len(tracks) > 0 {
+		paths = append(paths, TrackPaths(tracks)...)
+	}
+	if len(path) > 0 {
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
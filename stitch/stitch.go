@@ -0,0 +1,348 @@
+// Package stitch places stitching vias, which join the zones of a net on
+// different layers of a board.
+package stitch
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/twitchyliquid64/kcgen/drc"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Strategies for placing vias.
+const (
+	// StrategyGrid places vias on a square grid.
+	StrategyGrid = "grid"
+	// StrategyStaggered places vias on a grid with alternate rows offset
+	// by half the spacing, so they are more evenly spread.
+	StrategyStaggered = "staggered"
+	// StrategyEdge places vias along the edges of the zone fills, such as
+	// around the board outline and cutouts.
+	StrategyEdge = "edge"
+)
+
+// edgeInset is how far inside the fill, beyond the via itself, vias
+// placed along its edges are.
+const edgeInset = 0.1
+
+// Options describes the stitching vias to place.
+type Options struct {
+	// Net is the name of the net whose zones are stitched.
+	Net string `json:"net"`
+	// Strategy is one of StrategyGrid, StrategyStaggered or StrategyEdge.
+	// "alternating" is accepted for StrategyStaggered.
+	Strategy string `json:"strategy"`
+	// Spacing is the distance between vias.
+	Spacing float64 `json:"spacing"`
+	// ViaSize and ViaDrill default to those of the net class of the net.
+	ViaSize  float64 `json:"via_size,omitempty"`
+	ViaDrill float64 `json:"via_drill,omitempty"`
+	// Clearance is kept from copper of other nets, if it is larger than
+	// the clearance of the net classes.
+	Clearance float64 `json:"clearance,omitempty"`
+}
+
+// DefaultOptions returns options to stitch the GND zones of a board on a
+// 1.5mm grid.
+func DefaultOptions() Options {
+	return Options{Net: "GND", Strategy: StrategyGrid, Spacing: 1.5}
+}
+
+// Stitch places stitching vias as Plan does, and adds them to the board.
+func Stitch(p *pcb.PCB, opts Options) ([]*pcb.Via, error) {
+	vias, err := Plan(p, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vias {
+		p.Segments = append(p.Segments, v)
+	}
+	return vias, nil
+}
+
+// Plan returns through vias of the net, placed where its zones are filled
+// on at least two layers, clear of copper of other nets, pads, holes and
+// keepouts which do not allow vias. Zones of the net which have not been
+// filled are planned as they would be filled, leaving the board as it is.
+func Plan(p *pcb.PCB, opts Options) ([]*pcb.Via, error) {
+	net, ok := p.NetByName(opts.Net)
+	if !ok || net == 0 {
+		return nil, fmt.Errorf("no net named %q", opts.Net)
+	}
+	s, err := newStitcher(p, net, opts.Spacing, opts.ViaSize, opts.ViaDrill, opts.Clearance)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.collectFills(); err != nil {
+		return nil, err
+	}
+
+	var candidates []pcb.XY
+	switch opts.Strategy {
+	case StrategyGrid, "":
+		candidates = s.grid(false)
+	case StrategyStaggered, "alternating":
+		candidates = s.grid(true)
+	case StrategyEdge:
+		candidates = s.edges()
+	default:
+		return nil, fmt.Errorf("unknown strategy %q: must be %s, %s or %s", opts.Strategy, StrategyGrid, StrategyStaggered, StrategyEdge)
+	}
+
+	s.index()
+	var out []*pcb.Via
+	for _, at := range candidates {
+		at = pcb.XY{X: math.Round(at.X*1e3) / 1e3, Y: math.Round(at.Y*1e3) / 1e3}
+		if s.covered(at) && s.fits(at) {
+			out = append(out, s.place(at))
+		}
+	}
+	return out, nil
+}
+
+// viaRules returns the size, drill and clearance of vias of the named net,
+// from the arguments if they are positive or else the net class of the
+// net.
+func viaRules(p *pcb.PCB, net string, viaSize, viaDrill, minClearance float64) (size, drill, clearance float64) {
+	size, drill, clearance = p.EditorSetup.ViaSize, p.EditorSetup.ViaDrill, p.EditorSetup.TraceClearance
+	var class *pcb.NetClass
+	for i := range p.NetClasses {
+		nc := &p.NetClasses[i]
+		if nc.Name == "Default" && class == nil {
+			class = nc
+		}
+		for _, n := range nc.Nets {
+			if n == net {
+				class = nc
+			}
+		}
+	}
+	if class != nil {
+		size, drill, clearance = class.ViaDiameter, class.ViaDrill, class.Clearance
+	}
+	if viaSize > 0 {
+		size = viaSize
+	}
+	if viaDrill > 0 {
+		drill = viaDrill
+	}
+	return size, drill, math.Max(clearance, minClearance)
+}
+
+// fill is the filled area of a zone of the net on a layer.
+type fill struct {
+	layer string
+	polys [][]pcb.XY
+}
+
+// stitcher places vias of a net at least spacing apart.
+type stitcher struct {
+	p           *pcb.PCB
+	net         int
+	size, drill float64
+	r           float64
+	clearance   float64
+	spacing     float64
+
+	fills  []fill
+	copper *drc.Copper
+	// placed are the vias of the net, existing and planned.
+	placed []pcb.XY
+}
+
+func newStitcher(p *pcb.PCB, net int, spacing, viaSize, viaDrill, minClearance float64) (*stitcher, error) {
+	if spacing <= 0 {
+		return nil, fmt.Errorf("spacing must be positive, got %v", spacing)
+	}
+	size, drill, clearance := viaRules(p, p.Nets[net].Name, viaSize, viaDrill, minClearance)
+	if size <= 0 || drill <= 0 || drill >= size {
+		return nil, fmt.Errorf("invalid via size %v and drill %v", size, drill)
+	}
+	return &stitcher{p: p, net: net, size: size, drill: drill, r: size / 2, clearance: clearance, spacing: spacing}, nil
+}
+
+// index finds the copper vias must keep clear of, and the existing vias
+// of the net.
+func (s *stitcher) index() {
+	s.copper = drc.NewCopper(s.p)
+	for _, seg := range s.p.Segments {
+		if v, ok := seg.(*pcb.Via); ok && v.NetIndex == s.net {
+			s.placed = append(s.placed, v.At)
+		}
+	}
+}
+
+// place returns a through via at the point.
+func (s *stitcher) place(at pcb.XY) *pcb.Via {
+	s.placed = append(s.placed, at)
+	layers := s.p.CopperLayers()
+	return &pcb.Via{
+		At:       at,
+		Size:     s.size,
+		Drill:    s.drill,
+		Layers:   []string{layers[0].Name, layers[len(layers)-1].Name},
+		NetIndex: s.net,
+	}
+}
+
+func (s *stitcher) collectFills() error {
+	layers := map[string]bool{}
+	for i := range s.p.Zones {
+		z := &s.p.Zones[i]
+		if z.IsKeepout || z.NetNum != s.net || len(z.Layers) == 0 {
+			continue
+		}
+		polys := z.Polys
+		if len(polys) == 0 {
+			polys = drc.FillZone(s.p, z)
+		}
+		if len(polys) == 0 {
+			continue
+		}
+		s.fills = append(s.fills, fill{layer: z.Layers[0], polys: polys})
+		layers[z.Layers[0]] = true
+	}
+	if len(layers) < 2 {
+		return fmt.Errorf("net %s is filled on %d layers, need at least 2 to stitch", s.p.Nets[s.net].Name, len(layers))
+	}
+	return nil
+}
+
+// bounds returns the extent of the fills of the net.
+func (s *stitcher) bounds() (min, max pcb.XY) {
+	min = pcb.XY{X: math.Inf(1), Y: math.Inf(1)}
+	max = pcb.XY{X: math.Inf(-1), Y: math.Inf(-1)}
+	for _, f := range s.fills {
+		for _, poly := range f.polys {
+			for _, pt := range poly {
+				min.X, min.Y = math.Min(min.X, pt.X), math.Min(min.Y, pt.Y)
+				max.X, max.Y = math.Max(max.X, pt.X), math.Max(max.Y, pt.Y)
+			}
+		}
+	}
+	return min, max
+}
+
+// grid returns points on a grid aligned to multiples of the spacing, so
+// vias line up across zones and runs.
+func (s *stitcher) grid(staggered bool) []pcb.XY {
+	min, max := s.bounds()
+	var out []pcb.XY
+	for row := math.Ceil(min.Y / s.spacing); row*s.spacing <= max.Y; row++ {
+		x0 := math.Ceil(min.X/s.spacing) * s.spacing
+		if staggered && math.Mod(row, 2) != 0 {
+			x0 -= s.spacing / 2
+		}
+		for col := 0.0; x0+col*s.spacing <= max.X; col++ {
+			out = append(out, pcb.XY{X: x0 + col*s.spacing, Y: row * s.spacing})
+		}
+	}
+	return out
+}
+
+// edges returns points along the edges of the fills, just inside them.
+func (s *stitcher) edges() []pcb.XY {
+	inset := s.r + edgeInset
+	var out []pcb.XY
+	for _, f := range s.fills {
+		for _, poly := range f.polys {
+			// Fills are single polygons with holes joined to their
+			// outline by bridges, which are edges in both directions.
+			type edge [2]pcb.XY
+			seen := map[edge]bool{}
+			for i := range poly {
+				seen[edge{poly[i], poly[(i+1)%len(poly)]}] = true
+			}
+			side := 1.0
+			if signedArea(poly) < 0 {
+				side = -1
+			}
+
+			along := s.spacing / 2
+			for i := range poly {
+				a, b := poly[i], poly[(i+1)%len(poly)]
+				l := a.Distance(b)
+				if l == 0 || seen[edge{b, a}] {
+					continue
+				}
+				dx, dy := (b.X-a.X)/l, (b.Y-a.Y)/l
+				normal := pcb.XY{X: -dy * side * inset, Y: dx * side * inset}
+				for ; along <= l; along += s.spacing {
+					out = append(out, pcb.XY{X: a.X + dx*along + normal.X, Y: a.Y + dy*along + normal.Y})
+				}
+				along -= l
+			}
+		}
+	}
+	return out
+}
+
+// covered reports whether a via at the point is covered by fills of the
+// net on at least two layers.
+func (s *stitcher) covered(at pcb.XY) bool {
+	layers := map[string]bool{}
+	for _, f := range s.fills {
+		if !layers[f.layer] && covers(f.polys, at, s.r) {
+			layers[f.layer] = true
+		}
+	}
+	return len(layers) >= 2
+}
+
+// fits reports whether a via can be placed at the point, away from other
+// vias of the net and clear of the board edge and other copper.
+func (s *stitcher) fits(at pcb.XY) bool {
+	minDist := math.Max(s.spacing/2, 2*s.r+s.clearance)
+	for _, v := range s.placed {
+		if v.Distance(at) < minDist {
+			return false
+		}
+	}
+	if !s.copper.OnBoard(at, s.r, s.clearance) {
+		return false
+	}
+
+	for i := range s.p.Zones {
+		z := &s.p.Zones[i]
+		if z.IsKeepout && !z.Keepout.ViasAllowed && covers(z.BasePolys, at, -s.r) {
+			return false
+		}
+	}
+	for _, l := range s.p.CopperLayers() {
+		if !s.copper.Clear(l.Name, at, s.r, s.net, s.clearance) {
+			return false
+		}
+	}
+	return true
+}
+
+// covers reports whether the circle of radius r at p is inside the
+// polygons, by the even-odd rule. A negative r reports whether any of the
+// circle is inside.
+func covers(polys [][]pcb.XY, p pcb.XY, r float64) bool {
+	in := false
+	near := math.Inf(1)
+	for _, poly := range polys {
+		for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+			a, b := poly[i], poly[j]
+			if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+				in = !in
+			}
+			near = math.Min(near, p.Distance(pcb.ClosestOnSegment(p, a, b)))
+		}
+	}
+	if r < 0 {
+		return in || near < -r
+	}
+	return in && near >= r
+}
+
+func signedArea(poly []pcb.XY) float64 {
+	var a float64
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		a += p.X*q.Y - q.X*p.Y
+	}
+	return a / 2
+}
@@ -0,0 +1,145 @@
+package stitch
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/drc"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+func loadBoard(t *testing.T, name string) *pcb.PCB {
+	t.Helper()
+	p, err := pcb.DecodeFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestStitch(t *testing.T) {
+	for _, strategy := range []string{StrategyGrid, StrategyStaggered, "alternating", StrategyEdge} {
+		t.Run(strategy, func(t *testing.T) {
+			p := loadBoard(t, "stitch.kicad_pcb")
+			opts := DefaultOptions()
+			opts.Strategy = strategy
+			before := len(p.Segments)
+
+			vias, err := Stitch(p, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(vias) == 0 {
+				t.Fatal("Stitch() placed no vias")
+			}
+			if got, want := len(p.Segments), before+len(vias); got != want {
+				t.Errorf("board has %d segments, want %d", got, want)
+			}
+			for _, v := range vias {
+				if v.NetIndex != 1 || v.Size != 0.6 || v.Drill != 0.3 {
+					t.Errorf("via at %v has net %d, size %v and drill %v, want GND vias from the net class", v.At, v.NetIndex, v.Size, v.Drill)
+				}
+				if v.At.X > 8-0.3 && v.At.X < 12+0.3 && v.At.Y > 14-0.3 && v.At.Y < 18+0.3 {
+					t.Errorf("via at %v is in the keepout", v.At)
+				}
+			}
+
+			if vs := drc.Check(p, drc.Options{}); len(vs) > 0 {
+				t.Errorf("Check() = %v, want no violations", vs)
+			}
+		})
+	}
+}
+
+func TestStitchStaggered(t *testing.T) {
+	p := loadBoard(t, "stitch.kicad_pcb")
+	opts := DefaultOptions()
+	opts.Strategy = StrategyStaggered
+	vias, err := Plan(p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Alternate rows are offset by half the spacing.
+	for _, v := range vias {
+		row := int(math.Round(v.At.Y / opts.Spacing))
+		col := v.At.X / opts.Spacing
+		if row%2 == 1 {
+			col += 0.5
+		}
+		if math.Abs(col-math.Round(col)) > 1e-6 {
+			t.Errorf("via at %v is off the grid of row %d", v.At, row)
+		}
+	}
+}
+
+func TestStitchEdge(t *testing.T) {
+	p := loadBoard(t, "stitch.kicad_pcb")
+	opts := DefaultOptions()
+	opts.Strategy = StrategyEdge
+	vias, err := Plan(p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Vias follow the edges of the fills, so none are far from them.
+	var fills [][]pcb.XY
+	for i := range p.Zones {
+		if z := &p.Zones[i]; z.NetNum == 1 && !z.IsKeepout {
+			fills = append(fills, drc.FillZone(p, z)...)
+		}
+	}
+	for _, v := range vias {
+		near := math.Inf(1)
+		for _, poly := range fills {
+			for i := range poly {
+				near = math.Min(near, v.At.Distance(pcb.ClosestOnSegment(v.At, poly[i], poly[(i+1)%len(poly)])))
+			}
+		}
+		if want := v.Size/2 + edgeInset + 0.01; near > want {
+			t.Errorf("via at %v is %v from the fill edges, want at most %v", v.At, near, want)
+		}
+	}
+}
+
+func TestPlanLeavesFills(t *testing.T) {
+	p := loadBoard(t, "stitch.kicad_pcb")
+	vcc := &p.Zones[3]
+	square := [][]pcb.XY{{{X: 20, Y: 2}, {X: 22, Y: 2}, {X: 22, Y: 4}, {X: 20, Y: 4}}}
+	vcc.Polys = square
+
+	if _, err := Plan(p, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	for i, z := range p.Zones {
+		if z.NetNum == 1 && len(z.Polys) > 0 {
+			t.Errorf("Plan() filled zone %d", i)
+		}
+	}
+	if !reflect.DeepEqual(vcc.Polys, square) {
+		t.Errorf("Plan() changed the fill of the VCC zone to %v", vcc.Polys)
+	}
+}
+
+func TestStitchErrors(t *testing.T) {
+	tcs := []struct {
+		name   string
+		modify func(*pcb.PCB, *Options)
+		want   string
+	}{
+		{"unknown net", func(p *pcb.PCB, o *Options) { o.Net = "NOPE" }, `no net named "NOPE"`},
+		{"unknown strategy", func(p *pcb.PCB, o *Options) { o.Strategy = "spiral" }, `unknown strategy "spiral"`},
+		{"spacing", func(p *pcb.PCB, o *Options) { o.Spacing = 0 }, "spacing must be positive"},
+		{"drill", func(p *pcb.PCB, o *Options) { o.ViaDrill = 1 }, "invalid via size"},
+		{"one layer", func(p *pcb.PCB, o *Options) { o.Net = "VCC" }, "net VCC is filled on 1 layers"},
+	}
+	for _, tc := range tcs {
+		p := loadBoard(t, "stitch.kicad_pcb")
+		opts := DefaultOptions()
+		tc.modify(p, &opts)
+		if _, err := Plan(p, opts); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: Plan() error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package teardrop
+
+import (
+	"github.com/twitchyliquid64/kcgen/pcb"
+	"go.starlark.net/starlark"
+)
+
+// MakeTeardrops adds teardrops to a board from starlark, replacing those
+// already on it, and returns the number added.
+var MakeTeardrops = starlark.NewBuiltin("teardrops", func(t *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	opts := DefaultOptions()
+	var (
+		p             *pcb.PCB
+		curveSegments = opts.CurveSegments
+		skipSMD       = opts.SkipSMD
+		fill          = opts.Fill
+	)
+	var lengthRatio, widthRatio, maxLength, maxWidth starlark.Value
+	if err := starlark.UnpackArgs("teardrops", args, kwargs, "pcb", &p, "length_ratio?", &lengthRatio, "width_ratio?", &widthRatio,
+		"max_length?", &maxLength, "max_width?", &maxWidth, "curve_segments?", &curveSegments, "skip_smd?", &skipSMD, "fill?", &fill); err != nil {
+		return starlark.None, err
+	}
+	if err := pcb.UnpackFloats("teardrops",
+		pcb.FloatArg{Name: "length_ratio", Value: lengthRatio, Out: &opts.LengthRatio},
+		pcb.FloatArg{Name: "width_ratio", Value: widthRatio, Out: &opts.WidthRatio},
+		pcb.FloatArg{Name: "max_length", Value: maxLength, Out: &opts.MaxLength},
+		pcb.FloatArg{Name: "max_width", Value: maxWidth, Out: &opts.MaxWidth},
+	); err != nil {
+		return starlark.None, err
+	}
+	opts.CurveSegments, opts.SkipSMD, opts.Fill = curveSegments, skipSMD, fill
+
+	n, err := Add(p, opts)
+	if err != nil {
+		return starlark.None, err
+	}
+	return starlark.MakeInt(n), nil
+})
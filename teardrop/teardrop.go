@@ -0,0 +1,359 @@
+// Package teardrop adds teardrops, which widen tracks where they meet pads
+// and vias so the joint survives drilling and etching tolerances.
+package teardrop
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/twitchyliquid64/kcgen/drc"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+// Priority marks the zones of teardrops, as the teardrops action plugin
+// for pcbnew does, so they can be found and replaced. Their high priority
+// also keeps zones of other nets clear of them.
+const Priority = 0x4242
+
+const (
+	// minThickness is the minimum thickness of teardrop zones, small enough
+	// to keep their narrow ends.
+	minThickness = 0.1
+	// minLength is the shortest teardrop worth adding.
+	minLength = 0.01
+)
+
+// Options describes the teardrops to add.
+type Options struct {
+	// LengthRatio is the length of a teardrop beyond the edge of its pad or
+	// via, as a fraction of the size of the pad or via.
+	LengthRatio float64 `json:"length_ratio"`
+	// WidthRatio is the width of a teardrop where it meets its pad or via,
+	// as a fraction of the size of the pad or via.
+	WidthRatio float64 `json:"width_ratio"`
+	// MaxLength and MaxWidth limit the size of teardrops on large pads.
+	MaxLength float64 `json:"max_length"`
+	MaxWidth  float64 `json:"max_width"`
+	// CurveSegments is the number of segments approximating the curved
+	// sides of a teardrop. Sides are straight if it is zero.
+	CurveSegments int `json:"curve_segments"`
+	// SkipSMD skips tracks ending at SMD pads.
+	SkipSMD bool `json:"skip_smd,omitempty"`
+	// Fill fills the zones of the board once teardrops are added, so they
+	// are ready to plot and other zones keep clear of them.
+	Fill bool `json:"fill,omitempty"`
+}
+
+// DefaultOptions returns the options pcbnew uses for teardrops on round
+// pads and vias, with curved sides, filling the zones of the board.
+func DefaultOptions() Options {
+	return Options{LengthRatio: 0.5, WidthRatio: 1, MaxLength: 1, MaxWidth: 2, CurveSegments: 5, Fill: true}
+}
+
+// Remove removes the teardrops of a board, returning how many were
+// removed.
+func Remove(p *pcb.PCB) int {
+	var (
+		zones   []pcb.Zone
+		removed int
+	)
+	for _, z := range p.Zones {
+		if !z.IsKeepout && z.Priority == Priority {
+			removed++
+			continue
+		}
+		zones = append(zones, z)
+	}
+	p.Zones = zones
+	return removed
+}
+
+// Add adds a teardrop zone on the layer and net of each track which ends
+// in a pad or via of its net, replacing any teardrops already on the
+// board. It returns the number of teardrops added.
+func Add(p *pcb.PCB, opts Options) (int, error) {
+	if opts.LengthRatio <= 0 || opts.WidthRatio <= 0 {
+		return 0, fmt.Errorf("length and width ratios must be positive, got %v and %v", opts.LengthRatio, opts.WidthRatio)
+	}
+	if opts.MaxLength <= 0 || opts.MaxWidth <= 0 {
+		return 0, fmt.Errorf("maximum length and width must be positive, got %v and %v", opts.MaxLength, opts.MaxWidth)
+	}
+	if opts.CurveSegments < 0 {
+		return 0, fmt.Errorf("curve segments must not be negative, got %d", opts.CurveSegments)
+	}
+	Remove(p)
+
+	layers := map[string]int{}
+	for _, l := range p.Layers {
+		layers[l.Name] = l.Num
+	}
+	ends := collectEnds(p, opts.SkipSMD)
+	index := indexEnds(ends)
+	added := 0
+	for _, s := range p.Segments {
+		t, ok := s.(*pcb.Track)
+		if !ok || t.NetIndex == 0 {
+			continue
+		}
+		for _, i := range index.near(t.NetIndex, t.Start, t.End) {
+			e := &ends[i]
+			if !(e.inside(t.Start) || e.inside(t.End)) || !e.onLayer(layers, t.Layer) {
+				continue
+			}
+			poly := e.teardrop(t, opts)
+			if poly == nil {
+				continue
+			}
+			p.Zones = append(p.Zones, pcb.Zone{
+				NetNum:       t.NetIndex,
+				NetName:      p.Nets[t.NetIndex].Name,
+				Layers:       []string{t.Layer},
+				Tstamp:       "0",
+				Priority:     Priority,
+				Hatch:        pcb.ZoneHatch{Mode: "edge", Pitch: 0.508},
+				ConnectPads:  pcb.ZoneConnectPads{Mode: "yes", Clearance: p.EditorSetup.ZoneClearance},
+				Fill:         pcb.ZoneFill{Segments: 16, ThermalGap: 0.508, ThermalBridgeWidth: 0.508},
+				MinThickness: minThickness,
+				BasePolys:    [][]pcb.XY{poly},
+			})
+			added++
+		}
+	}
+
+	if opts.Fill {
+		drc.FillZones(p)
+	}
+	return added, nil
+}
+
+// end is a pad or via which tracks may end in.
+type end struct {
+	net    int
+	center pcb.XY
+	// size is the diameter of a via, or the smaller side of a pad.
+	size float64
+	// angle is the orientation of a pad.
+	angle float64
+	pad   *pcb.Pad
+	via   *pcb.Via
+}
+
+func collectEnds(p *pcb.PCB, skipSMD bool) []end {
+	var out []end
+	for _, s := range p.Segments {
+		if v, ok := s.(*pcb.Via); ok && v.NetIndex != 0 {
+			out = append(out, end{net: v.NetIndex, center: v.At, size: v.Size, via: v})
+		}
+	}
+	for i := range p.Modules {
+		m := &p.Modules[i]
+		for j := range m.Pads {
+			pad := &m.Pads[j]
+			if pad.NetNum == 0 || pad.Surface == pcb.SurfaceNPTH || (skipSMD && pad.Surface == pcb.SurfaceSMD) {
+				continue
+			}
+			pos := m.PadPosition(pad)
+			size := pad.Size.X
+			if pad.Shape != pcb.ShapeCircle {
+				size = math.Min(size, pad.Size.Y)
+			}
+			out = append(out, end{
+				net:    pad.NetNum,
+				center: pcb.XY{X: pos.X, Y: pos.Y}.Add(pad.DrillOffset.Rotate(pad.At.Z)),
+				size:   size,
+				angle:  pad.At.Z,
+				pad:    pad,
+			})
+		}
+	}
+	return out
+}
+
+// cellSize is the size of the cells of an endIndex, in millimeters.
+const cellSize = 1.0
+
+type cellKey struct {
+	net  int
+	x, y int64
+}
+
+// endIndex finds the pads and vias of a net at a point, by the cells of a
+// grid their copper overlaps.
+type endIndex map[cellKey][]int
+
+func cellOf(net int, p pcb.XY) cellKey {
+	return cellKey{net, int64(math.Floor(p.X / cellSize)), int64(math.Floor(p.Y / cellSize))}
+}
+
+func indexEnds(ends []end) endIndex {
+	ix := endIndex{}
+	for i, e := range ends {
+		r := math.Hypot(e.padSize().X, e.padSize().Y) / 2
+		lo := cellOf(e.net, pcb.XY{X: e.center.X - r, Y: e.center.Y - r})
+		hi := cellOf(e.net, pcb.XY{X: e.center.X + r, Y: e.center.Y + r})
+		for x := lo.x; x <= hi.x; x++ {
+			for y := lo.y; y <= hi.y; y++ {
+				k := cellKey{e.net, x, y}
+				ix[k] = append(ix[k], i)
+			}
+		}
+	}
+	return ix
+}
+
+// near returns the indices of the pads and vias of the net which may
+// contain any of the points, in ascending order.
+func (ix endIndex) near(net int, pts ...pcb.XY) []int {
+	var out []int
+	for _, p := range pts {
+		out = append(out, ix[cellOf(net, p)]...)
+	}
+	sort.Ints(out)
+	n := 0
+	for i, v := range out {
+		if i == 0 || v != out[n-1] {
+			out[n] = v
+			n++
+		}
+	}
+	return out[:n]
+}
+
+// onLayer reports whether the pad or via has copper on the layer, given
+// the numbers of the layers of the board.
+func (e *end) onLayer(layers map[string]int, layer string) bool {
+	if e.pad != nil {
+		for _, l := range e.pad.Layers {
+			if l == layer || l == "*.Cu" || (l == "F&B.Cu" && (layer == "F.Cu" || layer == "B.Cu")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(e.via.Layers) < 2 {
+		return len(e.via.Layers) == 1 && e.via.Layers[0] == layer
+	}
+	lo, hi := layers[e.via.Layers[0]], layers[e.via.Layers[1]]
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	n, ok := layers[layer]
+	return ok && n >= lo && n <= hi
+}
+
+// inside reports whether the point is within the copper of the pad or via.
+func (e *end) inside(at pcb.XY) bool {
+	if e.via != nil {
+		return at.Distance(e.center) <= e.size/2
+	}
+	l := pcb.XY{X: at.X - e.center.X, Y: at.Y - e.center.Y}.Rotate(-e.angle)
+	hx, hy := e.pad.Size.X/2, e.pad.Size.Y/2
+	switch e.pad.Shape {
+	case pcb.ShapeCircle:
+		return math.Hypot(l.X, l.Y) <= hx
+	case pcb.ShapeOval:
+		if hx > hy {
+			return math.Hypot(math.Max(math.Abs(l.X)-(hx-hy), 0), l.Y) <= hy
+		}
+		return math.Hypot(l.X, math.Max(math.Abs(l.Y)-(hy-hx), 0)) <= hx
+	case pcb.ShapeRoundRect:
+		r := e.pad.RoundRectRRatio * math.Min(e.pad.Size.X, e.pad.Size.Y)
+		return math.Hypot(math.Max(math.Abs(l.X)-(hx-r), 0), math.Max(math.Abs(l.Y)-(hy-r), 0)) <= r
+	default:
+		return math.Abs(l.X) <= hx && math.Abs(l.Y) <= hy
+	}
+}
+
+// teardrop returns the outline of the teardrop joining the track to the
+// pad or via, or nil if the track does not end in it or is too wide or
+// short for one.
+func (e *end) teardrop(t *pcb.Track, opts Options) []pcb.XY {
+	from, to := t.Start, t.End
+	if !e.inside(from) {
+		from, to = to, from
+	}
+	length := from.Distance(to)
+	if !e.inside(from) || e.inside(to) || length == 0 {
+		return nil
+	}
+	u := pcb.XY{X: (to.X - from.X) / length, Y: (to.Y - from.Y) / length}
+	n := pcb.XY{X: -u.Y, Y: u.X}
+
+	// The teardrop is centered on the point of the track closest to the
+	// center of the pad or via, unless that is outside it.
+	base := along(from, u, dot(pcb.XY{X: e.center.X - from.X, Y: e.center.Y - from.Y}, u))
+	if !e.inside(base) {
+		base = from
+	}
+	edge := e.exit(base, u)
+	avail := dot(pcb.XY{X: to.X - base.X, Y: to.Y - base.Y}, u)
+
+	width := math.Min(opts.WidthRatio*e.size, opts.MaxWidth)
+	l := math.Min(math.Min(opts.LengthRatio*e.size, opts.MaxLength), avail-edge)
+	if width <= t.Width || l < minLength {
+		return nil
+	}
+
+	tip := along(base, u, edge+l)
+	side := func(s float64) []pcb.XY {
+		p0 := along(tip, n, s*t.Width/2)
+		p2 := along(base, n, s*width/2)
+		if opts.CurveSegments == 0 {
+			return []pcb.XY{p0, p2}
+		}
+		// The sides follow the edge of the track up to the pad or via,
+		// then curve out to the full width.
+		c := along(along(base, u, edge), n, s*t.Width/2)
+		var out []pcb.XY
+		for i := 0; i <= opts.CurveSegments; i++ {
+			k := float64(i) / float64(opts.CurveSegments)
+			out = append(out, pcb.XY{
+				X: (1-k)*(1-k)*p0.X + 2*k*(1-k)*c.X + k*k*p2.X,
+				Y: (1-k)*(1-k)*p0.Y + 2*k*(1-k)*c.Y + k*k*p2.Y,
+			})
+		}
+		return out
+	}
+
+	left, right := side(1), side(-1)
+	poly := left
+	for i := len(right) - 1; i >= 0; i-- {
+		poly = append(poly, right[i])
+	}
+	for i := range poly {
+		poly[i] = pcb.XY{X: math.Round(poly[i].X*1e6) / 1e6, Y: math.Round(poly[i].Y*1e6) / 1e6}
+	}
+	return poly
+}
+
+// exit returns how far from the point, in the direction u, the edge of
+// the pad or via is.
+func (e *end) exit(from, u pcb.XY) float64 {
+	lo, hi := 0.0, math.Hypot(e.size, math.Max(e.padSize().X, e.padSize().Y))
+	for i := 0; i < 40; i++ {
+		mid := (lo + hi) / 2
+		if e.inside(along(from, u, mid)) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+func (e *end) padSize() pcb.XY {
+	if e.pad != nil {
+		return e.pad.Size
+	}
+	return pcb.XY{X: e.size, Y: e.size}
+}
+
+func along(p, d pcb.XY, k float64) pcb.XY {
+	return pcb.XY{X: p.X + d.X*k, Y: p.Y + d.Y*k}
+}
+
+func dot(a, b pcb.XY) float64 {
+	return a.X*b.X + a.Y*b.Y
+}
@@ -0,0 +1,187 @@
+package teardrop
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/twitchyliquid64/kcgen/drc"
+	"github.com/twitchyliquid64/kcgen/pcb"
+)
+
+func loadBoard(t *testing.T) *pcb.PCB {
+	t.Helper()
+	p, err := pcb.DecodeFile(filepath.Join("testdata", "teardrop.kicad_pcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func teardrops(p *pcb.PCB) []pcb.Zone {
+	var out []pcb.Zone
+	for _, z := range p.Zones {
+		if z.Priority == Priority {
+			out = append(out, z)
+		}
+	}
+	return out
+}
+
+func TestAdd(t *testing.T) {
+	p := loadBoard(t)
+	n, err := Add(p, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both ends of the two F.Cu SIG tracks, the via on B.Cu and the GND
+	// pad. The VCC track is wider than its pad, and the short SIG track
+	// is within its pad.
+	if n != 6 {
+		t.Errorf("Add() = %d, want 6", n)
+	}
+	var layers []string
+	for _, z := range teardrops(p) {
+		if len(z.Polys) != 1 {
+			t.Errorf("teardrop on %s of net %s has %d filled polygons, want 1", z.Layers[0], z.NetName, len(z.Polys))
+		}
+		layers = append(layers, z.Layers[0]+" "+z.NetName)
+	}
+	want := []string{"F.Cu SIG", "F.Cu SIG", "F.Cu SIG", "F.Cu SIG", "B.Cu SIG", "F.Cu GND"}
+	if !reflect.DeepEqual(layers, want) {
+		t.Errorf("teardrops on %v, want %v", layers, want)
+	}
+
+	// The teardrop of J1 pad 1, 1.7mm across, ends 0.85mm beyond its edge,
+	// where it is as wide as the track.
+	var far float64
+	for _, z := range teardrops(p) {
+		poly := z.BasePolys[0]
+		if z.Layers[0] == "F.Cu" && poly[len(poly)/2].Distance(pcb.XY{X: 5, Y: 10}) < 1 {
+			for _, pt := range poly {
+				far = math.Max(far, pt.Distance(pcb.XY{X: 5, Y: 10}))
+			}
+		}
+	}
+	if want := math.Hypot(1.7, 0.125); math.Abs(far-want) > 0.001 {
+		t.Errorf("teardrop of J1 pad 1 reaches %v from the pad, want %v", far, want)
+	}
+
+	if vs := drc.Check(p, drc.Options{}); len(vs) > 0 {
+		t.Errorf("Check() = %v, want no violations", vs)
+	}
+}
+
+func TestAddOptions(t *testing.T) {
+	p := loadBoard(t)
+	opts := DefaultOptions()
+	opts.SkipSMD = true
+	opts.CurveSegments = 0
+	opts.Fill = false
+	n, err := Add(p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("Add() = %d with SMD pads skipped, want 5", n)
+	}
+	for _, z := range teardrops(p) {
+		if got := len(z.BasePolys[0]); got != 4 {
+			t.Errorf("teardrop has %d points, want 4 for straight sides", got)
+		}
+		if len(z.Polys) != 0 {
+			t.Errorf("teardrop is filled, want it unfilled")
+		}
+	}
+}
+
+func TestAddReplaces(t *testing.T) {
+	p := loadBoard(t)
+	if _, err := Add(p, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	first := append([]pcb.Zone(nil), p.Zones...)
+	if _, err := Add(p, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Zones) != len(first) {
+		t.Fatalf("got %d zones after adding teardrops again, want %d", len(p.Zones), len(first))
+	}
+	for i := range first {
+		if !reflect.DeepEqual(first[i].BasePolys, p.Zones[i].BasePolys) {
+			t.Errorf("zone %d changed when teardrops were added again", i)
+		}
+	}
+
+	// Moving a track replaces its teardrops.
+	p.Segments[0].(*pcb.Track).End = pcb.XY{X: 5, Y: 5}
+	p.Segments[1].(*pcb.Track).Start = pcb.XY{X: 5, Y: 5}
+	if _, err := Add(p, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(teardrops(p)); got != 4 {
+		t.Errorf("got %d teardrops once the tracks no longer meet the via, want 4", got)
+	}
+
+	if got := Remove(p); got != 4 {
+		t.Errorf("Remove() = %d, want 4", got)
+	}
+	if len(p.Zones) != 1 {
+		t.Errorf("got %d zones after Remove(), want the GND zone", len(p.Zones))
+	}
+}
+
+// largeBoard returns a board of n vias on two nets, each with a track
+// leading away from it.
+func largeBoard(n int) *pcb.PCB {
+	p := &pcb.PCB{
+		Nets:         map[int]pcb.Net{0: {}, 1: {Name: "A"}, 2: {Name: "B"}},
+		LayersByName: map[string]*pcb.Layer{},
+	}
+	for _, l := range []*pcb.Layer{{Num: 0, Name: "F.Cu", Typ: "signal"}, {Num: 31, Name: "B.Cu", Typ: "signal"}} {
+		p.Layers = append(p.Layers, l)
+		p.LayersByName[l.Name] = l
+	}
+	for i := 0; i < n; i++ {
+		at := pcb.XY{X: float64(i%50) * 2, Y: float64(i/50) * 2}
+		p.Segments = append(p.Segments,
+			&pcb.Via{At: at, Size: 0.6, Drill: 0.3, Layers: []string{"F.Cu", "B.Cu"}, NetIndex: 1 + i%2},
+			&pcb.Track{Start: at, End: pcb.XY{X: at.X + 1.5, Y: at.Y}, Width: 0.25, Layer: "F.Cu", NetIndex: 1 + i%2},
+		)
+	}
+	return p
+}
+
+func TestAddLargeBoard(t *testing.T) {
+	p := largeBoard(3000)
+	opts := DefaultOptions()
+	opts.Fill = false
+	n, err := Add(p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3000 {
+		t.Errorf("Add() = %d, want 3000", n)
+	}
+}
+
+func TestAddErrors(t *testing.T) {
+	tcs := []struct {
+		name   string
+		modify func(*Options)
+		want   string
+	}{
+		{"length", func(o *Options) { o.LengthRatio = 0 }, "ratios must be positive"},
+		{"width", func(o *Options) { o.MaxWidth = -1 }, "maximum length and width must be positive"},
+		{"segments", func(o *Options) { o.CurveSegments = -1 }, "curve segments must not be negative"},
+	}
+	for _, tc := range tcs {
+		opts := DefaultOptions()
+		tc.modify(&opts)
+		if _, err := Add(loadBoard(t), opts); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: Add() error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}